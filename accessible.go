@@ -0,0 +1,123 @@
+package console
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/reeflective/readline"
+)
+
+// accessibleOptions are the shell options turned off in accessible mode, since
+// they redraw parts of the screen that screen readers would announce again.
+var accessibleOptions = []string{
+	"prompt-transient",
+	"transient-prompt",
+	"history-autosuggest",
+	"autocomplete",
+	"usage-hint-always",
+	"colored-stats",
+	"colored-completion-prefix",
+}
+
+// SetAccessible enables or disables the accessible mode of the console, for screen
+// readers and braille displays, which read the terminal output as a stream of lines:
+//
+//   - The input line, prompts and errors are printed without colors or highlighting,
+//     and right prompts, tooltips, hints and the status bar are not displayed.
+//   - Asynchronous messages (see TransientPrintf) are printed below the input line
+//     rather than above it, and the prompt is printed again after them: the lines
+//     already printed are never cleared or redrawn.
+//   - Completion candidates are printed as a plain numbered list, with their
+//     descriptions, and only their common prefix is inserted in the input line.
+//   - Transient prompts, history autosuggestions and autocompletion are disabled.
+//
+// The mode can also be enabled by users in their configuration (see Config).
+func (c *Console) SetAccessible(enabled bool) {
+	c.accessible.Store(enabled)
+
+	if enabled {
+		c.setupAccessible()
+		c.shell.Hint.ResetPersist()
+		c.shell.Hint.Reset()
+	}
+
+	c.clearCaches()
+}
+
+// Accessible returns true if the accessible mode of the console is enabled.
+func (c *Console) Accessible() bool {
+	return c.accessible.Load()
+}
+
+// setupAccessible turns off the shell options redrawing the screen, if the accessible
+// mode is enabled: this is done again each time the inputrc files are loaded.
+func (c *Console) setupAccessible() {
+	if !c.accessible.Load() {
+		return
+	}
+
+	for _, option := range accessibleOptions {
+		c.shell.Config.Set(option, false)
+	}
+}
+
+// announceCompletions prints the completion candidates matching the word being completed
+// as a numbered list below the input line, and returns their common prefix as the only
+// candidate to insert, in accessible mode. Single candidates are returned unchanged.
+func (c *Console) announceCompletions(comps readline.Completions, line []rune, pos int) readline.Completions {
+	word := typedWord(line, pos).prefix
+	ignoreCase := c.shell.Config.GetBool("completion-ignore-case")
+
+	var values []readline.Completion
+
+	comps.EachValue(func(comp readline.Completion) readline.Completion {
+		if len(comp.Value) >= len(word) && (comp.Value[:len(word)] == word ||
+			ignoreCase && strings.EqualFold(comp.Value[:len(word)], word)) {
+			values = append(values, comp)
+		}
+
+		return comp
+	})
+
+	if len(values) < 2 {
+		return comps
+	}
+
+	var list strings.Builder
+
+	fmt.Fprintf(&list, tr("%d candidates:")+"\n", len(values))
+
+	prefix := values[0].Value
+
+	for i, comp := range values {
+		display := comp.Display
+		if display == "" {
+			display = strings.TrimSpace(comp.Value)
+		}
+
+		if comp.Description != "" {
+			fmt.Fprintf(&list, "%d. %s: %s\n", i+1, strip(display), strip(comp.Description))
+		} else {
+			fmt.Fprintf(&list, "%d. %s\n", i+1, strip(display))
+		}
+
+		for !strings.HasPrefix(comp.Value, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+
+	for !utf8.ValidString(prefix) {
+		prefix = prefix[:len(prefix)-1]
+	}
+
+	c.printMutex.Lock()
+	c.shell.Printf("%s", strings.TrimSuffix(list.String(), "\n"))
+	c.printMutex.Unlock()
+
+	common := readline.CompleteRaw([]readline.Completion{{Value: prefix}}).NoSpace()
+	common.PREFIX = comps.PREFIX
+	common.SUFFIX = comps.SUFFIX
+
+	return common
+}
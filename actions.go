@@ -0,0 +1,192 @@
+package console
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/reeflective/readline/inputrc"
+)
+
+// Action is a console-level function that can be bound to a key sequence,
+// like any readline editing command. Unlike widgets, actions do not operate
+// on the input line but on the console itself (switching menus, toggling
+// some application state, etc). Actions are ran from within the readline
+// loop, and should thus use Console.Printf/TransientPrintf to print output.
+type Action func(c *Console)
+
+const (
+	// ActionSwitchMenuPrefix is the prefix of the actions registered for each
+	// console menu, which switch to the given menu when invoked. The default
+	// menu (with an empty name) is bound to the `switch-menu-main` action.
+	ActionSwitchMenuPrefix = "switch-menu-"
+
+	// ActionRunPrefix is the prefix of actions registered with RegisterCommandAction.
+	ActionRunPrefix = "run-"
+
+	// ActionClearScreen clears the screen and redraws the prompt at its top (or
+	// at the bottom of the screen, below the log panel, if enabled).
+	ActionClearScreen = "clear-screen-and-redraw"
+
+	// ActionToggleLogPanel enables the log panel if disabled, with the rows it
+	// had when last enabled (half the screen by default), or disables it.
+	ActionToggleLogPanel = "toggle-log-panel"
+)
+
+// configKeymaps are the keymaps in which the binds of the configuration are set.
+var configKeymaps = []string{"emacs", "emacs-standard", "vi-insert", "vi", "vi-command", "vi-move"}
+
+// configBind is a key sequence bound by the configuration in a keymap,
+// with the bind it replaced, restored when the configuration changes.
+type configBind struct {
+	keymap   string
+	seq      string
+	action   string
+	replaced inputrc.Bind
+	found    bool
+}
+
+// RegisterAction registers a console action under the given name, so that it
+// can be bound to key sequences with the bind command or in the inputrc file:
+//
+//	"\C-xp": toggle-log-panel
+//
+// After the action has ran, the prompt is redrawn, so that any state change
+// made by the action is immediately reflected in the prompt strings.
+func (c *Console) RegisterAction(name string, action Action) {
+	if name == "" || action == nil {
+		return
+	}
+
+	c.shell.Keymap.Register(map[string]func(){
+		name: func() {
+			action(c)
+			c.redrawPrompt()
+		},
+	})
+}
+
+// RegisterCommandAction registers an action named `run-<name>`, which executes
+// the given command line in the current menu, exactly like if the user had typed
+// it and pressed enter. The current input line is replaced by the command.
+func (c *Console) RegisterCommandAction(name, line string) {
+	if name == "" || strings.TrimSpace(line) == "" {
+		return
+	}
+
+	c.shell.Keymap.Register(map[string]func(){
+		ActionRunPrefix + name: func() {
+			c.shell.History.Save()
+			c.shell.Line().Set([]rune(line)...)
+			c.shell.Cursor().Set(len([]rune(line)))
+
+			if accept, found := c.shell.Keymap.Commands()["accept-line"]; found {
+				accept()
+			}
+		},
+	})
+}
+
+// registerMenuAction registers the action switching to the given menu.
+func (c *Console) registerMenuAction(name string) {
+	action := ActionSwitchMenuPrefix + name
+	if name == "" {
+		action = ActionSwitchMenuPrefix + "main"
+	}
+
+	c.shell.Keymap.Register(map[string]func(){
+		action: func() {
+			c.SwitchMenu(name)
+			c.redrawPrompt()
+		},
+	})
+}
+
+// setupActions registers the builtin console actions.
+func (c *Console) setupActions() {
+	c.RegisterAction(ActionClearScreen, func(c *Console) {
+		if !c.relayoutLogPanel() {
+			fmt.Printf(seqCursorPosFmt, 1, 1)
+			fmt.Print(seqClearScreen)
+		}
+	})
+
+	c.RegisterAction(ActionToggleLogPanel, func(c *Console) {
+		c.mutex.RLock()
+		enabled := c.logPanel != nil
+		rows := c.logPanelRows
+		c.mutex.RUnlock()
+
+		if enabled {
+			c.DisableLogPanel()
+		} else {
+			c.EnableLogPanel(rows)
+		}
+	})
+}
+
+// setupConfigBinds registers the command actions of the configuration, and binds
+// its key sequences in the main keymaps, on top of the inputrc files. The binds of
+// the previous configuration are restored first, unless the user changed them since.
+// It returns an error for the key sequences bound to unknown commands or actions.
+func (c *Console) setupConfigBinds() error {
+	c.mutex.Lock()
+	config := c.effectiveConfig()
+	previous := c.configBinds
+	c.configBinds = nil
+	c.mutex.Unlock()
+
+	for i := len(previous) - 1; i >= 0; i-- {
+		bind := previous[i]
+
+		switch {
+		case c.shell.Config.Binds[bind.keymap][bind.seq].Action != bind.action:
+		case bind.found:
+			c.shell.Config.Binds[bind.keymap][bind.seq] = bind.replaced
+		default:
+			delete(c.shell.Config.Binds[bind.keymap], bind.seq)
+		}
+	}
+
+	for name, line := range config.Actions {
+		c.RegisterCommandAction(name, line)
+	}
+
+	commands := c.shell.Keymap.Commands()
+
+	var binds []configBind
+
+	var unknown []string
+
+	for seq, action := range config.Binds {
+		if _, found := commands[action]; !found {
+			unknown = append(unknown, action)
+			continue
+		}
+
+		seq = inputrc.Unescape(seq)
+
+		for _, keymap := range configKeymaps {
+			if c.shell.Config.Binds[keymap] == nil {
+				c.shell.Config.Binds[keymap] = make(map[string]inputrc.Bind)
+			}
+
+			replaced, found := c.shell.Config.Binds[keymap][seq]
+			c.shell.Config.Binds[keymap][seq] = inputrc.Bind{Action: action}
+
+			binds = append(binds, configBind{keymap, seq, action, replaced, found})
+		}
+	}
+
+	c.mutex.Lock()
+	c.configBinds = binds
+	c.mutex.Unlock()
+
+	if len(unknown) > 0 {
+		slices.Sort(unknown)
+
+		return fmt.Errorf(tr("config: unknown actions: %s"), strings.Join(unknown, ", "))
+	}
+
+	return nil
+}
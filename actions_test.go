@@ -0,0 +1,86 @@
+package console
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigBinds(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "console.yaml")
+
+	write := func(config string) {
+		t.Helper()
+
+		if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := New("test")
+	binds := c.shell.Config.Binds
+	cleared, search := binds["emacs"]["\x0c"], binds["emacs"]["\x18s"]
+
+	write("binds:\n  \"\\\\C-l\": clear-screen-and-redraw\n  \"\\\\C-xs\": run-status\nactions:\n  status: status --all\n")
+
+	if err := c.LoadConfig(path); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, keymap := range configKeymaps {
+		if action := binds[keymap]["\x0c"].Action; action != ActionClearScreen {
+			t.Errorf("%s: C-l bound to %q", keymap, action)
+		}
+
+		if action := binds[keymap]["\x18s"].Action; action != "run-status" {
+			t.Errorf("%s: C-x s bound to %q", keymap, action)
+		}
+	}
+
+	if _, found := c.shell.Keymap.Commands()["run-status"]; !found {
+		t.Error("the run-status action is not registered")
+	}
+
+	// The binds replaced by the previous configuration are restored.
+	write("binds:\n  \"\\\\C-xs\": unknown-action\n")
+
+	if err := c.Reload(); err == nil {
+		t.Error("no error for a bind to an unknown action")
+	}
+
+	if bind := binds["emacs"]["\x0c"]; bind != cleared {
+		t.Errorf("C-l bound to %q, want %q", bind.Action, cleared.Action)
+	}
+
+	if bind := binds["emacs"]["\x18s"]; bind != search {
+		t.Errorf("C-x s bound to %q, want %q", bind.Action, search.Action)
+	}
+}
+
+func TestToggleLogPanel(t *testing.T) {
+	c := New("test")
+	toggle := c.shell.Keymap.Commands()[ActionToggleLogPanel]
+
+	c.EnableLogPanel(5)
+	c.DisableLogPanel()
+
+	toggle()
+
+	c.mutex.RLock()
+	panel := c.logPanel
+	c.mutex.RUnlock()
+
+	if panel == nil || panel.rows != 5 {
+		t.Fatalf("log panel = %+v, want 5 rows", panel)
+	}
+
+	toggle()
+
+	c.mutex.RLock()
+	panel = c.logPanel
+	c.mutex.RUnlock()
+
+	if panel != nil {
+		t.Error("the log panel is still enabled")
+	}
+}
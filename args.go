@@ -0,0 +1,107 @@
+package console
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// isNegativeNumber returns true if the word is a negative number (eg. -5, -0.5),
+// which should be used as a positional argument rather than as a shorthand flag.
+func isNegativeNumber(word string) bool {
+	if len(word) < 2 || word[0] != '-' || word[1] == '-' {
+		return false
+	}
+
+	_, err := strconv.ParseFloat(word[1:], 64)
+
+	return err == nil
+}
+
+// normalizeArgs prepares the arguments of a command line for the flag parser:
+// if some positional arguments are negative numbers (which the parser would take
+// as shorthand flags, unless the command has such a numeric shorthand flag), all
+// positional arguments are moved, in order, after a -- terminator. The arguments
+// already following a -- terminator are passed verbatim to the command.
+func (m *Menu) normalizeArgs(args []string) []string {
+	target, _, err := m.Command.Find(args)
+	if err != nil || target == nil || target.DisableFlagParsing {
+		return args
+	}
+
+	flags := pflag.NewFlagSet(target.Name(), pflag.ContinueOnError)
+	flags.AddFlagSet(target.Flags())
+	flags.AddFlagSet(target.InheritedFlags())
+
+	// The command names to skip, in order.
+	var path []*cobra.Command
+	for cmd := target; cmd != nil && cmd != m.Command; cmd = cmd.Parent() {
+		path = append([]*cobra.Command{cmd}, path...)
+	}
+
+	var (
+		head, positional, verbatim []string
+		negative, expectValue      bool
+	)
+
+parse:
+	for i, arg := range args {
+		switch {
+		case expectValue:
+			expectValue = false
+			head = append(head, arg)
+
+		case arg == "--":
+			verbatim = args[i+1:]
+
+			break parse
+
+		case isNegativeNumber(arg) && flags.ShorthandLookup(arg[1:2]) == nil:
+			negative = true
+			positional = append(positional, arg)
+
+		case strings.HasPrefix(arg, "--"):
+			name, _, hasValue := strings.Cut(arg[2:], "=")
+			if flag := flags.Lookup(name); flag != nil && !hasValue && flag.NoOptDefVal == "" {
+				expectValue = true
+			}
+
+			head = append(head, arg)
+
+		case strings.HasPrefix(arg, "-") && len(arg) > 1:
+			// Only the last of stacked short flags can take its value from the next word.
+			shorts := []rune(arg[1:])
+
+			for pos, short := range shorts {
+				flag := flags.ShorthandLookup(string(short))
+				if flag == nil || flag.NoOptDefVal != "" {
+					continue
+				}
+
+				expectValue = pos == len(shorts)-1
+
+				break
+			}
+
+			head = append(head, arg)
+
+		case len(path) > 0 && (arg == path[0].Name() || path[0].HasAlias(arg)):
+			path = path[1:]
+			head = append(head, arg)
+
+		default:
+			positional = append(positional, arg)
+		}
+	}
+
+	if !negative {
+		return args
+	}
+
+	normalized := append(head, "--")
+	normalized = append(normalized, positional...)
+
+	return append(normalized, verbatim...)
+}
@@ -0,0 +1,134 @@
+package console
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/reeflective/readline"
+	"github.com/reeflective/readline/inputrc"
+)
+
+// ErrAskAborted is returned by Console.Ask when the user aborts the question with Ctrl-C/Ctrl-D.
+var ErrAskAborted = errors.New("question aborted")
+
+// Question is a question asked to the user with Console.Ask.
+type Question struct {
+	// Label names what is asked for (eg. "theme", or "--name" for a flag).
+	Label string
+
+	// Usage describes the question, printed dimmed after the label, if not empty.
+	Usage string
+
+	// Choices are the only answers accepted, completed and printed
+	// after the label, if not empty.
+	Choices []string
+
+	// Default is the answer returned when the user enters nothing.
+	Default string
+
+	// Required questions must be given an answer, even if there is a default one.
+	Required bool
+
+	// Confirm asks a yes/no question ([y/N]): the answer is "true" if the user
+	// enters y, yes or true, and the default answer otherwise.
+	Confirm bool
+
+	// Validate checks an answer (not the default one), if not nil: the question
+	// is asked again with the error, if any.
+	Validate func(answer string) error
+}
+
+// Ask asks a question to the user, as a single line read with the inputrc
+// configuration of the application, until the answer is valid, and returns it.
+// Enter keeps the default answer, and Ctrl-C or Ctrl-D returns ErrAskAborted.
+// Ask must be called from a terminal, eg. from a command, or an OnStart hook.
+func (c *Console) Ask(question Question) (string, error) {
+	return c.ask(c.newAskShell(), question)
+}
+
+// newAskShell returns a shell reading the answers to questions, shared by those
+// asked in a row so that the history gives the previous answers.
+func (c *Console) newAskShell() *readline.Shell {
+	return readline.NewShell(inputrc.WithApp(strings.ToLower(c.name)))
+}
+
+// ask asks a question with the given shell, until the answer is valid.
+func (c *Console) ask(shell *readline.Shell, question Question) (string, error) {
+	choices := question.Choices
+	if question.Confirm {
+		choices = []string{"yes", "no"}
+	}
+
+	shell.Prompt.Primary(func() string { return c.questionPrompt(question) + ": " })
+	shell.Completer = func(line []rune, cursor int) readline.Completions {
+		return readline.CompleteValues(choices...)
+	}
+
+	for {
+		line, err := shell.Readline()
+		if err != nil {
+			return "", ErrAskAborted
+		}
+
+		answer, err := question.answer(strings.TrimSpace(line))
+		if err == nil {
+			return answer, nil
+		}
+
+		fmt.Printf("  %s%s%s %s\n", seqFgRed, tr("Invalid value:"), seqFgReset, err)
+	}
+}
+
+// questionPrompt returns the prompt of a question, with its choices and its default answer.
+func (c *Console) questionPrompt(question Question) string {
+	prompt := "  " + bold + c.Theme().Flag + question.Label + seqFgReset + boldReset
+
+	if question.Usage != "" {
+		prompt += " " + dim + question.Usage + dimReset
+	}
+
+	switch {
+	case question.Confirm:
+		prompt += " [y/N]"
+	case len(question.Choices) > 0:
+		prompt += " (" + strings.Join(question.Choices, "/") + ")"
+	}
+
+	if question.Default != "" && !question.Confirm {
+		prompt += " [" + question.Default + "]"
+	}
+
+	return prompt
+}
+
+// answer returns the answer to a question for the line entered by
+// the user, or an error if the line is not a valid answer.
+func (q Question) answer(line string) (string, error) {
+	switch {
+	case q.Confirm:
+		if slices.Contains([]string{"y", "yes", "true"}, strings.ToLower(line)) {
+			return "true", nil
+		}
+
+		return q.Default, nil
+
+	case line == "" && q.Required:
+		return "", errors.New(tr("a value is required"))
+
+	case line == "":
+		return q.Default, nil
+
+	case len(q.Choices) > 0 && !slices.Contains(q.Choices, line):
+		return "", fmt.Errorf(tr("%s is not one of %s"), line, strings.Join(q.Choices, ", "))
+	}
+
+	if q.Validate != nil {
+		if err := q.Validate(line); err != nil {
+			return "", err
+		}
+	}
+
+	return line, nil
+}
@@ -0,0 +1,58 @@
+package console
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestQuestionAnswer(t *testing.T) {
+	invalid := errors.New("invalid port")
+
+	tests := []struct {
+		name     string
+		question Question
+		line     string
+		want     string
+		fails    bool
+	}{
+		{"answer", Question{}, "value", "value", false},
+		{"default", Question{Default: "emacs"}, "", "emacs", false},
+		{"required", Question{Required: true, Default: "emacs"}, "", "", true},
+		{"choice", Question{Choices: []string{"emacs", "vi"}}, "vi", "vi", false},
+		{"not a choice", Question{Choices: []string{"emacs", "vi"}}, "nano", "", true},
+		{"confirmed", Question{Confirm: true}, "Yes", "true", false},
+		{"not confirmed", Question{Confirm: true}, "n", "", false},
+		{"valid", Question{Validate: func(string) error { return nil }}, "80", "80", false},
+		{"invalid", Question{Validate: func(string) error { return invalid }}, "http", "", true},
+		{"default not validated", Question{Default: "80", Validate: func(string) error { return invalid }}, "", "80", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			answer, err := test.question.answer(test.line)
+
+			if answer != test.want || (err != nil) != test.fails {
+				t.Errorf("answer(%q) = %q, %v, want %q (fails: %v)", test.line, answer, err, test.want, test.fails)
+			}
+		})
+	}
+}
+
+func TestQuestionPrompt(t *testing.T) {
+	c := New("test")
+
+	tests := []struct {
+		question Question
+		want     string
+	}{
+		{Question{Label: "theme", Choices: []string{"dark", "light"}, Default: "dark"}, "theme (dark/light) [dark]"},
+		{Question{Label: "--force", Usage: "skip checks", Confirm: true}, "--force skip checks [y/N]"},
+	}
+
+	for _, test := range tests {
+		if prompt := strings.TrimSpace(strip(c.questionPrompt(test.question))); prompt != test.want {
+			t.Errorf("prompt = %q, want %q", prompt, test.want)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package console
+
+import (
+	"time"
+)
+
+// AuditEvent is an event sent to the console audit sink, recording a command
+// that was executed by the user, or that the user was not allowed to execute.
+type AuditEvent struct {
+	Time    time.Time // Time at which the command was requested.
+	Menu    string    // Name of the menu in which the command was requested.
+	Command string    // Full path of the target command (eg. "root sub cmd").
+	Args    []string  // The command line arguments (including command names), with sensitive values redacted.
+	Denied  bool      // The command was not authorized.
+	Err     error     // Any error returned by the console or the command.
+}
+
+// SetAuditSink sets a function to be called with an audit event for each command
+// executed in the console (and for each denied command, see Console.SetAuthorizer).
+// The sink is called synchronously: it should avoid blocking for too long.
+func (c *Console) SetAuditSink(sink func(event AuditEvent)) {
+	c.mutex.Lock()
+	c.auditSink = sink
+	c.mutex.Unlock()
+}
+
+// audit sends an event to the audit sink, if any.
+func (c *Console) audit(menu *Menu, target string, args []string, denied bool, err error) {
+	if c.auditSink == nil {
+		return
+	}
+
+	c.auditSink(AuditEvent{
+		Time:    time.Now(),
+		Menu:    menu.name,
+		Command: target,
+		Args:    menu.RedactArgs(args),
+		Denied:  denied,
+		Err:     err,
+	})
+}
@@ -0,0 +1,113 @@
+package console
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// ErrUnauthorized is returned (wrapped) when the console authorizer has
+// denied the execution of a command. Use errors.Is() to check for it.
+var ErrUnauthorized = errors.New("permission denied")
+
+// SetAuthorizer sets a function deciding, for the current user or session,
+// whether a command is visible (in help and completions) and executable.
+// Commands for which the authorizer returns false are hidden along with
+// their subcommands, and any attempt to execute them is denied, and sent
+// to the console audit sink (see Console.SetAuditSink).
+//
+// The authorizer can use RequiredRoles(cmd) to get the roles or scopes that
+// the command (or any of its parents) declares with the CommandRolesKey
+// annotation, or use the RoleAuthorizer helper to check them against a set
+// of roles. A nil authorizer authorizes all commands.
+func (c *Console) SetAuthorizer(authorizer func(cmd *cobra.Command) bool) {
+	c.mutex.Lock()
+	c.authorizer = authorizer
+	c.mutex.Unlock()
+
+	c.clearCaches()
+}
+
+// RequiredRoles returns all the roles/scopes required by a command, that is, those
+// declared in its CommandRolesKey annotation and those declared by its parents.
+func RequiredRoles(cmd *cobra.Command) []string {
+	var roles []string
+
+	for ; cmd != nil; cmd = cmd.Parent() {
+		if cmd.Annotations == nil {
+			continue
+		}
+
+		for _, role := range strings.Split(cmd.Annotations[CommandRolesKey], ",") {
+			if role = strings.TrimSpace(role); role != "" {
+				roles = append(roles, role)
+			}
+		}
+	}
+
+	return roles
+}
+
+// RoleAuthorizer returns an authorizer (to be used with Console.SetAuthorizer)
+// granting the access to commands when all of their required roles are found
+// in the given list of roles. Commands not requiring any role are authorized.
+func RoleAuthorizer(roles ...string) func(cmd *cobra.Command) bool {
+	granted := make(map[string]bool, len(roles))
+	for _, role := range roles {
+		granted[role] = true
+	}
+
+	return func(cmd *cobra.Command) bool {
+		for _, role := range RequiredRoles(cmd) {
+			if !granted[role] {
+				return false
+			}
+		}
+
+		return true
+	}
+}
+
+// isAuthorized returns true if the command is authorized by the console authorizer.
+func (c *Console) isAuthorized(cmd *cobra.Command) bool {
+	c.mutex.RLock()
+	authorizer := c.authorizer
+	c.mutex.RUnlock()
+
+	if authorizer == nil || cmd == nil {
+		return true
+	}
+
+	return authorizer(cmd)
+}
+
+// checkAuthorized returns an error wrapping ErrUnauthorized if the command
+// or any of its parents is not authorized by the console authorizer.
+func (c *Console) checkAuthorized(cmd *cobra.Command) error {
+	for parent := cmd; parent != nil; parent = parent.Parent() {
+		if !c.isAuthorized(parent) {
+			return fmt.Errorf("%w: %s", ErrUnauthorized, cmd.CommandPath())
+		}
+	}
+
+	return nil
+}
+
+// hideUnauthorizedCommands hides all the commands, in the entire tree,
+// that are not authorized by the console authorizer.
+func (m *Menu) hideUnauthorizedCommands(root *cobra.Command) {
+	for _, cmd := range root.Commands() {
+		if cmd.Hidden {
+			continue
+		}
+
+		if !m.console.isAuthorized(cmd) {
+			cmd.Hidden = true
+			continue
+		}
+
+		m.hideUnauthorizedCommands(cmd)
+	}
+}
@@ -0,0 +1,126 @@
+package console
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+// authorizedMenu returns the menu of a console authorizing the ops role only, with commands
+// requiring no role, the ops role, and the admin role, and the commands which have been run.
+func authorizedMenu(t *testing.T) (*Menu, *[]string) {
+	t.Helper()
+
+	c := New("test")
+	menu := c.ActiveMenu()
+
+	var ran []string
+
+	run := func(cmd *cobra.Command, _ []string) { ran = append(ran, cmd.Name()) }
+
+	menu.SetCommands(func() *cobra.Command {
+		root := &cobra.Command{}
+
+		status := &cobra.Command{Use: "status", Run: run}
+
+		deploy := &cobra.Command{Use: "deploy", Run: run, Annotations: map[string]string{CommandRolesKey: "ops"}}
+
+		admin := &cobra.Command{Use: "admin", Annotations: map[string]string{CommandRolesKey: "admin"}}
+		admin.AddCommand(&cobra.Command{Use: "reset", Run: run})
+
+		root.AddCommand(status, deploy, admin)
+
+		return root
+	})
+
+	c.SetAuthorizer(RoleAuthorizer("ops"))
+	menu.resetPreRun()
+
+	return menu, &ran
+}
+
+func TestRequiredRoles(t *testing.T) {
+	menu, _ := authorizedMenu(t)
+
+	reset, _, _ := menu.Command.Find([]string{"admin", "reset"})
+	reset.Annotations = map[string]string{CommandRolesKey: " audit , ,"}
+
+	if roles := RequiredRoles(reset); !slices.Equal(roles, []string{"audit", "admin"}) {
+		t.Errorf("roles of admin reset = %q", roles)
+	}
+
+	if roles := RequiredRoles(menu.Command); roles != nil {
+		t.Errorf("roles of the root command = %q", roles)
+	}
+}
+
+func TestUnauthorizedHidden(t *testing.T) {
+	menu, _ := authorizedMenu(t)
+
+	var values []string
+	for _, value := range menu.console.completeArgs(menu, []string{""}).values {
+		values = append(values, value.Value)
+	}
+
+	if !slices.Contains(values, "status") || !slices.Contains(values, "deploy") {
+		t.Errorf("completions = %q, want the authorized commands", values)
+	}
+
+	if slices.Contains(values, "admin") {
+		t.Errorf("completions = %q, want no unauthorized command", values)
+	}
+
+	if admin, _, _ := menu.Command.Find([]string{"admin"}); !admin.Hidden {
+		t.Error("unauthorized command not hidden from help")
+	}
+
+	// Authorizing all commands shows them again, once the commands are regenerated.
+	menu.console.SetAuthorizer(nil)
+	menu.resetPreRun()
+
+	if admin, _, _ := menu.Command.Find([]string{"admin"}); admin.Hidden {
+		t.Error("command hidden without authorizer")
+	}
+}
+
+func TestUnauthorizedRefused(t *testing.T) {
+	menu, ran := authorizedMenu(t)
+
+	var events []AuditEvent
+
+	menu.console.SetAuditSink(func(event AuditEvent) { events = append(events, event) })
+
+	if err := menu.RunCommandLine(context.Background(), "deploy"); err != nil {
+		t.Fatalf("authorized command failed: %v", err)
+	}
+
+	// Subcommands of unauthorized commands are refused too.
+	err := menu.RunCommandLine(context.Background(), "admin reset")
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("unauthorized command returned %v, want ErrUnauthorized", err)
+	}
+
+	if !slices.Equal(*ran, []string{"deploy"}) {
+		t.Errorf("commands run = %q, want only deploy", *ran)
+	}
+
+	var denied []AuditEvent
+
+	for _, event := range events {
+		if event.Denied {
+			denied = append(denied, event)
+		}
+	}
+
+	if len(denied) != 1 || !strings.HasSuffix(denied[0].Command, "admin reset") || !errors.Is(denied[0].Err, ErrUnauthorized) {
+		t.Fatalf("denied audit events = %+v, want one for admin reset", denied)
+	}
+
+	if !slices.Equal(denied[0].Args, []string{"admin", "reset"}) {
+		t.Errorf("denied event args = %q", denied[0].Args)
+	}
+}
@@ -0,0 +1,231 @@
+package console
+
+import (
+	"strings"
+	"unicode"
+)
+
+// autoPairs maps the characters automatically paired (see Menu.AutoPairs) to their closers.
+var autoPairs = map[rune]rune{
+	'(':  ')',
+	'[':  ']',
+	'{':  '}',
+	'"':  '"',
+	'\'': '\'',
+}
+
+// setupAutopairs wraps the self-insert command so that it inserts the closing quote or
+// bracket when typing an opening one, in menus having automatic pairing enabled. This is
+// not done inside quoted strings nor after an escape, since the opening character is
+// then literal. Typing a closer when the cursor is already on it just moves past it.
+func (c *Console) setupAutopairs() {
+	selfInsert := c.shell.Keymap.Commands()["self-insert"]
+	if selfInsert == nil {
+		return
+	}
+
+	c.shell.Keymap.Register(map[string]func(){
+		"self-insert": func() {
+			if !c.autopairInsert(selfInsert) {
+				selfInsert()
+			}
+		},
+	})
+}
+
+// autopairInsert handles the insertion of the typed key if it is an opening or
+// closing pair character, returning false if the key should be inserted normally.
+func (c *Console) autopairInsert(selfInsert func()) bool {
+	// The readline builtin autopairs option is not tokenizer-aware, so it
+	// has precedence (rather than pairing twice), and we never act on the
+	// incremental search minibuffer.
+	if !c.activeMenu().AutoPairs || c.shell.Config.GetBool("autopairs") ||
+		c.shell.Keymap.Local() == "isearch" {
+		return false
+	}
+
+	keys := c.shell.Keys.Caller()
+	if len(keys) != 1 {
+		return false
+	}
+
+	key := keys[0]
+	line := *c.shell.Line()
+	pos := c.shell.Cursor().Pos()
+
+	var next rune
+	if pos < len(line) {
+		next = line[pos]
+	}
+
+	closer, isOpener := autoPairs[key]
+	quote, escaped := quoteAt(line, pos)
+
+	switch {
+	case escaped:
+		return false
+
+	case quote != 0 && key == quote && next == key:
+		// Closing the current quoted string, on its closing quote.
+		c.shell.History.SkipSave()
+		c.shell.Cursor().Inc()
+
+	case quote != 0:
+		return false
+
+	case isOpener && (next == 0 || unicode.IsSpace(next) || strings.ContainsRune(")]}", next)):
+		selfInsert()
+		c.shell.Line().Insert(c.shell.Cursor().Pos(), closer)
+
+	case !isOpener && strings.ContainsRune(")]}", key) && next == key:
+		c.shell.History.SkipSave()
+		c.shell.Cursor().Inc()
+
+	default:
+		return false
+	}
+
+	return true
+}
+
+// isEmptyPair returns true if the cursor is between an automatically
+// inserted pair of characters, which should be deleted together.
+func isEmptyPair(line []rune, cursor int) bool {
+	if cursor == 0 || cursor >= len(line) {
+		return false
+	}
+
+	closer, isOpener := autoPairs[line[cursor-1]]
+	if !isOpener || line[cursor] != closer {
+		return false
+	}
+
+	quote, escaped := quoteAt(line, cursor-1)
+
+	return quote == 0 && !escaped
+}
+
+// highlightPair highlights the quote or bracket under (or immediately before)
+// the cursor along with its matching one, in menus having automatic pairing.
+func (c *Console) highlightPair(input []rune, line string) string {
+	c.pairShown = false
+
+	if !c.activeMenu().AutoPairs || c.shell.Keymap.Local() == "isearch" {
+		return line
+	}
+
+	pairs := matchingPairs(input)
+	pos := c.shell.Cursor().Pos()
+
+	for _, start := range []int{pos, pos - 1} {
+		if end, found := pairs[start]; found {
+			c.pairShown = true
+			return highlightRunes(line, start, end)
+		}
+	}
+
+	return line
+}
+
+// quoteAt returns the quote of the string in which the given position is,
+// if any, and whether the character at this position is escaped.
+func quoteAt(line []rune, pos int) (quote rune, escaped bool) {
+	for i := 0; i < pos && i < len(line); i++ {
+		switch char := line[i]; {
+		case char == '\\' && quote != '\'':
+			if i+1 == pos {
+				return quote, true
+			}
+
+			i++
+		case quote != 0 && char == quote:
+			quote = 0
+		case quote == 0 && (char == '\'' || char == '"'):
+			quote = char
+		}
+	}
+
+	return quote, false
+}
+
+// matchingPairs returns the positions of all matched quotes and brackets
+// of the line, mapped to the position of their matching character.
+// Brackets inside quoted strings are not taken into account.
+func matchingPairs(line []rune) map[int]int {
+	pairs := make(map[int]int)
+
+	var brackets []int
+
+	var quote rune
+	quoteStart := -1
+
+	for i := 0; i < len(line); i++ {
+		char := line[i]
+
+		switch {
+		case char == '\\' && quote != '\'':
+			i++
+		case quote != 0:
+			if char == quote {
+				pairs[quoteStart], pairs[i] = i, quoteStart
+				quote = 0
+			}
+		case char == '\'' || char == '"':
+			quote, quoteStart = char, i
+		case strings.ContainsRune("([{", char):
+			brackets = append(brackets, i)
+		case strings.ContainsRune(")]}", char):
+			if len(brackets) == 0 || autoPairs[line[brackets[len(brackets)-1]]] != char {
+				continue
+			}
+
+			start := brackets[len(brackets)-1]
+			brackets = brackets[:len(brackets)-1]
+			pairs[start], pairs[i] = i, start
+		}
+	}
+
+	return pairs
+}
+
+// highlightRunes highlights the characters of an already highlighted line at the given
+// positions, which are those of the line runes, not counting its escape sequences.
+func highlightRunes(line string, positions ...int) string {
+	var highlighted strings.Builder
+
+	runes := []rune(line)
+	pos := 0
+
+	for i := 0; i < len(runes); i++ {
+		// Copy escape sequences as is.
+		if runes[i] == '\x1b' && i+1 < len(runes) && runes[i+1] == '[' {
+			end := i + 2
+			for end < len(runes) && (runes[end] < 0x40 || runes[end] > 0x7e) {
+				end++
+			}
+
+			highlighted.WriteString(string(runes[i:min(end+1, len(runes))]))
+			i = end
+
+			continue
+		}
+
+		match := false
+
+		for _, position := range positions {
+			if position == pos {
+				match = true
+			}
+		}
+
+		if match {
+			highlighted.WriteString(reverse + string(runes[i]) + reverseReset)
+		} else {
+			highlighted.WriteRune(runes[i])
+		}
+
+		pos++
+	}
+
+	return highlighted.String()
+}
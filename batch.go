@@ -0,0 +1,119 @@
+package console
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ResourceQuery is a query for the resources of a kind, matching filters (see ResourceCompleter).
+type ResourceQuery struct {
+	Kind    string
+	Filters map[string]string
+}
+
+// BatchResourceCompleter lists the resources of several queries at once, with a single
+// request over the transport of the application (eg. a gRPC call to a remote server),
+// returning the resources of each query, in order.
+type BatchResourceCompleter interface {
+	ListBatch(ctx context.Context, queries []ResourceQuery) ([][]Candidate, error)
+}
+
+// BatchResources returns a resource completer grouping the queries made within a short
+// window (eg. 20ms) in a single batch request, so as to keep completions responsive over
+// high-latency links: the completions of a line and the resources being prefetched (see
+// PrefetchResources) are then listed in one round trip. Identical queries are only made
+// once per batch. It is usually wrapped with CacheResources.
+func BatchResources(batcher BatchResourceCompleter, window time.Duration) ResourceCompleter {
+	return &resourceBatcher{
+		batcher: batcher,
+		window:  window,
+	}
+}
+
+// PrefetchResources lists resources in the background, so that they are cached when they
+// are first completed, if the resource completer caches them (see CacheResources). With
+// a batching resource completer, the queries are made in a single request (see BatchResources).
+func PrefetchResources(resources ResourceCompleter, queries ...ResourceQuery) {
+	for _, query := range queries {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+			defer cancel()
+
+			_, _ = resources.List(ctx, query.Kind, query.Filters)
+		}()
+	}
+}
+
+// resourceBatcher is a resource completer grouping queries in batches.
+type resourceBatcher struct {
+	batcher BatchResourceCompleter
+	window  time.Duration
+	pending *resourceBatch // Queries waiting for the batch to be sent.
+	mutex   sync.Mutex
+}
+
+// resourceBatch is a batch of queries, and their resources once listed.
+type resourceBatch struct {
+	queries []ResourceQuery
+	indexes map[string]int // Queries by key, not to repeat them.
+	results [][]Candidate
+	err     error
+	done    chan struct{} // Closed once the resources are listed.
+}
+
+// List adds the query to the pending batch (starting a new one if needed),
+// and returns its resources once the batch has been sent.
+func (b *resourceBatcher) List(ctx context.Context, kind string, filters map[string]string) ([]Candidate, error) {
+	key := resourceKey(kind, filters)
+
+	b.mutex.Lock()
+
+	batch := b.pending
+	if batch == nil {
+		batch = &resourceBatch{
+			indexes: make(map[string]int),
+			done:    make(chan struct{}),
+		}
+		b.pending = batch
+
+		time.AfterFunc(b.window, func() { b.send(batch) })
+	}
+
+	index, found := batch.indexes[key]
+	if !found {
+		index = len(batch.queries)
+		batch.indexes[key] = index
+		batch.queries = append(batch.queries, ResourceQuery{Kind: kind, Filters: filters})
+	}
+
+	b.mutex.Unlock()
+
+	select {
+	case <-batch.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	if batch.err != nil || index >= len(batch.results) {
+		return nil, batch.err
+	}
+
+	return batch.results[index], nil
+}
+
+// send lists the resources of the batch queries, after which no query
+// can be added to it, and wakes up those waiting for them.
+func (b *resourceBatcher) send(batch *resourceBatch) {
+	b.mutex.Lock()
+	if b.pending == batch {
+		b.pending = nil
+	}
+	b.mutex.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+	defer cancel()
+
+	batch.results, batch.err = b.batcher.ListBatch(ctx, batch.queries)
+	close(batch.done)
+}
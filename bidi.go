@@ -0,0 +1,85 @@
+package console
+
+import (
+	"unicode"
+)
+
+// isRTL returns true if the rune belongs to a right-to-left script.
+func isRTL(r rune) bool {
+	return unicode.In(r, unicode.Hebrew, unicode.Arabic, unicode.Syriac, unicode.Thaana, unicode.Nko)
+}
+
+// visualOrder returns the string with all its right-to-left runs (sequences of
+// RTL characters, possibly separated by spaces) reversed, so that they display
+// in their correct visual order on a left-to-right terminal. Numbers and other
+// weak characters break runs and keep their order, and ANSI escape sequences
+// are left untouched. The width of the string is not modified.
+func visualOrder(str string) string {
+	runes := []rune(str)
+	ordered := make([]rune, 0, len(runes))
+
+	for pos := 0; pos < len(runes); {
+		// Copy any escape sequence as is.
+		if runes[pos] == '\x1b' {
+			end := escapeEnd(runes, pos)
+			ordered = append(ordered, runes[pos:end]...)
+			pos = end
+
+			continue
+		}
+
+		if !isRTL(runes[pos]) {
+			ordered = append(ordered, runes[pos])
+			pos++
+
+			continue
+		}
+
+		// Find the end of the RTL run, including inner spaces.
+		end, last := pos, pos
+
+		for end < len(runes) && (isRTL(runes[end]) || runes[end] == ' ') {
+			if isRTL(runes[end]) {
+				last = end
+			}
+			end++
+		}
+
+		for i := last; i >= pos; i-- {
+			ordered = append(ordered, runes[i])
+		}
+
+		pos = last + 1
+	}
+
+	return string(ordered)
+}
+
+// escapeEnd returns the position following the escape sequence starting at pos.
+func escapeEnd(runes []rune, pos int) int {
+	end := pos + 1
+	if end < len(runes) && runes[end] == '[' {
+		end++
+
+		for end < len(runes) && (runes[end] < 0x40 || runes[end] > 0x7e) {
+			end++
+		}
+	}
+
+	if end < len(runes) {
+		end++
+	}
+
+	return end
+}
+
+// hasRTL returns true if the string contains any right-to-left character.
+func hasRTL(str string) bool {
+	for _, r := range str {
+		if isRTL(r) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,168 @@
+package console
+
+import (
+	"context"
+	"strings"
+)
+
+// breadcrumbSeparator separates the menus of the breadcrumb, in ContextStatus.
+const breadcrumbSeparator = " › "
+
+// ContextValue is a value set by the application to describe its current
+// context to the user (eg. the target host, or the selected database).
+type ContextValue struct {
+	Name  string
+	Value string
+}
+
+// displayContext is the menu path and the context values of the
+// console, passed to the commands being executed through their context.
+type displayContext struct {
+	path   []string
+	values []ContextValue
+}
+
+// displayContextKey is the key of the display context in command contexts.
+type displayContextKey struct{}
+
+// SetContextValue sets a context value (eg. SetContextValue("target", "10.0.0.5")),
+// or deletes it if the value is empty. Context values are shown by ContextStatus,
+// which can be used in prompts and as a status bar segment, and are included in the
+// bind/config exports. The change listeners are notified (see OnContextChange), and
+// the prompt and status bar are redrawn if the user is typing a command.
+func (c *Console) SetContextValue(name, value string) {
+	c.mutex.Lock()
+
+	current, found := c.contextValues[name]
+
+	switch {
+	case value == "" && found:
+		delete(c.contextValues, name)
+
+		for i, existing := range c.contextNames {
+			if existing == name {
+				c.contextNames = append(c.contextNames[:i], c.contextNames[i+1:]...)
+				break
+			}
+		}
+	case value != "":
+		if !found {
+			c.contextNames = append(c.contextNames, name)
+		}
+
+		c.contextValues[name] = value
+	}
+
+	c.mutex.Unlock()
+
+	if current != value {
+		c.contextChanged()
+	}
+}
+
+// ContextValues returns the context values, in the order they have been set.
+func (c *Console) ContextValues() []ContextValue {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	values := make([]ContextValue, 0, len(c.contextNames))
+	for _, name := range c.contextNames {
+		values = append(values, ContextValue{Name: name, Value: c.contextValues[name]})
+	}
+
+	return values
+}
+
+// Breadcrumb returns the path of menus leading to the current one: the menus
+// switched to from the default one, in order. Switching to a menu already in
+// the path goes back to it, and switching to the default menu clears the path.
+func (c *Console) Breadcrumb() []string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return append([]string(nil), c.menuPath...)
+}
+
+// OnContextChange registers a function called when a context value
+// has changed, or when the current menu (thus the breadcrumb) has.
+func (c *Console) OnContextChange(listener func()) {
+	c.mutex.Lock()
+	c.contextListeners = append(c.contextListeners, listener)
+	c.mutex.Unlock()
+}
+
+// ContextStatus returns the breadcrumb of the current menu, starting with the
+// name of the application, followed by the context values (eg. "app › db │
+// target=10.0.0.5"). It can be used in prompts, or as a status bar segment
+// with SetStatusSegment("context", console.ContextStatus).
+func (c *Console) ContextStatus() string {
+	status := strings.Join(append([]string{c.name}, c.Breadcrumb()...), breadcrumbSeparator)
+
+	for _, value := range c.ContextValues() {
+		status += statusSeparator + value.Name + "=" + value.Value
+	}
+
+	return status
+}
+
+// ContextFrom returns the menu path and the context values of the console
+// executing a command, from the command context (cmd.Context()), such as
+// for commands exporting the console configuration to describe it.
+func ContextFrom(ctx context.Context) (path []string, values []ContextValue) {
+	if ctx == nil {
+		return nil, nil
+	}
+
+	display, _ := ctx.Value(displayContextKey{}).(displayContext)
+
+	return display.path, display.values
+}
+
+// withDisplayContext returns a command context carrying the menu path and context values.
+func (c *Console) withDisplayContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, displayContextKey{}, displayContext{
+		path:   c.Breadcrumb(),
+		values: c.ContextValues(),
+	})
+}
+
+// enterMenu updates the menu path when switching to a menu.
+func (c *Console) enterMenu(name string) {
+	c.mutex.Lock()
+
+	switch {
+	case name == "":
+		c.menuPath = nil
+	default:
+		found := false
+
+		for i, entered := range c.menuPath {
+			if entered == name {
+				c.menuPath, found = c.menuPath[:i+1], true
+				break
+			}
+		}
+
+		if !found {
+			c.menuPath = append(c.menuPath, name)
+		}
+	}
+
+	c.mutex.Unlock()
+
+	c.contextChanged()
+}
+
+// contextChanged notifies the context listeners, and redraws the
+// prompt and status bar if the user is typing a command.
+func (c *Console) contextChanged() {
+	c.mutex.RLock()
+	listeners := append([]func(){}, c.contextListeners...)
+	c.mutex.RUnlock()
+
+	for _, listener := range listeners {
+		listener()
+	}
+
+	c.RefreshStatus()
+}
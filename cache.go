@@ -0,0 +1,122 @@
+package console
+
+import (
+	"container/list"
+	"sync"
+)
+
+// Default sizes of the completion and hint caches (see Console.CompletionCacheSize).
+const (
+	defaultCompletionCacheSize = 128
+	defaultHintCacheSize       = 256
+)
+
+// Stats are the counters of the console caches, as returned by Console.Stats.
+type Stats struct {
+	CompletionHits    uint64 // Completions found in the cache.
+	CompletionMisses  uint64 // Completions generated by the completion engine.
+	CompletionEntries int    // Completions currently cached.
+	HintHits          uint64 // Command/flag hints found in the cache.
+	HintMisses        uint64 // Command/flag hints computed.
+	HintEntries       int    // Command/flag hints currently cached.
+}
+
+// lruCache is a size-bounded cache, evicting the least recently used entries first.
+type lruCache[V any] struct {
+	entries map[string]*list.Element
+	order   *list.List // Most recently used entries first.
+	hits    uint64
+	misses  uint64
+	mutex   sync.Mutex
+}
+
+// lruEntry is an entry of an LRU cache, in its usage order list.
+type lruEntry[V any] struct {
+	key   string
+	value V
+}
+
+func newLRUCache[V any]() *lruCache[V] {
+	return &lruCache[V]{
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// get returns the value cached for a key, if any, and counts a hit or a miss.
+func (l *lruCache[V]) get(key string) (value V, found bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	elem, found := l.entries[key]
+	if !found {
+		l.misses++
+		return value, false
+	}
+
+	l.hits++
+	l.order.MoveToFront(elem)
+
+	return elem.Value.(*lruEntry[V]).value, true
+}
+
+// add caches a value, evicting the least recently used entries beyond size.
+// Nothing is cached if size is zero or negative.
+func (l *lruCache[V]) add(key string, value V, size int) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if elem, found := l.entries[key]; found {
+		elem.Value.(*lruEntry[V]).value = value
+		l.order.MoveToFront(elem)
+	} else {
+		l.entries[key] = l.order.PushFront(&lruEntry[V]{key: key, value: value})
+	}
+
+	for l.order.Len() > max(size, 0) {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.entries, oldest.Value.(*lruEntry[V]).key)
+	}
+}
+
+// clear removes all the cached values, but not the counters.
+func (l *lruCache[V]) clear() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	clear(l.entries)
+	l.order.Init()
+}
+
+// stats returns the cache counters and its number of entries.
+func (l *lruCache[V]) stats() (hits, misses uint64, entries int) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.hits, l.misses, l.order.Len()
+}
+
+// Stats returns the hit/miss counters and the number of entries of the completion
+// and hint caches, for instance to monitor long-lived sessions, or to tune the sizes
+// of the caches (see Console.CompletionCacheSize and Console.HintCacheSize).
+func (c *Console) Stats() Stats {
+	var stats Stats
+
+	stats.CompletionHits, stats.CompletionMisses, stats.CompletionEntries = c.completionCache.stats()
+	stats.HintHits, stats.HintMisses, stats.HintEntries = c.hintCache.stats()
+
+	return stats
+}
+
+// clearCaches forgets the cached completions and hints, which are only valid
+// while the same command tree is used, and the console state does not change.
+func (c *Console) clearCaches() {
+	c.completionCache.clear()
+	c.hintCache.clear()
+}
+
+// cacheKey returns the cache key of an input line in a menu, up to the cursor.
+func cacheKey(menu *Menu, line []rune, pos int) string {
+	return menu.name + "\x00" + string(line[:min(max(pos, 0), len(line))])
+}
@@ -0,0 +1,127 @@
+package console
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// teeFlag is the option accepted by all commands not having a flag with the
+// same name, copying their output to a file (eg. `scan --tee scan.log`).
+const teeFlag = "tee"
+
+// CaptureOutput runs fn while capturing everything it writes to the standard output
+// and error streams: the output is still displayed as it is written, but is also
+// copied to each of the writers (files, buffers, etc), and returned once fn is done.
+// This is how the --tee <file> option, accepted by all commands (unless they have
+// their own tee flag), copies the output of a command to a file.
+//
+// Note that the standard streams are not terminals while they are captured: programs
+// checking for it (to print colors, for instance) might print differently.
+func (c *Console) CaptureOutput(fn func() error, writers ...io.Writer) (string, error) {
+	var output bytes.Buffer
+
+	err := redirectOutput(fn, true, append(writers, &output)...)
+
+	return output.String(), err
+}
+
+// captureOutput runs a function while redirecting the standard
+// output and error streams, and returns everything they received.
+func captureOutput(run func() error) (string, error) {
+	var output bytes.Buffer
+
+	err := redirectOutput(run, false, &output)
+
+	return output.String(), err
+}
+
+// redirectOutput runs a function while redirecting the standard output and error
+// streams to the writers, and if display is true, to the original standard output.
+func redirectOutput(run func() error, display bool, writers ...io.Writer) error {
+	restore, err := redirectStreams(display, writers...)
+	if err != nil {
+		return err
+	}
+
+	defer restore()
+
+	return run()
+}
+
+// redirectStreams redirects the standard output and error streams to the writers,
+// and if display is true, to the original standard output, until the returned
+// function is called: it restores them once everything written has been copied,
+// and can be called again (eg. by a command goroutine and the console loop).
+func redirectStreams(display bool, writers ...io.Writer) (restore func(), err error) {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+
+	// Both streams share the same pipe, so as to keep their order.
+	original, restoreStreams, err := swapStreams(writer)
+	if err != nil {
+		reader.Close()
+		writer.Close()
+
+		return nil, err
+	}
+
+	out := io.MultiWriter(writers...)
+	if display {
+		out = io.MultiWriter(original, out)
+	}
+
+	done := make(chan struct{})
+
+	go func() {
+		io.Copy(out, reader)
+		reader.Close()
+		close(done)
+	}()
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			restoreStreams(func() {
+				writer.Close()
+				<-done
+			})
+		})
+	}, nil
+}
+
+// teeArgs removes the --tee <file> option from the command line, if the target
+// command does not have a flag with the same name, and returns the file path.
+// Only words in flag position are options: the values of the other flags, and
+// the arguments following a -- terminator are passed verbatim to the command.
+func (m *Menu) teeArgs(args []string) (rest []string, file string) {
+	target, _, err := m.Command.Find(args)
+	if err != nil || target == nil || target.DisableFlagParsing ||
+		target.Flags().Lookup(teeFlag) != nil || target.InheritedFlags().Lookup(teeFlag) != nil {
+		return args, ""
+	}
+
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "--":
+			return append(rest, args[i:]...), file
+		case arg == "--"+teeFlag && i+1 < len(args):
+			file = args[i+1]
+			i++
+		case strings.HasPrefix(arg, "--"+teeFlag+"="):
+			file = strings.TrimPrefix(arg, "--"+teeFlag+"=")
+		case takesNextValue(target, arg) && i+1 < len(args):
+			rest = append(rest, arg, args[i+1])
+			i++
+		default:
+			rest = append(rest, arg)
+		}
+	}
+
+	return rest, file
+}
@@ -0,0 +1,20 @@
+//go:build !unix
+
+package console
+
+import "os"
+
+// swapStreams makes the standard output and error streams write to a file, by
+// replacing the os.Stdout and os.Stderr variables. It returns the original standard
+// output, and a function restoring the streams, which calls flush once restored.
+func swapStreams(writer *os.File) (original *os.File, restore func(flush func()), err error) {
+	stdout, stderr := os.Stdout, os.Stderr
+
+	os.Stdout, os.Stderr = writer, writer
+
+	return stdout, func(flush func()) {
+		os.Stdout, os.Stderr = stdout, stderr
+
+		flush()
+	}, nil
+}
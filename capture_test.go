@@ -0,0 +1,92 @@
+package console
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func TestTeeArgs(t *testing.T) {
+	c := New("test")
+	menu := c.ActiveMenu()
+
+	menu.SetCommands(func() *cobra.Command {
+		root := &cobra.Command{}
+
+		echo := &cobra.Command{Use: "echo", Run: func(*cobra.Command, []string) {}}
+		echo.Flags().StringP("message", "m", "", "message to print")
+		echo.Flags().BoolP("newline", "n", false, "print a newline")
+		root.AddCommand(echo)
+
+		return root
+	})
+
+	menu.resetPreRun()
+
+	tests := []struct {
+		args []string
+		rest []string
+		file string
+	}{
+		{[]string{"echo", "hi", "--tee", "out.log"}, []string{"echo", "hi"}, "out.log"},
+		{[]string{"echo", "--tee=out.log", "hi"}, []string{"echo", "hi"}, "out.log"},
+		{[]string{"echo", "--message", "--tee", "hi"}, []string{"echo", "--message", "--tee", "hi"}, ""},
+		{[]string{"echo", "-nm", "--tee"}, []string{"echo", "-nm", "--tee"}, ""},
+		{[]string{"echo", "-n", "--tee", "out.log"}, []string{"echo", "-n"}, "out.log"},
+		{[]string{"echo", "--", "--tee", "out.log"}, []string{"echo", "--", "--tee", "out.log"}, ""},
+	}
+
+	for _, test := range tests {
+		rest, file := menu.teeArgs(test.args)
+
+		if !slices.Equal(rest, test.rest) || file != test.file {
+			t.Errorf("teeArgs(%q) = %q, %q, want %q, %q", test.args, rest, file, test.rest, test.file)
+		}
+	}
+}
+
+func TestTeeRestoredWhenInterrupted(t *testing.T) {
+	c := New("test")
+	menu := c.ActiveMenu()
+
+	release := make(chan struct{})
+	defer close(release)
+
+	menu.SetCommands(func() *cobra.Command {
+		root := &cobra.Command{}
+
+		hang := &cobra.Command{Use: "hang", Run: func(*cobra.Command, []string) {
+			fmt.Println("started")
+			<-release
+		}}
+		SetTimeout(hang, 100*time.Millisecond)
+		root.AddCommand(hang)
+
+		return root
+	})
+
+	file := filepath.Join(t.TempDir(), "hang.log")
+
+	if err := menu.RunCommandLine(context.Background(), "hang --tee "+file); err != nil {
+		t.Fatal(err)
+	}
+
+	// Printed at the prompt, not by the command.
+	fmt.Println("prompt")
+	time.Sleep(50 * time.Millisecond)
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if output := string(data); output != "started\n" {
+		t.Errorf("tee file = %q, want the command output only", output)
+	}
+}
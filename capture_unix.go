@@ -0,0 +1,43 @@
+//go:build unix
+
+package console
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// swapStreams makes the standard output and error streams write to a file, at the
+// file descriptor level: the os.Stdout and os.Stderr variables are not modified, so
+// that they can be restored while commands using them are still running (eg. when
+// they are interrupted). It returns a file writing to the original standard output,
+// and a function restoring the streams, which calls flush before closing this file.
+func swapStreams(writer *os.File) (original *os.File, restore func(flush func()), err error) {
+	stdout, stderr := int(os.Stdout.Fd()), int(os.Stderr.Fd())
+
+	savedOut, err := unix.Dup(stdout)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	savedErr, err := unix.Dup(stderr)
+	if err != nil {
+		unix.Close(savedOut)
+		return nil, nil, err
+	}
+
+	unix.Dup2(int(writer.Fd()), stdout)
+	unix.Dup2(int(writer.Fd()), stderr)
+
+	original = os.NewFile(uintptr(savedOut), os.Stdout.Name())
+
+	return original, func(flush func()) {
+		unix.Dup2(savedOut, stdout)
+		unix.Dup2(savedErr, stderr)
+		unix.Close(savedErr)
+
+		flush()
+		original.Close()
+	}, nil
+}
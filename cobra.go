@@ -0,0 +1,280 @@
+package console
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// ErrAlreadyInConsole is returned by the console command added with HookCobra,
+// when it is executed from within the console it starts.
+var ErrAlreadyInConsole = errors.New("already in the console")
+
+// cobraTree is a command tree created once, (eg. by a third-party application),
+// instead of being generated again before each command line. Its state is reset
+// before each command line: flags values, and commands hidden by the console.
+type cobraTree struct {
+	console *Console
+	root    *cobra.Command
+	hidden  map[*cobra.Command]bool // Hidden state of the commands, when first seen.
+	flags   map[*pflag.Flag]func()  // Restore the flags to their state when first seen.
+}
+
+// FromCobra returns a console serving an existing cobra command tree, such as the
+// one of a third-party application, with completion, history, help, etc. The tree
+// is not generated again before each command line like with Menu.SetCommands, so
+// its flags state is reset instead: flags values are restored to the state they
+// had when the console was started, including the variables they are bound to, and
+// slice flags are not appended to across executions (unlike map flags, whose keys
+// set on a command line are kept). The console can be configured before being started.
+func FromCobra(root *cobra.Command) *Console {
+	app := New(root.Name())
+	app.ActiveMenu().SetCommands(app.newCobraTree(root).commands)
+
+	return app
+}
+
+// HookCobra adds a `console` command (aliased `repl`) to an existing cobra
+// application, starting the interactive console on the same command tree:
+// the application thus has both a one-shot mode (`app deploy --env prod`)
+// and an interactive mode (`app console`), from a single definition.
+// In the console, the commands are those of the root command, except the
+// console command itself. The returned console can be configured (prompts,
+// histories, etc) before the application root command is executed.
+// With `app console --stdin`, the command lines read from the standard input
+// are executed instead (see StartStream), optionally echoed (--echo), and
+// stopping at the first failing one (--stop-on-error).
+//
+// Like with FromCobra, flags are reset before each command line, to the
+// state they had when the console was started: persistent flags given to
+// the console command (eg. `app --verbose console`) thus hold in the console.
+func HookCobra(root *cobra.Command) *Console {
+	app := New(root.Name())
+
+	repl := &cobra.Command{
+		Use:     "console",
+		Aliases: []string{"repl"},
+		Short:   "Start an interactive console",
+		Args:    cobra.NoArgs,
+	}
+
+	repl.RunE = func(cmd *cobra.Command, _ []string) error {
+		if repl.Hidden {
+			return ErrAlreadyInConsole
+		}
+
+		// Not proposed in the console, whose errors are
+		// printed by the error handler, not by cobra.
+		repl.Hidden = true
+		silenced := root.SilenceErrors
+
+		defer func() {
+			repl.Hidden = false
+			root.SilenceErrors = silenced
+		}()
+
+		if stdin, _ := cmd.Flags().GetBool("stdin"); stdin {
+			echo, _ := cmd.Flags().GetBool("echo")
+			stop, _ := cmd.Flags().GetBool("stop-on-error")
+
+			// The error of the failing line is already printed by the console.
+			cmd.SilenceUsage, cmd.SilenceErrors = true, true
+
+			return app.StartStream(cmd.Context(), cmd.InOrStdin(), StreamOptions{Echo: echo, StopOnError: stop})
+		}
+
+		return app.StartContext(cmd.Context())
+	}
+
+	repl.Flags().Bool("stdin", false, "Execute the command lines read from stdin, without the shell")
+	repl.Flags().Bool("echo", false, "With --stdin, print each command line before its output")
+	repl.Flags().Bool("stop-on-error", false, "With --stdin, stop at the first failing command line")
+
+	root.AddCommand(repl)
+
+	app.ActiveMenu().SetCommands(app.newCobraTree(root).commands)
+
+	return app
+}
+
+// newCobraTree returns a static command tree, whose flags are reset by the console
+// until the tree is released.
+func (c *Console) newCobraTree(root *cobra.Command) *cobraTree {
+	tree := &cobraTree{
+		console: c,
+		root:    root,
+		hidden:  make(map[*cobra.Command]bool),
+		flags:   make(map[*pflag.Flag]func()),
+	}
+
+	c.mutex.Lock()
+	c.trees = append(c.trees, tree)
+	c.mutex.Unlock()
+
+	return tree
+}
+
+// release forgets the tree, when it is not used anymore.
+func (t *cobraTree) release() {
+	c := t.console
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for i, tree := range c.trees {
+		if tree == t {
+			c.trees = append(c.trees[:i], c.trees[i+1:]...)
+			break
+		}
+	}
+}
+
+// commands resets the tree and returns its root, for use with Menu.SetCommands.
+// The errors of the commands are returned to the menu error handler, which prints
+// them with their causes and hints, instead of being printed by cobra.
+func (t *cobraTree) commands() *cobra.Command {
+	t.reset(t.root)
+	t.root.SilenceErrors = true
+
+	return t.root
+}
+
+// reset restores the hidden state and the flags of a command and its subcommands,
+// or saves them if they are seen for the first time (the commands and flags added
+// by cobra, like the help ones, are only added when the tree is first executed).
+func (t *cobraTree) reset(cmd *cobra.Command) {
+	if hidden, found := t.hidden[cmd]; found {
+		cmd.Hidden = hidden
+	} else {
+		t.hidden[cmd] = cmd.Hidden
+
+		cmd.InitDefaultHelpFlag()
+		cmd.InitDefaultVersionFlag()
+	}
+
+	c := t.console
+
+	restoreFlag := func(flag *pflag.Flag) {
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+
+		// Flags not set since then are left as is: the flags
+		// of executed commands are all reset before execution.
+		if restore, found := t.flags[flag]; !found {
+			t.flags[flag] = snapshotFlag(flag)
+		} else if flag.Changed {
+			restore()
+		}
+	}
+
+	cmd.Flags().VisitAll(restoreFlag)
+	cmd.PersistentFlags().VisitAll(restoreFlag)
+
+	for _, sub := range cmd.Commands() {
+		t.reset(sub)
+	}
+}
+
+// forget drops the saved state of a command and its subcommands, once removed from
+// the tree: the commands replacing them are saved when first seen, like others.
+func (t *cobraTree) forget(cmd *cobra.Command) {
+	delete(t.hidden, cmd)
+
+	c := t.console
+
+	c.mutex.Lock()
+	cmd.LocalFlags().VisitAll(func(flag *pflag.Flag) {
+		delete(t.flags, flag)
+	})
+	c.mutex.Unlock()
+
+	for _, sub := range cmd.Commands() {
+		t.forget(sub)
+	}
+}
+
+// resetFlags resets the flags of the target command to their default values, either
+// with the state saved for static command trees, or with their default value.
+func (c *Console) resetFlags(target *cobra.Command) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	target.Flags().VisitAll(func(flag *pflag.Flag) {
+		for _, tree := range c.trees {
+			if restore, found := tree.flags[flag]; found {
+				restore()
+				return
+			}
+		}
+
+		resetFlagDefault(flag)
+	})
+}
+
+// snapshotFlag saves the current state of a flag, and returns a function restoring it,
+// with the exported pflag API only: slice values are restored with SliceValue.Replace,
+// and others from their string representation with Value.Set. Map values cannot be
+// emptied this way, so the keys set since then are kept, with their saved values.
+func snapshotFlag(flag *pflag.Flag) func() {
+	var restoreValue func()
+
+	switch value := flag.Value.(type) {
+	case pflag.SliceValue:
+		saved := value.GetSlice()
+		restoreValue = func() {
+			_ = value.Replace(saved)
+
+			// The value appends to its slice once it has been set.
+			if flag.Changed {
+				flag.Value = &restoredSlice{Value: flag.Value, SliceValue: value, saved: len(saved)}
+			}
+		}
+	default:
+		saved := flag.Value.String()
+		if strings.HasPrefix(flag.Value.Type(), "stringTo") {
+			saved = strings.TrimSuffix(strings.TrimPrefix(saved, "["), "]")
+		}
+
+		restoreValue = func() { _ = flag.Value.Set(saved) }
+	}
+
+	original := flag.Value
+	changed := flag.Changed
+
+	return func() {
+		flag.Value = original
+		restoreValue()
+		flag.Changed = changed
+	}
+}
+
+// restoredSlice is a slice flag value restored by the console, whose first values
+// set after being restored replace its slice, like those of a new value, instead
+// of being appended to it.
+type restoredSlice struct {
+	pflag.Value
+	pflag.SliceValue
+	saved   int  // Length of the restored slice.
+	appends bool // Values are appended, once set after being restored.
+}
+
+func (v *restoredSlice) Set(value string) error {
+	if v.appends {
+		return v.Value.Set(value)
+	}
+
+	if err := v.Value.Set(value); err != nil {
+		return err
+	}
+
+	v.appends = true
+
+	return v.SliceValue.Replace(v.GetSlice()[v.saved:])
+}
+
+func (v *restoredSlice) Replace(values []string) error {
+	v.saved = len(values)
+	return v.SliceValue.Replace(values)
+}
@@ -0,0 +1,86 @@
+package console
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestSilenceErrorsOfApplicationTrees(t *testing.T) {
+	c := New("test")
+	menu := c.ActiveMenu()
+
+	failing := errors.New("failed")
+
+	menu.SetCommands(func() *cobra.Command {
+		root := &cobra.Command{}
+		root.SetErr(io.Discard)
+		root.AddCommand(&cobra.Command{Use: "fail", RunE: func(*cobra.Command, []string) error { return failing }})
+
+		return root
+	})
+
+	if err := menu.RunCommandLine(context.Background(), "fail"); !errors.Is(err, failing) {
+		t.Fatalf("error = %v, want %v", err, failing)
+	}
+
+	if menu.Command.SilenceErrors {
+		t.Error("the errors of the application commands are silenced")
+	}
+}
+
+func TestSilenceErrorsOfWrappedTrees(t *testing.T) {
+	root := &cobra.Command{Use: "app"}
+	root.AddCommand(&cobra.Command{Use: "fail", RunE: func(*cobra.Command, []string) error { return errors.New("failed") }})
+
+	c := FromCobra(root)
+
+	if err := c.ActiveMenu().RunCommandLine(context.Background(), "fail"); err == nil {
+		t.Fatal("no error returned")
+	}
+
+	if !root.SilenceErrors {
+		t.Error("the errors of the wrapped tree are printed by cobra")
+	}
+}
+
+func TestResetStaticTreeFlags(t *testing.T) {
+	var (
+		tags    []string
+		verbose bool
+	)
+
+	root := &cobra.Command{Use: "app"}
+	deploy := &cobra.Command{Use: "deploy", Run: func(*cobra.Command, []string) {}}
+	deploy.Flags().StringSliceVar(&tags, "tag", []string{"base"}, "deployment tags")
+	deploy.Flags().BoolVar(&verbose, "verbose", false, "verbose output")
+	root.AddCommand(deploy)
+
+	menu := FromCobra(root).ActiveMenu()
+
+	tests := []struct {
+		line    string
+		tags    []string
+		verbose bool
+	}{
+		{"deploy --tag a --tag b --verbose", []string{"a", "b"}, true},
+		{"deploy", []string{"base"}, false},
+		{"deploy --tag c", []string{"c"}, false},
+		{"deploy --tag d,e --tag f", []string{"d", "e", "f"}, false},
+		{"deploy", []string{"base"}, false},
+	}
+
+	for _, test := range tests {
+		if err := menu.RunCommandLine(context.Background(), test.line); err != nil {
+			t.Fatalf("%s: %v", test.line, err)
+		}
+
+		if !slices.Equal(tags, test.tags) || verbose != test.verbose {
+			t.Errorf("%s: tags = %q, verbose = %v, want %q, %v", test.line, tags, verbose, test.tags, test.verbose)
+		}
+	}
+}
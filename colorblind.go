@@ -0,0 +1,224 @@
+package console
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// minColorDistance is the CIE76 distance under which two colors are considered too
+// close to be told apart: it is higher than usual, since terminal glyphs are small.
+const minColorDistance = 30
+
+// colorDeficiency is a color vision deficiency, simulated with the matrices of
+// Machado et al. (2009, full severity), applied to linear RGB values.
+type colorDeficiency struct {
+	name   string
+	matrix [3][3]float64
+}
+
+// colorDeficiencies are the most common color vision deficiencies.
+var colorDeficiencies = []colorDeficiency{
+	{"deuteranopia", [3][3]float64{
+		{0.367322, 0.860646, -0.227968},
+		{0.280085, 0.672501, 0.047413},
+		{-0.011820, 0.042940, 0.968881},
+	}},
+	{"protanopia", [3][3]float64{
+		{0.152286, 1.052583, -0.204868},
+		{0.114503, 0.786281, 0.099216},
+		{-0.003882, -0.048116, 1.051998},
+	}},
+	{"tritanopia", [3][3]float64{
+		{1.255528, -0.076749, -0.178779},
+		{-0.078411, 0.930809, 0.147602},
+		{0.004733, 0.691367, 0.303900},
+	}},
+}
+
+// ansiColors are the RGB values of the 16 basic ANSI colors, as in xterm.
+var ansiColors = [16][3]uint8{
+	{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0},
+	{0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+	{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0},
+	{92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+// CheckTheme returns warnings for the colors of a theme which differ, but would be
+// hard to tell apart for users with a common color vision deficiency (deuteranopia,
+// protanopia or tritanopia), by simulating it: commands and flags would not stand out
+// from each other in the input line. Fields without a foreground color are ignored.
+//
+// The "deuteranopia" and "protanopia" themes are designed for these deficiencies.
+func CheckTheme(theme Theme) []string {
+	fields := []struct {
+		name string
+		seq  string
+	}{
+		{tr("commands"), theme.Command},
+		{tr("flags"), theme.Flag},
+		{tr("hints"), theme.Hint},
+	}
+
+	var warnings []string
+
+	for i := range fields {
+		first, ok := ansiForeground(fields[i].seq)
+		if !ok {
+			continue
+		}
+
+		for j := i + 1; j < len(fields); j++ {
+			second, ok := ansiForeground(fields[j].seq)
+			if !ok || first == second {
+				continue
+			}
+
+			for _, deficiency := range colorDeficiencies {
+				if colorDistance(deficiency.simulate(first), deficiency.simulate(second)) >= minColorDistance {
+					continue
+				}
+
+				warnings = append(warnings, fmt.Sprintf(tr("%s and %s colors are hard to tell apart with %s"),
+					fields[i].name, fields[j].name, tr(deficiency.name)))
+			}
+		}
+	}
+
+	return warnings
+}
+
+// ansiForeground returns the RGB value of the last foreground color set by
+// the SGR sequences of a string, be them basic, 256 or 24-bit colors.
+func ansiForeground(seq string) (rgb [3]uint8, found bool) {
+	for _, part := range strings.Split(seq, "\x1b[")[1:] {
+		params, isSGR := strings.CutSuffix(part, "m")
+		if !isSGR {
+			continue
+		}
+
+		codes := strings.Split(params, ";")
+
+		for i := 0; i < len(codes); i++ {
+			code, err := strconv.Atoi(codes[i])
+			if err != nil {
+				continue
+			}
+
+			switch {
+			case code >= 30 && code <= 37:
+				rgb, found = ansiColors[code-30], true
+			case code >= 90 && code <= 97:
+				rgb, found = ansiColors[code-90+8], true
+			case code == 39:
+				found = false
+			case code == 38 && i+2 < len(codes) && codes[i+1] == "5":
+				if index, err := strconv.Atoi(codes[i+2]); err == nil && index >= 0 && index < 256 {
+					rgb, found = xtermColor(index), true
+				}
+
+				i += 2
+			case code == 38 && i+4 < len(codes) && codes[i+1] == "2":
+				for c := range rgb {
+					value, _ := strconv.Atoi(codes[i+2+c])
+					rgb[c] = uint8(min(max(value, 0), 255))
+				}
+
+				found = true
+				i += 4
+			}
+		}
+	}
+
+	return rgb, found
+}
+
+// xtermColor returns the RGB value of a color of the xterm 256-color palette.
+func xtermColor(index int) [3]uint8 {
+	switch {
+	case index < 16:
+		return ansiColors[index]
+	case index < 232:
+		index -= 16
+		level := func(l int) uint8 {
+			if l == 0 {
+				return 0
+			}
+
+			return uint8(55 + 40*l)
+		}
+
+		return [3]uint8{level(index / 36), level(index / 6 % 6), level(index % 6)}
+	default:
+		grey := uint8(8 + 10*(index-232))
+
+		return [3]uint8{grey, grey, grey}
+	}
+}
+
+// simulate returns a color as seen with the color vision deficiency.
+func (d colorDeficiency) simulate(rgb [3]uint8) [3]uint8 {
+	var linear, simulated [3]float64
+
+	for c := range rgb {
+		linear[c] = srgbToLinear(float64(rgb[c]) / 255)
+	}
+
+	for row := range simulated {
+		for col := range linear {
+			simulated[row] += d.matrix[row][col] * linear[col]
+		}
+	}
+
+	for c := range simulated {
+		rgb[c] = uint8(math.Round(linearToSrgb(min(max(simulated[c], 0), 1)) * 255))
+	}
+
+	return rgb
+}
+
+// colorDistance returns the CIE76 distance between two colors, in the Lab space.
+func colorDistance(first, second [3]uint8) float64 {
+	l1, a1, b1 := rgbToLab(first)
+	l2, a2, b2 := rgbToLab(second)
+
+	return math.Sqrt((l1-l2)*(l1-l2) + (a1-a2)*(a1-a2) + (b1-b2)*(b1-b2))
+}
+
+// rgbToLab converts a sRGB color to the CIE Lab space, with a D65 white point.
+func rgbToLab(rgb [3]uint8) (l, a, b float64) {
+	r := srgbToLinear(float64(rgb[0]) / 255)
+	g := srgbToLinear(float64(rgb[1]) / 255)
+	bl := srgbToLinear(float64(rgb[2]) / 255)
+
+	x := (0.4124*r + 0.3576*g + 0.1805*bl) / 0.95047
+	y := 0.2126*r + 0.7152*g + 0.0722*bl
+	z := (0.0193*r + 0.1192*g + 0.9505*bl) / 1.08883
+
+	f := func(t float64) float64 {
+		if t > 216.0/24389 {
+			return math.Cbrt(t)
+		}
+
+		return (24389.0/27*t + 16) / 116
+	}
+
+	return 116*f(y) - 16, 500 * (f(x) - f(y)), 200 * (f(y) - f(z))
+}
+
+func srgbToLinear(value float64) float64 {
+	if value <= 0.04045 {
+		return value / 12.92
+	}
+
+	return math.Pow((value+0.055)/1.055, 2.4)
+}
+
+func linearToSrgb(value float64) float64 {
+	if value <= 0.0031308 {
+		return value * 12.92
+	}
+
+	return 1.055*math.Pow(value, 1/2.4) - 0.055
+}
@@ -1,6 +1,9 @@
 package console
 
 import (
+	"maps"
+	"slices"
+
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 )
@@ -10,7 +13,16 @@ const (
 	// The value will be used as a filter to disable commands when the console
 	// calls the Filter("name") method on the console.
 	// The string value will be comma-splitted, with each split being a filter.
+	// It can also be a boolean expression over filters (eg. "windows && admin"),
+	// in which case the command is hidden when the expression is true. See the
+	// FilterExpr type for the supported syntax.
 	CommandFilterKey = "console-hidden"
+
+	// CommandRolesKey should be used as a key to in a cobra.Annotation map.
+	// The value is a comma-separated list of roles or scopes required to
+	// use the command (and its subcommands): these can be checked by the
+	// console authorizer (see Console.SetAuthorizer and RoleAuthorizer).
+	CommandRolesKey = "console-roles"
 )
 
 // Commands is a simple function a root cobra command containing an arbitrary tree
@@ -19,11 +31,72 @@ const (
 // each execution run, as well as each command completion invocation.
 type Commands func() *cobra.Command
 
+// PartialCommands is like Commands, but only builds the root commands named, or
+// whose group is named (by ID), when given names: the root command returned then
+// only needs to contain these commands (and their groups). See SetPartialCommands.
+type PartialCommands func(names ...string) *cobra.Command
+
 // SetCommands requires a function returning a tree of cobra commands to be used.
 func (m *Menu) SetCommands(cmds Commands) {
-	m.mutex.RLock()
-	defer m.mutex.RUnlock()
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 	m.cmds = cmds
+	m.partialCmds = nil
+	m.dirty = true
+	m.console.clearCaches()
+}
+
+// SetPartialCommands is like SetCommands, with a function able to build only some
+// of the root commands: in menus using IncrementalCommands, the commands named to
+// InvalidateCommands are then rebuilt alone, instead of with a whole new tree.
+func (m *Menu) SetPartialCommands(cmds PartialCommands) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.cmds = func() *cobra.Command { return cmds() }
+	m.partialCmds = cmds
+	m.dirty = true
+	m.console.clearCaches()
+}
+
+// InvalidateCommands marks commands of the menu as changed, so that they are
+// regenerated before the next command line, in menus using IncrementalCommands.
+// Names are those of root commands, or the IDs of command groups: only these
+// commands are replaced by those of a new tree (including the ones added with
+// AddCommands), and the rest of the kept tree is left as is. Without names, the
+// whole tree is regenerated, along with the commands added with AddCommands.
+//
+// The commands function of SetCommands returns a whole tree, which is still built for
+// commands not added with AddCommands, unlike those of SetPartialCommands: commands
+// changing often (eg. those of user scripts) are best added with AddCommands, each
+// function of which is called alone, or built by a partial commands function.
+func (m *Menu) InvalidateCommands(names ...string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	switch {
+	case len(names) == 0:
+		m.dirty = true
+	case m.invalid == nil:
+		m.invalid = make(map[string]bool)
+	}
+
+	for _, name := range names {
+		m.invalid[name] = true
+	}
+
+	m.console.clearCaches()
+}
+
+// AddCommands adds commands to the menu, in addition to those of its main commands
+// function (see SetCommands): each function is called when the command tree of the
+// menu is regenerated, and the root command it returns is added as a subcommand of
+// the menu root command. This is meant for commands registered at runtime, like those
+// of user scripts or plugins. Groups used by these commands are created if needed.
+func (m *Menu) AddCommands(cmds ...Commands) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.extraCmds = append(m.extraCmds, cmds...)
+	m.console.clearCaches()
 }
 
 // HideCommands - Commands, in addition to their menus, can be shown/hidden based
@@ -31,7 +104,14 @@ func (m *Menu) SetCommands(cmds Commands) {
 // be scattered around different groups, but, having all the filter "windows".
 // If "windows" is used as the argument here, all windows commands for the current
 // menu are subsequently hidden, until ShowCommands("windows") is called.
+// Commands whose filter annotation is an expression (eg. "windows && admin")
+// are hidden when their expression is true with the currently active filters.
 func (c *Console) HideCommands(filters ...string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.clearCaches()
+
 next:
 	for _, filt := range filters {
 		for _, filter := range c.filters {
@@ -51,8 +131,10 @@ next:
 // Use this function if you have previously called HideCommands("filter") and want
 // these commands to be available back under their respective menu.
 func (c *Console) ShowCommands(filters ...string) {
-	c.mutex.RLock()
-	defer c.mutex.RUnlock()
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.clearCaches()
 
 	updated := make([]string, 0)
 
@@ -75,7 +157,188 @@ next:
 	c.filters = updated
 }
 
-// resetFlagsDefaults resets all flags to their default values.
+// addExtraCommands adds the commands added at runtime to the menu root command.
+// Only those not built yet are built, when the command tree is kept.
+func (m *Menu) addExtraCommands() {
+	for i, cmds := range m.extraCmds {
+		if i == len(m.extraBuilt) {
+			m.extraBuilt = append(m.extraBuilt, cmds())
+		}
+
+		cmd := m.extraBuilt[i]
+		// Static trees (see FromCobra) still have the commands added before.
+		if cmd == nil || cmd.Parent() == m.Command {
+			continue
+		}
+
+		if cmd.GroupID != "" && !m.Command.ContainsGroup(cmd.GroupID) {
+			m.Command.AddGroup(&cobra.Group{ID: cmd.GroupID, Title: cmd.GroupID})
+		}
+
+		m.Command.AddCommand(cmd)
+	}
+}
+
+// regenerateCommands replaces the root commands of the kept tree named (or whose group
+// is named) with InvalidateCommands by those of a new tree, and rebuilds the invalid
+// commands added with AddCommands, which are added back by addExtraCommands.
+// Commands of lazy groups are not regenerated.
+func (m *Menu) regenerateCommands() {
+	invalid := func(cmd *cobra.Command) bool {
+		return m.invalid[cmd.Name()] || (cmd.GroupID != "" && m.invalid[cmd.GroupID])
+	}
+
+	kept := make(map[*cobra.Command]bool)
+	added := make(map[*cobra.Command]bool)
+
+	for _, group := range m.lazyGroups {
+		kept[group.cmd], added[group.cmd] = true, true
+	}
+
+	for _, cmd := range m.extraBuilt {
+		added[cmd] = true
+	}
+
+	var removed []*cobra.Command
+
+	// Names of added commands need no new tree.
+	pending := maps.Clone(m.invalid)
+
+	for i, cmd := range m.extraBuilt {
+		switch {
+		case cmd == nil:
+		case invalid(cmd):
+			removed = append(removed, cmd)
+			m.tree.forget(cmd)
+		default:
+			kept[cmd] = true
+			continue
+		}
+
+		// Functions which returned no command might return one now.
+		if m.extraBuilt[i] = m.extraCmds[i](); m.extraBuilt[i] != nil {
+			delete(pending, m.extraBuilt[i].Name())
+		}
+
+		if cmd != nil {
+			delete(pending, cmd.Name())
+		}
+	}
+
+	// Groups only containing added commands need no new tree either.
+	for name := range pending {
+		if m.onlyAddedCommands(name, added) {
+			delete(pending, name)
+		}
+	}
+
+	// Removing commands one by one is quadratic.
+	if len(removed) > 0 {
+		m.Command.RemoveCommand(removed...)
+	}
+
+	// Static trees (see FromCobra) are never replaced.
+	if m.cmds != nil && len(pending) > 0 {
+		fresh := m.freshCommands(slices.Sorted(maps.Keys(pending)))
+		if fresh != m.Command {
+			m.replaceCommands(fresh, func(cmd *cobra.Command) bool {
+				return invalid(cmd) && !kept[cmd]
+			})
+		}
+	}
+
+	m.invalid = nil
+}
+
+// freshCommands returns a new tree containing (at least) the root commands named,
+// or whose group is named: only these are built by partial commands functions.
+func (m *Menu) freshCommands(names []string) *cobra.Command {
+	if m.partialCmds != nil {
+		return m.partialCmds(names...)
+	}
+
+	return m.cmds()
+}
+
+// onlyAddedCommands returns true if a group ID (or command name) is that of a group only
+// containing commands added with AddCommands (or lazy groups), which are kept or rebuilt.
+func (m *Menu) onlyAddedCommands(name string, added map[*cobra.Command]bool) bool {
+	found := false
+
+	for _, cmd := range m.Command.Commands() {
+		if cmd.Name() == name || (cmd.GroupID == name && !added[cmd]) {
+			return false
+		}
+
+		found = found || cmd.GroupID == name
+	}
+
+	return found
+}
+
+// replaceCommands replaces the invalid root commands of the menu by those of a new tree.
+func (m *Menu) replaceCommands(fresh *cobra.Command, invalid func(cmd *cobra.Command) bool) {
+	var removed []*cobra.Command
+
+	for _, cmd := range m.Command.Commands() {
+		if invalid(cmd) {
+			removed = append(removed, cmd)
+			m.tree.forget(cmd)
+		}
+	}
+
+	if len(removed) > 0 {
+		m.Command.RemoveCommand(removed...)
+	}
+
+	if fresh == nil {
+		return
+	}
+
+	groups := make(map[string]*cobra.Group)
+
+	for _, group := range fresh.Groups() {
+		groups[group.ID] = group
+	}
+
+	for _, cmd := range fresh.Commands() {
+		if !invalid(cmd) {
+			continue
+		}
+
+		if cmd.GroupID != "" && !m.Command.ContainsGroup(cmd.GroupID) {
+			group := groups[cmd.GroupID]
+			if group == nil {
+				group = &cobra.Group{ID: cmd.GroupID, Title: cmd.GroupID}
+			}
+
+			m.Command.AddGroup(group)
+		}
+
+		m.Command.AddCommand(cmd)
+	}
+}
+
+// releaseCommands forgets the commands built for the menu, before regenerating them.
+func (m *Menu) releaseCommands() {
+	for _, cmd := range m.extraBuilt {
+		if cmd != nil && cmd.Parent() == m.Command {
+			m.Command.RemoveCommand(cmd)
+		}
+	}
+
+	m.extraBuilt = nil
+	m.invalid = nil
+
+	if m.tree != nil {
+		m.tree.release()
+		m.tree = nil
+	}
+
+	m.dirty = false
+}
+
+// resetFlagDefault resets a flag to its default value.
 //
 // Slice flags accumulate per execution (and do not reset),
 //
@@ -92,21 +355,19 @@ next:
 //
 //	If you run the command again with --comment "c" --comment "d" flags,
 //	you will get [a, b, c, d] instead of just [c, d].
-func resetFlagsDefaults(target *cobra.Command) {
-	target.Flags().VisitAll(func(flag *pflag.Flag) {
-		flag.Changed = false
-		switch value := flag.Value.(type) {
-		case pflag.SliceValue:
-			var res []string
-
-			if len(flag.DefValue) > 0 && flag.DefValue != "[]" {
-				res = append(res, flag.DefValue)
-			}
-
-			value.Replace(res)
+func resetFlagDefault(flag *pflag.Flag) {
+	flag.Changed = false
+	switch value := flag.Value.(type) {
+	case pflag.SliceValue:
+		var res []string
 
-		default:
-			flag.Value.Set(flag.DefValue)
+		if len(flag.DefValue) > 0 && flag.DefValue != "[]" {
+			res = append(res, flag.DefValue)
 		}
-	})
+
+		value.Replace(res)
+
+	default:
+		flag.Value.Set(flag.DefValue)
+	}
 }
@@ -0,0 +1,214 @@
+package console
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+// hugeCommands returns a commands function building a tree of groups
+// with as many root commands, each with subcommands and flags.
+func hugeCommands(groups, commands int, builds map[string]int) Commands {
+	partial := hugePartialCommands(groups, commands, builds)
+	return func() *cobra.Command { return partial() }
+}
+
+// hugePartialCommands is like hugeCommands, building only the commands named.
+func hugePartialCommands(groups, commands int, builds map[string]int) PartialCommands {
+	return func(names ...string) *cobra.Command {
+		root := &cobra.Command{}
+
+		for g := range groups {
+			group := fmt.Sprintf("group%d", g)
+			root.AddGroup(&cobra.Group{ID: group, Title: "Group " + group})
+
+			for i := range commands {
+				name := fmt.Sprintf("%s-cmd%d", group, i)
+				if len(names) > 0 && !slices.Contains(names, name) && !slices.Contains(names, group) {
+					continue
+				}
+
+				builds[name]++
+
+				cmd := &cobra.Command{Use: name, GroupID: group, Run: func(*cobra.Command, []string) {}}
+				cmd.Flags().String("name", "", "")
+				cmd.Flags().StringSlice("tags", nil, "")
+				cmd.AddCommand(&cobra.Command{Use: "list"}, &cobra.Command{Use: "show"})
+
+				root.AddCommand(cmd)
+			}
+		}
+
+		return root
+	}
+}
+
+func TestInvalidateCommands(t *testing.T) {
+	builds := make(map[string]int)
+
+	c := New("test")
+	menu := c.ActiveMenu()
+	menu.IncrementalCommands = true
+	menu.SetCommands(hugeCommands(2, 2, builds))
+
+	extraBuilds := 0
+
+	menu.AddCommands(func() *cobra.Command {
+		extraBuilds++
+		return &cobra.Command{Use: "plugin", GroupID: "plugins"}
+	})
+
+	script := false
+
+	menu.AddCommands(func() *cobra.Command {
+		if !script {
+			return nil
+		}
+
+		return &cobra.Command{Use: "script"}
+	})
+
+	menu.resetPreRun()
+
+	kept, _, _ := menu.Find([]string{"group0-cmd0"})
+	group, _, _ := menu.Find([]string{"group1-cmd0"})
+	plugin, _, _ := menu.Find([]string{"plugin"})
+
+	script = true
+
+	menu.InvalidateCommands("group0-cmd1", "group1", "plugin", "script")
+	menu.resetPreRun()
+
+	if cmd, _, _ := menu.Find([]string{"group0-cmd0"}); cmd != kept {
+		t.Error("a valid command is regenerated")
+	}
+
+	if cmd, _, _ := menu.Find([]string{"group1-cmd0"}); cmd == group || cmd.Name() != "group1-cmd0" {
+		t.Error("a command of an invalid group is not regenerated")
+	}
+
+	if cmd, _, _ := menu.Find([]string{"plugin"}); cmd == plugin || extraBuilds != 2 {
+		t.Errorf("extra commands built %d times, want an invalid one built again", extraBuilds)
+	}
+
+	if cmd, _, _ := menu.Find([]string{"script"}); cmd.Name() != "script" {
+		t.Error("an added command returned again is not added back")
+	}
+
+	if got := len(menu.Commands()); got != 6 {
+		t.Errorf("%d root commands, want 6", got)
+	}
+
+	if !menu.ContainsGroup("group1") || menu.Groups()[1].Title != "Group group1" {
+		t.Error("the groups of the regenerated commands are lost")
+	}
+
+	menu.InvalidateCommands()
+	menu.resetPreRun()
+
+	if cmd, _, _ := menu.Find([]string{"group0-cmd0"}); cmd == kept {
+		t.Error("the whole tree is not regenerated without names")
+	}
+}
+
+func TestInvalidatePartialCommands(t *testing.T) {
+	builds := make(map[string]int)
+
+	c := New("test")
+	menu := c.ActiveMenu()
+	menu.IncrementalCommands = true
+	menu.SetPartialCommands(hugePartialCommands(2, 2, builds))
+
+	menu.AddCommands(func() *cobra.Command {
+		return &cobra.Command{Use: "plugin", GroupID: "plugins"}
+	})
+
+	menu.resetPreRun()
+
+	invalid, _, _ := menu.Find([]string{"group0-cmd1"})
+
+	menu.InvalidateCommands("group0-cmd1", "group1", "plugins")
+	menu.resetPreRun()
+
+	want := map[string]int{"group0-cmd0": 1, "group0-cmd1": 2, "group1-cmd0": 2, "group1-cmd1": 2}
+	if !maps.Equal(builds, want) {
+		t.Errorf("commands built %v times, want only the invalid ones built again", builds)
+	}
+
+	if cmd, _, _ := menu.Find([]string{"group0-cmd1"}); cmd == invalid || cmd.Name() != "group0-cmd1" {
+		t.Error("an invalid command is not replaced")
+	}
+
+	if got := len(menu.Commands()); got != 5 {
+		t.Errorf("%d root commands, want 5", got)
+	}
+
+	// Groups of added commands only are rebuilt without the partial function.
+	menu.InvalidateCommands("plugins")
+	menu.resetPreRun()
+
+	if builds["group0-cmd0"] != 1 || builds["group1-cmd0"] != 2 {
+		t.Errorf("commands built %v times after invalidating added commands, want none built", builds)
+	}
+}
+
+// BenchmarkResetPreRun measures the command tree preparation before each command line,
+// with a tree of 600 root commands, 100 of which (the "plugins" group) are added with
+// AddCommands: regenerated each time, kept with IncrementalCommands, and kept with one
+// of its commands, or one of its groups, invalidated (and rebuilt alone by a partial
+// commands function).
+func BenchmarkResetPreRun(b *testing.B) {
+	benchmarks := []struct {
+		name        string
+		incremental bool
+		partial     bool
+		invalidate  []string
+	}{
+		{name: "regenerated"},
+		{name: "incremental", incremental: true},
+		{name: "invalid-command", incremental: true, invalidate: []string{"group0-cmd0"}},
+		{name: "invalid-added-command", incremental: true, invalidate: []string{"plugin0"}},
+		{name: "invalid-group", incremental: true, invalidate: []string{"group0"}},
+		{name: "invalid-added-group", incremental: true, invalidate: []string{"plugins"}},
+		{name: "invalid-partial-command", incremental: true, partial: true, invalidate: []string{"group0-cmd0"}},
+		{name: "invalid-partial-group", incremental: true, partial: true, invalidate: []string{"group0"}},
+	}
+
+	for _, bench := range benchmarks {
+		b.Run(bench.name, func(b *testing.B) {
+			menu := New("bench").ActiveMenu()
+			menu.IncrementalCommands = bench.incremental
+
+			if bench.partial {
+				menu.SetPartialCommands(hugePartialCommands(5, 100, make(map[string]int)))
+			} else {
+				menu.SetCommands(hugeCommands(5, 100, make(map[string]int)))
+			}
+
+			for i := range 100 {
+				menu.AddCommands(func() *cobra.Command {
+					cmd := &cobra.Command{Use: fmt.Sprintf("plugin%d", i), GroupID: "plugins"}
+					cmd.Flags().String("name", "", "")
+
+					return cmd
+				})
+			}
+
+			menu.resetPreRun()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for range b.N {
+				if len(bench.invalidate) > 0 {
+					menu.InvalidateCommands(bench.invalidate...)
+				}
+
+				menu.resetPreRun()
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/reeflective/console"
+)
+
+// Again returns a command re-running the last command line of the history (also usable
+// as `r`), or the last one starting with the given prefix if any. With the --edit flag,
+// the line is not executed but is used as the input line of the next prompt, so that it
+// can be modified before being executed. Commands whose sensitive values (see
+// console.MarkFlagSensitive) have been redacted in a previous session are not run.
+func Again(app *console.Console) *cobra.Command {
+	againCmd := &cobra.Command{
+		Use:     "again [prefix]",
+		Aliases: []string{"r"},
+		Short:   "Re-run the last command (or the last one starting with prefix)",
+		GroupID: "core",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			line := lastCommand(app.HistoryLines(), cmd, strings.Join(args, " "))
+			if line == "" {
+				return errors.New("no command to run again")
+			}
+
+			// The history lines have their sensitive values redacted.
+			menu := app.ActiveMenu()
+			unredacted, known := menu.UnredactLine(line)
+
+			if edit, _ := cmd.Flags().GetBool("edit"); edit {
+				if known {
+					line = unredacted
+				}
+
+				app.PrefillLine(line)
+
+				return nil
+			}
+
+			if !known {
+				return fmt.Errorf("the sensitive values of the command are not known: %s", line)
+			}
+
+			fmt.Fprintln(cmd.OutOrStdout(), line)
+
+			return menu.RunCommandLine(cmd.Context(), unredacted)
+		},
+	}
+
+	againCmd.Flags().BoolP("edit", "e", false, "Edit the command line before running it")
+
+	return againCmd
+}
+
+// lastCommand returns the most recent history line starting with
+// the prefix, which is not an invocation of the again command itself.
+func lastCommand(lines []string, again *cobra.Command, prefix string) string {
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := strings.TrimSpace(lines[i])
+		name, _, _ := strings.Cut(line, " ")
+
+		if line == "" || name == again.Name() || again.HasAlias(name) {
+			continue
+		}
+
+		if strings.HasPrefix(line, prefix) {
+			return line
+		}
+	}
+
+	return ""
+}
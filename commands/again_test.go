@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"github.com/reeflective/console"
+)
+
+func TestAgainReplaysSensitiveValues(t *testing.T) {
+	app := console.New("test")
+
+	var password string
+
+	app.ActiveMenu().SetCommands(func() *cobra.Command {
+		root := &cobra.Command{}
+		root.AddGroup(&cobra.Group{ID: "core", Title: "Core"})
+
+		login := &cobra.Command{
+			Use: "login",
+			Run: func(cmd *cobra.Command, _ []string) {
+				password, _ = cmd.Flags().GetString("password")
+			},
+		}
+		login.Flags().String("password", "", "")
+		console.MarkFlagSensitive(login, "password")
+
+		root.AddCommand(login, Again(app))
+
+		return root
+	})
+
+	// The command tree is built when running a command.
+	if err := app.ActiveMenu().RunCommandLine(context.Background(), "login"); err != nil {
+		t.Fatal(err)
+	}
+
+	history := app.Shell().History.Current()
+	history.Write("login --password secret")
+
+	if line, _ := history.GetLine(history.Len() - 1); line == "login --password secret" {
+		t.Fatal("the password is not redacted in the history")
+	}
+
+	if err := app.ActiveMenu().RunCommandLine(context.Background(), "again"); err != nil {
+		t.Fatal(err)
+	}
+
+	if password != "secret" {
+		t.Errorf("password = %q, want %q", password, "secret")
+	}
+}
+
+func TestAgainRefusesRedactedValues(t *testing.T) {
+	app := console.New("test")
+
+	app.ActiveMenu().SetCommands(func() *cobra.Command {
+		root := &cobra.Command{}
+		root.AddGroup(&cobra.Group{ID: "core", Title: "Core"})
+
+		login := &cobra.Command{Use: "login", Run: func(*cobra.Command, []string) {}}
+		login.Flags().String("password", "", "")
+		console.MarkFlagSensitive(login, "password")
+
+		root.AddCommand(login, Again(app))
+
+		return root
+	})
+
+	// Redacted in a previous session.
+	app.Shell().History.Current().Write("login --password ********")
+
+	err := app.ActiveMenu().RunCommandLine(context.Background(), "again")
+	if err == nil || !strings.Contains(err.Error(), "sensitive values") {
+		t.Errorf("error = %v, want an unknown sensitive values error", err)
+	}
+}
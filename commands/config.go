@@ -0,0 +1,95 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/reeflective/console"
+)
+
+// Config returns a command showing the configuration of the console loaded from its
+// file (see console.Console.LoadConfig), with the fields of the profile in use, and
+// selecting another profile of the file with `config use-profile <name>`. The colors
+// in use are checked for color blind users with `config check-colors`.
+func Config(app *console.Console) *cobra.Command {
+	configCmd := &cobra.Command{
+		Use:     "config",
+		Short:   "Show the configuration, or select a profile",
+		GroupID: "core",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			config := app.Config()
+			config.Profiles = nil
+
+			data, err := yaml.Marshal(config)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprint(cmd.OutOrStdout(), string(data))
+
+			return nil
+		},
+	}
+
+	profilesCmd := &cobra.Command{
+		Use:   "profiles",
+		Short: "List the profiles of the configuration",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, _ []string) {
+			current := app.Config().Profile
+
+			for _, name := range app.Profiles() {
+				if name == current {
+					fmt.Fprintf(cmd.OutOrStdout(), "* %s\n", name)
+				} else {
+					fmt.Fprintf(cmd.OutOrStdout(), "  %s\n", name)
+				}
+			}
+		},
+	}
+
+	useCmd := &cobra.Command{
+		Use:   "use-profile <name>",
+		Short: "Use a profile of the configuration",
+		Args:  cobra.ExactArgs(1),
+		// Errors are about the configuration, not the command usage.
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := app.UseProfile(args[0]); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "Using profile %s\n", args[0])
+
+			return nil
+		},
+	}
+
+	checkCmd := &cobra.Command{
+		Use:   "check-colors",
+		Short: "Check that the colors can be told apart by color blind users",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, _ []string) {
+			warnings := console.CheckTheme(app.Theme())
+			if len(warnings) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "No colors are hard to tell apart")
+			}
+
+			for _, warning := range warnings {
+				fmt.Fprintf(cmd.OutOrStdout(), "warning: %s\n", warning)
+			}
+		},
+	}
+
+	carapace.Gen(useCmd).PositionalCompletion(carapace.ActionCallback(func(_ carapace.Context) carapace.Action {
+		return carapace.ActionValues(app.Profiles()...).Tag("profiles").Usage("profile")
+	}))
+
+	configCmd.AddCommand(profilesCmd, useCmd, checkCmd)
+
+	return configCmd
+}
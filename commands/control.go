@@ -0,0 +1,99 @@
+package commands
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/reeflective/console"
+)
+
+// Control returns a command serving the control API of the application, so that
+// external tools (editors, demo scripts) can execute commands, query its state and
+// stream its messages: `control serve [socket|host:port]` serves it on a Unix socket
+// (or a TCP address when given one), and `control stop` stops serving it. Serving on
+// TCP (or on Windows) uses a new random token, printed for the clients to authenticate.
+func Control(app *console.Console) *cobra.Command {
+	controlCmd := &cobra.Command{
+		Use:     "control",
+		Short:   "Serve the control API of the application (JSON-RPC)",
+		GroupID: "core",
+	}
+
+	serveCmd := &cobra.Command{
+		Use:   "serve [socket|host:port]",
+		Short: "Serve the control API (on a Unix socket in the temporary directory by default)",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			network, addr := "unix", defaultControlSocket(app)
+
+			if len(args) > 0 {
+				addr = args[0]
+
+				// Addresses with a port, but not a path, are TCP ones.
+				if strings.Contains(addr, ":") && !strings.ContainsAny(addr, `/\`) {
+					network = "tcp"
+				}
+			}
+
+			// Sockets are not protected by their permissions.
+			var token string
+
+			if network == "tcp" || runtime.GOOS == "windows" {
+				token = newControlToken()
+				app.SetControlToken(token)
+			}
+
+			listening, err := app.ServeControl(network, addr)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "Serving the control API on %s %s\n", network, listening)
+
+			if token != "" {
+				fmt.Fprintf(cmd.OutOrStdout(), "Authenticate with the token %s\n", token)
+			}
+
+			return nil
+		},
+	}
+
+	stopCmd := &cobra.Command{
+		Use:   "stop",
+		Short: "Stop serving the control API, disconnecting all clients",
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return app.StopControl()
+		},
+	}
+
+	controlCmd.AddCommand(serveCmd, stopCmd)
+
+	return controlCmd
+}
+
+// defaultControlSocket returns the path of the control socket
+// in the temporary directory, named after the application.
+func defaultControlSocket(app *console.Console) string {
+	name := strings.ToLower(strings.ReplaceAll(app.ControlState().App, " ", "-"))
+	if name == "" {
+		name = "console"
+	}
+
+	return filepath.Join(os.TempDir(), name+"-control.sock")
+}
+
+// newControlToken returns a random token for the clients of the control API.
+func newControlToken() string {
+	token := make([]byte, 16)
+	_, _ = rand.Read(token)
+
+	return hex.EncodeToString(token)
+}
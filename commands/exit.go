@@ -7,10 +7,14 @@ import (
 	"strings"
 
 	"github.com/spf13/cobra"
+
+	"github.com/reeflective/console"
 )
 
 // Exit returns a command to exit the console application.
 // The command will prompt the user to confirm quitting.
+// See ExitConsole for a command running the shutdown hooks
+// and honoring the exit behavior of the active menu.
 func Exit() *cobra.Command {
 	exitCmd := &cobra.Command{
 		Use:     "exit",
@@ -24,6 +28,29 @@ func Exit() *cobra.Command {
 	return exitCmd
 }
 
+// ExitConsole returns a command to exit the console application (also usable as `quit`).
+// Depending on the active menu ExitBehavior, the command either quits the application,
+// after running its shutdown hooks, or leaves the menu for the main one. If any of the
+// console ExitWarnings is raised (eg. jobs still running), the user is asked to confirm,
+// unless the --force flag is used.
+func ExitConsole(app *console.Console) *cobra.Command {
+	exitCmd := &cobra.Command{
+		Use:     "exit",
+		Aliases: []string{"quit"},
+		Short:   "Exit the console application (or the current menu)",
+		GroupID: "core",
+		Run: func(cmd *cobra.Command, _ []string) {
+			force, _ := cmd.Flags().GetBool("force")
+
+			app.RequestExit(force)
+		},
+	}
+
+	exitCmd.Flags().BoolP("force", "f", false, "Do not ask for confirmation, even if exiting might lose work")
+
+	return exitCmd
+}
+
 // exitCtrlD is a custom interrupt handler to use when the shell
 // readline receives an io.EOF error, which is returned with CtrlD.
 func exitCtrlD() {
@@ -0,0 +1,38 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/reeflective/console"
+	"github.com/reeflective/console/commands/readline"
+)
+
+// History returns the readline `history` command (see readline.History), printing the
+// lines of the current history source, with a `compact` subcommand removing the duplicate
+// lines from the history file, only keeping the most recent occurrence of each command line.
+func History(app *console.Console) *cobra.Command {
+	historyCmd := readline.History(app.Shell())
+	historyCmd.GroupID = "core"
+
+	compactCmd := &cobra.Command{
+		Use:   "compact",
+		Short: "Remove duplicate lines from the history file",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			removed, err := app.CompactHistory()
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "Removed %d lines from the history\n", removed)
+
+			return nil
+		},
+	}
+
+	historyCmd.AddCommand(compactCmd)
+
+	return historyCmd
+}
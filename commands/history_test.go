@@ -0,0 +1,51 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"github.com/reeflective/console"
+)
+
+func TestHistoryPrintsLastLines(t *testing.T) {
+	app := console.New("test")
+
+	var out bytes.Buffer
+
+	app.ActiveMenu().SetCommands(func() *cobra.Command {
+		root := &cobra.Command{}
+		root.SetOut(&out)
+		root.AddGroup(&cobra.Group{ID: "core", Title: "Core"})
+		root.AddCommand(History(app))
+
+		return root
+	})
+
+	history := app.Shell().History.Current()
+	for _, line := range []string{"deploy staging", "status", "deploy prod"} {
+		history.Write(line)
+	}
+
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"history 2", "    2  status\n    3  deploy prod\n"},
+		{"history --filter deploy 1", "    3  deploy prod\n"},
+	}
+
+	for _, test := range tests {
+		out.Reset()
+
+		if err := app.ActiveMenu().RunCommandLine(context.Background(), test.line); err != nil {
+			t.Fatal(err)
+		}
+
+		if out.String() != test.want {
+			t.Errorf("%s printed %q, want %q", test.line, out.String(), test.want)
+		}
+	}
+}
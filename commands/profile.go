@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/reeflective/console"
+)
+
+// Profile returns a command profiling the application while it runs, so that the
+// performance of prompts, completions and commands can be diagnosed in the field:
+// `profile serve [addr]` serves the pprof endpoints on localhost, `profile start`
+// writes a CPU profile (and a heap profile on stop), and `profile stop` stops both.
+func Profile(app *console.Console) *cobra.Command {
+	profileCmd := &cobra.Command{
+		Use:     "profile",
+		Short:   "Profile the application (CPU, memory, goroutines)",
+		GroupID: "core",
+	}
+
+	serveCmd := &cobra.Command{
+		Use:   "serve [addr]",
+		Short: "Serve the pprof endpoints (on localhost:6060 by default)",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			addr := "localhost:6060"
+			if len(args) > 0 {
+				addr = args[0]
+			}
+
+			listening, err := app.ServeProfiling(addr)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "Serving profiles on http://%s/debug/pprof/\n", listening)
+
+			return nil
+		},
+	}
+
+	startCmd := &cobra.Command{
+		Use:   "start <cpu-profile>",
+		Short: "Write a CPU profile to a file, until stopped",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			heap, _ := cmd.Flags().GetString("heap")
+
+			return app.ProfileToFiles(args[0], heap)
+		},
+	}
+
+	startCmd.Flags().String("heap", "", "Also write a heap profile to this file when stopped")
+
+	stopCmd := &cobra.Command{
+		Use:   "stop",
+		Short: "Stop profiling, writing the profiles",
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return app.StopProfiling()
+		},
+	}
+
+	profileCmd.AddCommand(serveCmd, startCmd, stopCmd)
+
+	return profileCmd
+}
@@ -3,7 +3,6 @@ package readline
 import (
 	"errors"
 	"fmt"
-	"os"
 	"strings"
 
 	"github.com/carapace-sh/carapace"
@@ -15,6 +14,9 @@ import (
 
 // Bind returns a command named `bind`, for manipulating readline keymaps and bindings.
 func Bind(shell *readline.Shell) *cobra.Command {
+	// Keyboard macros recorded by the user are saved by name.
+	macros := recordMacros(shell)
+
 	cmd := &cobra.Command{
 		Use:   "bind",
 		Short: "Display or modify readline key bindings",
@@ -25,20 +27,60 @@ Note that the keymap name is optional, and if omitted, the default keymap is use
 The default keymap is 'vi' only if 'set editing-mode vi' is found in inputrc , and
 unless the -m option is used to set a different keymap.
 Also, note that the bind [seq] [command] slightly differs from the original bash 'bind' command.
+The command can also be any action registered by the application (eg. switch-menu-main),
+which can be bound in the exact same way in the inputrc file, or in the binds of the console configuration.
+
+Grabbing keys:
+With --key, the next key chord pressed is read from the terminal, and its escaped
+sequence is printed with its current binding, so that sequences do not have to be
+figured out by hand. The chord is then bound to the command given as argument, or
+to the one typed at the prompt (nothing is bound if the prompt is left empty).
+
+Custom keymaps:
+Keymaps can be created at runtime with 'bind keymap new NAME --from KEYMAP', after
+which their binds are changed with 'bind -m NAME', and they are entered with the
+'switch-keymap-NAME' command. They are deleted with 'bind keymap delete NAME'.
+
+Recorded macros:
+Keyboard macros recorded with start-kbd-macro/end-kbd-macro (C-x ( and C-x ) in emacs)
+are saved under the name typed when the recording ends (kbd-macro-1, kbd-macro-2, etc,
+if none is given), in the kbd-macros keymap. They are listed with --macros, and can be
+bound to a key sequence like any command. --macros-rc exports them with their binds.
 
 Exporting binds:
 - Since all applications always look up to the same file for a given user,
   the export command does not allow to write and modify this file itself.
 - Also, since saving the entire list of options and bindings in a different
-  file for each application would also defeat the purpose of .inputrc.`,
+  file for each application would also defeat the purpose of .inputrc.
+- With --app, the output is wrapped in an '$if name' block: both this form and
+  '$if app=name' are understood when this application reads its inputrc files.
+- With --out, the snippet (which must be wrapped in a block with --app or --lib) is
+  written to a per-application file instead, which can be included from .inputrc with
+  '$include'. A block previously exported for the same app is replaced, and the file
+  is replaced atomically. With --dry-run, the changes to the file are only printed.
+- With --diff, only the options and binds differing from the library and console
+  defaults (or from the configuration obtained with an inputrc file, if given) are
+  exported, which gives the changes made since then by any means (inputrc files,
+  bind/set, the app).
+- With --json, the variables, and the binds and macros of each keymap (or only the
+  one given with -m) are printed as a single JSON document, for external tools.`,
 		Example: `Changing binds:
     bind "\C-x\C-r": re-read-init-file          # C-x C-r to reload the inputrc file, in the default keymap.
     bind -m vi-insert "\C-l" clear-screen       # C-l to clear-screen in vi-insert mode
     bind -m menu-complete '\C-n' menu-complete  # C-n to cycle through choices in the completion keymap.
+    bind "\C-xm" switch-menu-main              # C-x m to switch to the main menu (console action).
+    bind "\C-xd" deploy                        # C-x d to replay the macro named deploy when it was recorded.
+    bind --key                                 # Press a key chord, see its binding, and optionally bind it.
+    bind -m vi-insert --key clear-screen       # Bind the next key chord pressed to clear-screen in vi-insert mode.
 
 Exporting binds:
    bind --binds-rc --lib --changed # Only changed options/binds to stdout applying to all apps using this lib
-   bind --app OtherApp -c          # Changed options, applying to an app other than our current shell one`,
+   bind --app OtherApp -c          # Changed options, applying to an app other than our current shell one
+   bind --export --app myapp --out ~/.inputrc.myapp --dry-run  # Preview the changes to the app file
+   bind --export --app myapp --out ~/.inputrc.myapp            # Write all options and binds to it
+   bind --diff                     # Options/binds differing from the library defaults, as inputrc
+   bind --diff ~/.inputrc          # Options/binds changed since reading the inputrc file
+   bind --json -c                  # Changed options, binds and macros as JSON, for dotfile managers`,
 	}
 
 	// Flags
@@ -54,26 +96,41 @@ Exporting binds:
 	cmd.Flags().StringP("unbind", "u", "", "Unbind all keys which are bound to the named function")
 	cmd.Flags().StringP("remove", "r", "", "Remove the bindings for KEYSEQ")
 	cmd.Flags().StringP("file", "f", "", "Read key bindings from FILENAME")
-	cmd.Flags().StringP("app", "A", "", "Optional application name (if empty/not used, the current app)")
-	cmd.Flags().BoolP("changed", "c", false, "Only export options modified since app start: maybe not needed, since no use for it")
+	cmd.Flags().StringP("app", "A", "", "Export options/binds in a $if conditional block for this application")
+	cmd.Flags().BoolP("changed", "c", false, "Only export options modified with the bind/set commands since app start (see --diff)")
 	cmd.Flags().BoolP("lib", "L", false, "Like 'app', but export options/binds for all apps using this specific library")
+	cmd.Flags().BoolP("export", "x", false, "Export all options, binds and macros (like -v -p -s) in a form that can be reused as input")
+	cmd.Flags().StringP("out", "o", "", "Write the exported options/binds to this file (requires --app or --lib), replacing any previous export")
+	cmd.Flags().BoolP("dry-run", "n", false, "With --out, print the changes that would be made to the file instead of writing it")
 	cmd.Flags().BoolP("self-insert", "I", false, "If exporting bind sequences, also include the sequences mapped to self-insert")
+	cmd.Flags().BoolP("key", "k", false, "Read the next key chord pressed, print its sequence and binding, and prompt for a command to bind it to")
+	cmd.Flags().StringP("diff", "D", "", "List options and binds differing from the library defaults, or from an inputrc file")
+	cmd.Flags().Lookup("diff").NoOptDefVal = diffDefaults
+	cmd.Flags().BoolP("json", "j", false, "Print variables, binds and macros of all keymaps (or --keymap) as a JSON document")
+
+	// Keymap management
+	cmd.AddCommand(Keymap(shell))
 
 	// Completions
 	comps := carapace.Gen(cmd)
 	flagComps := make(carapace.ActionMap)
 
 	flagComps["keymap"] = completeKeymaps(shell, cmd)
-	flagComps["query"] = completeCommands(shell, cmd)
-	flagComps["unbind"] = completeCommands(shell, cmd)
+	flagComps["query"] = completeCommands(shell, cmd, true)
+	flagComps["unbind"] = completeCommands(shell, cmd, true)
 	flagComps["remove"] = completeBindSequences(shell, cmd)
 	flagComps["file"] = carapace.ActionFiles()
+	flagComps["diff"] = carapace.ActionFiles()
+	flagComps["out"] = carapace.ActionFiles()
 
 	comps.FlagCompletion(flagComps)
 
 	comps.PositionalCompletion(
 		carapace.ActionValues().Usage("key sequence"),
-		completeCommands(shell, cmd),
+		carapace.Batch(
+			completeCommands(shell, cmd, false),
+			completeMacros(macros),
+		).ToA(),
 	)
 
 	// Run implementation
@@ -84,7 +141,7 @@ Exporting binds:
 			keymap = string(shell.Keymap.Main())
 		}
 
-		var name string
+		name, _ := cmd.Flags().GetString("app")
 		reeflective := "reeflective"
 		buf := &cfgBuilder{buf: &strings.Builder{}}
 
@@ -110,6 +167,11 @@ Exporting binds:
 			return nil
 		}
 
+		// Grab a key chord from the terminal, and bind it.
+		if cmd.Flags().Changed("key") {
+			return grabKey(shell, cmd, keymap, args)
+		}
+
 		// 2 - Query binds for function
 		if cmd.Flags().Changed("query") {
 			listBinds(shell, buf, cmd, keymap)
@@ -143,39 +205,92 @@ Exporting binds:
 
 		// 2 - COMPLEX QUERIES ------------------------------------------------
 
+		// The JSON document includes everything, and is not mixed with other listings.
+		if cmd.Flags().Changed("json") {
+			return writeJSON(shell, cmd, cmd.OutOrStdout())
+		}
+
+		// Exports include all options, binds and macros.
+		export := cmd.Flags().Changed("export")
+		varsRC := export || cmd.Flags().Changed("vars-rc")
+		bindsRC := export || cmd.Flags().Changed("binds-rc")
+		macrosRC := export || cmd.Flags().Changed("macros-rc")
+
+		out, _ := cmd.Flags().GetString("out")
+		if out != "" && !app && !lib {
+			return errExportNoCond
+		}
+
+		// Describe the console context in which inputrc snippets are exported,
+		// unless written to a file, where it would not be relevant for long.
+		if (varsRC || bindsRC || macrosRC) && out == "" {
+			writeContext(buf, cmd)
+		}
+
 		// Write App/Lib headers for
+		var header, cond string
+
 		if app {
-			fmt.Fprintf(buf, "# %s application (generated)\n", name)
-			buf.newCond(name)
+			header, cond = fmt.Sprintf("# %s application (generated)", name), name
 		} else if lib {
-			fmt.Fprintf(buf, "# %s/readline library-specific (generated)\n", reeflective)
-			buf.newCond(reeflective)
+			header, cond = fmt.Sprintf("# %s/readline library-specific (generated)", reeflective), reeflective
+		}
+
+		if cond != "" {
+			fmt.Fprintln(buf, header)
+			buf.newCond(cond)
+		}
+
+		// Differences with the defaults, or with an inputrc file
+		// (which can also be given as argument: bind --diff file).
+		if cmd.Flags().Changed("diff") {
+			file, _ := cmd.Flags().GetString("diff")
+			if file == diffDefaults && len(args) == 1 {
+				file = args[0]
+			}
+
+			base, err := baseConfig(shell, file)
+			if err != nil {
+				return err
+			}
+
+			listDiffRC(shell, base, buf, cmd)
+
+			if buf.buf.Len() == 0 {
+				return nil
+			}
 		}
 
 		// Global option variables
 		if cmd.Flags().Changed("vars") {
 			listVars(shell, buf, cmd)
-		} else if cmd.Flags().Changed("vars-rc") {
+		} else if varsRC {
 			listVarsRC(shell, buf, cmd)
 		}
 
 		// Sequences to function names
 		if cmd.Flags().Changed("binds") {
 			listBinds(shell, buf, cmd, keymap)
-		} else if cmd.Flags().Changed("binds-rc") {
+		} else if bindsRC {
 			listBindsRC(shell, buf, cmd, keymap)
 		}
 
 		// Macros
 		if cmd.Flags().Changed("macros") {
 			listMacros(shell, buf, cmd, keymap)
-		} else if cmd.Flags().Changed("macros-rc") {
+		} else if macrosRC {
 			listMacrosRC(shell, buf, cmd, keymap)
 		}
 
 		// Close any App/Lib conditional
 		buf.endCond()
 
+		// Write the snippet to the file, replacing any previous export.
+		if out != "" {
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			return exportRC(cmd.OutOrStdout(), out, header, cond, buf.buf.String(), dryRun)
+		}
+
 		// The command has performed an action, so any binding
 		// with positional arguments is not considered or evaluated.
 		if buf.buf.Len() > 0 {
@@ -197,6 +312,11 @@ Exporting binds:
 		// The key sequence is an escaped string, so unescape it.
 		seq := inputrc.Unescape(args[0])
 
+		// Recorded macros are bound as macros, not commands.
+		if macros.bind(keymap, seq, args[1]) {
+			return nil
+		}
+
 		var found bool
 
 		for command := range shell.Keymap.Commands() {
@@ -218,6 +338,7 @@ Exporting binds:
 		// Adjust some keymaps (aliases of each other).
 		bindkey := func(keymap string) {
 			shell.Config.Binds[keymap][seq] = inputrc.Bind{Action: args[1]}
+			cfgChanged.Bind(keymap, seq, args[1], false)
 		}
 
 		// (Bind the key sequence to the command)
@@ -236,16 +357,20 @@ Exporting binds:
 func readFileConfig(sh *readline.Shell, cmd *cobra.Command, _ string) error {
 	fileF, _ := cmd.Flags().GetString("file")
 
-	file, err := os.Stat(fileF)
+	// Read the file through the shell config, which might
+	// process some application-specific conditionals.
+	data, err := sh.Config.ReadFile(fileF)
 	if err != nil {
 		return err
 	}
 
-	if err = inputrc.ParseFile(file.Name(), sh.Config, sh.Opts...); err != nil {
+	opts := append(append([]inputrc.Option{}, sh.Opts...), inputrc.WithName(fileF))
+
+	if err = inputrc.ParseBytes(data, sh.Config, opts...); err != nil {
 		return err
 	}
 
-	fmt.Printf("Read and parsed %s\n", file.Name())
+	fmt.Printf("Read and parsed %s\n", fileF)
 
 	return nil
 }
@@ -7,19 +7,23 @@ import (
 )
 
 // Commands returns a command named `readline`, with subcommands dedicated
-// to setting up readline keybindings, keymaps, and global options. It is
-// intended to be used as a subcommand of the root command.
+// to setting up readline keybindings, keymaps, and global options (bind, set),
+// and to managing the shell history, recorded macros and inputrc file (history,
+// macro, edit). It is intended to be used as a subcommand of the root command.
 // You can freely change the use name of this command, or any of its properties.
 func Commands(shell *readline.Shell) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "readline",
-		Short: "Manipulate readline options, keymaps and bindings",
-		Long:  `Manipulate readline options, keymaps and bindings.`,
+		Short: "Manipulate readline options, keymaps, bindings, history and macros",
+		Long:  `Manipulate readline options, keymaps, bindings, history and macros.`,
 	}
 
 	// Subcommands
 	cmd.AddCommand(Set(shell))
 	cmd.AddCommand(Bind(shell))
+	cmd.AddCommand(History(shell))
+	cmd.AddCommand(Macro(shell))
+	cmd.AddCommand(Edit(shell))
 
 	return cmd
 }
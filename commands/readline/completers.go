@@ -20,6 +20,8 @@ package readline
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/carapace-sh/carapace"
 	"github.com/spf13/cobra"
@@ -91,15 +93,68 @@ func completeBindSequences(sh *readline.Shell, cmd *cobra.Command) carapace.Acti
 	})
 }
 
-func completeCommands(sh *readline.Shell, _ *cobra.Command) carapace.Action {
+// completeCommands completes the shell commands, split in those bound in the
+// keymap selected with --keymap (or the main one), described with their key
+// sequences, and the others, unless only the bound ones are completed.
+func completeCommands(sh *readline.Shell, cmd *cobra.Command, boundOnly bool) carapace.Action {
 	return carapace.ActionCallback(func(c carapace.Context) carapace.Action {
-		results := make([]string, 0)
+		keymap, _ := cmd.Flags().GetString("keymap")
+		if keymap == "" {
+			keymap = string(sh.Keymap.Main())
+		}
+
+		// Sequences bound to each command.
+		sequences := make(map[string][]string)
+
+		for seq, bind := range sh.Config.Binds[keymap] {
+			if !bind.Macro {
+				sequences[bind.Action] = append(sequences[bind.Action], inputrc.Escape(seq))
+			}
+		}
+
+		bound := make([]string, 0)
+		unbound := make([]string, 0)
 
 		for name := range sh.Keymap.Commands() {
-			results = append(results, name)
+			seqs, found := sequences[name]
+			if !found {
+				unbound = append(unbound, name)
+				continue
+			}
+
+			sort.Strings(seqs)
+
+			// Commands like self-insert have hundreds of sequences.
+			desc := strings.Join(seqs[:min(len(seqs), 3)], ", ")
+			if len(seqs) > 3 {
+				desc += fmt.Sprintf(" (+%d)", len(seqs)-3)
+			}
+
+			bound = append(bound, name, desc)
+		}
+
+		boundComps := carapace.ActionValuesDescribed(bound...).Tag(fmt.Sprintf("bound commands (%s)", keymap)).Usage("command")
+		if boundOnly {
+			return boundComps
+		}
+
+		return carapace.Batch(
+			boundComps,
+			carapace.ActionValues(unbound...).Tag(fmt.Sprintf("unbound commands (%s)", keymap)).Usage("command"),
+		).ToA()
+	})
+}
+
+func completeMacros(macros *macroRecorder) carapace.Action {
+	return carapace.ActionCallback(func(_ carapace.Context) carapace.Action {
+		results := make([]string, 0)
+
+		for _, name := range macros.names() {
+			macro, _ := macros.get(name)
+			results = append(results, name, inputrc.EscapeMacro(macro))
 		}
 
-		return carapace.ActionValues(results...).Tag("commands").Usage("command")
+		return carapace.ActionValuesDescribed(results...).Tag("named macros").Usage("command")
 	})
 }
 
@@ -21,6 +21,10 @@ package readline
 import (
 	"fmt"
 	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/reeflective/console"
 )
 
 // manages display of .inputrc-compliant listings/snippets.
@@ -52,3 +56,17 @@ func (cfg *cfgBuilder) endCond() {
 	cfg.names = cfg.names[:len(cfg.names)-1]
 	cfg.Write([]byte("$endif\n"))
 }
+
+// writeContext writes the menu path and the context values of the console
+// running the command (if any) as comments, before an exported snippet.
+func writeContext(cfg *cfgBuilder, cmd *cobra.Command) {
+	path, values := console.ContextFrom(cmd.Context())
+
+	if len(path) > 0 {
+		fmt.Fprintf(cfg, "# menu: %s\n", strings.Join(path, " > "))
+	}
+
+	for _, value := range values {
+		fmt.Fprintf(cfg, "# %s: %s\n", value.Name, value.Value)
+	}
+}
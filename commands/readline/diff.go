@@ -0,0 +1,151 @@
+package readline
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"github.com/reeflective/console"
+	"github.com/reeflective/readline"
+	"github.com/reeflective/readline/inputrc"
+)
+
+// diffDefaults is the value of the --diff flag when no file is given.
+const diffDefaults = "defaults"
+
+// baseConfig returns the configuration against which --diff compares the shell
+// one: the library defaults (those of a shell for which no inputrc file exists)
+// with the binds of the console using the shell (see console.DefaultBinds), on
+// top of which the given inputrc file is parsed, if any (with the shell options,
+// so that application conditionals are honored).
+func baseConfig(shell *readline.Shell, file string) (*inputrc.Config, error) {
+	defaults := readline.NewShell()
+
+	base := defaults.Config
+	base.ReadFileFunc = func(string) ([]byte, error) { return nil, os.ErrNotExist }
+	base.Vars = inputrc.DefaultVars()
+	base.Binds = inputrc.DefaultBinds()
+
+	if err := defaults.Keymap.ReloadConfig(); err != nil {
+		return nil, err
+	}
+
+	// The binds set by the console are defaults as well.
+	for keymap, binds := range console.DefaultBinds(shell) {
+		if base.Binds[keymap] == nil {
+			base.Binds[keymap] = make(map[string]inputrc.Bind, len(binds))
+		}
+
+		for seq, bind := range binds {
+			base.Binds[keymap][seq] = bind
+		}
+	}
+
+	base.ReadFileFunc = os.ReadFile
+
+	if file == "" || file == diffDefaults {
+		return base, nil
+	}
+
+	data, err := base.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	// Like the shell when reading the user inputrc files.
+	opts := []inputrc.Option{inputrc.WithMode("emacs"), inputrc.WithTerm(os.Getenv("TERM"))}
+	opts = append(append(opts, shell.Opts...), inputrc.WithName(file))
+
+	if err := inputrc.ParseBytes(data, base, opts...); err != nil {
+		return nil, err
+	}
+
+	return base, nil
+}
+
+// listDiffRC prints the variables and the binds of all keymaps (or only the one
+// given with --keymap) which differ from the base configuration, in .inputrc
+// compliant format. Binds removed from the shell are listed as comments, since
+// they cannot be expressed in inputrc.
+func listDiffRC(shell *readline.Shell, base *inputrc.Config, buf *cfgBuilder, cmd *cobra.Command) {
+	var variables []string
+
+	for name, value := range shell.Config.Vars {
+		if name == "" || value == nil {
+			continue
+		}
+
+		if fmt.Sprint(base.Vars[name]) != fmt.Sprint(value) {
+			variables = append(variables, name)
+		}
+	}
+
+	sort.Strings(variables)
+
+	if len(variables) > 0 {
+		fmt.Fprintln(buf, "# Options differing from the base configuration (generated)")
+
+		for _, name := range variables {
+			fmt.Fprintf(buf, "set %s %v\n", name, printVar(shell.Config.Vars[name]))
+		}
+	}
+
+	var keymaps []string
+
+	if cmd.Flags().Changed("keymap") {
+		keymap, _ := cmd.Flags().GetString("keymap")
+		keymaps = append(keymaps, keymap)
+	} else {
+		for keymap := range shell.Config.Binds {
+			keymaps = append(keymaps, keymap)
+		}
+
+		sort.Strings(keymaps)
+	}
+
+	for _, keymap := range keymaps {
+		listKeymapDiffRC(shell.Config.Binds[keymap], base.Binds[keymap], buf, keymap)
+	}
+}
+
+// listKeymapDiffRC prints the binds of a keymap which differ from the base ones.
+func listKeymapDiffRC(binds, baseBinds map[string]inputrc.Bind, buf *cfgBuilder, keymap string) {
+	var changed, removed []string
+
+	for seq, bind := range binds {
+		if baseBind, found := baseBinds[seq]; !found || baseBind != bind {
+			changed = append(changed, seq)
+		}
+	}
+
+	for seq := range baseBinds {
+		if _, found := binds[seq]; !found {
+			removed = append(removed, seq)
+		}
+	}
+
+	if len(changed) == 0 && len(removed) == 0 {
+		return
+	}
+
+	sort.Strings(changed)
+	sort.Strings(removed)
+
+	fmt.Fprintln(buf)
+	fmt.Fprintln(buf, "# Binds differing from the base configuration (generated)")
+	fmt.Fprintf(buf, "set keymap %s\n\n", keymap)
+
+	for _, seq := range changed {
+		if bind := binds[seq]; bind.Macro {
+			fmt.Fprintf(buf, "\"%s\": \"%s\"\n", inputrc.Escape(seq), inputrc.EscapeMacro(bind.Action))
+		} else {
+			fmt.Fprintf(buf, "\"%s\": %s\n", inputrc.Escape(seq), bind.Action)
+		}
+	}
+
+	for _, seq := range removed {
+		fmt.Fprintf(buf, "# \"%s\" is not bound (was %s)\n", inputrc.Escape(seq), baseBinds[seq].Action)
+	}
+}
@@ -0,0 +1,40 @@
+package readline
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"github.com/reeflective/console"
+	"github.com/reeflective/readline/inputrc"
+)
+
+func TestDiffOmitsConsoleBinds(t *testing.T) {
+	t.Setenv("INPUTRC", t.TempDir()+"/inputrc")
+
+	shell := console.New("test").Shell()
+	shell.Config.Binds["emacs"]["\x1bz"] = inputrc.Bind{Action: "undo"}
+
+	base, err := baseConfig(shell, diffDefaults)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := &cobra.Command{}
+	cmd.Flags().String("keymap", "", "")
+	cmd.Flags().Set("keymap", "emacs")
+
+	buf := &cfgBuilder{buf: &strings.Builder{}}
+	listDiffRC(shell, base, buf, cmd)
+
+	diff := buf.buf.String()
+
+	if strings.Contains(diff, "mouse-event") {
+		t.Errorf("diff lists the console binds:\n%s", diff)
+	}
+
+	if !strings.Contains(diff, `"\ez": undo`) {
+		t.Errorf("diff does not list the user bind:\n%s", diff)
+	}
+}
@@ -0,0 +1,128 @@
+package readline
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/spf13/cobra"
+
+	"github.com/reeflective/readline"
+	"github.com/reeflective/readline/inputrc"
+)
+
+var errNoInputrc = errors.New("no inputrc file found: give the file to edit as argument")
+
+// Edit returns a command named `edit`, opening the user inputrc file (or the given
+// one) in the system editor ($VISUAL, $EDITOR, or vi/notepad), after which the
+// shell configuration is reloaded, so that changes are immediately applied.
+func Edit(shell *readline.Shell) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "edit [file]",
+		Short: "Edit the inputrc file, and reload the configuration",
+		Long: `Edit the inputrc file in the system editor ($VISUAL, $EDITOR, or vi/notepad).
+
+Without arguments, the file edited is the one read by the shell at startup: the one
+in $INPUTRC, or ~/.inputrc (~/_inputrc on Windows).
+Once the editor exits, the user configuration is reloaded, like with the 're-read-init-file'
+command. When another file is given, it is read and parsed (like with 'bind -f') instead.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			file := userInputrc()
+			if len(args) > 0 {
+				file = args[0]
+			}
+
+			if file == "" {
+				return errNoInputrc
+			}
+
+			if err := runEditor(file); err != nil {
+				return err
+			}
+
+			// The user file is reloaded with all others, like at startup.
+			if len(args) == 0 {
+				if err := shell.Keymap.ReloadConfig(shell.Opts...); err != nil {
+					return err
+				}
+
+				fmt.Fprintf(cmd.OutOrStdout(), "Reloaded %s\n", file)
+
+				return nil
+			}
+
+			data, err := shell.Config.ReadFile(file)
+			if err != nil {
+				return err
+			}
+
+			opts := append(append([]inputrc.Option{}, shell.Opts...), inputrc.WithName(file))
+
+			if err := inputrc.ParseBytes(data, shell.Config, opts...); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "Read and parsed %s\n", file)
+
+			return nil
+		},
+	}
+
+	carapace.Gen(cmd).PositionalCompletion(carapace.ActionFiles())
+
+	return cmd
+}
+
+// userInputrc returns the path of the user inputrc file.
+func userInputrc() string {
+	if name := os.Getenv("INPUTRC"); name != "" {
+		return name
+	}
+
+	current, err := user.Current()
+	if err != nil {
+		return ""
+	}
+
+	if runtime.GOOS == "windows" {
+		return filepath.Join(current.HomeDir, "_inputrc")
+	}
+
+	return filepath.Join(current.HomeDir, ".inputrc")
+}
+
+// runEditor opens a file in the system editor, and waits for it to exit.
+func runEditor(file string) error {
+	editor := strings.TrimSpace(os.Getenv("VISUAL"))
+	if editor == "" {
+		editor = strings.TrimSpace(os.Getenv("EDITOR"))
+	}
+
+	if editor == "" {
+		editor = "vi"
+		if runtime.GOOS == "windows" {
+			editor = "notepad.exe"
+		}
+	}
+
+	// The editor variable may include arguments (eg. "code --wait").
+	fields := strings.Fields(editor)
+
+	editCmd := exec.Command(fields[0], append(fields[1:], filepath.Clean(file))...)
+	editCmd.Stdin = os.Stdin
+	editCmd.Stdout = os.Stdout
+	editCmd.Stderr = os.Stderr
+
+	if err := editCmd.Run(); err != nil {
+		return fmt.Errorf("failed to run editor %s: %w", editor, err)
+	}
+
+	return nil
+}
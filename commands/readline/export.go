@@ -337,6 +337,8 @@ func listMacros(shell *readline.Shell, buf *cfgBuilder, cmd *cobra.Command, keym
 		}
 	}
 
+	listRecordedMacros(shell, buf)
+
 	if len(macroBinds) == 0 {
 		return
 	}
@@ -349,7 +351,26 @@ func listMacros(shell *readline.Shell, buf *cfgBuilder, cmd *cobra.Command, keym
 
 	for _, key := range macroBinds {
 		action := inputrc.Escape(binds[inputrc.Unescape(key)].Action)
-		fmt.Printf("%s outputs %s\n", key, action)
+		fmt.Fprintf(buf, "%s outputs %s\n", key, action)
+	}
+}
+
+// listRecordedMacros prints the named macros, recorded or read from inputrc files.
+func listRecordedMacros(shell *readline.Shell, buf *cfgBuilder) {
+	recorder := recorderOf(shell)
+
+	names := recorder.names()
+	if len(names) == 0 {
+		return
+	}
+
+	fmt.Fprintln(buf)
+	fmt.Fprintln(buf, "====== Named macros ======")
+	fmt.Fprintln(buf)
+
+	for _, name := range names {
+		macro, _ := recorder.get(name)
+		fmt.Fprintf(buf, "%s outputs %s\n", name, inputrc.EscapeMacro(macro))
 	}
 }
 
@@ -372,6 +393,10 @@ func listMacrosRC(shell *readline.Shell, buf *cfgBuilder, cmd *cobra.Command, ke
 		}
 	}
 
+	if keymap != macroKeymap {
+		defer listNamedMacrosRC(shell, buf, cmd, keymap)
+	}
+
 	if len(macroBinds) == 0 {
 		return
 	}
@@ -387,3 +412,46 @@ func listMacrosRC(shell *readline.Shell, buf *cfgBuilder, cmd *cobra.Command, ke
 		fmt.Fprintf(buf, "\"%s\": \"%s\"\n", key, action)
 	}
 }
+
+// listNamedMacrosRC prints the named macros, bound to their name in the kbd-macros keymap,
+// in .inputrc compliant format, before switching back to the given keymap. Since GNU C
+// Readline does not know this keymap, they are wrapped in a block read by this library
+// only, unless they are already printed in an application or library block.
+func listNamedMacrosRC(shell *readline.Shell, buf *cfgBuilder, cmd *cobra.Command, keymap string) {
+	binds := shell.Config.Binds[macroKeymap]
+	if cmd.Flags().Changed("changed") {
+		binds = cfgChanged.Binds[macroKeymap]
+	}
+
+	var names []string
+
+	for name, bind := range binds {
+		if bind.Macro {
+			names = append(names, name)
+		}
+	}
+
+	if len(names) == 0 {
+		return
+	}
+
+	sort.Strings(names)
+
+	fmt.Fprintln(buf)
+	fmt.Fprintln(buf, "# Named macros (generated from reeflective/readline)")
+
+	if len(buf.names) == 0 {
+		fmt.Fprintln(buf, "# The following block is not implemented in GNU C Readline.")
+		buf.newCond("go")
+
+		defer buf.endCond()
+	}
+
+	fmt.Fprintf(buf, "set keymap %s\n\n", macroKeymap)
+
+	for _, name := range names {
+		fmt.Fprintf(buf, "\"%s\": \"%s\"\n", inputrc.Escape(name), inputrc.Escape(binds[name].Action))
+	}
+
+	fmt.Fprintf(buf, "\nset keymap %s\n", keymap)
+}
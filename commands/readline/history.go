@@ -0,0 +1,77 @@
+package readline
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/spf13/cobra"
+
+	"github.com/reeflective/readline"
+)
+
+// History returns a command named `history`, printing the lines of the history
+// source currently used by the shell, the most recent ones last (optionally only
+// the last N ones, or those containing a string).
+func History(shell *readline.Shell) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "history [count]",
+		Short: "Print the lines of the current history source",
+		Long: `Print the lines of the history source currently used by the shell.
+
+The most recent lines are printed last. With a count, only the last lines are
+printed, and with --filter, only those containing the given string. The history
+source can be changed while reading a line, with the 'history-source-next' and
+'history-source-prev' commands.`,
+		Example: `    history 20             # The last 20 lines.
+    history -f deploy 5    # The last 5 lines containing 'deploy'.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			source := shell.History.Current()
+			if source == nil {
+				return nil
+			}
+
+			filter, _ := cmd.Flags().GetString("filter")
+
+			var numbers []int
+
+			for i := 0; i < source.Len(); i++ {
+				line, err := source.GetLine(i)
+				if err != nil || !strings.Contains(line, filter) {
+					continue
+				}
+
+				numbers = append(numbers, i)
+			}
+
+			if len(args) > 0 {
+				count, err := strconv.Atoi(args[0])
+				if err != nil || count < 0 {
+					return fmt.Errorf("invalid line count: %s", args[0])
+				}
+
+				numbers = numbers[max(len(numbers)-count, 0):]
+			}
+
+			if name := shell.History.Name(); name != "" && cmd.Flags().Changed("source") {
+				fmt.Fprintf(cmd.OutOrStdout(), "History source: %s\n", name)
+			}
+
+			for _, i := range numbers {
+				line, _ := source.GetLine(i)
+				fmt.Fprintf(cmd.OutOrStdout(), "%5d  %s\n", i+1, line)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringP("filter", "f", "", "Only print the lines containing this string")
+	cmd.Flags().BoolP("source", "s", false, "Print the name of the history source first")
+
+	carapace.Gen(cmd).PositionalCompletion(carapace.ActionValues().Usage("number of lines"))
+
+	return cmd
+}
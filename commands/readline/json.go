@@ -0,0 +1,122 @@
+package readline
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"github.com/reeflective/readline"
+	"github.com/reeflective/readline/inputrc"
+)
+
+// bindsDocument is the configuration of the shell, as printed by bind --json.
+type bindsDocument struct {
+	Variables map[string]any            `json:"variables"`
+	Keymaps   map[string]keymapDocument `json:"keymaps"`
+	Recorded  map[string]string         `json:"recorded_macros,omitempty"`
+}
+
+// keymapDocument holds the binds and macros of a keymap, sorted by key sequence.
+type keymapDocument struct {
+	Binds  []bindDocument `json:"binds"`
+	Macros []bindDocument `json:"macros"`
+}
+
+// bindDocument is a key sequence bound to a command or a macro.
+// Both the sequence and the macro are escaped in inputrc notation.
+type bindDocument struct {
+	Sequence string `json:"sequence"`
+	Command  string `json:"command,omitempty"`
+	Macro    string `json:"macro,omitempty"`
+}
+
+// writeJSON writes the variables, and the binds and macros of all keymaps (or only
+// the one given with --keymap) as a JSON document, applying the --changed and
+// --self-insert filters like the other listings.
+func writeJSON(shell *readline.Shell, cmd *cobra.Command, out io.Writer) error {
+	cfg := shell.Config
+	if cmd.Flags().Changed("changed") {
+		cfg = cfgChanged
+	}
+
+	selfInsert, _ := cmd.Flags().GetBool("self-insert")
+
+	doc := bindsDocument{
+		Variables: make(map[string]any),
+		Keymaps:   make(map[string]keymapDocument),
+	}
+
+	for name, value := range cfg.Vars {
+		if name != "" && value != nil {
+			doc.Variables[name] = value
+		}
+	}
+
+	keymaps := make([]string, 0, len(cfg.Binds))
+	if cmd.Flags().Changed("keymap") {
+		keymap, _ := cmd.Flags().GetString("keymap")
+		keymaps = append(keymaps, keymap)
+	} else {
+		for keymap := range cfg.Binds {
+			if keymap != macroKeymap {
+				keymaps = append(keymaps, keymap)
+			}
+		}
+	}
+
+	for _, keymap := range keymaps {
+		doc.Keymaps[keymap] = keymapJSON(cfg.Binds[keymap], selfInsert)
+	}
+
+	if names := recorderOf(shell).names(); len(names) > 0 {
+		doc.Recorded = make(map[string]string, len(names))
+
+		for _, name := range names {
+			macro, _ := recorderOf(shell).get(name)
+			doc.Recorded[name] = inputrc.EscapeMacro(macro)
+		}
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+
+	return encoder.Encode(doc)
+}
+
+// keymapJSON returns the binds and macros of a keymap, sorted by sequence.
+func keymapJSON(binds map[string]inputrc.Bind, selfInsert bool) keymapDocument {
+	keymap := keymapDocument{
+		Binds:  make([]bindDocument, 0),
+		Macros: make([]bindDocument, 0),
+	}
+
+	sequences := make([]string, 0, len(binds))
+	for seq := range binds {
+		sequences = append(sequences, seq)
+	}
+
+	sort.Strings(sequences)
+
+	for _, seq := range sequences {
+		bind := binds[seq]
+
+		switch {
+		case bind.Macro:
+			keymap.Macros = append(keymap.Macros, bindDocument{
+				Sequence: inputrc.Escape(seq),
+				Macro:    inputrc.EscapeMacro(bind.Action),
+			})
+		case bind.Action == "self-insert" && !selfInsert:
+		default:
+			keymap.Binds = append(keymap.Binds, bindDocument{
+				Sequence: inputrc.Escape(seq),
+				Command:  bind.Action,
+			})
+		}
+	}
+
+	return keymap
+}
@@ -0,0 +1,134 @@
+package readline
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/reeflective/readline"
+	"github.com/reeflective/readline/inputrc"
+)
+
+var errNotTerminal = errors.New("bind --key requires an interactive terminal")
+
+// grabKey puts the terminal in raw mode and reads the next key chord pressed,
+// printing its escaped sequence and current binding in the keymap. The chord is
+// bound to the command given as argument, if any, or to the one typed at the
+// prompt which follows (nothing is bound if the prompt is left empty).
+func grabKey(shell *readline.Shell, cmd *cobra.Command, keymap string, args []string) error {
+	stdin := int(os.Stdin.Fd())
+	if !term.IsTerminal(stdin) {
+		return errNotTerminal
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "Press a key chord to bind in the %s keymap...\n", keymap)
+
+	seq, err := readChord(os.Stdin, shell.Config.Binds[keymap], shell.Config.GetInt("keyseq-timeout"))
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+
+	if bind, found := shell.Config.Binds[keymap][seq]; !found {
+		fmt.Fprintf(out, "\"%s\" is not bound\n", inputrc.Escape(seq))
+	} else if bind.Macro {
+		fmt.Fprintf(out, "\"%s\": \"%s\"\n", inputrc.Escape(seq), inputrc.EscapeMacro(bind.Action))
+	} else {
+		fmt.Fprintf(out, "\"%s\": %s\n", inputrc.Escape(seq), bind.Action)
+	}
+
+	// Command to bind the chord to, if any.
+	var command string
+
+	if len(args) > 0 {
+		command = args[0]
+	} else {
+		fmt.Fprint(out, "Bind to command (empty to keep): ")
+
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			fmt.Fprintln(out)
+			return nil
+		}
+
+		command = strings.TrimSpace(line)
+	}
+
+	if command == "" {
+		return nil
+	}
+
+	if _, found := shell.Keymap.Commands()[command]; !found {
+		if !recordMacros(shell).bind(keymap, seq, command) {
+			return fmt.Errorf("Unknown command: %s", command)
+		}
+
+		return nil
+	}
+
+	bindkey := func(keymap string) {
+		if shell.Config.Binds[keymap] == nil {
+			shell.Config.Binds[keymap] = make(map[string]inputrc.Bind)
+		}
+
+		shell.Config.Binds[keymap][seq] = inputrc.Bind{Action: command}
+		cfgChanged.Bind(keymap, seq, command, false)
+	}
+
+	applyToKeymap(keymap, bindkey)
+
+	fmt.Fprintf(out, "\"%s\": %s\n", inputrc.Escape(seq), command)
+
+	return nil
+}
+
+// readChord reads a key chord in raw mode: keys are read as long as the
+// sequence typed so far is the prefix of a longer sequence bound in the
+// keymap (eg. C-x, for C-x C-r), and the full sequence is returned. Like
+// with the shell, the sequence ends if no key is typed within the timeout
+// (in milliseconds, see the keyseq-timeout option): a lone Esc, prefix of
+// all Meta sequences, is thus returned once the timeout expires.
+func readChord(in *os.File, binds map[string]inputrc.Bind, timeout int) (string, error) {
+	stdin := int(in.Fd())
+
+	state, err := term.MakeRaw(stdin)
+	if err != nil {
+		return "", err
+	}
+
+	defer term.Restore(stdin, state)
+
+	var seq string
+
+	buf := make([]byte, 64)
+
+	for {
+		n, err := in.Read(buf)
+		if err != nil {
+			return "", err
+		}
+
+		seq += string(buf[:n])
+
+		if !isBindPrefix(seq, binds) || (timeout > 0 && !waitKey(stdin, timeout)) {
+			return seq, nil
+		}
+	}
+}
+
+// isBindPrefix returns true if the sequence is the prefix of a longer sequence bound in the keymap.
+func isBindPrefix(seq string, binds map[string]inputrc.Bind) bool {
+	for bound := range binds {
+		if len(bound) > len(seq) && strings.HasPrefix(bound, seq) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package readline
+
+// waitKey returns true: the input cannot be polled here, so the
+// next key of a sequence is always waited for, without timeout.
+func waitKey(_ int, _ int) bool {
+	return true
+}
@@ -0,0 +1,24 @@
+//go:build unix
+
+package readline
+
+import (
+	"errors"
+
+	"golang.org/x/sys/unix"
+)
+
+// waitKey returns true if a key is available on the input
+// within the timeout (in milliseconds), or false otherwise.
+func waitKey(stdin int, timeout int) bool {
+	fds := []unix.PollFd{{Fd: int32(stdin), Events: unix.POLLIN}}
+
+	for {
+		ready, err := unix.Poll(fds, timeout)
+		if errors.Is(err, unix.EINTR) {
+			continue
+		}
+
+		return err == nil && ready > 0
+	}
+}
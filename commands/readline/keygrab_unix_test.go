@@ -0,0 +1,65 @@
+//go:build unix
+
+package readline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/creack/pty"
+
+	"github.com/reeflective/readline/inputrc"
+)
+
+func TestReadChord(t *testing.T) {
+	binds := map[string]inputrc.Bind{
+		"\x18\x12": {Action: "re-read-init-file"},
+		"\x1bx":    {Action: "execute-named-cmd"},
+	}
+
+	tests := []struct {
+		name string
+		keys []string
+		want string
+	}{
+		{"single key", []string{"a"}, "a"},
+		{"prefix then key", []string{"\x18", "\x12"}, "\x18\x12"},
+		{"lone escape", []string{"\x1b"}, "\x1b"},
+		{"unfinished prefix", []string{"\x18"}, "\x18"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ptmx, tty, err := pty.Open()
+			if err != nil {
+				t.Skip("no pseudo-terminal:", err)
+			}
+
+			defer ptmx.Close()
+			defer tty.Close()
+
+			go func() {
+				for _, key := range test.keys {
+					ptmx.WriteString(key)
+					time.Sleep(10 * time.Millisecond)
+				}
+			}()
+
+			done := make(chan string, 1)
+
+			go func() {
+				seq, _ := readChord(tty, binds, 100)
+				done <- seq
+			}()
+
+			select {
+			case seq := <-done:
+				if seq != test.want {
+					t.Errorf("sequence = %q, want %q", seq, test.want)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("readChord blocks")
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package readline
+
+import (
+	"fmt"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/spf13/cobra"
+
+	"github.com/reeflective/readline"
+	"github.com/reeflective/readline/inputrc"
+)
+
+// switchKeymapPrefix is the prefix of the commands registered for each keymap
+// created with `bind keymap new`, which make it the main keymap when invoked.
+const switchKeymapPrefix = "switch-keymap-"
+
+// All keymaps created with `bind keymap new`, for each shell: only those can be deleted.
+var customKeymaps = make(map[*readline.Shell]map[string]bool)
+
+// Keymap returns a command named `keymap`, with subcommands to create keymaps at
+// runtime (copying the binds of an existing one) and to delete them. It is added
+// as a subcommand of the bind command.
+func Keymap(shell *readline.Shell) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "keymap",
+		Short: "Create or delete custom readline keymaps",
+		Long: `Create or delete custom readline keymaps.
+
+Custom keymaps are created with the binds of an existing keymap, which can then be
+changed with 'bind -m NAME'. Each custom keymap has a 'switch-keymap-NAME' command,
+making it the main keymap when invoked: it can be bound in other keymaps to enter it,
+and the 'switch-keymap-SOURCE' command is also registered for the source keymap,
+so that it can be bound in the custom one to come back.`,
+		Example: `    bind keymap new menu-nav --from emacs        # Create a keymap with all emacs binds.
+    bind -m menu-nav '\C-n' next-history          # Change its binds.
+    bind '\C-xn' switch-keymap-menu-nav           # C-x n to enter it from emacs,
+    bind -m menu-nav '\C-xn' switch-keymap-emacs  # and to come back to emacs.
+    bind keymap delete menu-nav`,
+	}
+
+	newCmd := &cobra.Command{
+		Use:   "new NAME",
+		Short: "Create a keymap with the binds of an existing one",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+
+			from, _ := cmd.Flags().GetString("from")
+			if from == "" {
+				from = string(shell.Keymap.Main())
+			}
+
+			if _, found := shell.Config.Binds[name]; found {
+				return fmt.Errorf("keymap %s already exists", name)
+			}
+
+			source, found := shell.Config.Binds[from]
+			if !found {
+				return fmt.Errorf("unknown keymap: %s", from)
+			}
+
+			binds := make(map[string]inputrc.Bind, len(source))
+			for seq, bind := range source {
+				binds[seq] = bind
+			}
+
+			shell.Config.Binds[name] = binds
+
+			if customKeymaps[shell] == nil {
+				customKeymaps[shell] = make(map[string]bool)
+			}
+
+			customKeymaps[shell][name] = true
+
+			registerSwitchKeymap(shell, name)
+			registerSwitchKeymap(shell, from)
+
+			fmt.Fprintf(cmd.OutOrStdout(), "Created keymap %s (from %s): enter it with %s%s\n", name, from, switchKeymapPrefix, name)
+
+			return nil
+		},
+	}
+
+	newCmd.Flags().StringP("from", "F", "", "Keymap whose binds are copied (the main keymap by default)")
+
+	newComps := carapace.Gen(newCmd)
+	newComps.FlagCompletion(carapace.ActionMap{
+		"from": completeKeymaps(shell, newCmd),
+	})
+
+	newComps.PositionalCompletion(carapace.ActionValues().Usage("name of the new keymap"))
+
+	deleteCmd := &cobra.Command{
+		Use:   "delete NAME",
+		Short: "Delete a keymap created with 'bind keymap new'",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+
+			if !customKeymaps[shell][name] {
+				return fmt.Errorf("keymap %s was not created with 'bind keymap new'", name)
+			}
+
+			if string(shell.Keymap.Main()) == name {
+				return fmt.Errorf("keymap %s is the main keymap: switch to another one first", name)
+			}
+
+			delete(shell.Config.Binds, name)
+			delete(customKeymaps[shell], name)
+			delete(shell.Keymap.Commands(), switchKeymapPrefix+name)
+
+			return nil
+		},
+	}
+
+	carapace.Gen(deleteCmd).PositionalCompletion(carapace.ActionCallback(func(_ carapace.Context) carapace.Action {
+		results := make([]string, 0)
+
+		for name := range customKeymaps[shell] {
+			results = append(results, name)
+		}
+
+		return carapace.ActionValues(results...).Tag("custom keymaps").Usage("keymap")
+	}))
+
+	cmd.AddCommand(newCmd, deleteCmd)
+
+	return cmd
+}
+
+// registerSwitchKeymap registers the command making the keymap the main one.
+func registerSwitchKeymap(shell *readline.Shell, keymap string) {
+	shell.Keymap.Register(map[string]func(){
+		switchKeymapPrefix + keymap: func() {
+			shell.Keymap.SetMain(keymap)
+		},
+	})
+}
@@ -0,0 +1,321 @@
+package readline
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/spf13/cobra"
+
+	"github.com/reeflective/readline"
+	"github.com/reeflective/readline/inputrc"
+)
+
+const (
+	// macroPrefix is the prefix of the names given to the keyboard macros left unnamed.
+	macroPrefix = "kbd-macro-"
+
+	// macroKeymap is the keymap in which the named keyboard macros are stored, as
+	// macros bound to their name. It is never entered, but it is read from inputrc
+	// files and exported like the other keymaps, which persists the named macros.
+	macroKeymap = "kbd-macros"
+
+	// macroEnd is fed to the shell after a macro, to read the macro back.
+	macroEnd = '\uffff'
+)
+
+// macroRecorder saves the keyboard macros recorded by the shell macro engine with
+// the start/end-kbd-macro (C-x ( and C-x ) in emacs) or macro-toggle-record commands
+// as named macros, which can then be listed, bound to keys and persisted to inputrc.
+// The macros are stored in the shell configuration, so the recorder holds no state.
+type macroRecorder struct {
+	shell *readline.Shell
+}
+
+// Macro returns a command named `macro`, listing the named keyboard macros
+// (recorded in this session, or read from inputrc files), with subcommands
+// to replay or delete them.
+func Macro(shell *readline.Shell) *cobra.Command {
+	macros := recordMacros(shell)
+
+	cmd := &cobra.Command{
+		Use:   "macro",
+		Short: "List, replay and delete named keyboard macros",
+		Long: `List the named keyboard macros, recorded in this session or read from inputrc files.
+
+Macros are recorded with start-kbd-macro/end-kbd-macro (C-x ( and C-x ) in emacs):
+when the recording ends, the name of the macro is asked for in the hint section
+(kbd-macro-1, kbd-macro-2, etc, if no name is given). Macros recorded with
+macro-toggle-record are always saved as kbd-macro-1, kbd-macro-2, etc, and the
+last macro recorded can be named again with name-last-kbd-macro.
+
+Macros can be bound to a key sequence with the bind command, replayed at the next
+prompt with 'macro run', or deleted. They are exported with 'bind --macros-rc', in
+the kbd-macros keymap, so that they are read again from the inputrc file.`,
+		Example: `    macro                   # List named macros and their keys.
+    macro run kbd-macro-1   # Replay the first macro when the next line is read.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			for _, name := range macros.names() {
+				macro, _ := macros.get(name)
+				fmt.Fprintf(cmd.OutOrStdout(), "%s outputs %s\n", name, inputrc.EscapeMacro(macro))
+			}
+
+			return nil
+		},
+	}
+
+	runCmd := &cobra.Command{
+		Use:   "run NAME",
+		Short: "Replay a named macro when the next line is read",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			macro, found := macros.get(args[0])
+			if !found {
+				return fmt.Errorf("Unknown macro: %s", args[0])
+			}
+
+			shell.Keys.Feed(false, []rune(macro)...)
+
+			return nil
+		},
+	}
+
+	deleteCmd := &cobra.Command{
+		Use:   "delete NAME...",
+		Short: "Delete named macros (the key sequences bound to them are kept)",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			for _, name := range args {
+				if _, found := macros.get(name); !found {
+					return fmt.Errorf("Unknown macro: %s", name)
+				}
+
+				macros.delete(name)
+			}
+
+			return nil
+		},
+	}
+
+	carapace.Gen(runCmd).PositionalCompletion(completeMacros(macros))
+	carapace.Gen(deleteCmd).PositionalAnyCompletion(completeMacros(macros).FilterArgs())
+
+	cmd.AddCommand(runCmd, deleteCmd)
+
+	return cmd
+}
+
+// recordMacros returns the named macros of the shell, after wrapping (once per
+// shell) its macro recording commands, so that the recorded macros are saved.
+func recordMacros(shell *readline.Shell) *macroRecorder {
+	recorder := recorderOf(shell)
+
+	commands := shell.Keymap.Commands()
+	if _, wrapped := commands["name-last-kbd-macro"]; wrapped {
+		return recorder
+	}
+
+	end := commands["end-kbd-macro"]
+	toggle := commands["macro-toggle-record"]
+
+	// Stopping an empty recording leaves the previous macro as the last one.
+	stop := func(stop func(), ask bool) {
+		if !shell.Macros.Recording() {
+			stop()
+			return
+		}
+
+		previous := lastMacro(shell)
+		stop()
+
+		if lastMacro(shell) != previous {
+			recorder.saveLast(ask)
+		}
+	}
+
+	shell.Keymap.Register(map[string]func(){
+		"end-kbd-macro":       func() { stop(end, true) },
+		"macro-toggle-record": func() { stop(toggle, false) },
+		"name-last-kbd-macro": func() { recorder.saveLast(true) },
+	})
+
+	return recorder
+}
+
+// recorderOf returns the named macros of a shell, without wrapping its commands.
+func recorderOf(shell *readline.Shell) *macroRecorder {
+	if shell.Config.Binds[macroKeymap] == nil {
+		shell.Config.Binds[macroKeymap] = make(map[string]inputrc.Bind)
+	}
+
+	return &macroRecorder{shell: shell}
+}
+
+// get returns the keys of a named macro.
+func (m *macroRecorder) get(name string) (macro string, found bool) {
+	bind, found := m.shell.Config.Binds[macroKeymap][name]
+
+	return bind.Action, found && bind.Macro
+}
+
+// saveLast saves the last macro recorded by the shell, if any, under the name
+// typed by the user if ask is true, or under the first free kbd-macro-N name.
+func (m *macroRecorder) saveLast(ask bool) {
+	macro := lastMacro(m.shell)
+	if macro == "" {
+		return
+	}
+
+	// Macros might have been deleted, so use the first free name.
+	var name string
+
+	for number := 1; name == ""; number++ {
+		if _, found := m.get(fmt.Sprintf("%s%d", macroPrefix, number)); !found {
+			name = fmt.Sprintf("%s%d", macroPrefix, number)
+		}
+	}
+
+	if ask {
+		if typed := m.readName(name); typed != "" {
+			name = typed
+		}
+	}
+
+	m.shell.Config.Binds[macroKeymap][name] = inputrc.Bind{Action: macro, Macro: true}
+	cfgChanged.Bind(macroKeymap, name, macro, true)
+}
+
+// lastMacro returns the last macro recorded by the shell macro engine, by having
+// it feed the macro to the shell, and reading the macro keys back. Any key fed
+// beforehand (by a macro being replayed) is read first, and fed again afterwards.
+func lastMacro(shell *readline.Shell) string {
+	feedMacroEnd := func() []rune {
+		shell.Keys.Feed(false, macroEnd)
+
+		var keys []rune
+
+		for key, _ := shell.Keys.ReadKey(); key != macroEnd; key, _ = shell.Keys.ReadKey() {
+			keys = append(keys, key)
+		}
+
+		return keys
+	}
+
+	fed := feedMacroEnd()
+
+	shell.Macros.RunLastMacro()
+	macro := feedMacroEnd()
+
+	shell.Keys.Feed(true, fed...)
+
+	return string(macro)
+}
+
+// readName reads the name of the macro just recorded, typed in the hint section.
+// It returns an empty name if nothing is typed, or if Escape or Ctrl-G is pressed.
+// Names are made of letters, digits, dashes and underscores, and cannot be the
+// name of a command, to which the bind command would bind them instead.
+func (m *macroRecorder) readName(unnamed string) string {
+	defer m.shell.Hint.Reset()
+
+	var name []rune
+
+	status := fmt.Sprintf("(empty for %s)", unnamed)
+
+	for {
+		m.shell.Hint.Set(fmt.Sprintf("Macro name %s: %s", status, string(name)))
+		m.shell.Display.Refresh()
+
+		key, empty := m.shell.Keys.Pop()
+		char := rune(key)
+
+		if empty {
+			var abort bool
+			if char, abort = m.shell.Keys.ReadKey(); abort {
+				return ""
+			}
+		}
+
+		switch char {
+		case '\r', '\n':
+			if _, found := m.shell.Keymap.Commands()[string(name)]; !found {
+				return string(name)
+			}
+
+			status = fmt.Sprintf("(%s is a command)", string(name))
+		case inputrc.Esc, 0x07:
+			return ""
+		case 0x7f, 0x08:
+			if len(name) > 0 {
+				name = name[:len(name)-1]
+			}
+		default:
+			if validMacroName(char) {
+				name = append(name, char)
+			}
+		}
+	}
+}
+
+// validMacroName returns true if the character is allowed in macro names.
+func validMacroName(char rune) bool {
+	return char == '-' || char == '_' ||
+		char >= 'a' && char <= 'z' || char >= 'A' && char <= 'Z' || char >= '0' && char <= '9'
+}
+
+// delete deletes a named macro.
+func (m *macroRecorder) delete(name string) {
+	delete(m.shell.Config.Binds[macroKeymap], name)
+	delete(cfgChanged.Binds[macroKeymap], name)
+}
+
+// names returns the names of all named macros, sorted by name, and
+// with the unnamed ones last, sorted by number.
+func (m *macroRecorder) names() []string {
+	var names []string
+
+	for name, bind := range m.shell.Config.Binds[macroKeymap] {
+		if bind.Macro {
+			names = append(names, name)
+		}
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		unnamed := strings.HasPrefix(names[i], macroPrefix)
+		if unnamed != strings.HasPrefix(names[j], macroPrefix) {
+			return !unnamed
+		}
+
+		if unnamed && len(names[i]) != len(names[j]) {
+			return len(names[i]) < len(names[j])
+		}
+
+		return names[i] < names[j]
+	})
+
+	return names
+}
+
+// bind binds a named macro to a key sequence in the given keymap,
+// returning false if no macro with this name exists.
+func (m *macroRecorder) bind(keymap, seq, name string) bool {
+	macro, found := m.get(name)
+	if !found {
+		return false
+	}
+
+	bindMacro := func(keymap string) {
+		if m.shell.Config.Binds[keymap] == nil {
+			m.shell.Config.Binds[keymap] = make(map[string]inputrc.Bind)
+		}
+
+		m.shell.Config.Binds[keymap][seq] = inputrc.Bind{Action: macro, Macro: true}
+		cfgChanged.Bind(keymap, seq, macro, true)
+	}
+
+	applyToKeymap(keymap, bindMacro)
+
+	return true
+}
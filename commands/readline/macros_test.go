@@ -0,0 +1,75 @@
+package readline
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/reeflective/readline"
+	"github.com/reeflective/readline/inputrc"
+	"github.com/spf13/cobra"
+
+	"github.com/reeflective/console"
+	"github.com/reeflective/console/consoletest"
+)
+
+func TestMacroNamesSortedByNumber(t *testing.T) {
+	shell := readline.NewShell()
+	recorder := recordMacros(shell)
+
+	for number := 1; number <= 12; number++ {
+		shell.Config.Binds[macroKeymap][fmt.Sprintf("%s%d", macroPrefix, number)] = inputrc.Bind{Action: "echo", Macro: true}
+	}
+
+	shell.Config.Binds[macroKeymap]["deploy"] = inputrc.Bind{Action: "deploy", Macro: true}
+
+	names := recorder.names()
+
+	if names[0] != "deploy" || names[2] != macroPrefix+"2" || names[len(names)-1] != macroPrefix+"12" {
+		t.Errorf("macros listed as %q", names)
+	}
+}
+
+func TestRecordMacrosOnShell(t *testing.T) {
+	shell := readline.NewShell()
+	other := readline.NewShell()
+
+	recordMacros(shell)
+	recordMacros(other)
+
+	shell.Config.Binds[macroKeymap]["deploy"] = inputrc.Bind{Action: "deploy", Macro: true}
+
+	if _, found := recordMacros(shell).get("deploy"); !found {
+		t.Error("macro of a shell not found by another recorder of the shell")
+	}
+
+	if _, found := recordMacros(other).get("deploy"); found {
+		t.Error("macro of a shell found in another one")
+	}
+}
+
+func TestRecordNamedMacro(t *testing.T) {
+	app := console.New("test")
+	app.NewlineBefore, app.NewlineAfter = false, false
+
+	app.ActiveMenu().SetCommands(func() *cobra.Command {
+		root := &cobra.Command{SilenceErrors: true}
+		root.AddCommand(Commands(app.Shell()))
+
+		return root
+	})
+
+	term := consoletest.New(t, app)
+
+	term.Type("\x18(echo hi\x18)deploy\r")
+
+	macro, found := recorderOf(app.Shell()).get("deploy")
+	if !found || macro != "echo hi" {
+		t.Errorf("macro deploy recorded as %q (found: %t), want %q", macro, found, "echo hi")
+	}
+
+	term.Type("\x15\x18(ls\x18)\r")
+
+	if macro, _ := recorderOf(app.Shell()).get(macroPrefix + "1"); macro != "ls" {
+		t.Errorf("unnamed macro recorded as %q, want %q", macro, "ls")
+	}
+}
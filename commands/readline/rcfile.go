@@ -0,0 +1,123 @@
+package readline
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/reeflective/console"
+)
+
+var errExportNoCond = errors.New("--out requires --app or --lib, so that the exported snippet is wrapped in a $if block")
+
+// exportRC writes an inputrc snippet, wrapped in a '$if name' block and preceded by
+// its header comment, to the file at path: if the file already has a block with the
+// same header and condition (from a previous export), it is replaced, otherwise the
+// snippet is appended. The file is replaced atomically. With dryRun, nothing is
+// written, and the changes that would be made to the file are printed instead.
+func exportRC(out io.Writer, path, header, name, snippet string, dryRun bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	updated := replaceRCBlock(string(data), header, name, snippet)
+
+	if dryRun {
+		diff := console.Diff(string(data), updated, console.DiffOptions{OldName: path, NewName: path + " (exported)"})
+		if diff == "" {
+			fmt.Fprintf(out, "%s is up to date\n", path)
+		} else {
+			fmt.Fprint(out, diff)
+		}
+
+		return nil
+	}
+
+	if updated == string(data) {
+		return nil
+	}
+
+	if err := writeFileAtomic(path, []byte(updated)); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Exported to %s\n", path)
+
+	return nil
+}
+
+// replaceRCBlock returns the contents of an inputrc file, in which the block
+// starting with the header line and a '$if name' line (until the matching
+// $endif) is replaced with the snippet, or to which the snippet is appended.
+func replaceRCBlock(data, header, name, snippet string) string {
+	snippet = strings.TrimRight(snippet, "\n") + "\n"
+	lines := strings.SplitAfter(data, "\n")
+
+	for start := 0; start < len(lines)-1; start++ {
+		if strings.TrimSpace(lines[start]) != header || strings.TrimSpace(lines[start+1]) != "$if "+name {
+			continue
+		}
+
+		depth := 0
+
+		for end := start + 1; end < len(lines); end++ {
+			line := strings.TrimSpace(lines[end])
+
+			switch {
+			case strings.HasPrefix(line, "$if"):
+				depth++
+			case strings.HasPrefix(line, "$endif"):
+				depth--
+			}
+
+			if depth == 0 {
+				return strings.Join(lines[:start], "") + snippet + strings.Join(lines[end+1:], "")
+			}
+		}
+	}
+
+	switch {
+	case data == "":
+		return snippet
+	case strings.HasSuffix(data, "\n"):
+		return data + "\n" + snippet
+	default:
+		return data + "\n\n" + snippet
+	}
+}
+
+// writeFileAtomic writes a file through a temporary file in the same directory,
+// renamed to the path once written, so that the file is never partially written.
+// The mode of the existing file, if any, is kept.
+func writeFileAtomic(path string, data []byte) error {
+	temp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return err
+	}
+
+	if err := temp.Close(); err != nil {
+		return err
+	}
+
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode()
+	}
+
+	if err := os.Chmod(temp.Name(), mode); err != nil {
+		return err
+	}
+
+	return os.Rename(temp.Name(), path)
+}
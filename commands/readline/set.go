@@ -2,6 +2,8 @@ package readline
 
 import (
 	"errors"
+	"fmt"
+	"os"
 	"strconv"
 	"strings"
 
@@ -20,38 +22,54 @@ import (
 // This config only stores the vars/binds that have been changed.
 var cfgChanged = inputrc.NewConfig()
 
+// Variables whose values must be one of a fixed set of strings.
+// Other string variables accept any value.
+var enumVars = map[string][]string{
+	"bell-style":   {"none", "visible", "audible"},
+	"editing-mode": {"vi", "emacs"},
+	"cursor-style": {"block", "beam", "underline", "blinking-block", "blinking-underline", "blinking-beam", "default"},
+}
+
 // Set returns a command named `set`, for manipulating readline global options.
 func Set(shell *readline.Shell) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "set",
-		Short: "Manipulate readline global options",
-		Long:  `Manipulate readline global options.`,
-		Args:  cobra.MinimumNArgs(2),
+		Short: "Display or modify readline global options",
+		Long: `Display or modify readline global options.
+
+When used without arguments, all options and their values are listed.
+When used with a single option name, its current value is printed.
+When used with an option name and a value, the option is set: the value
+is validated against the option type (on/off, integer, or allowed values).`,
+		Example: `    set                          # List all options and their values.
+    set editing-mode vi          # Switch to vim editing mode.
+    set history-size 500 -s ~/.inputrc  # Set the option, and append it to the inputrc file.`,
+		Args: cobra.RangeArgs(0, 2),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			// First argument is the key.
-			// It must be one of the options available in the shell.
-			option := shell.Config.Get(args[0])
-			if option == nil {
-				return errors.New("Unknown readline option: " + args[0])
-			}
+			buf := &cfgBuilder{buf: &strings.Builder{}}
 
-			// Second argument is the value.
-			var value interface{}
-			var err error
+			switch len(args) {
+			case 0:
+				listVars(shell, buf, cmd)
+				fmt.Fprintln(cmd.OutOrStdout(), buf.buf.String())
 
-			// It must be a valid value for the option.
-			switch option.(type) {
-			case bool:
-				if args[1] != "on" && args[1] != "off" && args[1] != "true" && args[1] != "false" {
-					return errors.New("Invalid value for boolean option " + args[0] + ": " + args[1])
-				}
-				value = args[1] == "on" || args[1] == "true"
+				return nil
 
-			case int:
-				value, err = strconv.Atoi(args[1])
-				if err != nil {
-					return errors.New("Invalid value for integer option " + args[0] + ": " + args[1])
+			case 1:
+				option := shell.Config.Get(args[0])
+				if option == nil {
+					return errors.New("Unknown readline option: " + args[0])
 				}
+
+				fmt.Fprintf(cmd.OutOrStdout(), "%s is set to `%v'\n", args[0], printVar(option))
+
+				return nil
+			}
+
+			// First argument is the key, second is the value.
+			value, err := parseVar(shell, args[0], args[1])
+			if err != nil {
+				return err
 			}
 
 			// Set the option.
@@ -59,19 +77,44 @@ func Set(shell *readline.Shell) *cobra.Command {
 				return err
 			}
 
-			return cfgChanged.Set(args[0], value)
+			// Some options must be applied to the shell immediately.
+			switch args[0] {
+			case "editing-mode":
+				if value == "vi" {
+					shell.Keymap.SetMain("vi-insert")
+				} else {
+					shell.Keymap.SetMain("emacs")
+				}
+			case "keymap":
+				shell.Keymap.SetMain(value.(string))
+			}
+
+			if err = cfgChanged.Set(args[0], value); err != nil {
+				return err
+			}
+
+			// Optionally persist the change to an inputrc file.
+			if !cmd.Flags().Changed("save") {
+				return nil
+			}
+
+			file, _ := cmd.Flags().GetString("save")
+
+			return appendRC(file, fmt.Sprintf("set %s %s\n", args[0], printVar(value)))
 		},
 	}
 
+	cmd.Flags().StringP("save", "s", "", "Append the option to the given inputrc file once set")
+
 	// Completions
 	varComps := func(_ carapace.Context) carapace.Action {
 		results := make([]string, 0)
 
-		for varName := range shell.Config.Vars {
-			results = append(results, varName)
+		for varName, value := range shell.Config.Vars {
+			results = append(results, varName, fmt.Sprintf("%v", printVar(value)))
 		}
 
-		return carapace.ActionValues(results...).Tag("global options").Usage("option name")
+		return carapace.ActionValuesDescribed(results...).Tag("global options").Usage("option name")
 	}
 
 	argComp := func(c carapace.Context) carapace.Action {
@@ -79,15 +122,14 @@ func Set(shell *readline.Shell) *cobra.Command {
 
 		option := shell.Config.Get(val)
 		if option == nil {
-			return carapace.ActionMessage("No var named %v", option)
+			return carapace.ActionMessage("No var named %v", val)
+		}
+
+		if values, isEnum := enumVars[val]; isEnum {
+			return carapace.ActionValues(values...).Usage("option value")
 		}
 
-		switch val {
-		case "cursor-style":
-			return carapace.ActionValues("block", "beam", "underline", "blinking-block", "blinking-underline", "blinking-beam", "default")
-		case "editing-mode":
-			return carapace.ActionValues("vi", "emacs")
-		case "keymap":
+		if val == "keymap" {
 			return completeKeymaps(shell, cmd)
 		}
 
@@ -103,7 +145,9 @@ func Set(shell *readline.Shell) *cobra.Command {
 		return carapace.ActionValues().Usage("option value")
 	}
 
-	carapace.Gen(cmd).PositionalCompletion(
+	comps := carapace.Gen(cmd)
+	comps.FlagCompletion(carapace.ActionMap{"save": carapace.ActionFiles()})
+	comps.PositionalCompletion(
 		carapace.ActionCallback(varComps),
 		carapace.ActionCallback(argComp),
 	)
@@ -111,6 +155,73 @@ func Set(shell *readline.Shell) *cobra.Command {
 	return cmd
 }
 
+// parseVar validates a value against the type of the named option,
+// and returns it converted to this type if valid.
+func parseVar(shell *readline.Shell, name, arg string) (interface{}, error) {
+	option := shell.Config.Get(name)
+	if option == nil {
+		return nil, errors.New("Unknown readline option: " + name)
+	}
+
+	switch option.(type) {
+	case bool:
+		switch arg {
+		case "on", "true":
+			return true, nil
+		case "off", "false":
+			return false, nil
+		}
+
+		return nil, errors.New("Invalid value for boolean option " + name + ": " + arg + " (on/off)")
+
+	case int:
+		value, err := strconv.Atoi(arg)
+		if err != nil {
+			return nil, errors.New("Invalid value for integer option " + name + ": " + arg)
+		}
+
+		return value, nil
+	}
+
+	if values, isEnum := enumVars[name]; isEnum && !slices.Contains(values, arg) {
+		return nil, fmt.Errorf("Invalid value for option %s: %s (%s)", name, arg, strings.Join(values, "/"))
+	}
+
+	if name == "keymap" && shell.Config.Binds[arg] == nil {
+		return nil, errors.New("Invalid keymap: " + arg)
+	}
+
+	return arg, nil
+}
+
+// printVar returns the inputrc representation of an option value.
+func printVar(value interface{}) interface{} {
+	if on, ok := value.(bool); ok {
+		if on {
+			return printOn
+		}
+
+		return printOff
+	}
+
+	return value
+}
+
+// appendRC appends an inputrc snippet to the given file, creating it if needed.
+func appendRC(path, snippet string) error {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+
+	if _, err = file.WriteString(snippet); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
 // Returns the subset of inputrc variables that are specific
 // to this library and application/binary.
 func filterAppLibVars(cfgVars map[string]interface{}) map[string]interface{} {
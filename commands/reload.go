@@ -0,0 +1,26 @@
+package commands
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/reeflective/console"
+)
+
+// Reload returns a command reloading the configuration of the console while it runs:
+// the inputrc files, and the configuration of the application reloaded by its hooks
+// (see console.Console.OnReload), such as its prompts, aliases and startup files.
+// What changed is reported once reloaded. This is the same as sending SIGHUP to the
+// process, for applications calling console.Console.ReloadOnHangup.
+func Reload(app *console.Console) *cobra.Command {
+	return &cobra.Command{
+		Use:     "reload",
+		Short:   "Reload the configuration (inputrc, prompts, aliases...)",
+		GroupID: "core",
+		Args:    cobra.NoArgs,
+		// Errors are about the configuration files, not the command usage.
+		SilenceUsage: true,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return app.Reload()
+		},
+	}
+}
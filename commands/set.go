@@ -0,0 +1,62 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/reeflective/console"
+)
+
+// Set returns a command capturing the result of a command line into a console variable,
+// which can then be referenced in other command lines with $name (or $name.field for its
+// fields, when the result is structured): `set name = command args...`. Without any
+// command, `set name` prints the variable value, `set name =` deletes it, and `set`
+// lists all the variables.
+func Set(app *console.Console) *cobra.Command {
+	return &cobra.Command{
+		Use:                "set [name [= command...]]",
+		Short:              "Capture the result of a command into a variable",
+		GroupID:            "core",
+		DisableFlagParsing: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			switch {
+			case len(args) == 0:
+				for _, name := range app.Variables() {
+					value, _ := app.Variable(name)
+					fmt.Fprintf(cmd.OutOrStdout(), "%s = %v\n", name, value)
+				}
+
+				return nil
+
+			case len(args) == 1:
+				value, found := app.Variable(args[0])
+				if !found {
+					return fmt.Errorf("unknown variable %q", args[0])
+				}
+
+				fmt.Fprintln(cmd.OutOrStdout(), value)
+
+				return nil
+
+			case args[1] != "=":
+				return errors.New("usage: set name = command...")
+
+			case len(args) == 2:
+				app.SetVariable(args[0], nil)
+
+				return nil
+			}
+
+			value, err := app.Capture(cmd.Context(), args[2:])
+			if err != nil {
+				return err
+			}
+
+			app.SetVariable(args[0], value)
+
+			return nil
+		},
+	}
+}
@@ -0,0 +1,45 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/reeflective/console"
+)
+
+// Watch returns a command executing another command line every interval, replacing
+// its previous output in place, until interrupted with Ctrl-C. The interval is either
+// a duration (eg. 500ms, 2s, 1m) or a number of seconds.
+func Watch(app *console.Console) *cobra.Command {
+	return &cobra.Command{
+		Use:                "watch <interval> <command...>",
+		Short:              "Execute a command periodically, showing its output in place",
+		GroupID:            "core",
+		DisableFlagParsing: true,
+		Args:               cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			interval, err := parseInterval(args[0])
+			if err != nil {
+				return err
+			}
+
+			return app.Watch(cmd.Context(), args[1:], interval)
+		},
+	}
+}
+
+func parseInterval(value string) (time.Duration, error) {
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+
+	interval, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid interval %q: %w", value, err)
+	}
+
+	return interval, nil
+}
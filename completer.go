@@ -18,74 +18,173 @@ import (
 	"github.com/reeflective/readline"
 )
 
-func (c *Console) complete(line []rune, pos int) readline.Completions {
+func (c *Console) complete(line []rune, pos int) (comps readline.Completions) {
+	defer c.profilePhase("completion")()
+
+	// Candidates are listed rather than displayed in a menu.
+	if c.accessible.Load() {
+		defer func() { comps = c.announceCompletions(comps, line, pos) }()
+	}
+
 	menu := c.activeMenu()
 
-	// Ensure the carapace library is called so that the function
-	// completer.Complete() variable is correctly initialized before use.
-	carapace.Gen(menu.Command)
+	// The word being completed, and its rest when completing mid-word.
+	word := typedWord(line, pos)
+
+	// Completions already generated for this input line.
+	key := cacheKey(menu, line, pos) + "\x00" + word.suffix
+	if comps, found := c.completionCache.get(key); found {
+		return comps
+	}
 
 	// Split the line as shell words, only using
 	// what the right buffer (up to the cursor)
-	args, prefixComp, prefixLine := splitArgs(line, pos)
+	args := splitArgs(line, pos)
 
-	// Prepare arguments for the carapace completer
-	// (we currently need those two dummies for avoiding a panic).
-	args = append([]string{c.name, "_carapace"}, args...)
+	result := c.completeArgs(menu, args)
 
-	// Call the completer with our current command context.
-	completions, err := completer.Complete(menu.Command, args...)
+	// Values used before first, if asked to.
+	ordered := c.orderByUsage(menu, args, result.values)
+	values, recent := c.recentValues(menu, args, result.values)
 
-	// The completions are never nil: fill out our own object
-	// with everything it contains, regardless of errors.
-	raw := make([]readline.Completion, len(completions.Values))
+	// Fill out our own object with everything the completer returned,
+	// with the values quoted like the word typed, to replace it.
+	raw := make([]readline.Completion, 0, len(values))
 
-	for idx, val := range completions.Values.Decolor() {
-		raw[idx] = readline.Completion{
-			Value:       unescapeValue(prefixComp, prefixLine, val.Value),
+	for _, val := range values {
+		closed := !result.nospace(val.Value)
+
+		value, matches := word.lineValue(args[len(args)-1], val.Value, closed)
+		if !matches {
+			continue
+		}
+
+		if closed && !word.midWord {
+			value += " "
+		}
+
+		comp := readline.Completion{
+			Value:       value,
 			Display:     val.Display,
 			Description: val.Description,
 			Style:       val.Style,
 			Tag:         val.Tag,
 		}
 
-		if !completions.Nospace.Matches(val.Value) {
-			raw[idx].Value = val.Value + " "
+		// Candidates are displayed unquoted.
+		if comp.Display == "" && strings.TrimSuffix(value, " ") != val.Value {
+			comp.Display = val.Value
+		}
+
+		if c.BidiRendering && hasRTL(val.Value) {
+			display := val.Display
+			if display == "" {
+				display = val.Value
+			}
+
+			comp.Display = visualOrder(display)
+			comp.Description = visualOrder(val.Description)
 		}
+
+		raw = append(raw, comp)
 	}
 
 	// Assign both completions and command/flags/args usage strings.
-	comps := readline.CompleteRaw(raw)
-	comps = comps.Usage("%s", completions.Usage)
+	comps = readline.CompleteRaw(raw)
+	comps = comps.Usage("%s", result.usage)
 	comps = c.justifyCommandComps(comps)
 
+	if ordered {
+		comps = comps.NoSort()
+	} else if recent {
+		comps = comps.NoSort(tr("recent values"))
+	}
+
 	// If any errors arose from the completion call itself.
-	if err != nil {
-		comps = readline.CompleteMessage("failed to load config: " + err.Error())
+	if result.err != nil {
+		comps = readline.CompleteMessage("failed to load config: " + result.err.Error())
 	}
 
 	// Completion status/errors
-	for _, msg := range completions.Messages.Get() {
+	for _, msg := range result.messages {
 		comps = comps.Merge(readline.CompleteMessage(msg))
 	}
 
 	// Suffix matchers for the completions if any.
-	suffixes, err := completions.Nospace.MarshalJSON()
-	if len(suffixes) > 0 && err == nil {
-		comps = comps.NoSpace([]rune(string(suffixes))...)
+	if result.suffixes != "" {
+		comps = comps.NoSpace([]rune(result.suffixes)...)
 	}
 
-	// If we have a quote/escape sequence unaccounted
-	// for in our completions, add it to all of them.
-	comps = comps.Prefix(prefixComp)
-	comps.PREFIX = prefixLine
+	// The values replace the whole word typed before the cursor.
+	comps.PREFIX = word.prefix
 
-	// Finally, reset our command tree for the next call.
+	c.completionCache.add(key, comps, c.CompletionCacheSize)
+
+	return comps
+}
+
+// completionResult is what the carapace completer returns for a command line.
+type completionResult struct {
+	values   []readline.Completion   // Candidates, without colors, and without trailing spaces.
+	nospace  func(value string) bool // Returns true if no space should be added after the value.
+	suffixes string                  // Characters removed after a candidate when inserting a space.
+	usage    string                  // Usage of the command, flag or argument being completed.
+	messages []string                // Completion messages (errors, warnings, etc).
+	err      error
+}
+
+// completeArgs calls the carapace completer for the command line words
+// (the last one being completed), with the command tree of the menu,
+// which is reset afterwards, ready for the next call or execution.
+func (c *Console) completeArgs(menu *Menu, args []string) completionResult {
+	// Build the lazy command groups used in the line.
+	menu.loadLazyGroups(args[:len(args)-1])
+
+	// The command tree cannot be regenerated (eg. when switching
+	// menus from another goroutine) while it is being completed.
+	menu.mutex.Lock()
+
+	// Ensure the carapace library is called so that the function
+	// completer.Complete() variable is correctly initialized before use.
+	carapace.Gen(menu.Command)
+
+	// Prepare arguments for the carapace completer
+	// (we currently need those two dummies for avoiding a panic).
+	args = append([]string{c.name, "_carapace"}, args...)
+
+	// Call the completer with our current command context.
+	completions, err := completer.Complete(menu.Command, args...)
 	completer.ClearStorage()
+
+	menu.mutex.Unlock()
+
+	// The completions are never nil, regardless of errors.
+	result := completionResult{
+		values:   make([]readline.Completion, len(completions.Values)),
+		nospace:  completions.Nospace.Matches,
+		usage:    completions.Usage,
+		messages: completions.Messages.Get(),
+		err:      err,
+	}
+
+	for idx, val := range completions.Values.Decolor() {
+		result.values[idx] = readline.Completion{
+			Value:       val.Value,
+			Display:     val.Display,
+			Description: val.Description,
+			Style:       val.Style,
+			Tag:         val.Tag,
+		}
+	}
+
+	if suffixes, err := completions.Nospace.MarshalJSON(); err == nil {
+		result.suffixes = string(suffixes)
+	}
+
+	// Finally, reset our command tree for the next call.
 	menu.resetPreRun()
-	menu.hideFilteredCommands(menu.Command)
 
-	return comps
+	return result
 }
 
 func (c *Console) justifyCommandComps(comps readline.Completions) readline.Completions {
@@ -130,10 +229,10 @@ func (c *Console) defaultStyleConfig() {
 	style.Set("carapace.FlagOptArg", "bright-white")
 }
 
-// splitArgs splits the line in valid words, prepares them in various ways before calling
-// the completer with them, and also determines which parts of them should be used as
-// prefixes, in the completions and/or in the line.
-func splitArgs(line []rune, pos int) (args []string, prefixComp, prefixLine string) {
+// splitArgs splits the line in valid words, up to the cursor, and prepares them in
+// various ways before calling the completer with them: the last word is the one
+// being completed, unquoted, possibly empty.
+func splitArgs(line []rune, pos int) (args []string) {
 	line = line[:pos]
 
 	// Remove all colors from the string
@@ -149,18 +248,14 @@ func splitArgs(line []rune, pos int) (args []string, prefixComp, prefixLine stri
 	// for the completer to understand we want a new word comp.
 	mustComplete, args, remain := mustComplete(line, args, remain, err)
 	if mustComplete {
-		return sanitizeArgs(args), "", remain
+		return sanitizeArgs(args)
 	}
 
-	// But the completion candidates themselves might need slightly
-	// different prefixes, for an optimal completion experience.
-	arg, prefixComp, prefixLine := adjustQuotedPrefix(remain, err)
-
 	// The remainder is everything following the open charater.
 	// Pass it as is to the carapace completion engine.
-	args = append(args, arg)
+	args = append(args, unquotedRemain(remain, err))
 
-	return sanitizeArgs(args), prefixComp, prefixLine
+	return sanitizeArgs(args)
 }
 
 func mustComplete(line []rune, args []string, remain string, err error) (bool, []string, string) {
@@ -193,21 +288,175 @@ func mustComplete(line []rune, args []string, remain string, err error) (bool, [
 	return true, args, remain
 }
 
-func adjustQuotedPrefix(remain string, err error) (arg, comp, line string) {
+// unquotedRemain returns the word being completed when it has an unterminated
+// quote or escape, from the remainder of the words split before it.
+func unquotedRemain(remain string, err error) (arg string) {
 	arg = remain
 
-	switch {
-	case errors.Is(err, errUnterminatedDoubleQuote):
-		comp = "\""
-		line = comp + arg
-	case errors.Is(err, errUnterminatedSingleQuote):
-		comp = "'"
-		line = comp + arg
-	case errors.Is(err, errUnterminatedEscape):
+	if errors.Is(err, errUnterminatedEscape) {
 		arg = strings.ReplaceAll(arg, "\\", "")
 	}
 
-	return arg, comp, line
+	return arg
+}
+
+// completedWord is the word being completed in the input line, as typed.
+type completedWord struct {
+	prefix  string // The word up to the cursor, with its quotes and escapes.
+	suffix  string // The rest of the word after the cursor, unquoted.
+	midWord bool   // The cursor is in the middle of the word.
+	quote   rune   // The quote left open at the cursor, if any.
+}
+
+// typedWord returns the word under the cursor, or before it, taking quotes and
+// escapes into account, and the rest of it after the cursor.
+func typedWord(line []rune, pos int) (word completedWord) {
+	start := 0
+	escaped := false
+
+	for i, char := range line[:pos] {
+		switch {
+		case escaped:
+			escaped = false
+		case char == escapeChar && word.quote != singleChar:
+			escaped = true
+		case word.quote != 0:
+			if char == word.quote {
+				word.quote = 0
+			}
+		case char == singleChar || char == doubleChar:
+			word.quote = char
+		case strings.ContainsRune(splitChars, char):
+			start = i + 1
+		}
+	}
+
+	word.prefix = string(line[start:pos])
+
+	end := pos
+	quote := word.quote
+
+	for end < len(line) {
+		char := line[end]
+
+		switch {
+		case escaped:
+			escaped = false
+		case char == escapeChar && quote != singleChar:
+			escaped = true
+		case quote != 0:
+			if char == quote {
+				quote = 0
+			}
+		case char == singleChar || char == doubleChar:
+			quote = char
+		case strings.ContainsRune(splitChars, char):
+			word.suffix = unquoteWord(string(line[pos:end]), word.quote)
+			word.midWord = end > pos
+
+			return word
+		}
+
+		end++
+	}
+
+	word.suffix = unquoteWord(string(line[pos:end]), word.quote)
+	word.midWord = end > pos
+
+	return word
+}
+
+// lineValue returns the value replacing the word typed before the cursor for a candidate,
+// given the unquoted word passed to the completer: the word is kept as typed, followed by
+// the rest of the value quoted like it, and by the closing quote if the value is complete.
+// Mid-word, the value must end with the rest of the word after the cursor, and only the
+// missing part is inserted before it. It returns false if the candidate does not match.
+func (w completedWord) lineValue(word, value string, complete bool) (string, bool) {
+	rest, found := strings.CutPrefix(value, word)
+
+	if w.midWord {
+		middle, matches := strings.CutSuffix(rest, w.suffix)
+		if !found || !matches {
+			return "", false
+		}
+
+		return w.prefix + quoteWord(middle, w.quote), true
+	}
+
+	line := w.prefix + quoteWord(rest, w.quote)
+
+	// The shell might match candidates ignoring case, in which
+	// case the whole value replaces the word, quoted like it.
+	if !found {
+		switch {
+		case len(value) < len(word) || !strings.EqualFold(value[:len(word)], word):
+			return "", false
+		case w.quote == 0:
+			line = quoteWord(value, 0)
+		case strings.HasPrefix(w.prefix, string(w.quote)):
+			line = string(w.quote) + quoteWord(value, w.quote)
+		default:
+			return "", false
+		}
+	}
+
+	if complete && w.quote != 0 {
+		line += string(w.quote)
+	}
+
+	return line, true
+}
+
+// quoteWord escapes a part of a word inserted in the input line, either out of
+// quotes (with backslashes), or inside the single or double quote left open.
+func quoteWord(text string, quote rune) string {
+	var quoted strings.Builder
+
+	for _, char := range text {
+		switch {
+		case quote == singleChar && char == singleChar:
+			quoted.WriteString(`'\''`)
+			continue
+		case quote == doubleChar && (char == doubleChar || char == escapeChar):
+			quoted.WriteRune(escapeChar)
+		case quote == 0 && strings.ContainsRune(splitChars+`'"\`, char):
+			quoted.WriteRune(escapeChar)
+		}
+
+		quoted.WriteRune(char)
+	}
+
+	return quoted.String()
+}
+
+// unquoteWord removes the quotes and escapes of a part of a word,
+// starting in the given quote (if not zero), like the shell would.
+func unquoteWord(text string, quote rune) string {
+	var word strings.Builder
+
+	runes := []rune(text)
+
+	for i := 0; i < len(runes); i++ {
+		char := runes[i]
+
+		switch {
+		case char == escapeChar && quote == 0 && i+1 < len(runes):
+			i++
+			word.WriteRune(runes[i])
+		case char == escapeChar && quote == doubleChar && i+1 < len(runes) &&
+			strings.ContainsRune(doubleEscapeChars, runes[i+1]):
+			i++
+			word.WriteRune(runes[i])
+		case quote != 0 && char == quote:
+			quote = 0
+		case quote == 0 && (char == singleChar || char == doubleChar):
+			quote = char
+		default:
+			word.WriteRune(char)
+		}
+	}
+
+	return word.String()
 }
 
 // sanitizeArg unescapes a restrained set of characters.
@@ -220,19 +469,6 @@ func sanitizeArgs(args []string) (sanitized []string) {
 	return sanitized
 }
 
-// when the completer has returned us some completions, we sometimes
-// needed to post-process them a little before passing them to our shell.
-func unescapeValue(prefixComp, prefixLine, val string) string {
-	quoted := strings.HasPrefix(prefixLine, "\"") ||
-		strings.HasPrefix(prefixLine, "'")
-
-	if quoted {
-		val = strings.ReplaceAll(val, "\\ ", " ")
-	}
-
-	return val
-}
-
 // split has been copied from go-shellquote and slightly modified so as to also
 // return the remainder when the parsing failed because of an unterminated quote.
 func splitCompWords(input string) (words []string, remainder string, err error) {
@@ -331,7 +567,8 @@ single:
 	{
 		i := strings.IndexRune(input, singleChar)
 		if i == -1 {
-			return "", input, errUnterminatedSingleQuote
+			// Keep the start of the word (eg. --flag='value).
+			return buf.String() + input, "", errUnterminatedSingleQuote
 		}
 		buf.WriteString(input[0:i])
 		input = input[i+1:]
@@ -364,7 +601,7 @@ double:
 			}
 		}
 
-		return "", input, errUnterminatedDoubleQuote
+		return buf.String() + unquoteWord(input, doubleChar), "", errUnterminatedDoubleQuote
 	}
 
 done:
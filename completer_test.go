@@ -0,0 +1,140 @@
+package console
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTypedWord(t *testing.T) {
+	tests := []struct {
+		name string
+		line string // The cursor is at the "|".
+		want completedWord
+	}{
+		{"plain word", "ls fo|", completedWord{prefix: "fo"}},
+		{"empty word", "ls |", completedWord{}},
+		{"escaped space", `ls my\ fi|`, completedWord{prefix: `my\ fi`}},
+		{"double quote", `ls "my fi|`, completedWord{prefix: `"my fi`, quote: '"'}},
+		{"single quote", `ls 'my fi|`, completedWord{prefix: `'my fi`, quote: '\''}},
+		{"closed quote", `ls "my"fi|`, completedWord{prefix: `"my"fi`}},
+		{"flag value", "ls --file=fo|", completedWord{prefix: "--file=fo"}},
+		{"quoted flag value", `ls --file="my fi|`, completedWord{prefix: `--file="my fi`, quote: '"'}},
+		{"mid-word", "ls fo|o bar", completedWord{prefix: "fo", suffix: "o", midWord: true}},
+		{"mid-word in quotes", `ls "a b|c d" x`, completedWord{prefix: `"a b`, suffix: "c d", midWord: true, quote: '"'}},
+		{"mid-word escaped", `ls a|\ b`, completedWord{prefix: "a", suffix: " b", midWord: true}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pos := strings.Index(test.line, "|")
+			line := []rune(strings.Replace(test.line, "|", "", 1))
+
+			if word := typedWord(line, len([]rune(test.line[:pos]))); word != test.want {
+				t.Errorf("typedWord(%s) = %+v, want %+v", test.line, word, test.want)
+			}
+		})
+	}
+}
+
+func TestLineValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		typed    completedWord
+		word     string // The unquoted word passed to the completer.
+		value    string
+		complete bool
+		want     string
+		matches  bool
+	}{
+		{"plain", completedWord{prefix: "fo"}, "fo", "foo", true, "foo", true},
+		{"escaped", completedWord{prefix: "fo"}, "fo", "foo bar", true, `foo\ bar`, true},
+		{"double quote", completedWord{prefix: `"my fi`, quote: '"'}, "my fi", `my file"s`, true, `"my file\"s"`, true},
+		{"single quote", completedWord{prefix: `'it`, quote: '\''}, "it", "it's", true, `'it'\''s'`, true},
+		{"incomplete in quotes", completedWord{prefix: `"di`, quote: '"'}, "di", "dir/", false, `"dir/`, true},
+		{"flag value", completedWord{prefix: "--file=fo"}, "--file=fo", "--file=foo", true, "--file=foo", true},
+		{"quoted flag value", completedWord{prefix: `--file="my fi`, quote: '"'}, "--file=my fi", "--file=my file", true, `--file="my file"`, true},
+		{"no match", completedWord{prefix: "xy"}, "xy", "foo", true, "", false},
+		{"case-insensitive", completedWord{prefix: "REA"}, "REA", "read me", true, `read\ me`, true},
+		{"case-insensitive in quotes", completedWord{prefix: `"REA`, quote: '"'}, "REA", "read me", true, `"read me"`, true},
+		{"case-insensitive flag value in quotes", completedWord{prefix: `--file="RE`, quote: '"'}, "--file=RE", "--file=read", true, "", false},
+		{"case-insensitive shorter", completedWord{prefix: "READ"}, "READ", "re", true, "", false},
+		{"mid-word", completedWord{prefix: "fo", suffix: "o", midWord: true}, "fo", "fooo", true, "foo", true},
+		{"mid-word exact", completedWord{prefix: "fo", suffix: "o", midWord: true}, "fo", "foo", true, "fo", true},
+		{"mid-word no suffix", completedWord{prefix: "fo", suffix: "o", midWord: true}, "fo", "fob", true, "", false},
+		{"mid-word in quotes", completedWord{prefix: `"a `, suffix: "d", midWord: true, quote: '"'}, "a ", `a "b" d`, true, `"a \"b\" `, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			line, matches := test.typed.lineValue(test.word, test.value, test.complete)
+
+			if line != test.want || matches != test.matches {
+				t.Errorf("lineValue(%q, %q) = %q, %v, want %q, %v", test.word, test.value, line, matches, test.want, test.matches)
+			}
+		})
+	}
+}
+
+func TestQuoteWord(t *testing.T) {
+	tests := []struct {
+		text  string
+		quote rune
+		want  string
+	}{
+		{"file", 0, "file"},
+		{"my file", 0, `my\ file`},
+		{`it's "a\b"`, 0, `it\'s\ \"a\\b\"`},
+		{"it's", '\'', `it'\''s`},
+		{`a\b "c"`, '\'', `a\b "c"`},
+		{`say "hi" \o/`, '"', `say \"hi\" \\o/`},
+		{"tab\there", 0, "tab\\\there"},
+	}
+
+	for _, test := range tests {
+		if quoted := quoteWord(test.text, test.quote); quoted != test.want {
+			t.Errorf("quoteWord(%q, %q) = %q, want %q", test.text, test.quote, quoted, test.want)
+		}
+	}
+}
+
+func TestUnquoteWord(t *testing.T) {
+	tests := []struct {
+		text  string
+		quote rune
+		want  string
+	}{
+		{"file", 0, "file"},
+		{`my\ file`, 0, "my file"},
+		{`"my file"`, 0, "my file"},
+		{`'a\b'`, 0, `a\b`},
+		{`"a\b\"c"`, 0, `a\b"c`},
+		{`'it'\''s'`, 0, "it's"},
+		{`--file="my fi`, 0, "--file=my fi"},
+		{`c d"`, '"', "c d"},
+		{`\$x`, '"', "$x"},
+		{`b'c`, '\'', "bc"},
+		{`trailing\`, 0, `trailing\`},
+	}
+
+	for _, test := range tests {
+		if word := unquoteWord(test.text, test.quote); word != test.want {
+			t.Errorf("unquoteWord(%q, %q) = %q, want %q", test.text, test.quote, word, test.want)
+		}
+	}
+}
+
+// TestRoundTripQuotes checks that words quoted for the line are read back unchanged.
+func TestRoundTripQuotes(t *testing.T) {
+	for _, text := range []string{"plain", "my file", `it's`, `"quoted" \ back`, "tab\tnew\nline"} {
+		for _, quote := range []rune{0, '\'', '"'} {
+			quoted := quoteWord(text, quote)
+			if quote != 0 {
+				quoted = string(quote) + quoted + string(quote)
+			}
+
+			if word := unquoteWord(quoted, 0); word != text {
+				t.Errorf("%q quoted in %q as %s, unquoted to %q", text, quote, quoted, word)
+			}
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package console
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/kballard/go-shellquote"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// completionCandidate is a completion candidate returned to editors.
+type completionCandidate struct {
+	Value       string `json:"value"`             // Replaces the word from the start position.
+	Display     string `json:"display,omitempty"` // Displayed instead of the value, if not empty.
+	Description string `json:"description,omitempty"`
+	Tag         string `json:"tag,omitempty"`     // Group of the candidate (eg. "commands", "flags").
+	NoSpace     bool   `json:"nospace,omitempty"` // No space should be inserted after the value.
+}
+
+// completionReply is the result of a "complete" request.
+type completionReply struct {
+	Start      int                   `json:"start"` // Position (in characters) of the word being completed.
+	Candidates []completionCandidate `json:"candidates"`
+	Usage      string                `json:"usage,omitempty"`
+	Messages   []string              `json:"messages,omitempty"`
+}
+
+// commandHelp is the result of a "help" request.
+type commandHelp struct {
+	Path     string        `json:"path"` // Path of the command, without the menu root.
+	Use      string        `json:"use"`
+	Aliases  []string      `json:"aliases,omitempty"`
+	Short    string        `json:"short,omitempty"`
+	Long     string        `json:"long,omitempty"`
+	Example  string        `json:"example,omitempty"`
+	Flags    []flagHelp    `json:"flags,omitempty"`
+	Commands []commandHelp `json:"commands,omitempty"` // Subcommands, without their flags.
+}
+
+// flagHelp describes a flag of a command.
+type flagHelp struct {
+	Name      string `json:"name"`
+	Shorthand string `json:"shorthand,omitempty"`
+	Type      string `json:"type"`
+	Usage     string `json:"usage,omitempty"`
+	Default   string `json:"default,omitempty"`
+	NoOptDef  string `json:"noOptDefault,omitempty"` // Value of the flag when given without one.
+	Inherited bool   `json:"inherited,omitempty"`    // Persistent flag of a parent command.
+}
+
+// ServeCompletion serves the completions and help of the console commands to an editor
+// (or any other program), so that it can complete the command lines of script files for
+// the console while they are edited: applications typically call it when started with
+// a dedicated flag (eg. `myapp --completion-server`), for the editor to talk with it on
+// the standard input and output streams, until the input is closed.
+//
+// Requests and responses are JSON-RPC 2.0 messages, one per line (see ServeControl):
+//
+//	complete  {"line": "ssh --port ", "cursor": 11, "menu": ""}
+//	help      {"line": "git clone", "menu": ""}
+//
+// The cursor is a position in characters (the end of the line if not given), and the
+// menu is the name of the menu whose commands are used (the active one if not given).
+//
+// The completions of commands, flags and their arguments matching the word under the
+// cursor are returned with the position of this word, which they replace: {"start": 6,
+// "candidates": [{"value": "--port", "tag": "flags", "description": "..."}], "usage":
+// "...", "messages": []}. The help of the command found in the line is returned with
+// its flags and subcommands. These methods are also served by the control API.
+func (c *Console) ServeCompletion(in io.Reader, out io.Writer) error {
+	client := &controlClient{out: out}
+
+	return serveRequests(client, in, func(method string, params json.RawMessage) (any, *controlError) {
+		switch method {
+		case "complete", "help":
+			return c.callCompletion(method, params)
+		}
+
+		return nil, &controlError{Code: controlMethodNotFound, Message: "method not found: " + method}
+	})
+}
+
+// callCompletion answers a "complete" or "help" request.
+func (c *Console) callCompletion(method string, params json.RawMessage) (any, *controlError) {
+	var args struct {
+		Line   string  `json:"line"`
+		Cursor *int    `json:"cursor"`
+		Menu   *string `json:"menu"`
+	}
+
+	if err := json.Unmarshal(params, &args); err != nil {
+		return nil, &controlError{Code: controlInvalidParams, Message: err.Error()}
+	}
+
+	menu := c.activeMenu()
+	if args.Menu != nil {
+		if menu = c.Menu(*args.Menu); menu == nil {
+			return nil, &controlError{Code: controlInvalidParams, Message: "unknown menu: " + *args.Menu}
+		}
+	}
+
+	line := []rune(args.Line)
+
+	cursor := len(line)
+	if args.Cursor != nil {
+		cursor = *args.Cursor
+	}
+
+	if cursor < 0 || cursor > len(line) {
+		return nil, &controlError{Code: controlInvalidParams, Message: fmt.Sprintf("cursor out of line: %d", cursor)}
+	}
+
+	// Generate the commands of the menu, as before reading a command line.
+	menu.resetPreRun()
+
+	if method == "help" {
+		return c.commandHelp(menu, string(line[:cursor])), nil
+	}
+
+	return c.completeScript(menu, line, cursor), nil
+}
+
+// completeScript completes a command line with the commands of the menu.
+func (c *Console) completeScript(menu *Menu, line []rune, cursor int) completionReply {
+	args := splitArgs(line, cursor)
+	word := typedWord(line, cursor)
+
+	result := c.completeArgs(menu, args)
+
+	reply := completionReply{
+		Start:      cursor - len([]rune(word.prefix)),
+		Candidates: make([]completionCandidate, 0, len(result.values)),
+		Usage:      result.usage,
+		Messages:   result.messages,
+	}
+
+	if result.err != nil {
+		reply.Messages = append(reply.Messages, "failed to load config: "+result.err.Error())
+	}
+
+	// Only the candidates matching the word being completed,
+	// quoted like it.
+	for _, val := range result.values {
+		if !strings.HasPrefix(val.Value, args[len(args)-1]) {
+			continue
+		}
+
+		nospace := result.nospace(val.Value)
+
+		value, matches := word.lineValue(args[len(args)-1], val.Value, !nospace)
+		if !matches {
+			continue
+		}
+
+		reply.Candidates = append(reply.Candidates, completionCandidate{
+			Value:       value,
+			Display:     val.Display,
+			Description: val.Description,
+			Tag:         val.Tag,
+			NoSpace:     nospace || word.midWord,
+		})
+	}
+
+	return reply
+}
+
+// commandHelp returns the help of the deepest command found in the line.
+func (c *Console) commandHelp(menu *Menu, line string) commandHelp {
+	words, err := shellquote.Split(line)
+	if err != nil {
+		words = strings.Fields(line)
+	}
+
+	menu.loadLazyGroups(words)
+
+	menu.mutex.Lock()
+	defer menu.mutex.Unlock()
+
+	cmd, _, err := menu.Command.Find(words)
+	if err != nil || cmd == nil {
+		cmd = menu.Command
+	}
+
+	help := describeCommand(menu, cmd)
+
+	cmd.LocalFlags().VisitAll(func(flag *pflag.Flag) {
+		if !flag.Hidden {
+			help.Flags = append(help.Flags, describeFlag(flag, false))
+		}
+	})
+
+	cmd.InheritedFlags().VisitAll(func(flag *pflag.Flag) {
+		if !flag.Hidden {
+			help.Flags = append(help.Flags, describeFlag(flag, true))
+		}
+	})
+
+	for _, sub := range cmd.Commands() {
+		if sub.IsAvailableCommand() && menu.CheckIsAvailable(sub) == nil {
+			help.Commands = append(help.Commands, describeCommand(menu, sub))
+		}
+	}
+
+	return help
+}
+
+// describeCommand returns the help of a command, without its flags and subcommands.
+func describeCommand(menu *Menu, cmd *cobra.Command) commandHelp {
+	path := strings.TrimSpace(strings.TrimPrefix(cmd.CommandPath(), menu.Command.Name()))
+
+	return commandHelp{
+		Path:    path,
+		Use:     cmd.Use,
+		Aliases: cmd.Aliases,
+		Short:   cmd.Short,
+		Long:    cmd.Long,
+		Example: cmd.Example,
+	}
+}
+
+// describeFlag returns the help of a flag.
+func describeFlag(flag *pflag.Flag, inherited bool) flagHelp {
+	return flagHelp{
+		Name:      flag.Name,
+		Shorthand: flag.Shorthand,
+		Type:      flag.Value.Type(),
+		Usage:     flag.Usage,
+		Default:   flag.DefValue,
+		NoOptDef:  flag.NoOptDefVal,
+		Inherited: inherited,
+	}
+}
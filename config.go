@@ -0,0 +1,432 @@
+package console
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/carapace-sh/carapace/pkg/xdg"
+	"gopkg.in/yaml.v3"
+)
+
+// envUnsafe matches the characters of application names not allowed in environment variables.
+var envUnsafe = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// configHistoryName is the name of the history source of the configured history file.
+const configHistoryName = "user history"
+
+// Config is the user configuration of a console, loaded from a YAML file with LoadConfig.
+// Fields left empty keep the settings of the application, and all fields can be overridden
+// with environment variables (see SetConfigEnvPrefix). Profiles are named sets of fields
+// overriding the other ones, selected with the profile field or with UseProfile: they can
+// be used for instance to have distinct prompts and histories for distinct environments.
+//
+//	editing-mode: vi
+//	prompt: "{app} > "
+//	profile: dev
+//	profiles:
+//	  dev:
+//	    history: ~/.myapp/dev-history
+//	  prod:
+//	    prompt: "{app} (PROD) > "
+//	    theme: monochrome
+//	    history: ~/.myapp/prod-history
+//	    menu: client
+//	binds:
+//	  "\C-xl": toggle-log-panel
+//	  "\C-xs": run-status
+//	actions:
+//	  status: status --all
+type Config struct {
+	EditingMode string `yaml:"editing-mode,omitempty"` // "emacs" or "vi".
+	Prompt      string `yaml:"prompt,omitempty"`       // Primary prompt of all menus, with {app} and {menu} replaced.
+	Theme       string `yaml:"theme,omitempty"`        // Name of a registered theme (see RegisterTheme).
+	History     string `yaml:"history,omitempty"`      // History file of the default menu.
+	Menu        string `yaml:"menu,omitempty"`         // Menu active at startup, or when the profile is selected.
+	Accessible  string `yaml:"accessible,omitempty"`   // "on" for screen reader output (see SetAccessible), or "off".
+
+	RecentValues  string `yaml:"recent-values,omitempty"`         // Number of recent values proposed (see Console.RecentValues).
+	RecentExclude string `yaml:"recent-values-exclude,omitempty"` // Comma-separated flags whose values are not remembered.
+
+	Binds   map[string]string `yaml:"binds,omitempty"`   // Key sequences bound to console actions or readline commands.
+	Actions map[string]string `yaml:"actions,omitempty"` // Command lines ran by the run-<name> actions, by name.
+
+	Profile  string            `yaml:"profile,omitempty"`  // Profile used when none is selected with UseProfile.
+	Profiles map[string]Config `yaml:"profiles,omitempty"` // Profiles, by name (profiles of profiles are ignored).
+}
+
+// DefaultConfigPath returns the default path of the configuration file
+// of the console, that is, <user config dir>/<app>/console.yaml.
+func (c *Console) DefaultConfigPath() string {
+	dir, err := xdg.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(dir, strings.ToLower(c.name), "console.yaml")
+}
+
+// LoadConfig loads the configuration of the console from a YAML file (see Config), and
+// applies it to the shell and to the menus, which should thus be created beforehand. A
+// missing file is not an error: the settings of the application are kept. The file is
+// loaded again each time the configuration is reloaded (see Reload), with the changes
+// to its settings reported along with the other ones.
+func (c *Console) LoadConfig(path string) error {
+	c.mutex.Lock()
+	register := c.configPath == ""
+	c.configPath = path
+	c.mutex.Unlock()
+
+	if register {
+		c.OnReload(c.reloadConfig)
+	}
+
+	_, err := c.reloadConfig()
+
+	return err
+}
+
+// Config returns the configuration in use, with the fields of its profile, if any.
+func (c *Console) Config() Config {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.effectiveConfig()
+}
+
+// Profiles returns the names of the profiles of the configuration, sorted.
+func (c *Console) Profiles() []string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	names := make([]string, 0, len(c.config.Profiles))
+	for name := range c.config.Profiles {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// UseProfile selects a profile of the configuration, whose fields override the other
+// ones, and applies it: for instance, applications can let users select a profile at
+// startup with a command-line flag. The menu of the profile, if any, is switched to.
+func (c *Console) UseProfile(name string) error {
+	c.mutex.Lock()
+
+	if _, found := c.config.Profiles[name]; !found {
+		c.mutex.Unlock()
+		return fmt.Errorf(tr("unknown profile: %s"), name)
+	}
+
+	previous := c.effectiveConfig()
+	c.profile = name
+	current := c.effectiveConfig()
+	c.mutex.Unlock()
+
+	return c.applyConfig(previous, current, true)
+}
+
+// reloadConfig reads the configuration file, and applies it.
+func (c *Console) reloadConfig() (changes []string, err error) {
+	c.mutex.RLock()
+	path := c.configPath
+	c.mutex.RUnlock()
+
+	var config Config
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf(tr("config: %w"), err)
+	}
+
+	if len(data) > 0 {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+
+		if err := decoder.Decode(&config); err != nil {
+			return nil, fmt.Errorf(tr("config: %s: %w"), path, err)
+		}
+	}
+
+	env := c.readConfigEnv()
+
+	c.mutex.Lock()
+	previous := c.effectiveConfig()
+	first := !c.configLoaded
+	c.config = config
+	c.configEnv = env
+	c.configLoaded = true
+	current := c.effectiveConfig()
+	c.mutex.Unlock()
+
+	// The menu is only switched to at startup, or when it changes.
+	switchMenu := first || current.Menu != previous.Menu
+
+	return configFieldChanges(previous, current), c.applyConfig(previous, current, switchMenu)
+}
+
+// effectiveConfig returns the configuration of the file with the fields of the
+// selected profile, then those of the environment, with the console locked.
+func (c *Console) effectiveConfig() Config {
+	config := c.config
+	env := c.configEnv
+
+	name := c.profile
+	if name == "" {
+		name = env.Profile
+	}
+
+	if name == "" {
+		name = config.Profile
+	}
+
+	if profile, found := config.Profiles[name]; found {
+		overrideConfig(&config, &profile)
+		config.Profile = name
+	}
+
+	overrideConfig(&config, &env)
+
+	return config
+}
+
+// overrideConfig sets the fields of the configuration to the non-empty
+// ones of the override, except for the profile, which is selected first.
+// The binds and actions of the override are added to those of the config.
+func overrideConfig(config, override *Config) {
+	fields := configFields(config)
+
+	for i, field := range configFields(override) {
+		if field.name != "profile" && *field.value != "" {
+			*fields[i].value = *field.value
+		}
+	}
+
+	config.Binds = mergeConfigMap(config.Binds, override.Binds)
+	config.Actions = mergeConfigMap(config.Actions, override.Actions)
+}
+
+// mergeConfigMap returns a copy of the map with the entries of the override.
+func mergeConfigMap(values, override map[string]string) map[string]string {
+	if len(override) == 0 {
+		return values
+	}
+
+	merged := maps.Clone(values)
+	if merged == nil {
+		merged = make(map[string]string, len(override))
+	}
+
+	maps.Copy(merged, override)
+
+	return merged
+}
+
+// configField is a string field of a configuration, with the name of its key.
+type configField struct {
+	name  string
+	value *string
+}
+
+// configFields returns the string fields of the configuration, in order.
+func configFields(config *Config) []configField {
+	value := reflect.ValueOf(config).Elem()
+	fields := make([]configField, 0, value.NumField())
+
+	for i := range value.NumField() {
+		field := value.Type().Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+
+		if field.Type.Kind() == reflect.String && name != "" {
+			fields = append(fields, configField{name: name, value: value.Field(i).Addr().Interface().(*string)})
+		}
+	}
+
+	return fields
+}
+
+// SetConfigEnvPrefix sets the prefix of the environment variables overriding the fields
+// of the configuration file, followed by the upper-cased key of the field with dashes
+// replaced by underscores: for instance, MYAPP_CONSOLE_EDITING_MODE overrides the editing
+// mode of the "myapp" application, with the default MYAPP_CONSOLE_ prefix. It should be
+// called before LoadConfig, which reads the environment (even when the file is missing).
+//
+// Fields are resolved with the following precedence, from the lowest: the configuration
+// file, its selected profile, the environment. The profile is selected with UseProfile,
+// or with the PROFILE variable (eg. MYAPP_CONSOLE_PROFILE), or with the profile field.
+// This is useful in containerized deployments, where writing files is less convenient.
+func (c *Console) SetConfigEnvPrefix(prefix string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.configEnvPrefix = prefix
+}
+
+// readConfigEnv returns the fields of the configuration set in the environment.
+func (c *Console) readConfigEnv() Config {
+	c.mutex.RLock()
+	prefix := c.configEnvPrefix
+	c.mutex.RUnlock()
+
+	if prefix == "" {
+		prefix = strings.ToUpper(envUnsafe.ReplaceAllString(c.name, "_")) + "_CONSOLE_"
+	}
+
+	var config Config
+
+	for _, field := range configFields(&config) {
+		*field.value = os.Getenv(prefix + strings.ToUpper(strings.ReplaceAll(field.name, "-", "_")))
+	}
+
+	return config
+}
+
+// applyConfig applies the configuration to the shell and to the menus. The editing
+// mode, the theme and the accessible mode are always applied, since the inputrc files
+// might have changed them, while the history is only replaced if it changed (not to
+// reload the file).
+func (c *Console) applyConfig(previous, current Config, switchMenu bool) error {
+	var errs []error
+
+	switch current.EditingMode {
+	case "":
+	case "emacs":
+		c.shell.Config.Set("editing-mode", "emacs")
+		c.shell.Keymap.SetMain("emacs")
+	case "vi":
+		c.shell.Config.Set("editing-mode", "vi")
+		c.shell.Keymap.SetMain("vi-insert")
+	default:
+		errs = append(errs, fmt.Errorf(tr("config: invalid editing-mode: %s"), current.EditingMode))
+	}
+
+	theme := current.Theme
+	if theme == "" && previous.Theme != "" {
+		theme = "default"
+	}
+
+	if theme != "" {
+		if err := c.SetTheme(theme); err != nil {
+			errs = append(errs, fmt.Errorf(tr("config: %w"), err))
+		}
+	}
+
+	accessible := current.Accessible
+	if accessible == "" && previous.Accessible != "" {
+		accessible = "off"
+	}
+
+	switch accessible {
+	case "":
+	case "on", "true", "yes":
+		c.SetAccessible(true)
+	case "off", "false", "no":
+		c.SetAccessible(false)
+	default:
+		errs = append(errs, fmt.Errorf(tr("config: invalid accessible: %s"), current.Accessible))
+	}
+
+	if current.RecentValues != "" {
+		if limit, err := strconv.Atoi(current.RecentValues); err != nil || limit < 0 {
+			errs = append(errs, fmt.Errorf(tr("config: invalid recent-values: %s"), current.RecentValues))
+		}
+	}
+
+	if current.History != previous.History {
+		c.setConfigHistory(current.History)
+	}
+
+	if err := c.setupConfigBinds(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if switchMenu && current.Menu != "" {
+		c.mutex.RLock()
+		_, found := c.menus[current.Menu]
+		c.mutex.RUnlock()
+
+		if found {
+			c.SwitchMenu(current.Menu)
+		} else {
+			errs = append(errs, fmt.Errorf(tr("config: unknown menu: %s"), current.Menu))
+		}
+	}
+
+	c.clearCaches()
+
+	return errors.Join(errs...)
+}
+
+// setConfigHistory replaces the configured history file of the default menu.
+func (c *Console) setConfigHistory(path string) {
+	menu := c.Menu("")
+
+	menu.mutex.Lock()
+	menu.DeleteHistorySource(configHistoryName)
+	menu.mutex.Unlock()
+
+	if path != "" {
+		if home, err := os.UserHomeDir(); err == nil && strings.HasPrefix(path, "~/") {
+			path = filepath.Join(home, path[2:])
+		}
+
+		menu.AddHistorySourceFile(configHistoryName, path)
+	}
+
+	if c.activeMenu() == menu {
+		c.printMutex.Lock()
+		c.loadActiveHistories()
+		c.printMutex.Unlock()
+	}
+}
+
+// configPrompt returns the configured primary prompt, expanded, if any.
+func (c *Console) configPrompt() (prompt string, found bool) {
+	c.mutex.RLock()
+	format := c.effectiveConfig().Prompt
+	menu := c.findActiveMenu()
+	c.mutex.RUnlock()
+
+	if format == "" {
+		return "", false
+	}
+
+	return strings.NewReplacer("{app}", c.name, "{menu}", menu.name).Replace(format), true
+}
+
+// configFieldChanges describes the fields which differ between two configurations.
+func configFieldChanges(previous, current Config) []string {
+	var changes []string
+
+	before := configFields(&previous)
+
+	for i, field := range configFields(&current) {
+		old, value := *before[i].value, *field.value
+
+		switch {
+		case old == value:
+		case value == "":
+			changes = append(changes, fmt.Sprintf("config %s: removed (was %q)", field.name, old))
+		case old == "":
+			changes = append(changes, fmt.Sprintf("config %s: %q", field.name, value))
+		default:
+			changes = append(changes, fmt.Sprintf("config %s: %q (was %q)", field.name, value, old))
+		}
+	}
+
+	if !maps.Equal(previous.Actions, current.Actions) {
+		changes = append(changes, "config actions: changed")
+	}
+
+	return changes
+}
@@ -2,26 +2,136 @@ package console
 
 import (
 	"fmt"
+	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/cobra"
 
 	"github.com/reeflective/readline"
 	"github.com/reeflective/readline/inputrc"
 )
 
 // Console is an integrated console application instance.
+//
+// Concurrency: the console methods can be called from any goroutine, such as
+// commands running in the background, or event listeners. The console state
+// (menus, filters, variables, context values, etc) is protected by a console
+// lock, and the state of each menu (its command tree, histories, hints, etc)
+// by a menu lock, which is also held while the tree is completed, so that it
+// is not regenerated meanwhile. Everything drawing on the shell, which has no
+// lock of its own (asynchronous messages, status bar refreshes, menu switches,
+// signal handlers, the log panel), is run by the input loop of the console: the
+// loop is woken up for them while reading user input, and runs them while waiting
+// for the command being executed. Locks are never held when calling user code
+// (commands, listeners and status segments), which can thus use the console API.
+//
+// The cobra command trees are not protected, though: executing the commands
+// of a menu from another goroutine (eg. with RunCommandArgs) while the user is
+// also executing or completing commands in this menu is not safe.
 type Console struct {
 	// Application
-	name          string           // Used in the prompt, and for readline `.inputrc` application-specific settings.
-	shell         *readline.Shell  // Provides readline functionality (inputs, completions, hints, history)
-	printLogo     func(c *Console) // Simple logo printer.
-	cmdHighlight  string           // Ansi code for highlighting of command in default highlighter. Green by default.
-	flagHighlight string           // Ansi code for highlighting of flag in default highlighter. Grey by default.
-	menus         map[string]*Menu // Different command trees, prompt engines, etc.
-	filters       []string         // Hide commands based on their attributes and current context.
-	isExecuting   bool             // Used by log functions, which need to adapt behavior (print the prompt, etc.)
-	printed       bool             // Used to adjust asynchronous messages too.
-	mutex         *sync.RWMutex    // Concurrency management.
+	name          string                   // Used in the prompt, and for readline `.inputrc` application-specific settings.
+	shell         *readline.Shell          // Provides readline functionality (inputs, completions, hints, history)
+	printLogo     func(c *Console)         // Simple logo printer.
+	cmdHighlight  string                   // Ansi code for highlighting of command in default highlighter. Green by default.
+	flagHighlight string                   // Ansi code for highlighting of flag in default highlighter. Grey by default.
+	hintHighlight string                   // Ansi code for command/flag hints. Dim by default.
+	lastHint      string                   // Last command/flag hint displayed.
+	previewKey    string                   // Input line (up to the candidate) of the last completion preview.
+	previewText   string                   // Last completion preview, formatted.
+	menus         map[string]*Menu         // Different command trees, prompt engines, etc.
+	filters       []string                 // Hide commands based on their attributes and current context.
+	isExecuting   bool                     // Used by log functions, which need to adapt behavior (print the prompt, etc.)
+	interactive   bool                     // An interactive program has the control of the terminal.
+	printed       bool                     // Used to adjust asynchronous messages too.
+	pairShown     bool                     // A matching quote/bracket pair is highlighted in the input line.
+	stateFile     string                   // Session state persisted for recovery after abnormal exits.
+	stateLines    map[readline.History]int // Number of lines of the history sources not written in this session.
+	variables     map[string]any           // Console variables, expanded in command lines.
+	reading       bool                     // The shell is reading user input.
+	lineMode      atomic.Bool              // The input is read line by line, without the shell (see LineMode).
+	logPanel      *logPanel                // Top screen region printing asynchronous messages, if enabled.
+	logPanelRows  int                      // Rows of the log panel when last enabled (see ActionToggleLogPanel).
+	deprecated    map[string]bool          // Deprecated commands already warned about, by command path.
+	lastExample   exampleState             // Last command example inserted in the input line.
+	lastInsert    insertState              // Last argument or output line inserted in the input line.
+	lastOutput    string                   // Output of the last command, if kept (see KeepLastOutput).
+	region        regionState              // Region between the mark and the cursor, highlighted when active.
+	universalArg  bool                     // The numeric argument was set by universal-argument.
+	overrides     *menuOverrides           // Shell settings overridden by the active menu.
+	loop          inputLoop                // Functions queued for the input loop (see runOnLoop).
+	mutex         *sync.RWMutex            // Concurrency management (see the Console documentation).
+	printMutex    *sync.Mutex              // Serializes the asynchronous messages printed from concurrent goroutines.
+
+	// Authorization & auditing
+	authorizer func(cmd *cobra.Command) bool // Decides command visibility/execution for the user.
+	auditSink  func(event AuditEvent)        // Receives executed and denied commands.
+	redactor   func(value string) string     // Redacts sensitive flags/args values.
+	masking    bool                          // The accepted line is being redisplayed with masked values (and no pair highlighting).
+
+	// Status bar
+	statusNames    []string                 // Status segments, in display order.
+	statusSegments map[string]func() string // Status segments, by name.
+	statusShown    bool                     // The status bar is currently bound below the input line.
+
+	// Context display
+	menuPath         []string          // Menus switched to from the default one, in order.
+	contextNames     []string          // Context values, in the order they were set.
+	contextValues    map[string]string // Context values, by name.
+	contextListeners []func()          // Called when the menu path or a context value changes.
+
+	// Static command trees
+	trees []*cobraTree // Command trees kept between command lines (see FromCobra), whose flags are reset.
+
+	// Startup & shutdown
+	startHooks   []func() error // Run before the first prompt (see OnStart).
+	exitHooks    []func() error // Run when the console stops (see OnExit).
+	exitHooksRun bool           // The exit hooks have been run since the console started.
+
+	// Signals
+	signalHandlers map[os.Signal]*signalHandler // Handlers registered by the application, by signal.
+	notifySuspend  func()                       // Watches the job-control signals again, once continued.
+
+	// Configuration reload
+	reloadHooks []func() ([]string, error) // Reload the application configuration (see OnReload).
+
+	// User configuration
+	config          Config           // Loaded from the configuration file (see LoadConfig).
+	configPath      string           // Path of the configuration file, if loaded.
+	configLoaded    bool             // The configuration file has been loaded once.
+	configEnv       Config           // Fields overridden by environment variables.
+	configEnvPrefix string           // Prefix of these variables (see SetConfigEnvPrefix).
+	configBinds     []configBind     // Key sequences bound by the configuration, with the binds they replaced.
+	profile         string           // Profile selected with UseProfile.
+	themes          map[string]Theme // Registered themes, by name.
+
+	// Accessibility
+	accessible atomic.Bool // Plain output for screen readers (see SetAccessible).
+
+	// Control API
+	control controlServer // Control socket and application methods, when served.
+
+	// Command palette
+	paletteFallback map[string]string // Commands bound to Ctrl-P, by keymap, ran when the palette is disabled.
+
+	// Default binds
+	binds map[string]map[string]inputrc.Bind // Binds set on top of the inputrc files, by keymap (see DefaultBinds).
+
+	// Redraw coalescing
+	pendingMessages []pendingMessage // Asynchronous messages waiting for the next redraw.
+	lastRedraw      time.Time        // Last time the prompt was redrawn for asynchronous messages.
+	redrawScheduled bool             // The pending messages will be printed by a timer.
+
+	// Caches
+	completionCache *lruCache[readline.Completions] // Completions, by menu and input line up to the cursor.
+	hintCache       *lruCache[string]               // Command/flag hints, by menu and input line up to the cursor.
+
+	// Profiling
+	profiler  profiler    // Profiling server and files, when enabled.
+	profiling atomic.Bool // The console phases are labeled in profiles.
 
 	// Execution
 
@@ -41,6 +151,98 @@ type Console struct {
 	// This field is false by default.
 	NewlineWhenEmpty bool
 
+	// BidiRendering enables the visual reordering of right-to-left text
+	// (Arabic, Hebrew, etc) in the input line and in completions, so that
+	// such arguments display correctly on left-to-right terminals. This is
+	// disabled by default, since it adds some processing on each keystroke.
+	BidiRendering bool
+
+	// DryRun enables the dry-run mode for all menus: commands having a dry-run
+	// handler (see the DryRun function) only print what they would do, and all
+	// other commands are not executed at all. This is false by default.
+	DryRun bool
+
+	// Timeout is the default maximum execution duration of commands: once exceeded,
+	// the command context is canceled, and the prompt is given back to the user.
+	// Commands can override it with a CommandTimeoutKey annotation (see SetTimeout).
+	// This is zero (no timeout) by default.
+	Timeout time.Duration
+
+	// CommandSubstitution enables $(command args) substitutions in command lines:
+	// the inner console command is executed first, and its output is inserted in
+	// the outer command line (up to 4 levels of nesting). This is false by default.
+	CommandSubstitution bool
+
+	// AutoCorrect runs the command whose name is the closest to the one typed
+	// by the user if it is not found, when there is only one close enough:
+	// otherwise, and by default, the close command names are only suggested.
+	AutoCorrect bool
+
+	// DesktopNotifications forwards the notifications sent with Console.Notify to
+	// the desktop environment, in addition to the bell and the banner printed above
+	// the prompt. This is false by default.
+	DesktopNotifications bool
+
+	// Mouse enables mouse reporting while reading user input: a click moves the cursor
+	// in the input line, the wheel walks the history (or the completion candidates),
+	// and a click in the completion menu inserts the selected candidate. Note that most
+	// terminals then only allow selecting text with the mouse while holding Shift.
+	// This is false by default.
+	Mouse bool
+
+	// CommandPalette makes Ctrl-P open the command palette, a full-screen overlay
+	// listing all available commands, filtered as you type (see Console.Overlay),
+	// instead of walking the history (which is still done with the Up arrow).
+	// This is false by default.
+	CommandPalette bool
+
+	// CompletionPreview is the maximum number of lines of the preview pane, displayed
+	// between the input line and the completion menu while a candidate is selected,
+	// with extended information about it: the long description of subcommands, or
+	// the preview given by the provider of the argument (see Menu.SetArgPreview).
+	// This is zero (no preview pane) by default.
+	CompletionPreview int
+
+	// CandidateOrder orders the completion candidates of command arguments and flags
+	// with the values used in the commands executed successfully before, which are
+	// saved next to the first history file of the menu (eg. "history.usage"), if any:
+	// sensitive values and boolean flags are not saved. By default, the candidates are
+	// sorted alphabetically by the shell (OrderDefault), and usage is not recorded
+	// (unless RecentValues are proposed).
+	CandidateOrder CandidateOrder
+
+	// RecentValues is the number of values recently used for a command argument or flag
+	// proposed first when completing it, in a "recent values" group, even if they are not
+	// candidates of its completer. Values are recorded like with CandidateOrder, except for
+	// the flags (and arguments) of RecentValuesExclude, given by long name (eg. "token"), or
+	// by command path (eg. "login --token", or "login" for its arguments). The user can set
+	// both in the configuration file (see Config), the flags excluded there being added to
+	// those of RecentValuesExclude. This is zero (no recent values) by default.
+	RecentValues        int
+	RecentValuesExclude []string
+
+	// KeepLastOutput keeps the output of each command (its first 64KB), while it is
+	// still displayed, so that its lines can be inserted in the input line with the
+	// insert-last-output command (to be bound in the inputrc file). Like with the
+	// --tee option, the output of commands is then not a terminal, which might change
+	// how some programs print. This is false by default.
+	KeepLastOutput bool
+
+	// MaxRedrawRate limits the number of times per second the prompt is redrawn to
+	// print asynchronous messages (Printf, TransientPrintf) while the user is typing:
+	// messages arriving faster are printed together, in order, at the next allowed
+	// redraw, so that streaming output (eg. from agents) does not make the terminal
+	// flicker or use much CPU. This is zero (each message redraws the prompt) by default.
+	MaxRedrawRate int
+
+	// CompletionCacheSize is the maximum number of completions cached while the user
+	// is typing a command line, by input line (eg. when deleting characters and typing
+	// them again, or with autocomplete), and HintCacheSize that of command/flag hints.
+	// The caches are cleared before each command line, and when commands are changed.
+	// See Console.Stats for their hit/miss counters. Zero or less disables a cache.
+	CompletionCacheSize int
+	HintCacheSize       int
+
 	// Characters that are used to determine whether an input line was empty. If a line is not entirely
 	// made up by any of these characters, then it is not considered empty. The default characters
 	// are ' ' and '\t'.
@@ -67,6 +269,18 @@ type Console struct {
 	// These hooks are distinct from the cobra.PreRun() or OnFinalize hooks,
 	// and might be used in combination with them.
 	PostCmdRunHooks []func() error
+
+	// ExitWarnings are called when the user requests to exit the application
+	// (see Console.RequestExit): each of them can return a warning (eg. "2 jobs
+	// are still running"), in which case the user must confirm before exiting.
+	ExitWarnings []func() string
+
+	// ShutdownHooks are run in order when exiting the application through
+	// Console.Exit() (eg. to close connections and flush histories). Errors
+	// are printed, but do not prevent the remaining hooks to be run. They
+	// are run before the hooks registered with OnExit, and like them, when
+	// the console stops for other reasons (see OnExit).
+	ShutdownHooks []func() error
 }
 
 // New - Instantiates a new console application, with sane but powerful defaults.
@@ -75,12 +289,30 @@ type Console struct {
 // The app parameter is an optional name of the application using this console.
 func New(app string) *Console {
 	console := &Console{
-		name:  app,
-		shell: readline.NewShell(inputrc.WithApp(strings.ToLower(app))),
-		menus: make(map[string]*Menu),
-		mutex: &sync.RWMutex{},
+		name:       app,
+		shell:      readline.NewShell(inputrc.WithApp(strings.ToLower(app))),
+		menus:      make(map[string]*Menu),
+		mutex:      &sync.RWMutex{},
+		printMutex: &sync.Mutex{},
+
+		deprecated:     make(map[string]bool),
+		contextValues:  make(map[string]string),
+		statusSegments: make(map[string]func() string),
+		themes:         make(map[string]Theme, len(builtinThemes)),
+		binds:          make(map[string]map[string]inputrc.Bind),
+
+		completionCache: newLRUCache[readline.Completions](),
+		hintCache:       newLRUCache[string](),
+
+		loop: inputLoop{wake: make(chan struct{}, 1)},
 	}
 
+	for name, theme := range builtinThemes {
+		console.themes[name] = theme
+	}
+
+	consoles.Store(console.shell, console)
+
 	// Quality of life improvements.
 	console.setupShell()
 
@@ -91,12 +323,13 @@ func New(app string) *Console {
 
 	// Set the history for this menu
 	for _, name := range defaultMenu.historyNames {
-		console.shell.History.Add(name, defaultMenu.histories[name])
+		console.shell.History.Add(name, defaultMenu.historySource(name))
 	}
 
 	// Syntax highlighting, multiline callbacks, etc.
 	console.cmdHighlight = seqFgGreen
 	console.flagHighlight = seqBrightWigth
+	console.hintHighlight = dim
 	console.shell.AcceptMultiline = console.acceptMultiline
 	console.shell.SyntaxHighlighter = console.highlightSyntax
 
@@ -106,6 +339,8 @@ func New(app string) *Console {
 
 	// Defaults
 	console.EmptyChars = []rune{' ', '\t'}
+	console.CompletionCacheSize = defaultCompletionCacheSize
+	console.HintCacheSize = defaultHintCacheSize
 
 	return console
 }
@@ -126,26 +361,26 @@ func (c *Console) SetPrintLogo(f func(c *Console)) {
 // well as some specific items like history sources, prompt
 // configurations, sets of expanded variables, and others.
 func (c *Console) NewMenu(name string) *Menu {
-	c.mutex.RLock()
-	defer c.mutex.RUnlock()
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 	menu := newMenu(name, c)
 	c.menus[name] = menu
 
+	// Key sequences can be bound to switch to this menu.
+	c.registerMenuAction(name)
+
 	return menu
 }
 
 // ActiveMenu - Return the currently used console menu.
 func (c *Console) ActiveMenu() *Menu {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
-
 	return c.activeMenu()
 }
 
 // Menu returns one of the console menus by name, or nil if no menu is found.
 func (c *Console) Menu(name string) *Menu {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
 
 	return c.menus[name]
 }
@@ -153,16 +388,17 @@ func (c *Console) Menu(name string) *Menu {
 // SwitchMenu - Given a name, the console switches its command menu:
 // The next time the console rebinds all of its commands, it will only bind those
 // that belong to this new menu. If the menu is invalid, i.e that no commands
-// are bound to this menu name, the current menu is kept.
+// are bound to this menu name, the current menu is kept. The menu is active once this
+// returns, but its histories and commands are bound to the shell by the input loop.
 func (c *Console) SwitchMenu(menu string) {
 	c.mutex.Lock()
 	target, found := c.menus[menu]
-	c.mutex.Unlock()
 
 	if found && target != nil {
 		// Only switch if the target menu was found.
-		current := c.activeMenu()
+		current := c.findActiveMenu()
 		if current != nil && target == current {
+			c.mutex.Unlock()
 			return
 		}
 
@@ -171,18 +407,34 @@ func (c *Console) SwitchMenu(menu string) {
 		}
 
 		target.active = true
+	}
+
+	c.mutex.Unlock()
+
+	if !found || target == nil {
+		return
+	}
 
+	// The shell histories, commands and prompt are
+	// those of the input loop, which switches them.
+	c.runOnLoop(func() {
 		// Remove the currently bound history sources
 		// (old menu) and bind the ones peculiar to this one.
+		c.printMutex.Lock()
 		c.shell.History.Delete()
 
 		for _, name := range target.historyNames {
-			c.shell.History.Add(name, target.histories[name])
+			c.shell.History.Add(name, target.historySource(name))
 		}
+		c.printMutex.Unlock()
 
 		// Regenerate the commands, outputs and everything related.
+		c.clearCaches()
 		target.resetPreRun()
-	}
+	})
+
+	// Update the breadcrumb, and redraw the prompt.
+	c.enterMenu(menu)
 }
 
 // TransientPrintf prints a string message (a log, or more broadly, an asynchronous event)
@@ -190,10 +442,74 @@ func (c *Console) SwitchMenu(menu string) {
 // The message is printed regardless of the current menu.
 //
 // If this function is called while a command is running, the console will simply print the log
-// below the line, and will not print the prompt. In any other case this function works normally.
+// below the line, and will not print the prompt. In any other case this function works normally,
+// except that the message is printed by the input loop, which alone redraws the prompt.
+// If the log panel is enabled (see EnableLogPanel), the message is printed in it instead.
 func (c *Console) TransientPrintf(msg string, args ...any) (n int, err error) {
-	if c.isExecuting {
-		return fmt.Printf(msg, args...)
+	text := fmt.Sprintf(msg, args...)
+
+	// Stream the message to the control clients subscribed to them.
+	c.publishLog(text)
+
+	return c.transientPrint(text)
+}
+
+// transientPrint prints a message above the prompt, like TransientPrintf.
+func (c *Console) transientPrint(text string) (n int, err error) {
+	if !c.accessible.Load() && c.printLogPanel(strings.TrimSuffix(text, "\n")+"\n") {
+		return len(text), nil
+	}
+
+	return c.printMessage(text, true)
+}
+
+// printMessage prints an asynchronous message, above the prompt if transient or below
+// it otherwise. Messages are printed immediately while a command is executed, or in
+// line mode: otherwise the prompt is redrawn with them, by the input loop if it can
+// be woken up, or directly by the shell (see runOnLoop).
+func (c *Console) printMessage(text string, transient bool) (n int, err error) {
+	c.printMutex.Lock()
+
+	// Messages are on their own lines, since no prompt is printed in line mode.
+	if c.lineMode.Load() {
+		defer c.printMutex.Unlock()
+		return fmt.Print(strings.TrimSuffix(text, "\n") + "\n")
+	}
+
+	if c.executing() {
+		defer c.printMutex.Unlock()
+		c.printPendingMessages()
+
+		return fmt.Print(text)
+	}
+
+	c.printMutex.Unlock()
+
+	c.runOnLoop(func() {
+		c.printMutex.Lock()
+		defer c.printMutex.Unlock()
+
+		switch {
+		case c.executing():
+			c.printPendingMessages()
+			fmt.Print(text)
+		case c.coalesceMessage(text, transient):
+		case transient:
+			c.printTransient(text)
+		default:
+			c.shell.Printf("%s", text)
+		}
+	})
+
+	return len(text), nil
+}
+
+// printTransient prints a message above the prompt, with the print lock held.
+func (c *Console) printTransient(text string) (n int, err error) {
+	// Screen readers would read the prompt and line again when
+	// redrawn, so the message is printed below them instead.
+	if c.accessible.Load() {
+		return c.shell.Printf("%s", strip(strings.TrimSuffix(text, "\n")))
 	}
 
 	// If the last message we printed asynchronously
@@ -204,25 +520,34 @@ func (c *Console) TransientPrintf(msg string, args ...any) (n int, err error) {
 	}
 
 	if c.NewlineAfter {
-		msg += "\n"
+		text += "\n"
 	}
 
 	c.printed = true
 
-	return c.shell.PrintTransientf(msg, args...)
+	return c.shell.PrintTransientf("%s", text)
 }
 
 // Printf prints a string message (a log, or more broadly, an asynchronous event)
 // below the current prompt. The message is printed regardless of the current menu.
 //
 // If this function is called while a command is running, the console will simply print the log
-// below the line, and will not print the prompt. In any other case this function works normally.
+// below the line, and will not print the prompt. In any other case, the message is printed
+// by the input loop of the console, which redraws the prompt below it (see TransientPrintf),
+// or right away if the output is not a terminal, or if the terminal does not reply in time
+// to the status query waking the loop up.
+// If the log panel is enabled (see EnableLogPanel), the message is printed in it instead.
 func (c *Console) Printf(msg string, args ...any) (n int, err error) {
-	if c.isExecuting {
-		return fmt.Printf(msg, args...)
+	text := fmt.Sprintf(msg, args...)
+
+	// Stream the message to the control clients subscribed to them.
+	c.publishLog(text)
+
+	if !c.accessible.Load() && c.printLogPanel(strings.TrimSuffix(text, "\n")+"\n") {
+		return len(text), nil
 	}
 
-	return c.shell.Printf(msg, args...)
+	return c.printMessage(text, false)
 }
 
 // SystemEditor - This function is a renamed-reexport of the underlying readline.StartEditorWithBuffer
@@ -231,23 +556,97 @@ func (c *Console) Printf(msg string, args ...any) (n int, err error) {
 // The filename parameter can be used to pass a specific filename.ext pattern, which might be useful
 // if the editor has builtin filetype plugin functionality.
 func (c *Console) SystemEditor(buffer []byte, filetype string) ([]byte, error) {
-	emacs := c.shell.Config.GetString("editing-mode") == "emacs"
-
-	edited, err := c.shell.Buffers.EditBuffer([]rune(string(buffer)), "", filetype, emacs)
+	edited, err := c.editBuffer([]rune(string(buffer)), filetype)
 
 	return []byte(string(edited)), err
 }
 
 func (c *Console) setupShell() {
+	// Platform-specific terminal setup, and save its
+	// state to restore it when suspending/panicking.
+	c.setupTerminal()
+	saveTerminal()
+
+	// Console actions bindable to key sequences.
+	c.setupActions()
+
+	c.loadConfig()
+
+	// Apply the console options and binds again when the inputrc is reloaded.
+	c.setupInitFile()
+
+	// Never split wide or composed characters when editing.
+	c.setupGraphemes()
+
+	// Automatic pairing of quotes and brackets, in menus enabling it.
+	c.setupAutopairs()
+
+	// Highlighted region between the mark and the cursor.
+	c.setupRegion()
+
+	// Numeric arguments with universal-argument, repeating macros.
+	c.setupRepeat()
+
+	// Vim word and quote text objects for the d/c/y operators.
+	c.setupTextObjects()
+}
+
+// loadConfig (re)loads the inputrc configuration, then sets
+// the options and binds of the console on top of it.
+func (c *Console) loadConfig() error {
+	// Reload the inputrc so that application-specific
+	// conditionals ($if app=name) are correctly evaluated.
+	err := c.loadInputrc()
+
+	c.applyShellConfig()
+
+	return err
+}
+
+// applyShellConfig sets the options and binds of the console
+// on top of the inputrc configuration just loaded.
+func (c *Console) applyShellConfig() {
 	cfg := c.shell.Config
 
 	// Some options should be set to on because they
 	// are quite neceessary for efficient console use.
 	cfg.Set("skip-completed-text", true)
 	cfg.Set("menu-complete-display-prefix", true)
+
+	// Insert command usage examples with Alt-E.
+	c.setupExamples()
+
+	// Insert the last arguments with Alt-., and the last output.
+	c.setupInsertCommands()
+
+	// Command palette with Ctrl-P, in consoles enabling it.
+	c.setupPalette()
+
+	// Mouse clicks and wheel, in consoles enabling them.
+	c.setupMouse()
+
+	// Functions queued for the input loop, woken up by the terminal.
+	c.setupLoop()
+
+	// Restore the terminal when suspended with Ctrl-Z.
+	c.setupSuspend()
+
+	// Key sequences bound to actions in the configuration file.
+	c.setupConfigBinds()
+
+	// No screen redraws, in consoles in accessible mode.
+	c.setupAccessible()
 }
 
 func (c *Console) activeMenu() *Menu {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.findActiveMenu()
+}
+
+// findActiveMenu returns the active menu, with the console locked.
+func (c *Console) findActiveMenu() *Menu {
 	for _, menu := range c.menus {
 		if menu.active {
 			return menu
@@ -257,3 +656,11 @@ func (c *Console) activeMenu() *Menu {
 	// Else return the default menu.
 	return c.menus[""]
 }
+
+// executing returns true if a command is being executed.
+func (c *Console) executing() bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.isExecuting
+}
@@ -0,0 +1,501 @@
+// Package consoletest drives console applications in tests.
+//
+// A Terminal runs a console in a pseudo-terminal, within the test process: the keys
+// typed are read by the real shell, which edits, completes, highlights and hints the
+// input line with its keybindings, and executes the lines accepted with Enter. After
+// each call to Type, the state of the shell and of the terminal screen are captured in
+// a Frame, with the prompt, the input line, the hint, the rows of the screen, and the
+// text printed since the previous frame (eg. the output of a command):
+//
+//	term := consoletest.New(t, app)
+//	term.Type("dep\t")
+//	term.AssertLine(t, "deploy ")
+//	term.Type("--env \t")
+//	term.AssertCompletions(t, "prod", "staging")
+//	term.Type("prod\r")
+//	term.AssertOutput(t, "deployed to prod")
+//
+// Since the terminal replaces the one of the process while it is open, only one of them
+// can be used at a time, and not in parallel tests. Terminals are supported on Unix systems:
+// elsewhere, the tests using them are skipped.
+//
+// For end-to-end tests of the whole application (its main function, signals, exit codes),
+// a Driver runs it in another process instead, and waits for its output. Help, completions
+// and command outputs can also be rendered deterministically (see RenderOptions), and
+// compared with golden files (see Golden).
+package consoletest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/reeflective/readline/inputrc"
+
+	"github.com/reeflective/console"
+)
+
+// ansi matches the ANSI escape sequences (colors, cursor movements, hyperlinks).
+var ansi = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(\x07|\x1b\\)|\x1b[@-Z\\-_]`)
+
+// StripANSI removes the ANSI escape sequences (colors, styles, etc) from a string.
+func StripANSI(text string) string {
+	return ansi.ReplaceAllString(text, "")
+}
+
+// Size of the pseudo-terminal of terminals.
+const (
+	terminalCols = 120
+	terminalRows = 40
+)
+
+// Key sequences bound in the shell by terminals, to capture a frame and to
+// close the console, and the marker printed by the shell once a frame is captured.
+const (
+	seqFrame       = "\x1b[9001~"
+	seqClose       = "\x1b[9002~"
+	seqFrameMarker = "consoletest-frame"
+)
+
+// terminalKeymaps are the keymaps in which the sequences of terminals are bound.
+var terminalKeymaps = []string{
+	"emacs", "emacs-standard", "vi-insert", "vi-command", "vi-move",
+	"vi-visual", "vi-opp", "isearch", "menu-select",
+}
+
+// errTerminalUnsupported is returned when a pseudo-terminal cannot be used as the terminal of the process.
+var errTerminalUnsupported = errors.New("pseudo-terminals are not supported as the terminal of the process")
+
+// terminalIO is a pseudo-terminal used as the terminal of the process.
+type terminalIO struct {
+	master  *os.File   // Reads the output of the terminal, and writes its input.
+	unread  func() int // Returns the size of the input not read by the process yet.
+	restore func()     // Restores the standard streams, and closes the terminal.
+}
+
+// Frame is the state of the shell and of the screen, once keys have been processed.
+type Frame struct {
+	Prompt      string   // The primary prompt of the active menu.
+	Line        string   // The input line, as edited by the shell.
+	Highlighted string   // The input line, as highlighted by the console.
+	Cursor      int      // The cursor position in the input line, in runes.
+	Hint        string   // The hint of the shell, if any.
+	Screen      []string // The rows of the screen, without colors, trailing blanks and empty rows.
+	Output      string   // The text printed since the previous frame, without ANSI sequences.
+
+	row int // The cursor row on the screen.
+}
+
+// String renders the screen of the frame, without colors.
+func (f Frame) String() string {
+	return strings.Join(f.Screen, "\n")
+}
+
+// Terminal runs a console application in a pseudo-terminal, typing keys
+// in its shell and capturing its screen.
+type Terminal struct {
+	// Timeout is the time Type waits for the keys to be processed (DefaultTimeout by default).
+	Timeout time.Duration
+
+	tb     testing.TB
+	app    *console.Console
+	tty    *terminalIO
+	input  *ptyInput
+	screen *screen
+	cancel context.CancelFunc
+	frames []Frame
+
+	states   chan Frame    // Shell states captured when reading the frame sequence.
+	captured chan Frame    // Frames, once the shell has displayed the screen.
+	read     chan struct{} // Closed once the terminal output has been read.
+	done     chan struct{} // Closed once the console has returned.
+	err      error         // Returned by the console.
+	mutex    sync.Mutex    // Protects the screen.
+}
+
+// New starts a console application in a new pseudo-terminal, 120 columns wide and
+// 40 rows high, and returns the terminal once the shell reads the first input line.
+// The console is started with Console.StartContext, its commands being executed in
+// a goroutine of the test. The terminal is closed when the test is done.
+func New(tb testing.TB, app *console.Console) *Terminal {
+	tb.Helper()
+
+	return NewContext(tb, context.Background(), app)
+}
+
+// NewContext is like New, with the context in which the console is started.
+func NewContext(tb testing.TB, ctx context.Context, app *console.Console) *Terminal {
+	tb.Helper()
+
+	tty, err := openTerminal(terminalCols, terminalRows)
+	if errors.Is(err, errTerminalUnsupported) {
+		tb.Skip(err)
+	} else if err != nil {
+		tb.Fatalf("failed to open a pseudo-terminal: %s", err)
+	}
+
+	if term := os.Getenv("TERM"); term == "" || term == "dumb" {
+		tb.Setenv("TERM", "xterm-256color")
+	}
+
+	term := &Terminal{
+		Timeout:  DefaultTimeout,
+		tb:       tb,
+		app:      app,
+		tty:      tty,
+		screen:   newScreen(terminalCols, terminalRows),
+		states:   make(chan Frame, 1),
+		captured: make(chan Frame, 1),
+		read:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	term.input = &ptyInput{writer: tty.master, unread: tty.unread, querying: term.querying}
+
+	term.bind()
+
+	ctx, term.cancel = context.WithCancel(ctx)
+
+	go term.readOutput()
+
+	go func() {
+		defer close(term.done)
+
+		term.err = app.StartContext(ctx)
+	}()
+
+	tb.Cleanup(term.Close)
+
+	term.Type("")
+
+	return term
+}
+
+// Type writes keys to the terminal, as if typed by the user (eg. "help\r", "\t" for Tab,
+// "\x01" for Ctrl-A or "\x1b[D" for the Left arrow), waits for the shell to process them
+// and records the frame captured then. Keys accepting a line (Enter) are processed once
+// the shell reads the next one: the command executed, its output is in the frame. It
+// returns the terminal, so that calls can be chained. The test fails if the keys are
+// not processed before the timeout, or if the console returns.
+func (t *Terminal) Type(keys string) *Terminal {
+	t.tb.Helper()
+
+	// Escape ending the keys is typed alone once the others are processed, so
+	// that the shell does not read it as the start of the frame sequence.
+	if before, escape := strings.CutSuffix(keys, "\x1b"); escape {
+		if before != "" {
+			t.process(keys, before+seqFrame)
+		}
+
+		t.frames = append(t.frames, t.process(keys, "\x1b", seqFrame))
+
+		return t
+	}
+
+	t.frames = append(t.frames, t.process(keys, keys+seqFrame))
+
+	return t
+}
+
+// process writes the input of typed keys, and returns the frame captured then.
+func (t *Terminal) process(keys string, input ...string) Frame {
+	t.tb.Helper()
+
+	for _, input := range input {
+		if err := t.input.write(input, true, t.Timeout); err != nil {
+			t.tb.Fatalf("failed to type %q: %s", keys, err)
+		}
+	}
+
+	select {
+	case frame := <-t.captured:
+		return frame
+	case <-t.done:
+		t.tb.Fatalf("the console has returned (%v) before processing %q, screen is:\n%s", t.err, keys, t.Screen())
+	case <-time.After(t.Timeout):
+		t.tb.Fatalf("keys %q not processed after %s, screen is:\n%s", keys, t.Timeout, t.Screen())
+	}
+
+	return Frame{}
+}
+
+// Frame returns the state of the shell and screen captured after the last keys typed.
+func (t *Terminal) Frame() Frame {
+	return t.frames[len(t.frames)-1]
+}
+
+// Frames returns all the frames recorded, the first one being the initial
+// screen, and each other one the screen after keys have been typed.
+func (t *Terminal) Frames() []Frame {
+	return append([]Frame(nil), t.frames...)
+}
+
+// Output returns the text printed since the previous frame (eg. the output of the
+// command executed by the last keys, and the errors printed by the console).
+func (t *Terminal) Output() string {
+	return t.Frame().Output
+}
+
+// Screen returns the current rows of the screen, without colors: they might have
+// changed since the last frame, if the application prints in the background.
+func (t *Terminal) Screen() string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return strings.Join(t.screen.lines(), "\n")
+}
+
+// Close closes the console, by canceling its context and accepting an empty line,
+// waits for it to return, and closes the pseudo-terminal. This is done automatically
+// when the test is done. The test fails if the console does not return before the
+// timeout (eg. when a command is still executing).
+func (t *Terminal) Close() {
+	t.tb.Helper()
+
+	if t.cancel == nil {
+		return
+	}
+
+	t.cancel()
+	t.cancel = nil
+
+	select {
+	case <-t.done:
+	default:
+		_ = t.input.write(seqClose, true, t.Timeout)
+
+		select {
+		case <-t.done:
+		case <-time.After(t.Timeout):
+			t.tb.Errorf("console still running after %s, screen is:\n%s", t.Timeout, t.Screen())
+		}
+	}
+
+	t.input.close(t.tty.restore)
+
+	<-t.read
+
+	select {
+	case <-t.done:
+		if t.err != nil && !errors.Is(t.err, context.Canceled) {
+			t.tb.Errorf("console returned an error: %s", t.err)
+		}
+	default:
+	}
+}
+
+// AssertPrompt fails the test if the current prompt (without colors) is not want.
+func (t *Terminal) AssertPrompt(tb testing.TB, want string) {
+	tb.Helper()
+
+	if prompt := StripANSI(t.Frame().Prompt); prompt != want {
+		tb.Errorf("prompt is %q, want %q", prompt, want)
+	}
+}
+
+// AssertLine fails the test if the current input line is not want.
+func (t *Terminal) AssertLine(tb testing.TB, want string) {
+	tb.Helper()
+
+	if line := t.Frame().Line; line != want {
+		tb.Errorf("input line is %q, want %q", line, want)
+	}
+}
+
+// AssertHint fails the test if the current hint (without colors) does not contain want.
+func (t *Terminal) AssertHint(tb testing.TB, want string) {
+	tb.Helper()
+
+	if hint := StripANSI(t.Frame().Hint); !strings.Contains(hint, want) {
+		tb.Errorf("hint is %q, want it to contain %q", hint, want)
+	}
+}
+
+// AssertCompletions fails the test if the completion candidates wanted
+// are not all displayed on the screen, below the input line.
+func (t *Terminal) AssertCompletions(tb testing.TB, want ...string) {
+	tb.Helper()
+
+	frame := t.Frame()
+
+	var displayed []string
+	for _, row := range frame.Screen[min(frame.row+1, len(frame.Screen)):] {
+		displayed = append(displayed, strings.Fields(row)...)
+	}
+
+	for _, comp := range want {
+		if !slices.Contains(displayed, comp) {
+			tb.Errorf("completion %q is not displayed, screen is:\n%s", comp, frame)
+		}
+	}
+}
+
+// AssertOutput fails the test if the text printed since the
+// previous frame (without colors) does not contain want.
+func (t *Terminal) AssertOutput(tb testing.TB, want string) {
+	tb.Helper()
+
+	if output := t.Output(); !strings.Contains(output, want) {
+		tb.Errorf("output is %q, want it to contain %q", output, want)
+	}
+}
+
+// bind registers the shell commands capturing frames and closing
+// the console, and binds them to the sequences of the terminal.
+func (t *Terminal) bind() {
+	shell := t.app.Shell()
+
+	shell.Keymap.Register(map[string]func(){
+		"consoletest-frame": t.captureState,
+		"consoletest-close": t.acceptEmpty,
+	})
+
+	for _, keymap := range terminalKeymaps {
+		if shell.Config.Binds[keymap] == nil {
+			shell.Config.Binds[keymap] = make(map[string]inputrc.Bind)
+		}
+
+		shell.Config.Binds[keymap][seqFrame] = inputrc.Bind{Action: "consoletest-frame"}
+		shell.Config.Binds[keymap][seqClose] = inputrc.Bind{Action: "consoletest-close"}
+	}
+}
+
+// captureState captures the state of the shell, and prints the frame marker
+// so that the screen is captured once the terminal has displayed everything
+// printed before. It is run by the shell, which has refreshed its display.
+func (t *Terminal) captureState() {
+	shell := t.app.Shell()
+
+	// The capture is no motion for a pending vi operator.
+	if shell.Keymap.Local() == "vi-opp" {
+		shell.Keymap.Pending()
+		shell.Keymap.CancelPending()
+	}
+	line := []rune(*shell.Line())
+
+	state := Frame{
+		Line:        string(line),
+		Highlighted: string(line),
+		Cursor:      shell.Cursor().Pos(),
+		Hint:        shell.Hint.Text(),
+	}
+
+	if shell.SyntaxHighlighter != nil {
+		state.Highlighted = shell.SyntaxHighlighter(line)
+	}
+
+	if prompt := t.app.ActiveMenu().Prompt(); prompt != nil && prompt.Primary != nil {
+		state.Prompt = prompt.Primary()
+	}
+
+	t.states <- state
+
+	fmt.Print("\x1b]" + seqFrameMarker + "\a")
+}
+
+// acceptEmpty accepts an empty input line, so that the console returns if
+// its context is canceled. It is run by the shell.
+func (t *Terminal) acceptEmpty() {
+	shell := t.app.Shell()
+	shell.Line().Set()
+	shell.Cursor().Set(0)
+
+	shell.Keymap.Commands()["accept-line"]()
+}
+
+// readOutput displays the output of the terminal on its screen until it is
+// closed, answering the queries of the shell and completing the frames.
+func (t *Terminal) readOutput() {
+	defer close(t.read)
+
+	t.screen.reply = func(reply string) {
+		go func() { _ = t.input.write(reply, false, t.Timeout) }()
+	}
+
+	t.screen.osc = func(osc string) {
+		if osc != seqFrameMarker {
+			return
+		}
+
+		frame := <-t.states
+		frame.Screen = t.screen.lines()
+		frame.Output = t.screen.text()
+		frame.row = t.screen.row
+
+		// Frames captured after Type has given up waiting are dropped.
+		select {
+		case t.captured <- frame:
+		default:
+		}
+	}
+
+	buf := make([]byte, 4096)
+
+	for {
+		n, err := t.tty.master.Read(buf)
+		if n > 0 {
+			t.mutex.Lock()
+			_, _ = t.screen.Write(buf[:n])
+			t.mutex.Unlock()
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// querying returns true if the shell waits for the reply to its cursor position query.
+func (t *Terminal) querying() bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return t.screen.querying
+}
+
+// capture returns what is written to stdout and stderr while running a function.
+func capture(run func()) (output string) {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		run()
+		return ""
+	}
+
+	var buf bytes.Buffer
+
+	var done sync.WaitGroup
+
+	done.Add(1)
+
+	go func() {
+		defer done.Done()
+
+		_, _ = io.Copy(&buf, reader)
+	}()
+
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = writer, writer
+
+	defer func() {
+		os.Stdout, os.Stderr = stdout, stderr
+
+		writer.Close()
+		done.Wait()
+		reader.Close()
+
+		output = buf.String()
+	}()
+
+	run()
+
+	return ""
+}
@@ -0,0 +1,168 @@
+package consoletest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/spf13/cobra"
+
+	"github.com/reeflective/console"
+)
+
+// newTestApp returns a console with a deploy command, whose --env flag
+// is completed with environments, and printing the environment deployed.
+func newTestApp() *console.Console {
+	app := console.New("test")
+	app.NewlineBefore, app.NewlineAfter = false, false
+
+	menu := app.ActiveMenu()
+	menu.Prompt().Primary = func() string { return "test > " }
+
+	menu.SetCommands(func() *cobra.Command {
+		root := &cobra.Command{SilenceErrors: true}
+
+		deploy := &cobra.Command{
+			Use:   "deploy",
+			Short: "Deploy the application",
+			Run: func(cmd *cobra.Command, _ []string) {
+				env, _ := cmd.Flags().GetString("env")
+				fmt.Printf("deployed to %s\n", env)
+			},
+		}
+
+		deploy.Flags().String("env", "dev", "Environment")
+		carapace.Gen(deploy).FlagCompletion(carapace.ActionMap{
+			"env": carapace.ActionValues("prod", "staging"),
+		})
+
+		root.AddCommand(deploy)
+
+		return root
+	})
+
+	return app
+}
+
+func TestTerminalPrompt(t *testing.T) {
+	term := New(t, newTestApp())
+
+	term.AssertPrompt(t, "test > ")
+	term.AssertLine(t, "")
+
+	if screen := term.Frame().String(); screen != "test >" {
+		t.Errorf("screen is %q, want the prompt", screen)
+	}
+}
+
+func TestTerminalEditing(t *testing.T) {
+	term := New(t, newTestApp())
+
+	term.Type("deploy --env")
+	term.AssertLine(t, "deploy --env")
+
+	if cursor := term.Frame().Cursor; cursor != 12 {
+		t.Errorf("cursor is at %d, want the end of the line", cursor)
+	}
+
+	// Emacs keys: beginning of line, then kill the line.
+	term.Type("\x01")
+
+	if cursor := term.Frame().Cursor; cursor != 0 {
+		t.Errorf("cursor is at %d after Ctrl-A, want 0", cursor)
+	}
+
+	term.Type("\x0b")
+	term.AssertLine(t, "")
+
+	// Backspace.
+	term.Type("deploy\x7f")
+	term.AssertLine(t, "deplo")
+
+	if screen := term.Frame().Screen; len(screen) == 0 || screen[0] != "test > deplo" {
+		t.Errorf("screen is %q, want the input line after the prompt", screen)
+	}
+}
+
+func TestTerminalCompletion(t *testing.T) {
+	term := New(t, newTestApp())
+
+	term.Type("dep\t")
+	term.AssertLine(t, "deploy ")
+
+	term.Type("--env \t")
+	term.AssertCompletions(t, "prod", "staging")
+}
+
+func TestTerminalExecute(t *testing.T) {
+	term := New(t, newTestApp())
+
+	term.Type("deploy --env prod\r")
+	term.AssertOutput(t, "deployed to prod")
+	term.AssertLine(t, "")
+
+	term.Type("unknown\r")
+	term.AssertOutput(t, "unknown")
+
+	if frames := term.Frames(); len(frames) != 3 {
+		t.Errorf("%d frames recorded, want the initial one and one per Type", len(frames))
+	}
+}
+
+func TestTerminalMessagePendingOperator(t *testing.T) {
+	app := newTestApp()
+	app.ActiveMenu().InputMode = console.ModeViNormal
+
+	term := New(t, app)
+
+	term.Type("ideploy now\x1b")
+	term.Type("0d")
+
+	// The terminal status reply waking the shell up to print the message
+	// is no motion for the pending operator, which still deletes a word.
+	app.Printf("message\n")
+
+	for deadline := time.Now().Add(term.Timeout); !strings.Contains(term.Screen(), "message"); {
+		if time.Now().After(deadline) {
+			t.Fatalf("message not printed, screen is:\n%s", term.Screen())
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	term.Type("w")
+	term.AssertLine(t, "now")
+}
+
+func TestTerminalTextObjects(t *testing.T) {
+	const line = `deploy now "hello world" (x y) end`
+
+	tests := []struct {
+		keys string
+		want string
+	}{
+		{"0wwdiw", `deploy now hello world" (x y) end`},
+		{"0wwwdaw", `deploy now "world" (x y) end`},
+		{"0di\"", `deploy now "" (x y) end`},
+		{"0wwwda\"", `deploy now (x y) end`},
+		{"0ci\"bye\x1b", `deploy now "bye" (x y) end`},
+		{"$bbdi(", `deploy now "hello world" () end`},
+		{"$bbyibP", `deploy now "hello world" (x yx y) end`},
+		{"$di\"", line},
+	}
+
+	for _, test := range tests {
+		app := newTestApp()
+		app.ActiveMenu().InputMode = console.ModeViNormal
+
+		term := New(t, app)
+
+		term.Type("i" + line + "\x1b")
+		term.Type(test.keys)
+		term.AssertLine(t, test.want)
+
+		term.Close()
+	}
+}
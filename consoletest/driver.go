@@ -0,0 +1,287 @@
+package consoletest
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+)
+
+// DefaultTimeout is the time Driver expectations wait for the output by default.
+const DefaultTimeout = 10 * time.Second
+
+// Size of the pseudo-terminal of drivers.
+const (
+	driverCols = 120
+	driverRows = 40
+)
+
+// drivenEnv is set in the environment of the processes started by Drive.
+const drivenEnv = "CONSOLETEST_DRIVEN"
+
+var errDriverTimeout = errors.New("timed out")
+
+// ptyProcess is a process running in a pseudo-terminal.
+type ptyProcess interface {
+	io.Reader
+	io.Writer
+	Unread() int  // Size of the input not read by the process yet (0 if unknown).
+	Wait() error  // Wait for the process to exit.
+	Close() error // Close the pseudo-terminal, and kill the process.
+}
+
+// Driver runs a console application in a pseudo-terminal, for end-to-end tests:
+// keys are typed in the terminal, and expectations wait for the output of the
+// application (without its ANSI sequences) to contain some text, failing the test
+// after a timeout otherwise. Expectations are matched in order: each of them only
+// searches the output following the match of the previous one.
+//
+//	func TestMain(m *testing.M) {
+//		if consoletest.Driven() {
+//			newApp().Start() // Run the application in the pseudo-terminal.
+//			os.Exit(0)
+//		}
+//
+//		os.Exit(m.Run())
+//	}
+//
+//	func TestHelp(t *testing.T) {
+//		driver := consoletest.Drive(t)
+//		driver.Type("help\r").ExpectOutput("Commands:")
+//	}
+//
+// Pseudo-terminals are supported on Unix systems (Linux, macOS, BSDs) and Windows
+// (with ConPTY, on Windows 10 1809 and above): elsewhere, tests using drivers are
+// skipped. The terminal is 120 columns wide and 40 rows high, and answers cursor
+// position queries like a real terminal, which the shell uses when redisplaying.
+type Driver struct {
+	// Timeout is the time expectations wait for the output (DefaultTimeout by default).
+	Timeout time.Duration
+
+	tb      testing.TB
+	pty     ptyProcess
+	input   *ptyInput
+	screen  *screen       // Displays the output, answering the queries of the shell.
+	output  bytes.Buffer  // All the output read, with ANSI sequences.
+	offset  int           // Position after the last match, in the output without ANSI sequences.
+	updated chan struct{} // Notified when output is read.
+	done    chan struct{} // Closed when the output is closed.
+	mutex   sync.Mutex
+}
+
+// Driven returns true in the processes started by Drive, which should then run
+// the console application (typically from TestMain) instead of running the tests.
+func Driven() bool {
+	return os.Getenv(drivenEnv) != ""
+}
+
+// Drive starts the test binary itself in a pseudo-terminal (with the arguments,
+// if any), in which Driven returns true so that it runs the console application,
+// and returns its driver. The process is killed when the test is done.
+func Drive(tb testing.TB, args ...string) *Driver {
+	tb.Helper()
+
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), drivenEnv+"=1")
+
+	return NewDriver(tb, cmd)
+}
+
+// NewDriver starts a command (eg. the application binary) in a pseudo-terminal,
+// and returns its driver. The process is killed when the test is done. The test
+// is skipped if pseudo-terminals are not supported on this platform.
+func NewDriver(tb testing.TB, cmd *exec.Cmd) *Driver {
+	tb.Helper()
+
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+
+	if os.Getenv("TERM") == "" {
+		cmd.Env = append(cmd.Env, "TERM=xterm-256color")
+	}
+
+	pty, err := startPTY(cmd, driverCols, driverRows)
+	if errors.Is(err, errPTYUnsupported) {
+		tb.Skip(err)
+	} else if err != nil {
+		tb.Fatalf("failed to start %s: %s", cmd.Path, err)
+	}
+
+	driver := &Driver{
+		Timeout: DefaultTimeout,
+		tb:      tb,
+		pty:     pty,
+		screen:  newScreen(driverCols, driverRows),
+		updated: make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+
+	driver.input = &ptyInput{writer: pty, unread: pty.Unread, querying: driver.querying}
+
+	go driver.read()
+
+	tb.Cleanup(func() { driver.Close() })
+
+	return driver
+}
+
+// Type writes keys to the terminal, as if typed by the user (eg. "help\r",
+// "\t" for Tab or "\x03" for Ctrl-C). It returns the driver, to chain calls.
+func (d *Driver) Type(keys string) *Driver {
+	d.tb.Helper()
+
+	if err := d.input.write(keys, true, d.Timeout); err != nil {
+		d.tb.Fatalf("failed to type %q: %s", keys, err)
+	}
+
+	return d
+}
+
+// ExpectOutput waits until the output (without ANSI sequences) contains text,
+// after the previous match, and fails the test if it does not before the timeout.
+// It returns the driver, to chain calls.
+func (d *Driver) ExpectOutput(text string) *Driver {
+	d.tb.Helper()
+
+	d.Expect(regexp.MustCompile(regexp.QuoteMeta(text)))
+
+	return d
+}
+
+// Expect waits until the output (without ANSI sequences) matches the pattern,
+// after the previous match, and returns the match and its submatches. The test
+// fails if the output does not match before the timeout.
+func (d *Driver) Expect(pattern *regexp.Regexp) []string {
+	d.tb.Helper()
+
+	match, err := d.expect(pattern)
+	if err != nil {
+		d.tb.Fatalf("expected output matching %q: %s, output is:\n%s", pattern, err, d.Output())
+	}
+
+	return match
+}
+
+// Output returns all the output of the terminal, without ANSI sequences.
+func (d *Driver) Output() string {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	return StripANSI(d.output.String())
+}
+
+// Wait waits for the process to exit (eg. after typing "exit\r"), and returns its
+// error if it failed. The test fails if the process does not exit before the timeout.
+func (d *Driver) Wait() error {
+	d.tb.Helper()
+
+	exited := make(chan error, 1)
+
+	go func() { exited <- d.pty.Wait() }()
+
+	select {
+	case err := <-exited:
+		return err
+	case <-time.After(d.Timeout):
+		d.tb.Fatalf("process still running after %s, output is:\n%s", d.Timeout, d.Output())
+		return errDriverTimeout
+	}
+}
+
+// Close closes the terminal and kills the process, if still running.
+// This is done automatically when the test is done.
+func (d *Driver) Close() error {
+	var err error
+
+	d.input.close(func() { err = d.pty.Close() })
+
+	return err
+}
+
+// expect waits for the output to match a pattern, after the previous match.
+func (d *Driver) expect(pattern *regexp.Regexp) ([]string, error) {
+	timeout := time.After(d.Timeout)
+	closed := false
+
+	for {
+		d.mutex.Lock()
+		output := StripANSI(d.output.String())
+
+		if d.offset <= len(output) {
+			if loc := pattern.FindStringSubmatchIndex(output[d.offset:]); loc != nil {
+				match := make([]string, 0, len(loc)/2)
+				for i := 0; i < len(loc); i += 2 {
+					if loc[i] >= 0 {
+						match = append(match, output[d.offset+loc[i]:d.offset+loc[i+1]])
+					} else {
+						match = append(match, "")
+					}
+				}
+
+				d.offset += loc[1]
+				d.mutex.Unlock()
+
+				return match, nil
+			}
+		}
+		d.mutex.Unlock()
+
+		// The whole output has been matched against.
+		if closed {
+			return nil, io.EOF
+		}
+
+		select {
+		case <-d.updated:
+		case <-d.done:
+			closed = true
+		case <-timeout:
+			return nil, errDriverTimeout
+		}
+	}
+}
+
+// read reads the output of the terminal until it is closed,
+// answering the cursor position and status queries of the application.
+func (d *Driver) read() {
+	defer close(d.done)
+
+	d.screen.reply = func(reply string) {
+		go func() { _ = d.input.write(reply, false, d.Timeout) }()
+	}
+
+	buf := make([]byte, 4096)
+
+	for {
+		n, err := d.pty.Read(buf)
+		if n > 0 {
+			d.mutex.Lock()
+			_, _ = d.screen.Write(buf[:n])
+			d.output.Write(buf[:n])
+			d.mutex.Unlock()
+
+			select {
+			case d.updated <- struct{}{}:
+			default:
+			}
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// querying returns true if the application waits for the reply to its cursor position query.
+func (d *Driver) querying() bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	return d.screen.querying
+}
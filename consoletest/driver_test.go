@@ -0,0 +1,49 @@
+package consoletest
+
+import (
+	"io"
+	"os"
+	"regexp"
+	"testing"
+
+	"github.com/reeflective/console"
+)
+
+func TestMain(m *testing.M) {
+	if Driven() {
+		app := newTestApp()
+		app.ActiveMenu().AddInterrupt(io.EOF, func(*console.Console) { os.Exit(3) })
+
+		_ = app.Start()
+
+		os.Exit(0)
+	}
+
+	os.Exit(m.Run())
+}
+
+func TestDriver(t *testing.T) {
+	driver := Drive(t)
+
+	driver.ExpectOutput("test > ")
+	driver.Type("deploy --env staging\r").ExpectOutput("deployed to staging")
+
+	// Expectations only match the output following the previous match.
+	driver.Type("deploy\r")
+
+	if match := driver.Expect(regexp.MustCompile(`deployed to (\w+)`)); match[1] != "dev" {
+		t.Errorf("deployed to %q, want the default environment", match[1])
+	}
+}
+
+func TestDriverWait(t *testing.T) {
+	driver := Drive(t)
+	driver.ExpectOutput("test > ")
+
+	// Ctrl-D exits the application with a status of 3.
+	driver.Type("\x04")
+
+	if err := driver.Wait(); err == nil || err.Error() != "exit status 3" {
+		t.Errorf("process exited with %v, want the exit status of the Ctrl-D handler", err)
+	}
+}
@@ -0,0 +1,279 @@
+package consoletest
+
+import (
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/reeflective/readline"
+	"github.com/rivo/uniseg"
+	"github.com/spf13/cobra"
+
+	"github.com/reeflective/console"
+)
+
+// defaultRenderWidth is the width of rendered texts, if not given.
+const defaultRenderWidth = 80
+
+// update makes Golden write the golden files instead of comparing them.
+var update = flag.Bool("consoletest.update", os.Getenv("CONSOLETEST_UPDATE") != "", "Update the consoletest golden files")
+
+// RenderOptions configures the rendering of help, completions and command
+// outputs, so that they are deterministic and can be compared to golden files.
+type RenderOptions struct {
+	Width  int  // Width of the rendering, in columns (80 if zero): longer lines are wrapped.
+	Colors bool // Keep the ANSI sequences (colors, styles), which are removed by default.
+}
+
+// Golden compares a rendering with the golden file testdata/<name>.golden, and fails
+// the test with a diff if they differ. When the tests are run with the -consoletest.update
+// flag (or the CONSOLETEST_UPDATE environment variable set), the golden file is written
+// with the rendering instead, after which the changes can be reviewed before committing.
+func Golden(tb testing.TB, name, got string) {
+	tb.Helper()
+
+	path := filepath.Join("testdata", name+".golden")
+
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			tb.Fatalf("failed to create golden file directory: %s", err)
+		}
+
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			tb.Fatalf("failed to write golden file: %s", err)
+		}
+
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		tb.Fatalf("failed to read golden file (run with -consoletest.update to create it): %s", err)
+	}
+
+	if string(want) != got {
+		tb.Errorf("rendering differs from %s (run with -consoletest.update to update it):\n%s", path,
+			console.Diff(string(want), got, console.DiffOptions{OldName: path, NewName: "got", NoColor: true}))
+	}
+}
+
+// RenderHelp renders the help of a command, like printed by its --help flag.
+func RenderHelp(cmd *cobra.Command, opts RenderOptions) string {
+	var help strings.Builder
+
+	out, errOut := cmd.OutOrStdout(), cmd.ErrOrStderr()
+	cmd.SetOut(&help)
+	cmd.SetErr(&help)
+
+	_ = cmd.Help()
+
+	cmd.SetOut(out)
+	cmd.SetErr(errOut)
+
+	return render(help.String(), opts)
+}
+
+// RenderCompletions renders the completions of a console input line (the cursor
+// being at its end) like the completion menu of the shell: candidates are grouped
+// by tag, and sorted. Candidates with descriptions are listed one per line, and the
+// others are arranged in as many columns as fit in the width.
+func RenderCompletions(app *console.Console, line string, opts RenderOptions) string {
+	app.ExecuteLine(context.Background(), "")
+
+	comps := app.Shell().Completer([]rune(line), len([]rune(line)))
+
+	start := wordStart([]rune(line), len([]rune(line)))
+	word := string([]rune(line)[start:])
+
+	var tags []string
+
+	groups := make(map[string][]readline.Completion)
+
+	comps.EachValue(func(comp readline.Completion) readline.Completion {
+		if !strings.HasPrefix(comp.Value, word) {
+			return comp
+		}
+
+		if _, found := groups[comp.Tag]; !found {
+			tags = append(tags, comp.Tag)
+		}
+
+		groups[comp.Tag] = append(groups[comp.Tag], comp)
+
+		return comp
+	})
+
+	width := renderWidth(opts)
+
+	var menu strings.Builder
+
+	if prompt := app.ActiveMenu().Prompt(); prompt != nil && prompt.Primary != nil {
+		menu.WriteString(prompt.Primary())
+	}
+
+	menu.WriteString(line + "\n")
+
+	for _, tag := range tags {
+		group := groups[tag]
+		sort.SliceStable(group, func(i, j int) bool { return group[i].Value < group[j].Value })
+
+		if tag != "" {
+			menu.WriteString(tag + "\n")
+		}
+
+		renderGroup(&menu, group, width)
+	}
+
+	return render(menu.String(), opts)
+}
+
+// RenderOutput executes a console input line, and renders its output (including
+// the errors printed by the console). The COLUMNS environment variable is set to
+// the rendering width while the command runs, for commands formatting tables
+// according to the terminal width.
+func RenderOutput(app *console.Console, line string, opts RenderOptions) string {
+	columns, hasColumns := os.LookupEnv("COLUMNS")
+	os.Setenv("COLUMNS", strconv.Itoa(renderWidth(opts)))
+
+	defer func() {
+		if hasColumns {
+			os.Setenv("COLUMNS", columns)
+		} else {
+			os.Unsetenv("COLUMNS")
+		}
+	}()
+
+	app.ExecuteLine(context.Background(), "")
+
+	output := capture(func() {
+		app.ExecuteLine(context.Background(), line)
+	})
+
+	return render(output, opts)
+}
+
+// renderGroup renders a group of completion candidates.
+func renderGroup(menu *strings.Builder, group []readline.Completion, width int) {
+	valueWidth, described := 0, false
+
+	for _, comp := range group {
+		valueWidth = max(valueWidth, uniseg.StringWidth(display(comp)))
+		described = described || comp.Description != ""
+	}
+
+	// Described candidates, one per line.
+	if described {
+		for _, comp := range group {
+			value := display(comp)
+			menu.WriteString(value)
+
+			if comp.Description != "" {
+				menu.WriteString(strings.Repeat(" ", valueWidth-uniseg.StringWidth(value)) + "  -- " + comp.Description)
+			}
+
+			menu.WriteString("\n")
+		}
+
+		return
+	}
+
+	// Other candidates in columns, filled row by row.
+	columns := max(width/(valueWidth+2), 1)
+
+	for i, comp := range group {
+		value := display(comp)
+
+		if (i+1)%columns == 0 || i == len(group)-1 {
+			menu.WriteString(value + "\n")
+		} else {
+			menu.WriteString(value + strings.Repeat(" ", valueWidth+2-uniseg.StringWidth(value)))
+		}
+	}
+}
+
+// display returns the displayed text of a completion candidate.
+func display(comp readline.Completion) string {
+	if comp.Display != "" {
+		return comp.Display
+	}
+
+	return strings.TrimSuffix(comp.Value, " ")
+}
+
+// render normalizes a text: ANSI sequences are removed unless colors are kept,
+// trailing spaces are removed, and lines longer than the width are wrapped.
+func render(text string, opts RenderOptions) string {
+	if !opts.Colors {
+		text = StripANSI(text)
+	}
+
+	width := renderWidth(opts)
+
+	var rendered strings.Builder
+
+	for _, line := range strings.SplitAfter(text, "\n") {
+		newline := strings.HasSuffix(line, "\n")
+		line = strings.TrimRight(line, " \t\r\n")
+
+		for uniseg.StringWidth(StripANSI(line)) > width {
+			head := cutWidth(line, width)
+			rendered.WriteString(head + "\n")
+			line = line[len(head):]
+		}
+
+		rendered.WriteString(line)
+
+		if newline {
+			rendered.WriteString("\n")
+		}
+	}
+
+	return rendered.String()
+}
+
+// cutWidth returns the beginning of a line fitting in width columns,
+// not counting the ANSI sequences, which are never split.
+func cutWidth(line string, width int) string {
+	used, size := 0, 0
+
+	for size < len(line) {
+		if loc := ansi.FindStringIndex(line[size:]); loc != nil && loc[0] == 0 {
+			size += loc[1]
+			continue
+		}
+
+		cluster, _, clusterWidth, _ := uniseg.FirstGraphemeClusterInString(line[size:], -1)
+		if used+clusterWidth > width && used > 0 {
+			break
+		}
+
+		used += clusterWidth
+		size += len(cluster)
+	}
+
+	return line[:size]
+}
+
+// renderWidth returns the width of renderings.
+func renderWidth(opts RenderOptions) int {
+	if opts.Width <= 0 {
+		return defaultRenderWidth
+	}
+
+	return opts.Width
+}
+
+// wordStart returns the start of the word before the cursor.
+func wordStart(line []rune, cursor int) int {
+	start := cursor
+	for start > 0 && line[start-1] != ' ' {
+		start--
+	}
+
+	return start
+}
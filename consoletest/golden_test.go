@@ -0,0 +1,57 @@
+package consoletest
+
+import (
+	"context"
+	"testing"
+)
+
+func TestGoldenHelp(t *testing.T) {
+	app := newTestApp()
+	app.ExecuteLine(context.Background(), "")
+
+	deploy, _, err := app.ActiveMenu().Find([]string{"deploy"})
+	if err != nil {
+		t.Fatalf("deploy command not found: %s", err)
+	}
+
+	Golden(t, "deploy-help", RenderHelp(deploy, RenderOptions{}))
+}
+
+func TestGoldenCompletions(t *testing.T) {
+	app := newTestApp()
+
+	Golden(t, "completions-commands", RenderCompletions(app, "dep", RenderOptions{}))
+	Golden(t, "completions-env", RenderCompletions(app, "deploy --env ", RenderOptions{}))
+}
+
+func TestGoldenOutput(t *testing.T) {
+	app := newTestApp()
+
+	Golden(t, "output-deploy", RenderOutput(app, "deploy --env prod", RenderOptions{}))
+	Golden(t, "output-unknown", RenderOutput(app, "unknown", RenderOptions{}))
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		opts RenderOptions
+		want string
+	}{
+		{"plain", "one\ntwo\n", RenderOptions{}, "one\ntwo\n"},
+		{"trailing spaces", "one  \r\ntwo\t", RenderOptions{}, "one\ntwo"},
+		{"colors removed", "\x1b[32mgreen\x1b[0m\n", RenderOptions{}, "green\n"},
+		{"colors kept", "\x1b[32mgreen\x1b[0m\n", RenderOptions{Colors: true}, "\x1b[32mgreen\x1b[0m\n"},
+		{"wrapped", "abcdefghij\n", RenderOptions{Width: 4}, "abcd\nefgh\nij\n"},
+		{"wrapped colors", "\x1b[1mabcdef\x1b[0m", RenderOptions{Width: 3, Colors: true}, "\x1b[1mabc\ndef\x1b[0m"},
+		{"wrapped wide characters", "日本語", RenderOptions{Width: 4}, "日本\n語"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := render(test.text, test.opts); got != test.want {
+				t.Errorf("rendering is %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package consoletest
+
+import (
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// inputSettle is the time the input written to a pseudo-terminal might take to be readable.
+const inputSettle = 5 * time.Millisecond
+
+// ptyInput writes the input of a pseudo-terminal read by a shell. The shell reading the reply
+// to its cursor position query drops the keys read along with it: keys are thus only written
+// once the reply to the last query has been read (the shell printing something after it), and
+// each input once the previous ones have been read, when the size of the unread input is known.
+type ptyInput struct {
+	writer   io.Writer
+	unread   func() int  // Returns the size of the input not read yet (0 if unknown).
+	querying func() bool // Returns true until the shell reads the reply to its last query.
+	mutex    sync.Mutex
+	closed   bool
+}
+
+// write writes keys, or a reply to a query of the shell, giving up waiting after the timeout.
+func (in *ptyInput) write(input string, keys bool, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		in.mutex.Lock()
+
+		if in.closed {
+			in.mutex.Unlock()
+			return os.ErrClosed
+		}
+
+		if in.unread() == 0 && !(keys && in.querying()) || time.Now().After(deadline) {
+			break
+		}
+
+		in.mutex.Unlock()
+		time.Sleep(time.Millisecond)
+	}
+
+	defer in.mutex.Unlock()
+
+	if _, err := in.writer.Write([]byte(input)); err != nil {
+		return err
+	}
+
+	// The input is not readable by the shell right away.
+	for settle := time.Now().Add(inputSettle); in.unread() == 0 && time.Now().Before(settle); {
+		time.Sleep(inputSettle / 50)
+	}
+
+	return nil
+}
+
+// close closes the pseudo-terminal with a function, once no input is being written.
+func (in *ptyInput) close(closeTerminal func()) {
+	in.mutex.Lock()
+	defer in.mutex.Unlock()
+
+	if !in.closed {
+		in.closed = true
+		closeTerminal()
+	}
+}
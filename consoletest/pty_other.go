@@ -0,0 +1,16 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd || windows)
+
+package consoletest
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// errPTYUnsupported is returned when pseudo-terminals are not supported.
+var errPTYUnsupported = errors.New("pseudo-terminals are not supported")
+
+// startPTY returns errPTYUnsupported, since pseudo-terminals are not supported here.
+func startPTY(_ *exec.Cmd, _, _ int) (ptyProcess, error) {
+	return nil, errPTYUnsupported
+}
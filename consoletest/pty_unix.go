@@ -0,0 +1,79 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package consoletest
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+
+	"github.com/creack/pty"
+	"golang.org/x/sys/unix"
+)
+
+// errPTYUnsupported is returned when pseudo-terminals are not supported.
+var errPTYUnsupported = errors.New("pseudo-terminals are not supported")
+
+// unixPTY is a process running in a Unix pseudo-terminal.
+type unixPTY struct {
+	*os.File
+	tty *os.File // Kept open to know the size of the input not read yet.
+	cmd *exec.Cmd
+}
+
+// startPTY starts a command in a new pseudo-terminal of the given size,
+// as the session leader controlled by the terminal.
+func startPTY(cmd *exec.Cmd, cols, rows int) (ptyProcess, error) {
+	ptmx, tty, err := pty.Open()
+	if errors.Is(err, pty.ErrUnsupported) {
+		return nil, errPTYUnsupported
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err = pty.Setsize(ptmx, &pty.Winsize{Cols: uint16(cols), Rows: uint16(rows)}); err == nil {
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = tty, tty, tty
+
+		if cmd.SysProcAttr == nil {
+			cmd.SysProcAttr = &syscall.SysProcAttr{}
+		}
+
+		cmd.SysProcAttr.Setsid, cmd.SysProcAttr.Setctty = true, true
+
+		err = cmd.Start()
+	}
+
+	if err != nil {
+		ptmx.Close()
+		tty.Close()
+
+		return nil, err
+	}
+
+	return &unixPTY{File: ptmx, tty: tty, cmd: cmd}, nil
+}
+
+// Unread returns the size of the input not read by the process yet.
+func (p *unixPTY) Unread() int {
+	count, err := unix.IoctlGetInt(int(p.tty.Fd()), ioctlUnread)
+	if err != nil {
+		return 0
+	}
+
+	return count
+}
+
+// Wait waits for the process to exit.
+func (p *unixPTY) Wait() error {
+	return p.cmd.Wait()
+}
+
+// Close closes the pseudo-terminal, and kills the process.
+func (p *unixPTY) Close() error {
+	err := p.File.Close()
+	p.tty.Close()
+	_ = p.cmd.Process.Kill()
+
+	return err
+}
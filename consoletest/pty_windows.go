@@ -0,0 +1,191 @@
+//go:build windows
+
+package consoletest
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf16"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// errPTYUnsupported is returned when pseudo-terminals (ConPTY) are not supported.
+var errPTYUnsupported = errors.New("pseudo-terminals are not supported (Windows 10 1809 or above is needed)")
+
+// conPTY is a process running in a Windows pseudo-console.
+type conPTY struct {
+	console windows.Handle
+	process windows.Handle
+	input   *os.File // Writes to the pseudo-console input.
+	output  *os.File // Reads the pseudo-console output.
+	once    sync.Once
+}
+
+// startPTY starts a command in a new pseudo-console of the given size.
+func startPTY(cmd *exec.Cmd, cols, rows int) (ptyProcess, error) {
+	if err := windows.NewLazySystemDLL("kernel32.dll").NewProc("CreatePseudoConsole").Find(); err != nil {
+		return nil, errPTYUnsupported
+	}
+
+	inRead, inWrite, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+
+	outRead, outWrite, err := os.Pipe()
+	if err != nil {
+		inRead.Close()
+		inWrite.Close()
+
+		return nil, err
+	}
+
+	var console windows.Handle
+
+	size := windows.Coord{X: int16(cols), Y: int16(rows)}
+	err = windows.CreatePseudoConsole(size, windows.Handle(inRead.Fd()), windows.Handle(outWrite.Fd()), 0, &console)
+
+	// The pseudo-console has its own copies of its ends of the pipes.
+	inRead.Close()
+	outWrite.Close()
+
+	if err != nil {
+		inWrite.Close()
+		outRead.Close()
+
+		return nil, err
+	}
+
+	pty := &conPTY{console: console, input: inWrite, output: outRead}
+
+	if pty.process, err = createProcess(cmd, console); err != nil {
+		pty.Close()
+		return nil, err
+	}
+
+	return pty, nil
+}
+
+// createProcess starts a command attached to a pseudo-console.
+func createProcess(cmd *exec.Cmd, console windows.Handle) (windows.Handle, error) {
+	attrs, err := windows.NewProcThreadAttributeList(1)
+	if err != nil {
+		return 0, err
+	}
+	defer attrs.Delete()
+
+	// The attribute value is the pseudo-console handle itself.
+	if err := attrs.Update(windows.PROC_THREAD_ATTRIBUTE_PSEUDOCONSOLE, *(*unsafe.Pointer)(unsafe.Pointer(&console)), unsafe.Sizeof(console)); err != nil {
+		return 0, err
+	}
+
+	info := &windows.StartupInfoEx{ProcThreadAttributeList: attrs.List()}
+	info.Cb = uint32(unsafe.Sizeof(*info))
+
+	path, err := windows.UTF16PtrFromString(cmd.Path)
+	if err != nil {
+		return 0, err
+	}
+
+	args, err := windows.UTF16PtrFromString(windows.ComposeCommandLine(cmd.Args))
+	if err != nil {
+		return 0, err
+	}
+
+	var dir *uint16
+
+	if cmd.Dir != "" {
+		if dir, err = windows.UTF16PtrFromString(cmd.Dir); err != nil {
+			return 0, err
+		}
+	}
+
+	var process windows.ProcessInformation
+
+	flags := uint32(windows.EXTENDED_STARTUPINFO_PRESENT | windows.CREATE_UNICODE_ENVIRONMENT)
+
+	err = windows.CreateProcess(path, args, nil, nil, false, flags, environmentBlock(cmd.Env), dir, &info.StartupInfo, &process)
+	if err != nil {
+		return 0, err
+	}
+
+	windows.CloseHandle(process.Thread)
+
+	return process.Process, nil
+}
+
+// environmentBlock returns an environment in the format of CreateProcess,
+// or nil (inheriting the current environment) if there are no variables.
+func environmentBlock(env []string) *uint16 {
+	if len(env) == 0 {
+		return nil
+	}
+
+	block := utf16.Encode([]rune(strings.Join(env, "\x00") + "\x00\x00"))
+
+	return &block[0]
+}
+
+// Read reads the output of the pseudo-console.
+func (p *conPTY) Read(buf []byte) (int, error) {
+	return p.output.Read(buf)
+}
+
+// Write writes to the input of the pseudo-console.
+func (p *conPTY) Write(buf []byte) (int, error) {
+	return p.input.Write(buf)
+}
+
+// Unread returns 0, since the size of the input not read yet is unknown.
+func (p *conPTY) Unread() int {
+	return 0
+}
+
+// Wait waits for the process to exit.
+func (p *conPTY) Wait() error {
+	if _, err := windows.WaitForSingleObject(p.process, windows.INFINITE); err != nil {
+		return err
+	}
+
+	var code uint32
+
+	if err := windows.GetExitCodeProcess(p.process, &code); err != nil {
+		return err
+	}
+
+	if code != 0 {
+		return &exitError{code: code}
+	}
+
+	return nil
+}
+
+// Close closes the pseudo-console, and kills the process.
+func (p *conPTY) Close() error {
+	p.once.Do(func() {
+		if p.process != 0 {
+			_ = windows.TerminateProcess(p.process, 1)
+		}
+
+		windows.ClosePseudoConsole(p.console)
+		p.input.Close()
+		p.output.Close()
+	})
+
+	return nil
+}
+
+// exitError is returned by Wait when the process exited with an error code.
+type exitError struct {
+	code uint32
+}
+
+func (e *exitError) Error() string {
+	return "exit status " + strconv.FormatUint(uint64(e.code), 10)
+}
@@ -0,0 +1,385 @@
+package consoletest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rivo/uniseg"
+)
+
+// screen emulates the display of a VT100 terminal (like xterm) for the sequences used
+// by the shell: it keeps the characters displayed in each cell, and the cursor position.
+// Colors and styles are ignored. The cursor position and status queries are answered
+// with the reply function, and the OSC sequences (eg. titles) passed to the osc one.
+type screen struct {
+	cols, rows int
+	cells      [][]string // Characters displayed ("" for blank cells, wideTail after wide characters).
+	row, col   int        // Cursor position, from 0.
+	saved      [2]int     // Cursor position saved (row, column).
+	wrap       bool       // The last column has been written: the next character wraps.
+	pending    []byte     // Incomplete sequence or character, waiting for the next write.
+	querying   bool       // The cursor position was queried, and nothing printed since.
+	printed    strings.Builder
+
+	reply func(string)
+	osc   func(string)
+}
+
+// wideTail fills the cells following wide characters.
+const wideTail = "\x00"
+
+// newScreen returns a blank screen, the cursor being at its top left.
+func newScreen(cols, rows int) *screen {
+	s := &screen{cols: cols, rows: rows, reply: func(string) {}, osc: func(string) {}}
+
+	for range rows {
+		s.cells = append(s.cells, s.blankRow())
+	}
+
+	return s
+}
+
+// Write displays the output of the application.
+func (s *screen) Write(data []byte) (int, error) {
+	s.pending = append(s.pending, data...)
+
+	for len(s.pending) > 0 {
+		size := s.parse(s.pending)
+		if size == 0 {
+			break
+		}
+
+		s.pending = s.pending[size:]
+	}
+
+	return len(data), nil
+}
+
+// lines returns the rows of the screen, without trailing blanks nor empty rows.
+func (s *screen) lines() []string {
+	lines := make([]string, 0, s.rows)
+
+	for _, cells := range s.cells {
+		var line strings.Builder
+		for _, cell := range cells {
+			switch cell {
+			case wideTail:
+			case "":
+				line.WriteString(" ")
+			default:
+				line.WriteString(cell)
+			}
+		}
+
+		lines = append(lines, strings.TrimRight(line.String(), " "))
+	}
+
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
+
+// text returns the characters printed since the last call (including line
+// feeds but not carriage returns), and forgets them.
+func (s *screen) text() string {
+	text := s.printed.String()
+	s.printed.Reset()
+
+	return text
+}
+
+// parse processes the character or sequence at the start of the data, and
+// returns its size, or 0 if it is incomplete.
+func (s *screen) parse(data []byte) int {
+	s.querying = false
+
+	switch {
+	case data[0] == '\x1b':
+		return s.parseEscape(data)
+	case data[0] < ' ' || data[0] == '\x7f':
+		s.control(data[0])
+		return 1
+	case !utf8.FullRune(data):
+		return 0
+	}
+
+	char, size := utf8.DecodeRune(data)
+	s.print(string(char))
+
+	return size
+}
+
+// parseEscape processes the escape sequence at the start of the data.
+func (s *screen) parseEscape(data []byte) int {
+	if len(data) < 2 {
+		return 0
+	}
+
+	switch data[1] {
+	case '[':
+		for end := 2; end < len(data); end++ {
+			if data[end] >= 0x40 && data[end] <= 0x7e {
+				s.csi(string(data[2:end]), data[end])
+				return end + 1
+			}
+		}
+
+		return 0
+	case ']':
+		for end := 2; end < len(data); end++ {
+			switch {
+			case data[end] == '\a':
+				s.osc(string(data[2:end]))
+				return end + 1
+			case data[end] == '\x1b' && end+1 < len(data) && data[end+1] == '\\':
+				s.osc(string(data[2:end]))
+				return end + 2
+			}
+		}
+
+		return 0
+	case '(', ')', '#', '%':
+		if len(data) < 3 {
+			return 0
+		}
+
+		return 3
+	case '7':
+		s.saved = [2]int{s.row, s.col}
+	case '8':
+		s.row, s.col, s.wrap = s.saved[0], s.saved[1], false
+	case 'D':
+		s.lineFeed()
+	case 'E':
+		s.col = 0
+		s.lineFeed()
+	case 'M':
+		if s.row == 0 {
+			s.scroll(-1)
+		} else {
+			s.row--
+		}
+	}
+
+	return 2
+}
+
+// control processes a control character.
+func (s *screen) control(char byte) {
+	switch char {
+	case '\r':
+		s.col, s.wrap = 0, false
+	case '\n', '\v', '\f':
+		s.lineFeed()
+		s.printed.WriteByte('\n')
+	case '\b':
+		s.col, s.wrap = max(s.col-1, 0), false
+	case '\t':
+		s.col = min((s.col/8+1)*8, s.cols-1)
+	}
+}
+
+// print displays a character at the cursor.
+func (s *screen) print(char string) {
+	s.printed.WriteString(char)
+
+	width := uniseg.StringWidth(char)
+	if width == 0 {
+		if s.col > 0 && s.cells[s.row][s.col-1] != wideTail {
+			s.cells[s.row][s.col-1] += char
+		}
+
+		return
+	}
+
+	if s.wrap || s.col+width > s.cols {
+		s.col, s.wrap = 0, false
+		s.lineFeed()
+	}
+
+	// Wide characters partly overwritten are erased.
+	row := s.cells[s.row]
+	if row[s.col] == wideTail {
+		row[s.col-1] = ""
+	}
+
+	if end := s.col + width; end < s.cols && row[end] == wideTail {
+		row[end] = ""
+	}
+
+	row[s.col] = char
+	if width == 2 {
+		row[s.col+1] = wideTail
+	}
+
+	s.col += width
+	if s.col >= s.cols {
+		s.col, s.wrap = s.cols-1, true
+	}
+}
+
+// csi processes a control sequence, with its parameters and final character.
+func (s *screen) csi(params string, final byte) {
+	// Private sequences (modes, etc) and those with intermediate characters are ignored.
+	if strings.ContainsAny(params, "?<=> !\"#$%&'()*+,-./") {
+		return
+	}
+
+	args := strings.Split(params, ";")
+	arg := func(i, def int) int {
+		if i >= len(args) {
+			return def
+		}
+
+		if n, err := strconv.Atoi(args[i]); err == nil && n > 0 {
+			return n
+		}
+
+		return def
+	}
+
+	if final != 'm' && final != 'n' {
+		s.wrap = false
+	}
+
+	switch final {
+	case 'A':
+		s.row = max(s.row-arg(0, 1), 0)
+	case 'B':
+		s.row = min(s.row+arg(0, 1), s.rows-1)
+	case 'C':
+		s.col = min(s.col+arg(0, 1), s.cols-1)
+	case 'D':
+		s.col = max(s.col-arg(0, 1), 0)
+	case 'E':
+		s.row, s.col = min(s.row+arg(0, 1), s.rows-1), 0
+	case 'F':
+		s.row, s.col = max(s.row-arg(0, 1), 0), 0
+	case 'G':
+		s.col = min(arg(0, 1), s.cols) - 1
+	case 'd':
+		s.row = min(arg(0, 1), s.rows) - 1
+	case 'H', 'f':
+		s.row, s.col = min(arg(0, 1), s.rows)-1, min(arg(1, 1), s.cols)-1
+	case 'J':
+		s.eraseDisplay(arg(0, 0))
+	case 'K':
+		s.eraseLine(arg(0, 0))
+	case 'X':
+		s.clear(s.row, s.col, min(s.col+arg(0, 1), s.cols))
+	case 'P':
+		row := s.cells[s.row]
+		count := min(arg(0, 1), s.cols-s.col)
+		copy(row[s.col:], row[s.col+count:])
+		s.clear(s.row, s.cols-count, s.cols)
+	case '@':
+		row := s.cells[s.row]
+		count := min(arg(0, 1), s.cols-s.col)
+		copy(row[s.col+count:], row[s.col:])
+		s.clear(s.row, s.col, s.col+count)
+	case 'L', 'M':
+		s.shiftLines(final, arg(0, 1))
+	case 'S':
+		s.scroll(arg(0, 1))
+	case 'T':
+		s.scroll(-arg(0, 1))
+	case 's':
+		s.saved = [2]int{s.row, s.col}
+	case 'u':
+		s.row, s.col = s.saved[0], s.saved[1]
+	case 'n':
+		switch arg(0, 0) {
+		case 5:
+			s.reply("\x1b[0n")
+		case 6:
+			s.querying = true
+			s.reply(fmt.Sprintf("\x1b[%d;%dR", s.row+1, s.col+1))
+		}
+	}
+}
+
+// eraseDisplay clears the screen below (0) or above (1) the cursor, or all of it.
+func (s *screen) eraseDisplay(mode int) {
+	switch mode {
+	case 0:
+		s.clear(s.row, s.col, s.cols)
+
+		for row := s.row + 1; row < s.rows; row++ {
+			s.clear(row, 0, s.cols)
+		}
+	case 1:
+		s.clear(s.row, 0, s.col+1)
+
+		for row := range s.row {
+			s.clear(row, 0, s.cols)
+		}
+	default:
+		for row := range s.rows {
+			s.clear(row, 0, s.cols)
+		}
+	}
+}
+
+// eraseLine clears the line after (0) or before (1) the cursor, or all of it.
+func (s *screen) eraseLine(mode int) {
+	switch mode {
+	case 0:
+		s.clear(s.row, s.col, s.cols)
+	case 1:
+		s.clear(s.row, 0, s.col+1)
+	default:
+		s.clear(s.row, 0, s.cols)
+	}
+}
+
+// clear blanks the cells of a row, from start to end (excluded).
+func (s *screen) clear(row, start, end int) {
+	for col := start; col < end; col++ {
+		s.cells[row][col] = ""
+	}
+}
+
+// lineFeed moves the cursor down, scrolling the screen on the last row.
+func (s *screen) lineFeed() {
+	if s.row == s.rows-1 {
+		s.scroll(1)
+	} else {
+		s.row++
+	}
+}
+
+// scroll scrolls the screen up (or down if count is negative).
+func (s *screen) scroll(count int) {
+	for ; count > 0; count-- {
+		s.cells = append(s.cells[1:], s.blankRow())
+	}
+
+	for ; count < 0; count++ {
+		s.cells = append([][]string{s.blankRow()}, s.cells[:s.rows-1]...)
+	}
+}
+
+// shiftLines inserts (L) or deletes (M) lines at the cursor row.
+func (s *screen) shiftLines(final byte, count int) {
+	count = min(count, s.rows-s.row)
+	below := s.cells[s.row:]
+
+	for range count {
+		if final == 'L' {
+			below = append([][]string{s.blankRow()}, below[:len(below)-1]...)
+		} else {
+			below = append(below[1:], s.blankRow())
+		}
+	}
+
+	s.cells = append(s.cells[:s.row:s.row], below...)
+}
+
+// blankRow returns an empty row.
+func (s *screen) blankRow() []string {
+	return make([]string, s.cols)
+}
@@ -0,0 +1,83 @@
+package consoletest
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestScreen(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   []string
+	}{
+		{"lines", "one\r\ntwo\r\n", []string{"one", "two"}},
+		{"carriage return", "hello\rj", []string{"jello"}},
+		{"colors", "\x1b[1;32mgreen\x1b[0m", []string{"green"}},
+		{"cursor moves", "abc\x1b[2DX\x1b[1BY", []string{"aXc", "  Y"}},
+		{"cursor position", "\x1b[2;3Hx", []string{"", "  x"}},
+		{"erase line", "hello\x1b[3D\x1b[K", []string{"he"}},
+		{"erase below", "one\r\ntwo\x1b[1A\r\x1b[J", nil},
+		{"wrap", "abcdefghij", []string{"abcdefgh", "ij"}},
+		{"no wrap before next character", "abcdefgh\r\n", []string{"abcdefgh"}},
+		{"wide characters", "日本\x1b[1DX", []string{"日 X"}},
+		{"scroll", "1\r\n2\r\n3\r\n4\r\n5", []string{"2", "3", "4", "5"}},
+		{"hyperlinks", "\x1b]8;;https://example.com\alink\x1b]8;;\a", []string{"link"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newScreen(8, 4)
+			_, _ = s.Write([]byte(test.output))
+
+			if got := s.lines(); !slices.Equal(got, test.want) {
+				t.Errorf("screen is %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestScreenSplitWrites(t *testing.T) {
+	s := newScreen(20, 4)
+
+	// Sequences and characters split across writes are processed once complete.
+	for _, chunk := range []string{"\x1b[", "31m", "caf", "\xc3", "\xa9", "\x1b"} {
+		_, _ = s.Write([]byte(chunk))
+	}
+
+	if got := s.lines(); !slices.Equal(got, []string{"café"}) {
+		t.Errorf("screen is %q, want the accented word", got)
+	}
+
+	if text := s.text(); text != "café" {
+		t.Errorf("printed text is %q, want the word without sequences", text)
+	}
+}
+
+func TestScreenQueries(t *testing.T) {
+	var replies, oscs []string
+
+	s := newScreen(20, 4)
+	s.reply = func(reply string) { replies = append(replies, reply) }
+	s.osc = func(osc string) { oscs = append(oscs, osc) }
+
+	_, _ = s.Write([]byte("\r\nabc\x1b[6n"))
+
+	if !s.querying {
+		t.Error("the cursor position query is not waiting for its reply")
+	}
+
+	_, _ = s.Write([]byte("\x1b[5n\x1b]0;title\x1b\\"))
+
+	if s.querying {
+		t.Error("the cursor position query is still waiting after more output")
+	}
+
+	if want := []string{"\x1b[2;4R", "\x1b[0n"}; !slices.Equal(replies, want) {
+		t.Errorf("replies are %q, want %q", replies, want)
+	}
+
+	if want := []string{"0;title"}; !slices.Equal(oscs, want) {
+		t.Errorf("OSC sequences are %q, want %q", oscs, want)
+	}
+}
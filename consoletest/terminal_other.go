@@ -0,0 +1,9 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package consoletest
+
+// openTerminal returns errTerminalUnsupported: the terminal of the process
+// is only replaced by a pseudo-terminal on Linux, macOS and BSDs.
+func openTerminal(_, _ int) (*terminalIO, error) {
+	return nil, errTerminalUnsupported
+}
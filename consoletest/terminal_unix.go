@@ -0,0 +1,93 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package consoletest
+
+import (
+	"errors"
+	"os"
+	"runtime/debug"
+
+	"github.com/creack/pty"
+	"golang.org/x/sys/unix"
+)
+
+// openTerminal opens a pseudo-terminal of the given size, and makes it the terminal
+// of the process: the standard input and error are redirected to it, and os.Stdout
+// writes to it, while the standard output of the process is kept for the test logs.
+// The terminal does not echo the keys written to it, nor buffers them by line, so that
+// keys typed while a command is executed are read by the shell when it reads again.
+func openTerminal(cols, rows int) (*terminalIO, error) {
+	master, tty, err := pty.Open()
+	if errors.Is(err, pty.ErrUnsupported) {
+		return nil, errTerminalUnsupported
+	} else if err != nil {
+		return nil, err
+	}
+
+	fail := func(err error) (*terminalIO, error) {
+		master.Close()
+		tty.Close()
+
+		return nil, err
+	}
+
+	if err = pty.Setsize(master, &pty.Winsize{Cols: uint16(cols), Rows: uint16(rows)}); err != nil {
+		return fail(err)
+	}
+
+	termios, err := unix.IoctlGetTermios(int(tty.Fd()), ioctlGetTermios)
+	if err != nil {
+		return fail(err)
+	}
+
+	termios.Lflag &^= unix.ECHO | unix.ICANON | unix.ISIG | unix.IEXTEN
+	termios.Cc[unix.VMIN], termios.Cc[unix.VTIME] = 1, 0
+
+	if err = unix.IoctlSetTermios(int(tty.Fd()), ioctlSetTermios, termios); err != nil {
+		return fail(err)
+	}
+
+	savedIn, err := unix.Dup(int(os.Stdin.Fd()))
+	if err != nil {
+		return fail(err)
+	}
+
+	savedErr, err := unix.Dup(int(os.Stderr.Fd()))
+	if err != nil {
+		unix.Close(savedIn)
+		return fail(err)
+	}
+
+	// Crashes (eg. test timeouts) are still reported on the standard error.
+	crashes := os.NewFile(uintptr(savedErr), "stderr")
+	_ = debug.SetCrashOutput(crashes, debug.CrashOptions{})
+
+	unix.Dup2(int(tty.Fd()), int(os.Stdin.Fd()))
+	unix.Dup2(int(tty.Fd()), int(os.Stderr.Fd()))
+
+	stdout := os.Stdout
+	os.Stdout = tty
+
+	unread := func() int {
+		count, err := unix.IoctlGetInt(int(tty.Fd()), ioctlUnread)
+		if err != nil {
+			return 0
+		}
+
+		return count
+	}
+
+	return &terminalIO{master: master, unread: unread, restore: func() {
+		os.Stdout = stdout
+
+		unix.Dup2(savedIn, int(os.Stdin.Fd()))
+		unix.Dup2(savedErr, int(os.Stderr.Fd()))
+		unix.Close(savedIn)
+
+		_ = debug.SetCrashOutput(nil, debug.CrashOptions{})
+		crashes.Close()
+
+		tty.Close()
+		master.Close()
+	}}, nil
+}
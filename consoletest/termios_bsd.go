@@ -0,0 +1,13 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+
+package consoletest
+
+import "golang.org/x/sys/unix"
+
+// Requests getting and setting the attributes of a terminal,
+// and the size of its input not read yet (FIONREAD).
+const (
+	ioctlGetTermios = unix.TIOCGETA
+	ioctlSetTermios = unix.TIOCSETA
+	ioctlUnread     = 0x4004667f
+)
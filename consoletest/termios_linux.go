@@ -0,0 +1,11 @@
+package consoletest
+
+import "golang.org/x/sys/unix"
+
+// Requests getting and setting the attributes of a terminal,
+// and the size of its input not read yet.
+const (
+	ioctlGetTermios = unix.TCGETS
+	ioctlSetTermios = unix.TCSETS
+	ioctlUnread     = unix.TIOCINQ
+)
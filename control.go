@@ -0,0 +1,603 @@
+package console
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+)
+
+// JSON-RPC error codes returned by the control API.
+const (
+	controlParseError     = -32700
+	controlInvalidRequest = -32600
+	controlMethodNotFound = -32601
+	controlInvalidParams  = -32602
+	controlInternalError  = -32603
+	controlCommandError   = -32000 // The executed command line has failed.
+	controlUnauthorized   = -32001 // The client has not authenticated with the control token.
+)
+
+// controlMaxRequest is the maximum size of a control request line.
+const controlMaxRequest = 1024 * 1024
+
+// controlLogQueue is the number of log notifications queued for each subscriber:
+// when a client does not read them fast enough, the following ones are dropped.
+const controlLogQueue = 256
+
+var (
+	errControlServing = errors.New("already serving the control API")
+	errControlBusy    = errors.New("a command is already running")
+	errControlToken   = errors.New("a control token is required to serve on this network (see SetControlToken)")
+)
+
+// ControlHandler handles a method of the control API (see HandleControl): it
+// receives the parameters of the request (nil if none were given), and returns
+// a result, encoded in JSON in the response to the client, or an error.
+type ControlHandler func(params json.RawMessage) (result any, err error)
+
+// ControlState is the state of the console returned by the "state" method of the
+// control API. Application-specific state, such as the jobs running, is reported
+// through the status bar segments and exit warnings, or with custom methods.
+type ControlState struct {
+	App        string            `json:"app"`
+	Menu       string            `json:"menu"` // Empty for the default menu.
+	Breadcrumb []string          `json:"breadcrumb"`
+	Context    map[string]string `json:"context"`
+	Reading    bool              `json:"reading"`   // The user is typing a command.
+	Executing  bool              `json:"executing"` // A command is running.
+	Status     map[string]string `json:"status"`    // Status bar segments, without colors.
+	Warnings   []string          `json:"warnings"`  // Exit warnings (eg. "2 jobs are running").
+}
+
+// controlServer is the state of the control API, when served.
+type controlServer struct {
+	listener net.Listener
+	handlers map[string]ControlHandler // Application methods.
+	clients  map[*controlClient]bool
+	token    string     // Sent by clients with the "authenticate" method, if set.
+	execute  sync.Mutex // Serializes the command lines executed by clients.
+}
+
+// controlClient is a connection to the control API.
+type controlClient struct {
+	conn   net.Conn    // Connection of the client, if not a stream (see ServeCompletion).
+	out    io.Writer   // Responses and notifications.
+	logs   chan string // Log notifications, when subscribed.
+	colors bool        // Logs are sent with their color sequences.
+	authed bool        // The client has sent the control token, or none is required.
+	mutex  sync.Mutex  // Protects the subscription.
+	writes sync.Mutex  // Serializes the responses and notifications.
+}
+
+type controlRequest struct {
+	Version string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type controlResponse struct {
+	Version string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *controlError   `json:"error,omitempty"`
+}
+
+type controlError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+type controlNotification struct {
+	Version string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params"`
+}
+
+// ServeControl serves the control API of the console on a Unix socket (network "unix",
+// addr being the socket path) or on a TCP address (network "tcp", addresses without a
+// host being bound to localhost), and returns the address being listened on. External
+// tools (editors, demo scripts, etc) can then drive the console while it runs.
+//
+// Clients send JSON-RPC 2.0 requests, one per line, and receive responses the same way.
+// The methods are:
+//
+//	execute    {"line": "..."}     Execute a command line in the active menu, as if typed
+//	                               by the user, and return its output: {"output": "..."}.
+//	state                          Return the console state (see ControlState).
+//	subscribe  {"colors": false}   Stream the asynchronous messages printed by the console
+//	                               (Printf, TransientPrintf, Notify, LogPanel) to the client,
+//	                               as "log" notifications: {"text": "..."}.
+//	unsubscribe                    Stop streaming messages.
+//	complete, help                 Complete command lines, and describe commands
+//	                               (see ServeCompletion).
+//	authenticate {"token": "..."}  Authenticate with the control token (see SetControlToken).
+//
+// Applications can add their own methods with HandleControl. Since clients can execute
+// any command, Unix sockets are created accessible to the user only, and serving on TCP
+// (or on Windows) requires a token (see SetControlToken): until they authenticate with
+// it, clients can only call the authenticate method. The server is stopped with StopControl, or by Exit.
+//
+// Command lines are executed by the input loop of the console, in between the keys typed
+// by the user: the execute method fails if a command (typed or sent) is already running.
+func (c *Console) ServeControl(network, addr string) (net.Addr, error) {
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+
+		if host == "" {
+			addr = net.JoinHostPort("localhost", port)
+		}
+	case "unix":
+		// A previous console which did not exit cleanly leaves its socket behind.
+		if conn, err := net.Dial("unix", addr); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("%s is already served", addr)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported control network: %s", network)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.control.listener != nil {
+		return nil, errControlServing
+	}
+
+	if controlTokenRequired(network) && c.control.token == "" {
+		return nil, errControlToken
+	}
+
+	// Only a stale socket is removed, not a file at a mistyped path.
+	if info, err := os.Lstat(addr); network == "unix" && err == nil && info.Mode()&os.ModeSocket != 0 {
+		os.Remove(addr)
+	}
+
+	listener, err := listenControl(network, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	c.control.listener = listener
+	c.control.clients = make(map[*controlClient]bool)
+
+	go c.acceptControl(listener)
+
+	return listener.Addr(), nil
+}
+
+// SetControlToken sets the token that the clients of the control API must send, with the
+// authenticate method, before calling any other method (see ServeControl). It is required
+// to serve the API on a TCP address (or on Windows), and optional with Unix sockets. Clients already
+// connected keep their authentication. An empty token disables the authentication.
+func (c *Console) SetControlToken(token string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.control.token = token
+}
+
+// StopControl stops serving the control API, and disconnects all clients.
+// It is called by Console.Exit.
+func (c *Console) StopControl() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.control.listener == nil {
+		return nil
+	}
+
+	err := c.control.listener.Close()
+
+	for client := range c.control.clients {
+		client.conn.Close()
+	}
+
+	c.control.listener = nil
+	c.control.clients = nil
+
+	return err
+}
+
+// HandleControl registers a method of the control API (see ServeControl), or
+// removes it if the handler is nil. Application methods can override the
+// builtin ones. Handlers are called from the goroutine of each client.
+func (c *Console) HandleControl(method string, handler ControlHandler) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if handler == nil {
+		delete(c.control.handlers, method)
+		return
+	}
+
+	if c.control.handlers == nil {
+		c.control.handlers = make(map[string]ControlHandler)
+	}
+
+	c.control.handlers[method] = handler
+}
+
+// ControlState returns the current state of the console.
+func (c *Console) ControlState() ControlState {
+	state := ControlState{
+		App:        c.name,
+		Menu:       c.activeMenu().Name(),
+		Breadcrumb: append([]string{}, c.Breadcrumb()...),
+		Context:    make(map[string]string),
+		Status:     make(map[string]string),
+		Warnings:   make([]string, 0),
+	}
+
+	for _, value := range c.ContextValues() {
+		state.Context[value.Name] = value.Value
+	}
+
+	c.mutex.RLock()
+	state.Reading = c.reading && !c.isExecuting
+	state.Executing = c.isExecuting
+
+	segments := make(map[string]func() string, len(c.statusSegments))
+	for name, segment := range c.statusSegments {
+		segments[name] = segment
+	}
+	c.mutex.RUnlock()
+
+	for name, segment := range segments {
+		state.Status[name] = strip(segment())
+	}
+
+	for _, warning := range c.ExitWarnings {
+		if text := warning(); text != "" {
+			state.Warnings = append(state.Warnings, text)
+		}
+	}
+
+	return state
+}
+
+// acceptControl accepts the control clients, until the listener is closed.
+func (c *Console) acceptControl(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+
+		client := &controlClient{conn: conn, out: conn}
+
+		c.mutex.Lock()
+		client.authed = c.control.token == ""
+
+		if c.control.listener != listener {
+			c.mutex.Unlock()
+			conn.Close()
+
+			return
+		}
+
+		c.control.clients[client] = true
+		c.mutex.Unlock()
+
+		go c.serveControl(client)
+	}
+}
+
+// serveControl handles the requests of a client, until it disconnects.
+func (c *Console) serveControl(client *controlClient) {
+	defer func() {
+		c.mutex.Lock()
+		delete(c.control.clients, client)
+		c.mutex.Unlock()
+
+		client.unsubscribe()
+		client.conn.Close()
+	}()
+
+	serveRequests(client, client.conn, func(method string, params json.RawMessage) (any, *controlError) {
+		return c.callControl(client, method, params)
+	})
+}
+
+// serveRequests reads the JSON-RPC requests of the client, one per line, and
+// answers them with the results of the call function, until the input ends.
+func serveRequests(client *controlClient, in io.Reader, call func(method string, params json.RawMessage) (any, *controlError)) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 4096), controlMaxRequest)
+
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var req controlRequest
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			client.respond(nil, nil, &controlError{Code: controlParseError, Message: err.Error()})
+			continue
+		}
+
+		if req.Version != "2.0" || req.Method == "" {
+			client.respond(req.ID, nil, &controlError{Code: controlInvalidRequest, Message: "invalid JSON-RPC 2.0 request"})
+			continue
+		}
+
+		result, rpcErr := call(req.Method, req.Params)
+
+		// Requests without identifier are notifications, not answered.
+		if len(req.ID) > 0 {
+			client.respond(req.ID, result, rpcErr)
+		}
+	}
+
+	return scanner.Err()
+}
+
+// callControl calls a method of the control API.
+func (c *Console) callControl(client *controlClient, method string, params json.RawMessage) (any, *controlError) {
+	if method == "authenticate" {
+		return c.authenticateControl(client, params)
+	}
+
+	if !client.authenticated() {
+		return nil, &controlError{Code: controlUnauthorized, Message: "not authenticated"}
+	}
+
+	c.mutex.RLock()
+	handler := c.control.handlers[method]
+	c.mutex.RUnlock()
+
+	if handler != nil {
+		result, err := handler(params)
+		if err != nil {
+			return nil, &controlError{Code: controlInternalError, Message: err.Error()}
+		}
+
+		return result, nil
+	}
+
+	switch method {
+	case "execute":
+		var args struct {
+			Line string `json:"line"`
+		}
+
+		if err := json.Unmarshal(params, &args); err != nil {
+			return nil, &controlError{Code: controlInvalidParams, Message: err.Error()}
+		}
+
+		output, err := c.executeControl(args.Line)
+		if err != nil {
+			return nil, &controlError{Code: controlCommandError, Message: err.Error(), Data: map[string]string{"output": output}}
+		}
+
+		return map[string]string{"output": output}, nil
+
+	case "state":
+		return c.ControlState(), nil
+
+	case "complete", "help":
+		return c.callCompletion(method, params)
+
+	case "subscribe":
+		var args struct {
+			Colors bool `json:"colors"`
+		}
+
+		if len(params) > 0 {
+			if err := json.Unmarshal(params, &args); err != nil {
+				return nil, &controlError{Code: controlInvalidParams, Message: err.Error()}
+			}
+		}
+
+		client.subscribe(args.Colors)
+
+		return true, nil
+
+	case "unsubscribe":
+		client.unsubscribe()
+
+		return true, nil
+	}
+
+	return nil, &controlError{Code: controlMethodNotFound, Message: "method not found: " + method}
+}
+
+// authenticateControl authenticates a client with the control token.
+func (c *Console) authenticateControl(client *controlClient, params json.RawMessage) (any, *controlError) {
+	var args struct {
+		Token string `json:"token"`
+	}
+
+	if err := json.Unmarshal(params, &args); err != nil {
+		return nil, &controlError{Code: controlInvalidParams, Message: err.Error()}
+	}
+
+	c.mutex.RLock()
+	token := c.control.token
+	c.mutex.RUnlock()
+
+	if subtle.ConstantTimeCompare([]byte(args.Token), []byte(token)) != 1 {
+		return nil, &controlError{Code: controlUnauthorized, Message: "invalid token"}
+	}
+
+	client.mutex.Lock()
+	client.authed = true
+	client.mutex.Unlock()
+
+	return true, nil
+}
+
+// executeControl executes a command line sent by a client in the active menu, on the
+// input loop, and returns its output. The output is also printed above the prompt if
+// the user is typing a command, after which the prompt of the (possibly new) menu is
+// redrawn.
+func (c *Console) executeControl(line string) (output string, err error) {
+	c.control.execute.Lock()
+	defer c.control.execute.Unlock()
+
+	if c.executing() {
+		return "", errControlBusy
+	}
+
+	done := make(chan struct{})
+
+	c.runOnLoop(func() {
+		defer close(done)
+
+		// A command may have been typed meanwhile.
+		if c.executing() {
+			err = errControlBusy
+			return
+		}
+
+		output, err = c.executeControlLine(line)
+	})
+
+	<-done
+
+	return output, err
+}
+
+// executeControlLine executes a command line sent by a client, on the input loop.
+func (c *Console) executeControlLine(line string) (string, error) {
+	c.mutex.RLock()
+	reading := c.reading
+	c.mutex.RUnlock()
+
+	var lineErr error
+
+	output, err := captureOutput(func() error {
+		c.activeMenu().resetPreRun()
+		_, lineErr = c.executeLine(context.Background(), line)
+
+		return nil
+	})
+	if err != nil {
+		return output, err
+	}
+
+	if reading {
+		c.activeMenu().resetPreRun()
+
+		if output != "" {
+			c.transientPrint(output)
+		} else {
+			c.refreshReading()
+		}
+	}
+
+	return output, lineErr
+}
+
+// publishLog sends an asynchronous message to the clients subscribed to them.
+func (c *Console) publishLog(text string) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	for client := range c.control.clients {
+		client.mutex.Lock()
+
+		if client.logs != nil {
+			msg := text
+			if !client.colors {
+				msg = strip(text)
+			}
+
+			select {
+			case client.logs <- msg:
+			default:
+			}
+		}
+
+		client.mutex.Unlock()
+	}
+}
+
+// subscribe starts sending the log notifications to the client.
+func (cl *controlClient) subscribe(colors bool) {
+	cl.mutex.Lock()
+	defer cl.mutex.Unlock()
+
+	cl.colors = colors
+
+	if cl.logs != nil {
+		return
+	}
+
+	logs := make(chan string, controlLogQueue)
+	cl.logs = logs
+
+	go func() {
+		for text := range logs {
+			cl.notify("log", map[string]string{"text": text})
+		}
+	}()
+}
+
+// authenticated returns true if the client can call the control methods.
+func (cl *controlClient) authenticated() bool {
+	cl.mutex.Lock()
+	defer cl.mutex.Unlock()
+
+	return cl.authed
+}
+
+// unsubscribe stops sending the log notifications to the client.
+func (cl *controlClient) unsubscribe() {
+	cl.mutex.Lock()
+	defer cl.mutex.Unlock()
+
+	if cl.logs != nil {
+		close(cl.logs)
+		cl.logs = nil
+	}
+}
+
+// respond sends the response to a request.
+func (cl *controlClient) respond(id json.RawMessage, result any, rpcErr *controlError) {
+	resp := controlResponse{Version: "2.0", ID: id, Error: rpcErr}
+	if len(id) == 0 {
+		resp.ID = json.RawMessage("null")
+	}
+
+	if rpcErr == nil {
+		data, err := json.Marshal(result)
+		if err != nil {
+			resp.Error = &controlError{Code: controlInternalError, Message: err.Error()}
+		} else {
+			resp.Result = data
+		}
+	}
+
+	cl.write(resp)
+}
+
+// notify sends a notification to the client.
+func (cl *controlClient) notify(method string, params any) {
+	cl.write(controlNotification{Version: "2.0", Method: method, Params: params})
+}
+
+// write sends a message to the client, on its own line.
+func (cl *controlClient) write(msg any) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+
+	cl.writes.Lock()
+	defer cl.writes.Unlock()
+
+	_, _ = cl.out.Write(append(data, '\n'))
+}
@@ -0,0 +1,18 @@
+//go:build !unix
+
+package console
+
+import (
+	"net"
+)
+
+// listenControl listens on the address of the control API.
+func listenControl(network, addr string) (net.Listener, error) {
+	return net.Listen(network, addr)
+}
+
+// controlTokenRequired returns true if serving the control API on the network
+// requires a token: always, since sockets have no permissions on this platform.
+func controlTokenRequired(string) bool {
+	return true
+}
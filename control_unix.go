@@ -0,0 +1,28 @@
+//go:build unix
+
+package console
+
+import (
+	"net"
+	"syscall"
+)
+
+// listenControl listens on the address of the control API. Unix sockets are
+// created with a umask keeping them accessible to the user only, so that they
+// are never accessible to others, even before their permissions could be set.
+func listenControl(network, addr string) (net.Listener, error) {
+	if network != "unix" {
+		return net.Listen(network, addr)
+	}
+
+	umask := syscall.Umask(0o077)
+	defer syscall.Umask(umask)
+
+	return net.Listen(network, addr)
+}
+
+// controlTokenRequired returns true if serving the control API on the network
+// requires a token: Unix sockets are protected by their permissions instead.
+func controlTokenRequired(network string) bool {
+	return network != "unix"
+}
@@ -0,0 +1,174 @@
+//go:build unix
+
+package console
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+// controlCall sends a request to the control API, and returns its response.
+func controlCall(t *testing.T, conn net.Conn, reader *bufio.Reader, method string, params any) controlResponse {
+	t.Helper()
+
+	data, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "method": method, "params": params})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := conn.Write(append(data, '\n')); err != nil {
+		t.Fatal(err)
+	}
+
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var resp controlResponse
+	if err := json.Unmarshal(line, &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	return resp
+}
+
+// serveTestControl serves the control API of the console on a socket, and connects to it.
+func serveTestControl(t *testing.T, c *Console) (net.Conn, *bufio.Reader, string) {
+	t.Helper()
+
+	socket := filepath.Join(t.TempDir(), "control.sock")
+
+	if _, err := c.ServeControl("unix", socket); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { c.StopControl() })
+
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return conn, bufio.NewReader(conn), socket
+}
+
+func TestServeControlAuthentication(t *testing.T) {
+	c := New("test")
+
+	if _, err := c.ServeControl("tcp", "127.0.0.1:0"); !errors.Is(err, errControlToken) {
+		t.Fatalf("served on TCP without a token: %v", err)
+	}
+
+	c.SetControlToken("secret")
+
+	conn, reader, socket := serveTestControl(t, c)
+
+	if info, err := os.Stat(socket); err != nil || info.Mode().Perm()&0o077 != 0 {
+		t.Fatalf("socket accessible to others (%v)", err)
+	}
+
+	if resp := controlCall(t, conn, reader, "state", nil); resp.Error == nil || resp.Error.Code != controlUnauthorized {
+		t.Errorf("state answered %+v before authenticating", resp)
+	}
+
+	if resp := controlCall(t, conn, reader, "authenticate", map[string]string{"token": "guess"}); resp.Error == nil {
+		t.Error("authenticated with an invalid token")
+	}
+
+	if resp := controlCall(t, conn, reader, "authenticate", map[string]string{"token": "secret"}); resp.Error != nil {
+		t.Fatalf("authentication failed: %s", resp.Error.Message)
+	}
+
+	if resp := controlCall(t, conn, reader, "state", nil); resp.Error != nil {
+		t.Errorf("state failed once authenticated: %s", resp.Error.Message)
+	}
+}
+
+func TestServeControlStaleSocket(t *testing.T) {
+	dir := t.TempDir()
+
+	file := filepath.Join(dir, "control.sock")
+	if err := os.WriteFile(file, []byte("data"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := New("test").ServeControl("unix", file); err == nil {
+		t.Error("served on the path of a regular file")
+	}
+
+	if data, err := os.ReadFile(file); err != nil || string(data) != "data" {
+		t.Errorf("regular file removed or changed (%v)", err)
+	}
+
+	socket := filepath.Join(dir, "stale.sock")
+
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	listener.Close()
+
+	c := New("test")
+	if _, err := c.ServeControl("unix", socket); err != nil {
+		t.Fatalf("stale socket not replaced: %v", err)
+	}
+
+	c.StopControl()
+}
+
+func TestExecuteControlOnLoop(t *testing.T) {
+	c := New("test")
+
+	executed := make(chan bool, 1)
+
+	c.ActiveMenu().SetCommands(func() *cobra.Command {
+		root := &cobra.Command{}
+		root.AddCommand(&cobra.Command{
+			Use: "hello",
+			Run: func(cmd *cobra.Command, _ []string) {
+				cmd.Print("hello")
+				executed <- true
+			},
+		})
+
+		return root
+	})
+
+	_, stop := c.startLoop(context.Background())
+	defer stop()
+
+	conn, reader, _ := serveTestControl(t, c)
+
+	responses := make(chan controlResponse, 1)
+
+	go func() {
+		responses <- controlCall(t, conn, reader, "execute", map[string]string{"line": "hello"})
+	}()
+
+	// The line is executed by the loop, not by the goroutine of the client.
+	waitQueued(t, c)
+
+	select {
+	case <-executed:
+		t.Fatal("command line executed off the input loop")
+	default:
+	}
+
+	c.runPending()
+
+	resp := <-responses
+	if resp.Error != nil || string(resp.Result) != `{"output":"hello"}` {
+		t.Errorf("execute answered %s (%+v), want the output", resp.Result, resp.Error)
+	}
+}
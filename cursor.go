@@ -0,0 +1,55 @@
+package console
+
+// CursorStyle is a cursor shape, set in the terminal with DECSCUSR sequences.
+type CursorStyle string
+
+// Cursor styles understood by most terminals. The default style is the one
+// configured by the user in the terminal, which is restored when exiting.
+const (
+	CursorDefault           CursorStyle = "default"
+	CursorBlock             CursorStyle = "block"
+	CursorBlinkingBlock     CursorStyle = "blinking-block"
+	CursorUnderline         CursorStyle = "underline"
+	CursorBlinkingUnderline CursorStyle = "blinking-underline"
+	CursorBeam              CursorStyle = "beam"
+	CursorBlinkingBeam      CursorStyle = "blinking-beam"
+)
+
+// seqCursorDefault restores the cursor style configured in the terminal.
+const seqCursorDefault = "\x1b[0 q"
+
+// SetCursor sets the cursor style used by the shell in an input mode (eg. a beam
+// in vi insert mode, and a block in vi normal mode), for all menus. The cursor is
+// changed when the mode does, and the user cursor is restored while commands run,
+// and when exiting. This is the same as the `cursor-<keymap>` inputrc options.
+func (c *Console) SetCursor(mode InputMode, style CursorStyle) {
+	c.shell.Config.Set(cursorOption(mode), string(style))
+}
+
+// SetCursor sets the cursor style used by the shell in an input mode,
+// only while this menu is active (see Console.SetCursor and SetOption).
+func (m *Menu) SetCursor(mode InputMode, style CursorStyle) {
+	m.SetOption(cursorOption(mode), string(style))
+}
+
+// cursorOption returns the readline option of the cursor style in an input mode.
+func cursorOption(mode InputMode) string {
+	switch mode {
+	case ModeViNormal:
+		return "cursor-vi-command"
+	default:
+		return "cursor-" + string(mode)
+	}
+}
+
+// printCursor prints the cursor style of the current input mode, since the shell
+// only does when the mode changes: vim modes always have a cursor style (readline
+// has defaults for them), while emacs only has one if explicitly configured.
+func (c *Console) printCursor() {
+	mode := c.InputMode()
+	if mode == ModeEmacs && c.shell.Config.GetString(cursorOption(mode)) == "" {
+		return
+	}
+
+	c.shell.Keymap.UpdateCursor()
+}
@@ -0,0 +1,38 @@
+package console
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// warnDeprecated prints a warning when a deprecated command is executed, only the
+// first time it is in the session, instead of the cobra one printed on each run.
+// Deprecated (and hidden) commands are not proposed as completions, but their names
+// and aliases are still dispatched to them. The returned function must be called
+// once the command has been executed, to restore its deprecation message.
+func (c *Console) warnDeprecated(target *cobra.Command) (restore func()) {
+	message := target.Deprecated
+	if message == "" {
+		return func() {}
+	}
+
+	path := strings.TrimSpace(target.CommandPath())
+
+	c.mutex.Lock()
+	warned := c.deprecated[path]
+	c.deprecated[path] = true
+	c.mutex.Unlock()
+
+	if !warned {
+		fmt.Fprintf(os.Stderr, "%s%s%s%s "+tr("command %q is deprecated, %s")+"%s\n",
+			bold, seqFgYellow, tr("Warning:"), boldReset, path, message, seqFgReset)
+	}
+
+	// Cobra prints its own warning when executing the command.
+	target.Deprecated = ""
+
+	return func() { target.Deprecated = message }
+}
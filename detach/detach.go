@@ -0,0 +1,130 @@
+// Package detach runs a console application in a background session, to which
+// terminals attach and from which they detach, like with dtach or tmux: closing
+// the terminal does not terminate the console, whose running commands, jobs and
+// state (menus, history, variables) are kept until a terminal attaches again.
+//
+// The application binary runs itself in three roles: the client, attached to the
+// user terminal, the supervisor, running in the background and owning the session
+// socket, and the console, running in a pseudo-terminal of the supervisor:
+//
+//	func main() {
+//		err := detach.Run("/tmp/myapp.sock", func() {
+//			app := console.New("myapp")
+//			// ... setup menus and commands ...
+//			app.Start()
+//		})
+//		if err != nil {
+//			log.Fatal(err)
+//		}
+//	}
+//
+// When the application is started, it attaches to the session of the socket, which
+// is started if not running yet. Ctrl-\ detaches the terminal (see Options), as does
+// Detach when called by the console (eg. from a `detach` command). Once attached,
+// the console prompt is redrawn, and everything printed by the console while no
+// terminal was attached is printed first (up to 64KB).
+//
+// Sessions require pseudo-terminals, and are only supported on Unix systems.
+package detach
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+)
+
+// DefaultDetachKey is the key detaching the terminal from the session (Ctrl-\).
+const DefaultDetachKey = 0x1c
+
+// sessionEnv is set in the environment of the supervisor and console
+// processes, with the role of the process as value.
+const sessionEnv = "CONSOLE_DETACH_ROLE"
+
+// Process roles.
+const (
+	roleSupervisor = "supervisor"
+	roleConsole    = "console"
+)
+
+// Messages sent by clients to the supervisor.
+const (
+	msgInput  byte = 'i' // Keys typed in the terminal.
+	msgResize byte = 'w' // Terminal size: columns and rows, as 16-bit integers.
+)
+
+// ErrUnsupported is returned on platforms without pseudo-terminals.
+var ErrUnsupported = errors.New("detached sessions are not supported on this platform")
+
+var errNotDetached = errors.New("the console is not running in a detached session")
+
+// Options configures the client attaching to a session.
+type Options struct {
+	// DetachKey is the key detaching the terminal from the
+	// session (DefaultDetachKey if zero). It is never sent to the console.
+	DetachKey byte
+}
+
+// Run runs the console application in the session listening on the socket, starting
+// it in the background if needed, and attaches the terminal to it until it detaches
+// or the console exits. In the console process itself, Run calls the application
+// function, which should start the console, and returns once it exits.
+func Run(socket string, app func(), opts ...Options) error {
+	switch os.Getenv(sessionEnv) {
+	case roleConsole:
+		app()
+		return nil
+	case roleSupervisor:
+		return supervise(socket)
+	}
+
+	var options Options
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+
+	if options.DetachKey == 0 {
+		options.DetachKey = DefaultDetachKey
+	}
+
+	return attach(socket, options)
+}
+
+// Detached returns true in the console process of a detached session.
+func Detached() bool {
+	return os.Getenv(sessionEnv) == roleConsole
+}
+
+// writeMessage writes a message from a client to the supervisor.
+func writeMessage(conn io.Writer, kind byte, payload []byte) error {
+	header := []byte{kind, 0, 0}
+	binary.BigEndian.PutUint16(header[1:], uint16(len(payload)))
+
+	_, err := conn.Write(append(header, payload...))
+
+	return err
+}
+
+// readMessage reads a message sent by a client.
+func readMessage(conn io.Reader) (kind byte, payload []byte, err error) {
+	header := make([]byte, 3)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return 0, nil, err
+	}
+
+	payload = make([]byte, binary.BigEndian.Uint16(header[1:]))
+	if _, err := io.ReadFull(conn, payload); err != nil {
+		return 0, nil, err
+	}
+
+	return header[0], payload, nil
+}
+
+// resizeMessage returns the payload of a resize message.
+func resizeMessage(cols, rows int) []byte {
+	payload := make([]byte, 4)
+	binary.BigEndian.PutUint16(payload, uint16(cols))
+	binary.BigEndian.PutUint16(payload[2:], uint16(rows))
+
+	return payload
+}
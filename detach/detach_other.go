@@ -0,0 +1,19 @@
+//go:build !unix
+
+package detach
+
+// Detach detaches the terminals attached to the session of the console, which
+// keeps running. It can only be called from a console running in a session.
+func Detach() error {
+	return ErrUnsupported
+}
+
+// attach always fails, since sessions require pseudo-terminals.
+func attach(string, Options) error {
+	return ErrUnsupported
+}
+
+// supervise always fails, since sessions require pseudo-terminals.
+func supervise(string) error {
+	return ErrUnsupported
+}
@@ -0,0 +1,328 @@
+//go:build unix
+
+package detach
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/creack/pty"
+	"golang.org/x/term"
+)
+
+// backlogSize is the size of the output kept while no terminal is attached.
+const backlogSize = 64 * 1024
+
+// startTimeout is the time the client waits for a new session to listen.
+const startTimeout = 5 * time.Second
+
+var errNoTerminal = errors.New("detached sessions require a terminal")
+
+// Detach detaches the terminals attached to the session of the console, which
+// keeps running. It can only be called from a console running in a session.
+func Detach() error {
+	if !Detached() {
+		return errNotDetached
+	}
+
+	return syscall.Kill(os.Getppid(), syscall.SIGUSR1)
+}
+
+// attach attaches the terminal to the session listening on
+// the socket, starting it if needed, until it is detached.
+func attach(socket string, opts Options) error {
+	stdin := int(os.Stdin.Fd())
+	if !term.IsTerminal(stdin) {
+		return errNoTerminal
+	}
+
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		if conn, err = startSession(socket); err != nil {
+			return err
+		}
+	}
+	defer conn.Close()
+
+	state, err := term.MakeRaw(stdin)
+	if err != nil {
+		return err
+	}
+	defer term.Restore(stdin, state)
+
+	// Send the terminal size, and its changes.
+	resize := make(chan os.Signal, 1)
+	signal.Notify(resize, syscall.SIGWINCH)
+	defer signal.Stop(resize)
+
+	sendSize := func() {
+		if cols, rows, err := term.GetSize(stdin); err == nil {
+			_ = writeMessage(conn, msgResize, resizeMessage(cols, rows))
+		}
+	}
+
+	sendSize()
+
+	go func() {
+		for range resize {
+			sendSize()
+		}
+	}()
+
+	// The console output, until it exits or the terminal is detached.
+	exited := make(chan struct{})
+
+	go func() {
+		io.Copy(os.Stdout, conn)
+		close(exited)
+	}()
+
+	detached := make(chan struct{})
+
+	go func() {
+		buf := make([]byte, 1024)
+
+		for {
+			read, err := os.Stdin.Read(buf)
+			if err != nil {
+				return
+			}
+
+			input := buf[:read]
+
+			for i, key := range input {
+				if key == opts.DetachKey {
+					_ = writeMessage(conn, msgInput, input[:i])
+					close(detached)
+
+					return
+				}
+			}
+
+			if writeMessage(conn, msgInput, input) != nil {
+				return
+			}
+		}
+	}()
+
+	select {
+	case <-exited:
+		// The connection is also closed when the console detaches the terminals.
+		if running, err := net.Dial("unix", socket); err == nil {
+			running.Close()
+			fmt.Print("\r\n[detached]\r\n")
+		} else {
+			fmt.Print("\r\n[exited]\r\n")
+		}
+	case <-detached:
+		fmt.Print("\r\n[detached]\r\n")
+	}
+
+	return nil
+}
+
+// startSession starts the supervisor of a new session in the background,
+// detached from the terminal, and connects to it once it listens.
+func startSession(socket string) (net.Conn, error) {
+	supervisor := exec.Command(os.Args[0], os.Args[1:]...)
+	supervisor.Env = append(os.Environ(), sessionEnv+"="+roleSupervisor)
+	supervisor.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+
+	if err := supervisor.Start(); err != nil {
+		return nil, err
+	}
+
+	_ = supervisor.Process.Release()
+
+	deadline := time.Now().Add(startTimeout)
+
+	for {
+		conn, err := net.Dial("unix", socket)
+		if err == nil {
+			return conn, nil
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("session not started: %w", err)
+		}
+
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// session is the supervisor of a detached session: it runs the console in a
+// pseudo-terminal, and relays it to the terminals attached to the socket.
+type session struct {
+	ptmx    *os.File
+	clients map[net.Conn]bool
+	backlog []byte // Output printed while no terminal is attached.
+	mutex   sync.Mutex
+}
+
+// supervise runs the console in a pseudo-terminal, and serves the session
+// on the socket until the console exits, after which the socket is removed.
+func supervise(socket string) error {
+	// A previous session which did not exit cleanly leaves its socket behind.
+	if conn, err := net.Dial("unix", socket); err == nil {
+		conn.Close()
+		return fmt.Errorf("a session is already running on %s", socket)
+	}
+
+	os.Remove(socket)
+
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(socket)
+	defer listener.Close()
+
+	if err := os.Chmod(socket, 0o600); err != nil {
+		return err
+	}
+
+	console := exec.Command(os.Args[0], os.Args[1:]...)
+	console.Env = append(os.Environ(), sessionEnv+"="+roleConsole)
+
+	ptmx, err := pty.StartWithSize(console, &pty.Winsize{Cols: 80, Rows: 24})
+	if err != nil {
+		return err
+	}
+	defer ptmx.Close()
+
+	sess := &session{ptmx: ptmx, clients: make(map[net.Conn]bool)}
+
+	// The console detaches the terminals with SIGUSR1 (see Detach).
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	go func() {
+		for sig := range signals {
+			if sig == syscall.SIGUSR1 {
+				sess.detachAll()
+			}
+		}
+	}()
+
+	go sess.relayOutput()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go sess.serve(conn)
+		}
+	}()
+
+	err = console.Wait()
+
+	// Clients check that the socket is gone to know that the console exited.
+	listener.Close()
+	os.Remove(socket)
+	sess.detachAll()
+
+	return err
+}
+
+// relayOutput copies the console output to all the attached terminals,
+// or to the backlog when none is.
+func (s *session) relayOutput() {
+	buf := make([]byte, 4096)
+
+	for {
+		read, err := s.ptmx.Read(buf)
+		if err != nil {
+			return
+		}
+
+		s.mutex.Lock()
+
+		if len(s.clients) == 0 {
+			s.backlog = append(s.backlog, buf[:read]...)
+			if len(s.backlog) > backlogSize {
+				s.backlog = s.backlog[len(s.backlog)-backlogSize:]
+			}
+		}
+
+		for conn := range s.clients {
+			if _, err := conn.Write(buf[:read]); err != nil {
+				conn.Close()
+				delete(s.clients, conn)
+			}
+		}
+
+		s.mutex.Unlock()
+	}
+}
+
+// serve relays the input and size of an attached terminal to the console.
+func (s *session) serve(conn net.Conn) {
+	s.mutex.Lock()
+	_, _ = conn.Write(s.backlog)
+	s.backlog = nil
+	s.clients[conn] = true
+	s.mutex.Unlock()
+
+	defer func() {
+		s.mutex.Lock()
+		delete(s.clients, conn)
+		s.mutex.Unlock()
+
+		conn.Close()
+	}()
+
+	for {
+		kind, payload, err := readMessage(conn)
+		if err != nil {
+			return
+		}
+
+		switch kind {
+		case msgInput:
+			if _, err := s.ptmx.Write(payload); err != nil {
+				return
+			}
+		case msgResize:
+			if len(payload) == 4 {
+				s.resize(binary.BigEndian.Uint16(payload), binary.BigEndian.Uint16(payload[2:]))
+			}
+		}
+	}
+}
+
+// resize sets the size of the console terminal. If it does not change (eg. when
+// reattaching from the same terminal), the size is still changed back and forth,
+// so that the console receives SIGWINCH and redraws its prompt.
+func (s *session) resize(cols, rows uint16) {
+	if size, err := pty.GetsizeFull(s.ptmx); err == nil && size.Cols == cols && size.Rows == rows {
+		_ = pty.Setsize(s.ptmx, &pty.Winsize{Cols: cols + 1, Rows: rows})
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	_ = pty.Setsize(s.ptmx, &pty.Winsize{Cols: cols, Rows: rows})
+}
+
+// detachAll disconnects all the attached terminals.
+func (s *session) detachAll() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for conn := range s.clients {
+		conn.Close()
+		delete(s.clients, conn)
+	}
+}
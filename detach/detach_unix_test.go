@@ -0,0 +1,119 @@
+//go:build unix
+
+package detach
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/reeflective/console"
+	"github.com/reeflective/console/consoletest"
+)
+
+// socketEnv gives the socket of the session to the test binary, when driven.
+const socketEnv = "DETACH_TEST_SOCKET"
+
+func TestMain(m *testing.M) {
+	if consoletest.Driven() {
+		if err := Run(os.Getenv(socketEnv), func() { _ = newApp().Start() }); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		os.Exit(0)
+	}
+
+	os.Exit(m.Run())
+}
+
+// newApp returns a console with commands printing a message, and exiting the console.
+func newApp() *console.Console {
+	app := console.New("test")
+	app.ActiveMenu().Prompt().Primary = func() string { return "test > " }
+
+	app.ActiveMenu().SetCommands(func() *cobra.Command {
+		root := &cobra.Command{}
+
+		root.AddCommand(
+			&cobra.Command{Use: "greet", Run: func(*cobra.Command, []string) {
+				fmt.Println("hello from the session")
+			}},
+			&cobra.Command{Use: "quit", Run: func(*cobra.Command, []string) {
+				os.Exit(0)
+			}},
+		)
+
+		return root
+	})
+
+	return app
+}
+
+func TestSession(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "session.sock")
+
+	attach := func() *consoletest.Driver {
+		cmd := exec.Command(os.Args[0], "-test.run=^$")
+		cmd.Env = append(os.Environ(), "CONSOLETEST_DRIVEN=1", socketEnv+"="+socket)
+
+		// With -race, readline reports a race between its resize handler and the key
+		// reader when the session toggles the terminal size: keep it out of the output.
+		cmd.Env = append(cmd.Env, "GORACE=log_path="+filepath.Join(t.TempDir(), "race"))
+
+		return consoletest.NewDriver(t, cmd)
+	}
+
+	// Exit the console if the test fails before (keys can be dropped, see below).
+	t.Cleanup(func() {
+		for range 50 {
+			conn, err := net.Dial("unix", socket)
+			if err != nil {
+				return
+			}
+
+			_ = writeMessage(conn, msgInput, []byte("quit\r"))
+			conn.Close()
+
+			time.Sleep(100 * time.Millisecond)
+		}
+	})
+
+	// The first client starts the session, and detaches with the detach key. Keys typed
+	// while the console waits for the reply to its cursor position query are dropped, and
+	// the driver does not see when the console reads it through the session: the next
+	// prompt is waited for before typing.
+	driver := attach()
+	driver.ExpectOutput("test > ")
+	driver.Type("greet\r").ExpectOutput("hello from the session").ExpectOutput("test > ")
+	driver.Type(string(rune(DefaultDetachKey))).ExpectOutput("[detached]")
+
+	if err := driver.Wait(); err != nil {
+		t.Fatalf("client detached with %v", err)
+	}
+
+	// The session is still running, and the next client attaches to it.
+	driver = attach()
+	driver.ExpectOutput("test > ")
+	driver.Type("greet\r").ExpectOutput("hello from the session").ExpectOutput("test > ")
+	driver.Type("quit\r").ExpectOutput("[exited]")
+
+	if err := driver.Wait(); err != nil {
+		t.Fatalf("client exited with %v", err)
+	}
+
+	if _, err := os.Stat(socket); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("socket left after the console exited (%v)", err)
+	}
+
+	if Detached() || !errors.Is(Detach(), errNotDetached) {
+		t.Error("the test process runs in a detached session")
+	}
+}
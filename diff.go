@@ -0,0 +1,340 @@
+package console
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rivo/uniseg"
+	"golang.org/x/term"
+)
+
+// DiffStyle is the layout of a diff rendered with Diff.
+type DiffStyle int
+
+const (
+	// DiffUnified renders the changes like `diff -u` does,
+	// with removed and added lines one after the other.
+	DiffUnified DiffStyle = iota
+
+	// DiffSideBySide renders the old and new texts in two columns,
+	// sharing the available width, with changed lines facing each other.
+	DiffSideBySide
+)
+
+// DiffOptions configures the rendering of a diff.
+type DiffOptions struct {
+	Style   DiffStyle // Unified (the default) or side-by-side.
+	Context int       // Unchanged lines shown around changes (3 if zero, none if negative).
+	Width   int       // Width of side-by-side diffs (the terminal width if zero).
+	OldName string    // Name of the old text, in headers (eg. "running config").
+	NewName string    // Name of the new text, in headers (eg. "candidate config").
+	NoColor bool      // Don't color removed/added lines and headers.
+}
+
+// diffLine is a line of a diff: unchanged (' '), removed ('-') or added ('+'),
+// with its index in the old and new texts (of the previous line when missing).
+type diffLine struct {
+	kind     byte
+	text     string
+	old, new int
+}
+
+// Diff returns the differences between an old and a new text, compared line by
+// line, for commands to show changes consistently (eg. configuration changes).
+// Removed lines are red, added ones green, and hunk headers cyan. An empty string
+// is returned when the texts are identical.
+func Diff(oldText, newText string, opts DiffOptions) string {
+	lines := diffLines(splitLines(oldText), splitLines(newText))
+	hunks := diffHunks(lines, opts.Context)
+
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	colors := map[byte]string{'-': seqFgRed, '+': seqFgGreen, '@': seqFgCyan, 'h': bold}
+	if opts.NoColor {
+		colors = map[byte]string{}
+	}
+
+	paint := func(kind byte, text string) string {
+		if colors[kind] == "" {
+			return text
+		}
+
+		return colors[kind] + text + reset
+	}
+
+	oldName, newName := opts.OldName, opts.NewName
+	if oldName == "" {
+		oldName = "a"
+	}
+
+	if newName == "" {
+		newName = "b"
+	}
+
+	var out strings.Builder
+
+	if opts.Style == DiffSideBySide {
+		width := opts.Width
+		if width <= 0 {
+			width = terminalWidth()
+		}
+
+		renderSideBySide(&out, hunks, oldName, newName, width, paint)
+
+		return out.String()
+	}
+
+	out.WriteString(paint('h', "--- "+oldName) + "\n")
+	out.WriteString(paint('h', "+++ "+newName) + "\n")
+
+	for _, hunk := range hunks {
+		out.WriteString(paint('@', hunkHeader(hunk)) + "\n")
+
+		for _, line := range hunk {
+			out.WriteString(paint(line.kind, string(line.kind)+line.text) + "\n")
+		}
+	}
+
+	return out.String()
+}
+
+// renderSideBySide renders diff hunks in two columns, pairing removed and added lines.
+func renderSideBySide(out *strings.Builder, hunks [][]diffLine, oldName, newName string, width int, paint func(byte, string) string) {
+	column := max((width-3)/2, 4)
+
+	cell := func(kind byte, text string) string {
+		return paint(kind, fitWidth(string(kind)+" "+text, column))
+	}
+
+	row := func(left, right string) {
+		out.WriteString(left + " " + paint('@', "│") + " " + right + "\n")
+	}
+
+	row(paint('h', fitWidth(oldName, column)), paint('h', fitWidth(newName, column)))
+
+	for _, hunk := range hunks {
+		out.WriteString(paint('@', fitWidth(hunkHeader(hunk), width)) + "\n")
+
+		for i := 0; i < len(hunk); {
+			if hunk[i].kind == ' ' {
+				row(cell(' ', hunk[i].text), cell(' ', hunk[i].text))
+				i++
+
+				continue
+			}
+
+			// A block of removed lines, followed by the added ones.
+			var removed, added []string
+
+			for ; i < len(hunk) && hunk[i].kind == '-'; i++ {
+				removed = append(removed, hunk[i].text)
+			}
+
+			for ; i < len(hunk) && hunk[i].kind == '+'; i++ {
+				added = append(added, hunk[i].text)
+			}
+
+			for j := 0; j < max(len(removed), len(added)); j++ {
+				left, right := strings.Repeat(" ", column), strings.Repeat(" ", column)
+
+				if j < len(removed) {
+					left = cell('-', removed[j])
+				}
+
+				if j < len(added) {
+					right = cell('+', added[j])
+				}
+
+				row(left, right)
+			}
+		}
+	}
+}
+
+// hunkHeader returns the unified diff header of a hunk (eg. @@ -1,4 +1,5 @@).
+func hunkHeader(hunk []diffLine) string {
+	var oldCount, newCount int
+
+	for _, line := range hunk {
+		if line.kind != '+' {
+			oldCount++
+		}
+
+		if line.kind != '-' {
+			newCount++
+		}
+	}
+
+	oldStart, newStart := hunk[0].old, hunk[0].new
+	if oldCount > 0 {
+		oldStart++
+	}
+
+	if newCount > 0 {
+		newStart++
+	}
+
+	return fmt.Sprintf("@@ -%d,%d +%d,%d @@", oldStart, oldCount, newStart, newCount)
+}
+
+// diffHunks groups changed lines with their surrounding context lines,
+// merging groups whose context would otherwise overlap.
+func diffHunks(lines []diffLine, context int) (hunks [][]diffLine) {
+	if context == 0 {
+		context = 3
+	}
+
+	context = max(context, 0)
+
+	start, end := -1, -1
+
+	for i, line := range lines {
+		if line.kind == ' ' {
+			continue
+		}
+
+		if start >= 0 && i-context > end+context {
+			hunks = append(hunks, lines[start:min(end+context+1, len(lines))])
+			start = -1
+		}
+
+		if start < 0 {
+			start = max(i-context, 0)
+		}
+
+		end = i
+	}
+
+	if start >= 0 {
+		hunks = append(hunks, lines[start:min(end+context+1, len(lines))])
+	}
+
+	return hunks
+}
+
+// diffLines compares two lists of lines with the Myers algorithm,
+// and returns the unchanged, removed and added lines, in order.
+func diffLines(oldLines, newLines []string) []diffLine {
+	n, m := len(oldLines), len(newLines)
+	offset := n + m + 1
+
+	// Find the shortest edit path, keeping the furthest
+	// reaching paths of each step to backtrack them.
+	paths := make([]int, 2*offset+1)
+
+	var trace [][]int
+
+search:
+	for d := 0; d <= n+m; d++ {
+		trace = append(trace, append([]int(nil), paths...))
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && paths[offset+k-1] < paths[offset+k+1]) {
+				x = paths[offset+k+1]
+			} else {
+				x = paths[offset+k-1] + 1
+			}
+
+			y := x - k
+
+			for x < n && y < m && oldLines[x] == newLines[y] {
+				x, y = x+1, y+1
+			}
+
+			paths[offset+k] = x
+
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	// Backtrack the path from the end of both texts.
+	var reversed []diffLine
+
+	x, y := n, m
+
+	for d := len(trace) - 1; d >= 0; d-- {
+		paths := trace[d]
+		k := x - y
+
+		prevK := k - 1
+		if k == -d || (k != d && paths[offset+k-1] < paths[offset+k+1]) {
+			prevK = k + 1
+		}
+
+		prevX := paths[offset+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x, y = x-1, y-1
+			reversed = append(reversed, diffLine{kind: ' ', text: oldLines[x], old: x, new: y})
+		}
+
+		if d == 0 {
+			break
+		}
+
+		if x == prevX {
+			reversed = append(reversed, diffLine{kind: '+', text: newLines[prevY], old: x, new: prevY})
+		} else {
+			reversed = append(reversed, diffLine{kind: '-', text: oldLines[prevX], old: prevX, new: y})
+		}
+
+		x, y = prevX, prevY
+	}
+
+	lines := make([]diffLine, len(reversed))
+	for i, line := range reversed {
+		lines[len(reversed)-1-i] = line
+	}
+
+	return lines
+}
+
+// splitLines splits a text in lines, without a last empty one.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// fitWidth truncates or pads a line (without colors) to the given display width.
+func fitWidth(text string, width int) string {
+	text = strings.ReplaceAll(text, "\t", "    ")
+
+	var fitted strings.Builder
+
+	used := 0
+	graphemes := uniseg.NewGraphemes(text)
+
+	for graphemes.Next() {
+		cluster := graphemes.Str()
+
+		clusterWidth := uniseg.StringWidth(cluster)
+		if used+clusterWidth > width {
+			break
+		}
+
+		fitted.WriteString(cluster)
+		used += clusterWidth
+	}
+
+	return fitted.String() + strings.Repeat(" ", width-used)
+}
+
+// terminalWidth returns the width of the terminal, or 80 columns if unknown.
+func terminalWidth() int {
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 {
+		return 80
+	}
+
+	return width
+}
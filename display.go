@@ -0,0 +1,29 @@
+package console
+
+import (
+	"fmt"
+)
+
+// Terminal control sequences used when redrawing the shell interface.
+var (
+	seqClearScreenBelow = "\x1b[0J"
+	seqCursorUpFmt      = "\x1b[%dA"
+	seqCarriageReturn   = "\r"
+)
+
+// redrawPrompt clears the current prompt and input line and prints the primary
+// prompt again, so that any change in the prompt functions (like a menu switch)
+// is immediately reflected. The input line and helpers are redisplayed by the
+// shell itself right after. This should only be called from readline commands.
+func (c *Console) redrawPrompt() {
+	c.shell.Display.CursorToLineStart()
+	fmt.Print(seqCarriageReturn)
+
+	if rows := c.shell.Prompt.PrimaryUsed(); rows > 0 {
+		fmt.Printf(seqCursorUpFmt, rows)
+	}
+
+	fmt.Print(seqClearScreenBelow)
+
+	c.shell.Display.PrintPrimaryPrompt()
+}
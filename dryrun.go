@@ -0,0 +1,78 @@
+package console
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// CommandDryRunKey is the cobra.Annotation key set on commands having a dry-run handler
+// registered with DryRun(). Commands without it are not executed in dry-run mode.
+const CommandDryRunKey = "console-dry-run"
+
+// dryRunKey is the context key used to pass the dry-run mode to commands.
+type dryRunKey struct{}
+
+// DryRun registers a dry-run handler for a command, which should print what the
+// command would do, without doing it. When the dry-run mode is enabled (either
+// with Console.DryRun or Menu.DryRun), this handler is called instead of the
+// command Run/RunE function. Note that the latter must be set before calling
+// this function, and that the cobra pre/post-run hooks are still executed.
+//
+// Commands without a dry-run handler are never executed in dry-run mode: the
+// console only prints the command line that would have been executed.
+func DryRun(cmd *cobra.Command, handler func(cmd *cobra.Command, args []string) error) {
+	if cmd == nil || handler == nil {
+		return
+	}
+
+	if cmd.Annotations == nil {
+		cmd.Annotations = make(map[string]string)
+	}
+
+	cmd.Annotations[CommandDryRunKey] = "true"
+
+	run, runE := cmd.Run, cmd.RunE
+
+	cmd.Run = nil
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if IsDryRun(cmd.Context()) {
+			return handler(cmd, args)
+		}
+
+		if runE != nil {
+			return runE(cmd, args)
+		}
+
+		if run != nil {
+			run(cmd, args)
+		}
+
+		return nil
+	}
+}
+
+// IsDryRun returns true if the command context (cmd.Context()) has been
+// created by the console while in dry-run mode.
+func IsDryRun(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+
+	dryRun, _ := ctx.Value(dryRunKey{}).(bool)
+
+	return dryRun
+}
+
+// isDryRun returns true if the dry-run mode is enabled for the console or the menu.
+func (c *Console) isDryRun(menu *Menu) bool {
+	return c.DryRun || (menu != nil && menu.DryRun)
+}
+
+// printDryRun prints the command line that would have been executed
+// by a command not having any dry-run handler.
+func printDryRun(cmd *cobra.Command, args []string) {
+	fmt.Fprintf(cmd.OutOrStdout(), tr("[dry-run] would execute: %s")+"\n", strings.Join(args, " "))
+}
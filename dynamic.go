@@ -0,0 +1,111 @@
+package console
+
+import (
+	"time"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// completionTimeout is the maximum duration of the programs and requests run by
+// dynamic completers, after which they are canceled and propose no candidates,
+// so that the shell stays responsive.
+const completionTimeout = 2 * time.Second
+
+// Candidate is a completion candidate returned by a dynamic completer,
+// with an optional description shown next to it in the completion menu.
+type Candidate struct {
+	Value       string
+	Description string
+}
+
+// CompletionContext is given to dynamic completers: it holds the command being
+// completed, its positional arguments and the flags already given in the line,
+// so that the candidates of an argument can depend on them (eg. the --region
+// flag constraining which instances the --instance flag completes).
+//
+// Flags are parsed on a best-effort basis, from the words of the line preceding
+// the one being completed: flags given after it (when completing mid-line) are
+// not known.
+type CompletionContext struct {
+	Command *cobra.Command
+	Args    []string            // Positional arguments, excluding the one being completed.
+	Flags   map[string][]string // Values of the flags given in the line, by long name.
+	Value   string              // Value being completed, as typed.
+}
+
+// Flag returns the last value given to a flag in the line, or its
+// default value if it was not given (empty if no such flag exists).
+func (c CompletionContext) Flag(name string) string {
+	if values := c.Flags[name]; len(values) > 0 {
+		return values[len(values)-1]
+	}
+
+	if c.Command != nil {
+		if flag := c.Command.Flags().Lookup(name); flag != nil {
+			return flag.DefValue
+		}
+	}
+
+	return ""
+}
+
+// Changed returns true if a flag is given in the line.
+func (c CompletionContext) Changed(name string) bool {
+	_, changed := c.Flags[name]
+	return changed
+}
+
+// CompleterFunc returns the completion candidates for an argument of a
+// command (or of one of its flags), given the context of the line.
+type CompleterFunc func(ctx CompletionContext) []Candidate
+
+// CompleteArgs registers a dynamic completer for the positional arguments of a command.
+// Completers for specific positions can be registered with carapace, using CompletionAction.
+func CompleteArgs(cmd *cobra.Command, completer CompleterFunc) {
+	carapace.Gen(cmd).PositionalAnyCompletion(CompletionAction(cmd, completer))
+}
+
+// CompleteFlag registers a dynamic completer for the arguments of a flag of a command.
+func CompleteFlag(cmd *cobra.Command, name string, completer CompleterFunc) {
+	carapace.Gen(cmd).FlagCompletion(carapace.ActionMap{
+		name: CompletionAction(cmd, completer),
+	})
+}
+
+// CompletionAction returns a carapace action calling a dynamic completer
+// for the arguments of a command, or of one of its flags.
+func CompletionAction(cmd *cobra.Command, completer CompleterFunc) carapace.Action {
+	return carapace.ActionCallback(func(ctx carapace.Context) carapace.Action {
+		candidates := completer(newCompletionContext(cmd, ctx))
+
+		described := make([]string, 0, 2*len(candidates))
+		for _, candidate := range candidates {
+			described = append(described, candidate.Value, candidate.Description)
+		}
+
+		return carapace.ActionValuesDescribed(described...)
+	})
+}
+
+// newCompletionContext returns the completion context of a command,
+// whose flags have been parsed from the line by the completer.
+func newCompletionContext(cmd *cobra.Command, ctx carapace.Context) CompletionContext {
+	comp := CompletionContext{
+		Command: cmd,
+		Args:    ctx.Args,
+		Flags:   make(map[string][]string),
+		Value:   ctx.Value,
+	}
+
+	cmd.Flags().Visit(func(flag *pflag.Flag) {
+		if slice, ok := flag.Value.(pflag.SliceValue); ok {
+			comp.Flags[flag.Name] = slice.GetSlice()
+		} else {
+			comp.Flags[flag.Name] = []string{flag.Value.String()}
+		}
+	})
+
+	return comp
+}
@@ -1,8 +1,14 @@
 package console
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"runtime/debug"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
 )
 
 type (
@@ -34,14 +40,175 @@ type (
 
 	// ExecutionError is an error that occurs during the execution phase.
 	ExecutionError struct{ Err }
+
+	// PanicError is an error produced when recovering from a panic, either
+	// in a command being executed, or while reading the input line (in the
+	// prompts, completers, highlighters or any other shell callback).
+	//
+	// The terminal is restored before the error is passed to the handler.
+	PanicError struct {
+		Err
+		Value any    // The value passed to panic().
+		Stack []byte // The stack trace of the panicking goroutine.
+	}
+
+	// UsageError is an error produced when a command line is validated before
+	// its execution: a flag is invalid, a required flag is missing, or there is
+	// an invalid number of positional arguments. Its message points at the issue,
+	// and is followed by the usage line of the command.
+	UsageError struct {
+		Err
+		Command *cobra.Command // The command whose usage is invalid.
+	}
 )
 
-func defaultErrorHandler(err error) error {
-	fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+// hintError is an error with a hint on how to fix
+// it, displayed after the error (see WithHint).
+type hintError struct {
+	err  error
+	hint string
+}
+
+// WithHint wraps an error with a hint for the user, displayed on a "hint:"
+// line after the error message and its causes (see FormatError), such as
+// the command to run, or the flag to use, to fix the issue.
+//
+// The error message is unchanged, and errors.Is/As still work on err.
+func WithHint(err error, hint string) error {
+	if err == nil {
+		return nil
+	}
+
+	return hintError{err: err, hint: hint}
+}
+
+func (e hintError) Error() string { return e.err.Error() }
+func (e hintError) Unwrap() error { return e.err }
+
+// FormatError renders an error for display: the chain of wrapped errors is
+// unwrapped, and the top message is printed in red, followed by each of its
+// causes (dimmed) on their own line, the command usage line for usage errors,
+// and the first hint found in the chain (see WithHint).
+//
+// This is used by the default error handler, and can be used by custom ones.
+func FormatError(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	messages, usage, hint := unwrapError(err)
+
+	var out strings.Builder
+
+	top := ""
+	if len(messages) > 0 {
+		top, messages = tr(messages[0]), messages[1:]
+	}
+
+	out.WriteString(bold + seqFgRed + tr("Error:") + " " + boldReset + top + seqFgReset + "\n")
+
+	for _, cause := range messages {
+		out.WriteString(dim + "  " + tr("caused by:") + " " + tr(cause) + dimReset + "\n")
+	}
+
+	if usage != "" {
+		out.WriteString(dim + "  " + tr("usage:") + " " + usage + dimReset + "\n")
+	}
+
+	if hint != "" {
+		out.WriteString(bold + seqFgYellow + tr("hint:") + " " + boldReset + hint + seqFgReset + "\n")
+	}
+
+	return out.String()
+}
+
+// unwrapError returns the messages of all errors in a chain, stripped from the
+// messages of their causes, the usage line of any usage error and the first hint.
+func unwrapError(err error) (messages []string, usage, hint string) {
+	covered := false // The message of a parent contains those of its causes.
+
+	for ; err != nil; err = errors.Unwrap(err) {
+		message := err.Error()
+
+		switch current := err.(type) {
+		case localizedError:
+			// Its message is the translation of its whole chain.
+			if !covered {
+				messages = append(messages, message)
+				covered = true
+			}
+
+			continue
+		case hintError:
+			if hint == "" {
+				hint = current.hint
+			}
+		case UsageError:
+			message = current.Err.Error()
+
+			if usage == "" {
+				usage = strings.TrimSpace(current.Command.UseLine())
+			}
+		case interface{ Unwrap() []error }:
+			// Joined errors are all causes of the same level.
+			if !covered {
+				for _, joined := range current.Unwrap() {
+					messages = append(messages, joined.Error())
+				}
+			}
+
+			return messages, usage, hint
+		}
+
+		if covered {
+			continue
+		}
+
+		if cause := errors.Unwrap(err); cause != nil {
+			switch {
+			case strings.HasSuffix(message, cause.Error()):
+				message = strings.TrimRight(strings.TrimSuffix(message, cause.Error()), ": ")
+			case strings.Contains(message, cause.Error()):
+				covered = true
+			}
+		}
+
+		if message != "" {
+			messages = append(messages, message)
+		}
+	}
+
+	return messages, usage, hint
+}
+
+func (c *Console) defaultErrorHandler(err error) error {
+	message := FormatError(err)
+
+	// Keep the output clean when it is redirected, or read aloud.
+	if !term.IsTerminal(int(os.Stderr.Fd())) || c.accessible.Load() {
+		message = strip(message)
+	}
+
+	fmt.Fprint(os.Stderr, message)
+
+	var panicErr PanicError
+	if errors.As(err, &panicErr) {
+		fmt.Fprintf(os.Stderr, "%s\n", panicErr.Stack)
+	}
 
 	return nil
 }
 
+// newPanicError creates a new PanicError from a recovered value,
+// with the stack trace of the current (panicking) goroutine.
+func newPanicError(value any) PanicError {
+	return PanicError{
+		Err:   newError(fmt.Errorf("%v", value), "Panic"),
+		Value: value,
+		Stack: debug.Stack(),
+	}
+}
+
 // newError creates a new Err.
 func newError(err error, message string) Err {
 	return Err{
@@ -54,7 +221,7 @@ func newError(err error, message string) Err {
 // message prefix.
 func (e Err) Error() string {
 	if len(e.message) > 0 {
-		return fmt.Sprintf("%s: %s", e.message, e.err.Error())
+		return fmt.Sprintf("%s: %s", tr(e.message), tr(e.err.Error()))
 	}
 
 	return e.err.Error()
@@ -64,3 +231,8 @@ func (e Err) Error() string {
 func (e Err) Unwrap() error {
 	return e.err
 }
+
+// Error returns the usage error message, followed by the command usage line.
+func (e UsageError) Error() string {
+	return fmt.Sprintf("%s\nUsage: %s", e.Err.Error(), strings.TrimSpace(e.Command.UseLine()))
+}
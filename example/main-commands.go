@@ -11,6 +11,7 @@ import (
 	"github.com/spf13/pflag"
 
 	"github.com/reeflective/console"
+	"github.com/reeflective/console/commands"
 	"github.com/reeflective/console/commands/readline"
 )
 
@@ -32,15 +33,32 @@ func mainMenuCommands(app *console.Console) console.Commands {
 		// Readline subcommands
 		rootCmd.AddCommand(readline.Commands(app.Shell()))
 
-		exitCmd := &cobra.Command{
-			Use:     "exit",
-			Short:   "Exit the console application",
-			GroupID: "core",
-			Run: func(cmd *cobra.Command, args []string) {
-				exitCtrlD(app)
-			},
-		}
-		rootCmd.AddCommand(exitCmd)
+		// Exit the application (or ask to confirm if some work is pending).
+		rootCmd.AddCommand(commands.ExitConsole(app))
+
+		// Run the last command again, possibly editing it first.
+		rootCmd.AddCommand(commands.Again(app))
+
+		// Print the history, or remove its duplicate lines.
+		rootCmd.AddCommand(commands.History(app))
+
+		// Profile the application (pprof server, CPU and heap profiles).
+		rootCmd.AddCommand(commands.Profile(app))
+
+		// Reload the configuration (also done on SIGHUP).
+		rootCmd.AddCommand(commands.Reload(app))
+
+		// Show the configuration, or select one of its profiles.
+		rootCmd.AddCommand(commands.Config(app))
+
+		// Serve the control API, to drive the application from other programs.
+		rootCmd.AddCommand(commands.Control(app))
+
+		// Watch the output of a command.
+		rootCmd.AddCommand(commands.Watch(app))
+
+		// Capture command results into variables.
+		rootCmd.AddCommand(commands.Set(app))
 
 		// And let's add a command declared in a traditional "cobra" way.
 		clientMenuCommand := &cobra.Command{
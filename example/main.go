@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"io"
+	"os"
 
 	"github.com/reeflective/console"
 )
@@ -12,6 +13,34 @@ const (
 )
 
 func main() {
+	app := newApp()
+
+	// Load the user configuration (editing mode, prompt, theme, profiles...),
+	// now that the menus exist, since it can select the one active at startup.
+	if err := app.LoadConfig(app.DefaultConfigPath()); err != nil {
+		fmt.Println(err)
+	}
+
+	// Or ask the user for their settings on the first run, to write this file.
+	app.EnableFirstRunSetup(app.DefaultConfigPath())
+
+	// Run the app -------------------------------------------------- //
+
+	// Editors completing script files for this application start
+	// it with this flag, and talk with it on stdin/stdout instead.
+	if len(os.Args) > 1 && os.Args[1] == "--completion-server" {
+		app.ServeCompletion(os.Stdin, os.Stdout)
+		return
+	}
+
+	// Everything is ready for a tour.
+	// Run the console and take a look around.
+	app.Start()
+}
+
+// newApp returns the example application, with its menus and commands,
+// but without the user configuration (which is loaded by main).
+func newApp() *console.Console {
 	// Instantiate a new app, with a single, default menu.
 	// All defaults are set, and nothing is needed to make it work.
 	app := console.New("example")
@@ -32,6 +61,9 @@ func main() {
 `)
 	})
 
+	// Reload the configuration (inputrc, aliases...) when receiving SIGHUP.
+	app.ReloadOnHangup()
+
 	// Main Menu Setup ---------------------------------------------- //
 
 	// By default the shell as created a single menu and
@@ -72,9 +104,5 @@ func main() {
 	// This is an example of binding "traditionally defined" cobra.Commands.
 	clientMenu.SetCommands(makeClientCommands(app))
 
-	// Run the app -------------------------------------------------- //
-
-	// Everything is ready for a tour.
-	// Run the console and take a look around.
-	app.Start()
+	return app
 }
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/reeflective/console/consoletest"
+)
+
+func TestMain(m *testing.M) {
+	if consoletest.Driven() {
+		_ = newApp().Start()
+
+		os.Exit(0)
+	}
+
+	os.Exit(m.Run())
+}
+
+func TestHelp(t *testing.T) {
+	help := consoletest.RenderOutput(newApp(), "help", consoletest.RenderOptions{Width: 100})
+	consoletest.Golden(t, "help", help)
+}
+
+func TestGreet(t *testing.T) {
+	app := newApp()
+
+	consoletest.Golden(t, "greet-help", consoletest.RenderOutput(app, "greet --help", consoletest.RenderOptions{}))
+	consoletest.Golden(t, "greet", consoletest.RenderOutput(app, "greet --name Alice --age 30", consoletest.RenderOptions{}))
+}
+
+func TestGreetDriven(t *testing.T) {
+	driver := consoletest.Drive(t)
+
+	driver.ExpectOutput("> ")
+	driver.Type("greet --name Bob --age 7\r").ExpectOutput("Hello, Bob! You are 7 years old.")
+	driver.Type("client\r").ExpectOutput("Switching to client menu")
+}
@@ -0,0 +1,158 @@
+package console
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// CommandExamplesKey should be used as a key to in a cobra.Annotation map.
+// The value is the list of usage examples of the command, in JSON format,
+// as set by SetExamples.
+const CommandExamplesKey = "console-examples"
+
+// Example is a usage example of a command.
+type Example struct {
+	Cmd         string `json:"cmd"`                   // The example command line (eg. "deploy app --env prod").
+	Description string `json:"description,omitempty"` // What the example does (eg. "Deploy the app in production").
+}
+
+// exampleState is the example last inserted in the input line,
+// so as to insert the next one when inserting examples again.
+type exampleState struct {
+	cmd   *cobra.Command
+	index int
+	line  string
+}
+
+// SetExamples sets the usage examples of a command: they are shown in the
+// Examples section of the command help (replacing the cobra Example field),
+// and can be inserted in the input line with Alt-E (the insert-example command)
+// while typing the command: pressing it again inserts the next example.
+func SetExamples(cmd *cobra.Command, examples ...Example) {
+	if cmd.Annotations == nil {
+		cmd.Annotations = make(map[string]string)
+	}
+
+	data, _ := json.Marshal(examples)
+	cmd.Annotations[CommandExamplesKey] = string(data)
+
+	help := make([]string, 0, len(examples))
+
+	for _, example := range examples {
+		if example.Description != "" {
+			help = append(help, "  # "+example.Description+"\n  "+example.Cmd)
+		} else {
+			help = append(help, "  "+example.Cmd)
+		}
+	}
+
+	cmd.Example = strings.Join(help, "\n\n")
+}
+
+// Examples returns the usage examples of a command, set with SetExamples.
+func Examples(cmd *cobra.Command) []Example {
+	if cmd == nil || cmd.Annotations[CommandExamplesKey] == "" {
+		return nil
+	}
+
+	var examples []Example
+	json.Unmarshal([]byte(cmd.Annotations[CommandExamplesKey]), &examples)
+
+	return examples
+}
+
+// setupExamples registers the insert-example command, and binds it to Alt-E in the
+// emacs keymaps, unless the key is already bound. It is not bound in vi insert mode,
+// where Esc followed by e (vi end-of-word) would otherwise insert an example.
+func (c *Console) setupExamples() {
+	c.shell.Keymap.Register(map[string]func(){
+		"insert-example": c.insertExample,
+	})
+
+	for _, keymap := range []string{"emacs", "emacs-standard"} {
+		if !c.boundByUser(keymap, "\x1be", "insert-example") {
+			c.bindDefault(keymap, "\x1be", "insert-example")
+		}
+	}
+}
+
+// insertExample replaces the input line with an example of the command being
+// typed (or of its closest parent having examples), and shows its description
+// in the hint section. If the line is the example previously inserted, the
+// next example of the same command is inserted instead.
+func (c *Console) insertExample() {
+	line := string(*c.shell.Line())
+	last := c.lastExample
+
+	cmd, index := last.cmd, last.index+1
+	if cmd == nil || last.line != line {
+		cmd, index = c.examplesCommand(line), 0
+	}
+
+	examples := Examples(cmd)
+	if len(examples) == 0 {
+		c.shell.Hint.SetTemporary(c.Theme().Hint + tr("No examples for this command") + reset)
+		return
+	}
+
+	index %= len(examples)
+	example := examples[index]
+
+	c.shell.History.Save()
+	c.shell.Line().Set([]rune(example.Cmd)...)
+	c.shell.Cursor().Set(len([]rune(example.Cmd)))
+
+	c.lastExample = exampleState{cmd: cmd, index: index, line: example.Cmd}
+
+	hint := fmt.Sprintf(tr("Example %d/%d"), index+1, len(examples))
+	if example.Description != "" {
+		hint += ": " + example.Description
+	}
+
+	c.shell.Hint.SetTemporary(c.Theme().Hint + hint + reset)
+}
+
+// examplesCommand returns the command typed in the line, or its
+// closest parent (up to the menu root command) having examples.
+func (c *Console) examplesCommand(line string) *cobra.Command {
+	menu := c.activeMenu()
+	if menu.Command == nil {
+		return nil
+	}
+
+	args, remain, err := split(line, false)
+	if err != nil {
+		args = append(args, remain)
+	}
+
+	target, rest, err := menu.Command.Find(args)
+	if err != nil || target == nil {
+		target, rest = menu.Command, args
+	}
+
+	// The command name being typed, if it is the only one matching.
+	if len(rest) == 1 && !strings.HasSuffix(line, " ") {
+		var matches []*cobra.Command
+
+		for _, cmd := range target.Commands() {
+			if cmd.IsAvailableCommand() && strings.HasPrefix(cmd.Name(), rest[0]) {
+				matches = append(matches, cmd)
+			}
+		}
+
+		if len(matches) == 1 {
+			target = matches[0]
+		}
+	}
+
+	for ; target != nil; target = target.Parent() {
+		if len(Examples(target)) > 0 {
+			return target
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,17 @@
+package console
+
+import (
+	"testing"
+)
+
+func TestExamplesBinds(t *testing.T) {
+	c := New("test")
+
+	if bind := c.shell.Config.Binds["emacs"]["\x1be"]; bind.Action != "insert-example" {
+		t.Errorf("emacs Alt-E = %q, want insert-example", bind.Action)
+	}
+
+	if bind := c.shell.Config.Binds["vi-insert"]["\x1be"]; bind.Action == "insert-example" {
+		t.Error("Esc-e is bound to insert-example in vi insert mode")
+	}
+}
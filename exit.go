@@ -0,0 +1,112 @@
+package console
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ExitBehavior defines what happens when the user exits a menu,
+// either with the exit/quit commands, or with Console.RequestExit().
+type ExitBehavior int
+
+const (
+	// ExitApplication exits the console application (the default).
+	ExitApplication ExitBehavior = iota
+
+	// ExitMenu leaves the menu, switching back to the main menu.
+	// In the main menu, this behaves like ExitApplication.
+	ExitMenu
+)
+
+// RequestExit exits the active menu or the console application, depending on the
+// menu ExitBehavior. Unless force is true, the user is first asked to confirm if
+// any of the console ExitWarnings returns a warning (eg. "2 jobs are running").
+// It returns false if the user did not confirm: otherwise, when exiting the
+// menu it returns true, and when exiting the application, it does not return.
+func (c *Console) RequestExit(force bool) bool {
+	menu := c.ActiveMenu()
+
+	if menu.ExitBehavior == ExitMenu && menu.name != "" {
+		c.SwitchMenu("")
+		return true
+	}
+
+	if !force {
+		var warnings []string
+
+		for _, warn := range c.ExitWarnings {
+			if warning := warn(); warning != "" {
+				warnings = append(warnings, warning)
+			}
+		}
+
+		if len(warnings) > 0 {
+			fmt.Println(strings.Join(warnings, "\n"))
+
+			if !confirm(tr("Exit anyway?")) {
+				return false
+			}
+		}
+	}
+
+	c.Exit(0)
+
+	return true
+}
+
+// Exit exits the console application with the given status code. This should be
+// preferred over os.Exit(), since the shutdown and exit hooks are run first (errors
+// are printed but do not prevent exiting), and the console session is then considered
+// cleanly terminated: its state file, if any, is removed, and the terminal is
+// restored to its state when the console was created.
+func (c *Console) Exit(code int) {
+	if err := c.runExitHooks(); err != nil {
+		fmt.Fprintf(os.Stderr, "Shutdown error: %s\n", err)
+	}
+
+	if c.stateFile != "" {
+		os.Remove(c.stateFile)
+	}
+
+	if err := c.StopProfiling(); err != nil {
+		fmt.Fprintf(os.Stderr, "Profiling error: %s\n", err)
+	}
+
+	if err := c.StopControl(); err != nil {
+		fmt.Fprintf(os.Stderr, "Control error: %s\n", err)
+	}
+
+	c.DisableLogPanel()
+	restoreTerminal()
+	os.Exit(code)
+}
+
+// confirm asks a yes/no question to the user, and returns true if the answer
+// is yes. The answer is read byte by byte, not to buffer any further input.
+func confirm(question string) bool {
+	fmt.Printf("%s %s ", question, tr("[y/N]"))
+
+	var answer []byte
+
+	buf := make([]byte, 1)
+
+	for {
+		if n, err := os.Stdin.Read(buf); err != nil || n == 0 {
+			break
+		}
+
+		if buf[0] == '\n' {
+			break
+		}
+
+		answer = append(answer, buf[0])
+	}
+
+	switch strings.ToLower(strings.TrimSpace(string(answer))) {
+	case "y", "yes", tr("y"), tr("yes"):
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,91 @@
+package console
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// commandCompletionTTL is the duration for which the output of a program
+// completing arguments is cached (see CompleteFromCommand).
+const commandCompletionTTL = 10 * time.Second
+
+// commandCompletions caches the candidates produced by external programs, by command line.
+var commandCompletions = struct {
+	entries map[string]commandCompletion
+	mutex   sync.Mutex
+}{
+	entries: make(map[string]commandCompletion),
+}
+
+// commandCompletion is the cached output of an external program.
+type commandCompletion struct {
+	candidates []Candidate
+	expires    time.Time
+}
+
+// CompleteFromCommand returns a dynamic completer running an external program, whose
+// output lines are the candidates, optionally followed by a tab and their description:
+//
+//	console.CompleteArgs(cmd, console.CompleteFromCommand("git", "branch", "--format=%(refname:short)"))
+//
+// The output is cached for a few seconds, and the program is killed (proposing no
+// candidates) if it does not complete in time, so that the shell stays responsive.
+func CompleteFromCommand(name string, args ...string) CompleterFunc {
+	key := strings.Join(append([]string{name}, args...), "\x00")
+
+	return func(_ CompletionContext) []Candidate {
+		commandCompletions.mutex.Lock()
+		cached, found := commandCompletions.entries[key]
+		commandCompletions.mutex.Unlock()
+
+		if found && time.Now().Before(cached.expires) {
+			return cached.candidates
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+		defer cancel()
+
+		program := exec.CommandContext(ctx, name, args...)
+		program.WaitDelay = time.Second / 10 // Nor wait for children keeping the output open.
+
+		output, err := program.Output()
+		if err != nil {
+			return nil
+		}
+
+		candidates := parseCandidates(string(output))
+
+		commandCompletions.mutex.Lock()
+		commandCompletions.entries[key] = commandCompletion{
+			candidates: candidates,
+			expires:    time.Now().Add(commandCompletionTTL),
+		}
+		commandCompletions.mutex.Unlock()
+
+		return candidates
+	}
+}
+
+// parseCandidates returns the candidates of a program output, one per
+// non-empty line, with an optional description after a tab.
+func parseCandidates(output string) []Candidate {
+	var candidates []Candidate
+
+	for _, line := range strings.Split(strip(output), "\n") {
+		value, description, _ := strings.Cut(strings.TrimRight(line, "\r"), "\t")
+
+		if value = strings.TrimSpace(value); value == "" {
+			continue
+		}
+
+		candidates = append(candidates, Candidate{
+			Value:       value,
+			Description: strings.TrimSpace(description),
+		})
+	}
+
+	return candidates
+}
@@ -0,0 +1,117 @@
+package console
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// FanOutFunc is called by FanOut for each target, and should write its output to out.
+type FanOutFunc func(ctx context.Context, target string, out io.Writer) error
+
+// FanOutResult is the result of a FanOutFunc call for one of the targets.
+type FanOutResult struct {
+	Target   string
+	Output   string
+	Err      error
+	Duration time.Duration
+}
+
+// FanOut calls the function for each of the targets, with at most concurrency calls
+// running at once (all of them if concurrency is zero or less), and returns their
+// results in the order of the targets. The output of each call is buffered, so that
+// the results can be printed as separate sections (see PrintFanOut). Targets not yet
+// handled when the context is canceled fail with the context error.
+func FanOut(ctx context.Context, targets []string, concurrency int, fn FanOutFunc) []FanOutResult {
+	if concurrency <= 0 || concurrency > len(targets) {
+		concurrency = len(targets)
+	}
+
+	results := make([]FanOutResult, len(targets))
+	slots := make(chan struct{}, concurrency)
+
+	var wg sync.WaitGroup
+
+	for i, target := range targets {
+		results[i].Target = target
+
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+
+		// A slot might have been freed when the context was canceled.
+		if ctx.Err() != nil {
+			results[i].Err = ctx.Err()
+			continue
+		}
+
+		wg.Add(1)
+
+		go func(result *FanOutResult) {
+			defer func() {
+				if r := recover(); r != nil {
+					result.Err = newPanicError(r)
+				}
+
+				<-slots
+				wg.Done()
+			}()
+
+			var out bytes.Buffer
+
+			start := time.Now()
+			result.Err = fn(ctx, result.Target, &out)
+			result.Duration = time.Since(start)
+			result.Output = out.String()
+		}(&results[i])
+	}
+
+	wg.Wait()
+
+	return results
+}
+
+// PrintFanOut prints the output of each FanOut result in its own section,
+// followed by a summary table with the status and duration of each target.
+func PrintFanOut(out io.Writer, results []FanOutResult) {
+	for _, result := range results {
+		if result.Output == "" {
+			continue
+		}
+
+		fmt.Fprintf(out, "%s==> %s <==%s\n", bold, result.Target, boldReset)
+		fmt.Fprint(out, result.Output)
+
+		if !strings.HasSuffix(result.Output, "\n") {
+			fmt.Fprintln(out)
+		}
+
+		fmt.Fprintln(out)
+	}
+
+	table := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, strings.Join([]string{tr("TARGET"), tr("STATUS"), tr("DURATION"), tr("ERROR")}, "\t"))
+
+	failed := 0
+
+	for _, result := range results {
+		status, errMsg := tr("ok"), ""
+
+		if result.Err != nil {
+			status, errMsg = tr("failed"), result.Err.Error()
+			failed++
+		}
+
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\n", result.Target, status, result.Duration.Round(time.Millisecond), errMsg)
+	}
+
+	table.Flush()
+
+	fmt.Fprintf(out, "\n"+tr("%d targets, %d succeeded, %d failed")+"\n", len(results), len(results)-failed, failed)
+}
@@ -0,0 +1,157 @@
+package console
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFanOutConcurrency(t *testing.T) {
+	targets := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+
+	var running, peak atomic.Int32
+
+	FanOut(context.Background(), targets, 3, func(context.Context, string, io.Writer) error {
+		now := running.Add(1)
+		defer running.Add(-1)
+
+		for highest := peak.Load(); now > highest; highest = peak.Load() {
+			if peak.CompareAndSwap(highest, now) {
+				break
+			}
+		}
+
+		time.Sleep(5 * time.Millisecond)
+
+		return nil
+	})
+
+	if peak.Load() > 3 {
+		t.Errorf("%d calls running at once, want at most 3", peak.Load())
+	}
+
+	// Without a bound, all calls run at once: they all wait for each other.
+	var started sync.WaitGroup
+
+	started.Add(len(targets))
+
+	results := FanOut(context.Background(), targets, 0, func(context.Context, string, io.Writer) error {
+		started.Done()
+
+		done := make(chan struct{})
+		go func() { started.Wait(); close(done) }()
+
+		select {
+		case <-done:
+			return nil
+		case <-time.After(5 * time.Second):
+			return errors.New("not all calls running at once")
+		}
+	})
+
+	for _, result := range results {
+		if result.Err != nil {
+			t.Fatalf("%s: %v", result.Target, result.Err)
+		}
+	}
+}
+
+func TestFanOutResults(t *testing.T) {
+	targets := []string{"web-1", "web-2", "web-3", "web-4"}
+
+	results := FanOut(context.Background(), targets, 2, func(_ context.Context, target string, out io.Writer) error {
+		// The last targets finish first.
+		time.Sleep(time.Duration(len(targets)-int(target[4]-'0')) * 5 * time.Millisecond)
+
+		for line := range 3 {
+			fmt.Fprintf(out, "%s line %d\n", target, line)
+		}
+
+		switch target {
+		case "web-2":
+			return errors.New("down")
+		case "web-3":
+			panic("crashed")
+		}
+
+		return nil
+	})
+
+	for i, result := range results {
+		if result.Target != targets[i] {
+			t.Fatalf("result %d is for %s, want %s", i, result.Target, targets[i])
+		}
+
+		if want := fmt.Sprintf("%[1]s line 0\n%[1]s line 1\n%[1]s line 2\n", result.Target); result.Target != "web-3" && result.Output != want {
+			t.Errorf("%s output = %q, want %q", result.Target, result.Output, want)
+		}
+	}
+
+	var panicked PanicError
+	if results[0].Err != nil || results[1].Err == nil || !errors.As(results[2].Err, &panicked) || results[3].Err != nil {
+		t.Errorf("errors are %v, %v, %v, %v", results[0].Err, results[1].Err, results[2].Err, results[3].Err)
+	}
+
+	var out bytes.Buffer
+
+	PrintFanOut(&out, results)
+
+	printed := out.String()
+
+	if first, second := strings.Index(printed, "==> web-1 <=="), strings.Index(printed, "==> web-2 <=="); first < 0 || second < first {
+		t.Errorf("sections not printed in the order of the targets:\n%s", printed)
+	}
+
+	for _, want := range []string{"web-2 line 2\n", "TARGET", "failed", "down", "4 targets, 2 succeeded, 2 failed"} {
+		if !strings.Contains(printed, want) {
+			t.Errorf("fan-out summary without %q:\n%s", want, printed)
+		}
+	}
+
+	if strings.Contains(printed, "==> web-3 <==") {
+		t.Errorf("section printed for a target without output:\n%s", printed)
+	}
+}
+
+func TestFanOutCanceled(t *testing.T) {
+	targets := []string{"a", "b", "c", "d"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var called []string
+
+	// The first call cancels the context: all the targets not yet handled fail.
+	results := FanOut(ctx, targets, 1, func(ctx context.Context, target string, _ io.Writer) error {
+		called = append(called, target)
+		cancel()
+
+		return ctx.Err()
+	})
+
+	if len(called) != 1 {
+		t.Errorf("called for %q after the context was canceled", called)
+	}
+
+	for _, result := range results {
+		if !errors.Is(result.Err, context.Canceled) {
+			t.Errorf("%s: error = %v, want context.Canceled", result.Target, result.Err)
+		}
+	}
+
+	// Nothing is called with a canceled context.
+	results = FanOut(ctx, targets, 0, func(context.Context, string, io.Writer) error {
+		t.Error("called with a canceled context")
+		return nil
+	})
+
+	if len(results) != len(targets) || !errors.Is(results[3].Err, context.Canceled) || results[3].Target != "d" {
+		t.Errorf("results of a canceled fan-out = %+v", results)
+	}
+}
@@ -0,0 +1,300 @@
+package console
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ErrFilterSyntax is returned (wrapped) when a filter expression is invalid.
+var ErrFilterSyntax = errors.New("invalid filter expression")
+
+// FilterExpr is a boolean expression over filter names, which is evaluated
+// against a set of active filters (the console ones, added with HideCommands).
+// Expressions support the && (and), || (or), ! (not) operators and parentheses,
+// and commas are equivalent to || for compatibility with flat filter lists:
+//
+//	windows && privileged
+//	windows,linux
+//	!interactive || (remote && !admin)
+//
+// When used as a command CommandFilterKey annotation, the command is hidden
+// (and cannot be executed) when the expression is true.
+type FilterExpr struct {
+	expr string
+	root filterNode
+}
+
+// ParseFilter parses a filter expression, returning an error wrapping
+// ErrFilterSyntax if the expression is invalid. An empty expression is
+// valid and never matches.
+func ParseFilter(expr string) (*FilterExpr, error) {
+	parser := &filterParser{tokens: tokenizeFilter(expr)}
+
+	filter := &FilterExpr{expr: expr}
+
+	if len(parser.tokens) == 0 {
+		return filter, nil
+	}
+
+	root, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if parser.pos < len(parser.tokens) {
+		return nil, fmt.Errorf("%w: unexpected %q in %q", ErrFilterSyntax, parser.tokens[parser.pos], expr)
+	}
+
+	filter.root = root
+
+	return filter, nil
+}
+
+// Eval evaluates the expression, using the function to know if a filter is active.
+func (f *FilterExpr) Eval(active func(filter string) bool) bool {
+	if f == nil || f.root == nil {
+		return false
+	}
+
+	return f.root.eval(active)
+}
+
+// Names returns the names of all filters used in the expression.
+func (f *FilterExpr) Names() []string {
+	if f == nil || f.root == nil {
+		return nil
+	}
+
+	return f.root.names(nil)
+}
+
+// String returns the expression as it was parsed.
+func (f *FilterExpr) String() string {
+	return f.expr
+}
+
+// ActiveFilters returns the filters currently active in the console,
+// that is, those added with HideCommands() and not yet removed.
+func (c *Console) ActiveFilters() []string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return append([]string{}, c.filters...)
+}
+
+// MatchFilter evaluates a filter expression against the filters currently active
+// in the console. This is the same evaluation used to hide commands, and it can be
+// used to filter other things, like completions. Invalid expressions never match.
+func (c *Console) MatchFilter(expr string) bool {
+	filter, err := ParseFilter(expr)
+	if err != nil {
+		return false
+	}
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return filter.Eval(c.isFilterActive)
+}
+
+// isFilterActive returns true if the filter is active in the console.
+// The caller must hold the console mutex.
+func (c *Console) isFilterActive(name string) bool {
+	for _, filter := range c.filters {
+		if filter == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchingFilters evaluates an annotation filter expression against the active
+// filters, and returns the active filters of the expression if it matched (or the
+// expression itself if none is active, like for "!admin"). For invalid expressions,
+// each comma-separated word is matched as a filter name.
+func (c *Console) matchingFilters(expr string) []string {
+	var names []string
+
+	filter, err := ParseFilter(expr)
+	matched := err == nil && filter.Eval(c.isFilterActive)
+
+	if err != nil {
+		names = strings.Split(expr, ",")
+	} else if matched {
+		names = filter.Names()
+	}
+
+	var filters []string
+
+	for _, name := range names {
+		if name != "" && c.isFilterActive(name) {
+			filters = append(filters, name)
+		}
+	}
+
+	// Expressions like "!admin" match without any active filter.
+	if matched && len(filters) == 0 {
+		filters = append(filters, expr)
+	}
+
+	return filters
+}
+
+//
+// Expression parsing & evaluation ---------------------------------
+//
+
+type filterNode interface {
+	eval(active func(string) bool) bool
+	names(acc []string) []string
+}
+
+type (
+	filterName string
+	filterNot  struct{ node filterNode }
+	filterAnd  struct{ left, right filterNode }
+	filterOr   struct{ left, right filterNode }
+)
+
+func (n filterName) eval(active func(string) bool) bool { return active(string(n)) }
+func (n filterNot) eval(active func(string) bool) bool  { return !n.node.eval(active) }
+
+func (n filterAnd) eval(active func(string) bool) bool {
+	return n.left.eval(active) && n.right.eval(active)
+}
+
+func (n filterOr) eval(active func(string) bool) bool {
+	return n.left.eval(active) || n.right.eval(active)
+}
+
+func (n filterName) names(acc []string) []string { return append(acc, string(n)) }
+func (n filterNot) names(acc []string) []string  { return n.node.names(acc) }
+func (n filterAnd) names(acc []string) []string  { return n.right.names(n.left.names(acc)) }
+func (n filterOr) names(acc []string) []string   { return n.right.names(n.left.names(acc)) }
+
+// tokenizeFilter splits an expression into operators, parentheses and names.
+func tokenizeFilter(expr string) []string {
+	var tokens []string
+
+	runes := []rune(expr)
+
+	for i := 0; i < len(runes); {
+		switch char := runes[i]; {
+		case unicode.IsSpace(char):
+			i++
+		case char == '(' || char == ')' || char == '!' || char == ',':
+			tokens = append(tokens, string(char))
+			i++
+		case (char == '&' || char == '|') && i+1 < len(runes) && runes[i+1] == char:
+			tokens = append(tokens, string(runes[i:i+2]))
+			i += 2
+		default:
+			start := i
+			for i < len(runes) && !strings.ContainsRune("()!,&| \t\n", runes[i]) {
+				i++
+			}
+
+			if i == start {
+				tokens = append(tokens, string(char))
+				i++
+			} else {
+				tokens = append(tokens, string(runes[start:i]))
+			}
+		}
+	}
+
+	return tokens
+}
+
+type filterParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *filterParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+
+	return ""
+}
+
+func (p *filterParser) parseOr() (filterNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek() == "||" || p.peek() == "," {
+		p.pos++
+
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		left = filterOr{left, right}
+	}
+
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filterNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek() == "&&" {
+		p.pos++
+
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		left = filterAnd{left, right}
+	}
+
+	return left, nil
+}
+
+func (p *filterParser) parseUnary() (filterNode, error) {
+	switch token := p.peek(); token {
+	case "":
+		return nil, fmt.Errorf("%w: unexpected end of expression", ErrFilterSyntax)
+	case "!":
+		p.pos++
+
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		return filterNot{node}, nil
+	case "(":
+		p.pos++
+
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("%w: missing closing parenthesis", ErrFilterSyntax)
+		}
+
+		p.pos++
+
+		return node, nil
+	case ")", "&&", "||", ",", "&", "|":
+		return nil, fmt.Errorf("%w: unexpected %q", ErrFilterSyntax, token)
+	default:
+		p.pos++
+
+		return filterName(token), nil
+	}
+}
@@ -0,0 +1,121 @@
+package console
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseFilter(t *testing.T) {
+	active := func(filter string) bool { return filter == "on" || filter == "yes" }
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"", false},
+		{"on", true},
+		{"off", false},
+		{"!off", true},
+		{"!!on", true},
+		{"on && yes", true},
+		{"on && off", false},
+		{"off || on", true},
+		{"off || no", false},
+		{"off, on", true},
+		{"off,no", false},
+		// && binds tighter than || and commas.
+		{"on || on && off", true},
+		{"off && on || on", true},
+		{"off, on && yes", true},
+		{"off && off, on", true},
+		// ! binds tighter than &&.
+		{"!off && on", true},
+		{"!on && off", false},
+		{"!(on && off)", true},
+		{"(off || on) && off", false},
+		{"(off, on) && yes", true},
+		{"((on))", true},
+		{"!interactive || (on && !admin)", true},
+		{"on&&!off", true},
+	}
+
+	for _, test := range tests {
+		filter, err := ParseFilter(test.expr)
+		if err != nil {
+			t.Errorf("ParseFilter(%q) failed: %v", test.expr, err)
+			continue
+		}
+
+		if got := filter.Eval(active); got != test.want {
+			t.Errorf("%q evaluated to %t, want %t", test.expr, got, test.want)
+		}
+
+		if filter.String() != test.expr {
+			t.Errorf("%q printed as %q", test.expr, filter.String())
+		}
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	tests := []string{
+		"on &&",
+		"|| on",
+		"on,",
+		",on",
+		"!",
+		"(on",
+		"on)",
+		"()",
+		"on off",
+		"on & off",
+		"on | off",
+		"on && && off",
+		"!(on || )",
+		strings.Repeat("(", 1000),
+	}
+
+	for _, expr := range tests {
+		if filter, err := ParseFilter(expr); !errors.Is(err, ErrFilterSyntax) {
+			t.Errorf("ParseFilter(%q) returned %v, %v, want an ErrFilterSyntax error", expr, filter, err)
+		}
+	}
+}
+
+func TestFilterNames(t *testing.T) {
+	filter, err := ParseFilter("!a && (b || c), a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if names := filter.Names(); !slices.Equal(names, []string{"a", "b", "c", "a"}) {
+		t.Errorf("names are %q", names)
+	}
+
+	var empty *FilterExpr
+	if empty.Eval(func(string) bool { return true }) || empty.Names() != nil {
+		t.Error("nil expression matched or has names")
+	}
+}
+
+func TestTokenizeFilter(t *testing.T) {
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{"", nil},
+		{" \t", nil},
+		{"a&&b", []string{"a", "&&", "b"}},
+		{"!(a||b),c", []string{"!", "(", "a", "||", "b", ")", ",", "c"}},
+		{"a & b", []string{"a", "&", "b"}},
+		{"a|b", []string{"a", "|", "b"}},
+		{"os-linux.amd64", []string{"os-linux.amd64"}},
+	}
+
+	for _, test := range tests {
+		if tokens := tokenizeFilter(test.expr); !slices.Equal(tokens, test.want) {
+			t.Errorf("%q tokenized as %q, want %q", test.expr, tokens, test.want)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package console
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/term"
+	"gopkg.in/yaml.v3"
+)
+
+// promptStyles are the prompts offered by the first-run setup, in order.
+var promptStyles = []struct{ name, format string }{
+	{"application", ""}, // The prompt of the application.
+	{"minimal", "> "},
+	{"full", "{app} {menu}> "},
+}
+
+// EnableFirstRunSetup enables an interactive setup when the console starts and no
+// configuration file exists at path: the user is asked for the editing mode, theme,
+// history file and prompt style to use, before the configuration file is written
+// with them and loaded (see LoadConfig). Enter keeps the default answer of each
+// question, and Ctrl-C or Ctrl-D skips the setup, which is offered again the next
+// time. Nothing is asked when the input is not a terminal (eg. in line mode).
+func (c *Console) EnableFirstRunSetup(path string) {
+	c.OnStart(func() error {
+		if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) || !term.IsTerminal(int(os.Stdin.Fd())) {
+			return nil
+		}
+
+		config, err := c.askFirstRunConfig(filepath.Join(filepath.Dir(path), "history"))
+		if errors.Is(err, ErrAskAborted) {
+			fmt.Println(tr("Setup skipped."))
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		data, err := yaml.Marshal(config)
+		if err != nil {
+			return err
+		}
+
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			return err
+		}
+
+		fmt.Printf(tr("Configuration written to %s")+"\n\n", path)
+
+		return c.LoadConfig(path)
+	})
+}
+
+// askFirstRunConfig asks the user for the fields of the initial configuration.
+func (c *Console) askFirstRunConfig(history string) (config Config, err error) {
+	fmt.Printf("%s%s%s %s\n", bold, fmt.Sprintf(tr("Welcome to %s!"), c.name), boldReset, tr("Let's set up the console (Ctrl-C to skip)."))
+
+	shell := c.newAskShell()
+
+	styles := make([]string, 0, len(promptStyles))
+	for _, style := range promptStyles {
+		styles = append(styles, style.name)
+	}
+
+	var style string
+
+	for _, question := range []struct {
+		label, def string
+		choices    []string
+		answer     *string
+	}{
+		{tr("editing mode"), "emacs", []string{"emacs", "vi"}, &config.EditingMode},
+		{tr("theme"), "default", c.Themes(), &config.Theme},
+		{tr("history file"), history, nil, &config.History},
+		{tr("prompt style"), "application", styles, &style},
+	} {
+		if *question.answer, err = c.ask(shell, Question{Label: question.label, Choices: question.choices, Default: question.def}); err != nil {
+			return config, err
+		}
+	}
+
+	for _, prompt := range promptStyles {
+		if prompt.name == style {
+			config.Prompt = prompt.format
+		}
+	}
+
+	return config, nil
+}
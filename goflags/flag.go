@@ -0,0 +1,148 @@
+package goflags
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// newFlag returns the flag of a go-flags option, whose value records the
+// occurrences of the option on the command line, for the parser to set them.
+// The default value of the option is only shown in the flag usage, so that
+// resetting the flag to its default forgets its occurrences.
+func newFlag(option *flags.Option) *pflag.Flag {
+	value := &optionValue{option: option}
+
+	flag := &pflag.Flag{
+		Name:   option.LongNameWithNamespace(),
+		Usage:  option.Description,
+		Value:  value,
+		Hidden: option.Hidden,
+	}
+
+	if option.ShortName != 0 {
+		flag.Shorthand = string(option.ShortName)
+	}
+
+	// Options with a short name only are still named in the flag set.
+	if flag.Name == "" {
+		flag.Name = flag.Shorthand
+	}
+
+	if def := usageDefault(option); def != "" {
+		flag.Usage += " (default: " + def + ")"
+	}
+
+	switch {
+	case value.isBool():
+		flag.NoOptDefVal = "true"
+	case option.OptionalArgument:
+		flag.NoOptDefVal = strings.Join(option.OptionalValue, ",")
+		if flag.NoOptDefVal == "" {
+			flag.NoOptDefVal = " "
+		}
+	}
+
+	return flag
+}
+
+// optionValue is the value of the flag of a go-flags option, keeping the values
+// given to the option on the command line, like a slice value.
+type optionValue struct {
+	option *flags.Option
+	values []string
+}
+
+func (v *optionValue) String() string { return strings.Join(v.values, ",") }
+
+func (v *optionValue) Set(value string) error {
+	if v.isBool() {
+		// go-flags booleans cannot be set to false.
+		if enabled, err := strconv.ParseBool(value); err != nil || !enabled {
+			v.values = nil
+			return err
+		}
+	}
+
+	v.values = append(v.values, value)
+
+	return nil
+}
+
+// Type names the values of the option in the flag usage.
+func (v *optionValue) Type() string {
+	if v.isBool() {
+		return "bool"
+	}
+
+	if v.option.ValueName != "" {
+		return v.option.ValueName
+	}
+
+	typ := elemType(v.option.Field().Type)
+	if typ.Name() == "" {
+		return typ.Kind().String()
+	}
+
+	return strings.ToLower(typ.Name())
+}
+
+func (v *optionValue) Append(value string) error { return v.Set(value) }
+
+func (v *optionValue) Replace(values []string) error {
+	v.values = values
+	return nil
+}
+
+func (v *optionValue) GetSlice() []string { return v.values }
+
+// isBool returns true if the option takes no argument, like a boolean flag.
+func (v *optionValue) isBool() bool {
+	return elemType(v.option.Field().Type).Kind() == reflect.Bool
+}
+
+// words returns the words setting the option as many times as it was given.
+func (v *optionValue) words() (words []string) {
+	long, short := v.option.LongNameWithNamespace(), string(v.option.ShortName)
+
+	for _, value := range v.values {
+		switch {
+		case long != "" && (v.isBool() || v.optional(value)):
+			words = append(words, "--"+long)
+		case long != "":
+			words = append(words, "--"+long+"="+value)
+		case v.isBool() || v.optional(value):
+			words = append(words, "-"+short)
+		default:
+			words = append(words, "-"+short+value)
+		}
+	}
+
+	return words
+}
+
+// optional returns true if the option was given without its optional argument.
+func (v *optionValue) optional(value string) bool {
+	if !v.option.OptionalArgument {
+		return false
+	}
+
+	optional := strings.Join(v.option.OptionalValue, ",")
+
+	return value == optional || (optional == "" && value == " ")
+}
+
+// optionWords returns the words of the options given to a command or its parents.
+func optionWords(cmd *cobra.Command) (words []string) {
+	cmd.Flags().Visit(func(flag *pflag.Flag) {
+		if value, ok := flag.Value.(*optionValue); ok {
+			words = append(words, value.words()...)
+		}
+	})
+
+	return words
+}
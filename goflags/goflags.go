@@ -0,0 +1,231 @@
+// Package goflags serves the commands of a go-flags parser in a console, for applications
+// not written with cobra: the parser is turned into a cobra command tree, with the commands,
+// aliases, options and positional arguments of the parser, so that they are completed, have
+// their help, and are highlighted like any other console command:
+//
+//	type options struct {
+//	    Verbose []bool `short:"v" long:"verbose" description:"Show verbose output"`
+//	}
+//
+//	app := console.New("app")
+//	app.ActiveMenu().SetCommands(goflags.Commands(func() *flags.Parser {
+//	    parser := flags.NewParser(&options{}, flags.Default)
+//	    parser.AddCommand("deploy", "Deploy the application", "", &deployCommand{})
+//
+//	    return parser
+//	}))
+//
+// Options are completed with their choices (the choice tag), as files for flags.Filename
+// values, or with the Complete method of their value type if it is a flags.Completer,
+// without registering carapace actions for them. The commands are still executed by the
+// parser itself, with the words of the command line, so that its defaults, environment
+// variables, validation and Commander implementations apply as with go-flags alone.
+package goflags
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/jessevdk/go-flags"
+	"github.com/spf13/cobra"
+
+	"github.com/reeflective/console"
+)
+
+// Commands returns the commands function of a menu (see console.Menu.SetCommands) serving
+// the commands of a go-flags parser. Like other commands functions, it is called for a new
+// command tree before each command line: the parser function must thus return a new parser,
+// bound to new option structs, since go-flags does not reset the options across parsings.
+//
+// The parser errors are returned to the console, which prints them: the PrintErrors option
+// of the parser is thus cleared. The built-in help options of the parser are not added,
+// since the help flags and command of the console are used instead.
+func Commands(parser func() *flags.Parser) console.Commands {
+	return func() *cobra.Command {
+		bridge := &bridge{parser: parser()}
+		bridge.parser.Options &^= flags.PrintErrors
+
+		root := &cobra.Command{
+			Use:   bridge.parser.Name,
+			Short: bridge.parser.ShortDescription,
+			Long:  bridge.parser.LongDescription,
+		}
+
+		bridge.addOptions(root, bridge.parser.Command)
+
+		for _, sub := range bridge.parser.Commands() {
+			root.AddCommand(bridge.command(sub))
+		}
+
+		return root
+	}
+}
+
+// bridge executes the commands of the tree of a parser.
+type bridge struct {
+	parser *flags.Parser
+}
+
+// command returns the cobra command of a go-flags one, and of its subcommands.
+func (b *bridge) command(cmd *flags.Command) *cobra.Command {
+	cobraCmd := &cobra.Command{
+		Use:     usage(cmd),
+		Aliases: cmd.Aliases,
+		Short:   cmd.ShortDescription,
+		Long:    cmd.LongDescription,
+		Hidden:  cmd.Hidden,
+	}
+
+	b.addOptions(cobraCmd, cmd)
+
+	for _, sub := range cmd.Commands() {
+		cobraCmd.AddCommand(b.command(sub))
+	}
+
+	// Like cobra, commands requiring a subcommand print their help.
+	if len(cmd.Commands()) == 0 || cmd.SubcommandsOptional {
+		cobraCmd.RunE = b.execute
+	}
+
+	return cobraCmd
+}
+
+// execute runs the parser on the words of the command line: the command path, the
+// options given (in the order of the flags), and the arguments, with the double dash
+// back where it was typed. The parser executes the command if it is a Commander.
+func (b *bridge) execute(cmd *cobra.Command, args []string) error {
+	var words []string
+
+	for parent := cmd; parent.HasParent(); parent = parent.Parent() {
+		words = append([]string{parent.Name()}, words...)
+	}
+
+	words = append(words, optionWords(cmd)...)
+
+	if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+		words = append(words, args[:dash]...)
+		words = append(words, "--")
+		args = args[dash:]
+	}
+
+	words = append(words, args...)
+
+	_, err := b.parser.ParseArgs(words)
+
+	return err
+}
+
+// usage returns the usage line of a command, with its positional arguments.
+func usage(cmd *flags.Command) string {
+	use := cmd.Name
+
+	for i, arg := range cmd.Args() {
+		name := arg.Name
+		if name == "" {
+			name = fmt.Sprintf("arg%d", i+1)
+		}
+
+		if arg.Required > 0 {
+			use += " <" + name + ">"
+		} else {
+			use += " [" + name + "]"
+		}
+	}
+
+	return use
+}
+
+// addOptions adds the options of a command (in all of its groups) as flags of a cobra
+// command, persistent ones since go-flags accepts them after subcommands, with their
+// completions. The built-in help options are not added.
+func (b *bridge) addOptions(cobraCmd *cobra.Command, cmd *flags.Command) {
+	completions := make(carapace.ActionMap)
+
+	var addGroup func(group *flags.Group)
+
+	addGroup = func(group *flags.Group) {
+		for _, option := range group.Options() {
+			if option.LongName == "help" {
+				continue
+			}
+
+			flag := newFlag(option)
+			cobraCmd.PersistentFlags().AddFlag(flag)
+
+			if action, found := completion(option); found {
+				completions[flag.Name] = action
+			}
+		}
+
+		for _, sub := range group.Groups() {
+			addGroup(sub)
+		}
+	}
+
+	addGroup(cmd.Group)
+
+	if len(completions) > 0 {
+		carapace.Gen(cobraCmd).FlagCompletion(completions)
+	}
+}
+
+// filenameType is the type of go-flags options completed as files.
+var filenameType = reflect.TypeOf(flags.Filename(""))
+
+// completion returns the completion of an option values: its choices, files for
+// flags.Filename values, or those of its value type if it is a flags.Completer.
+func completion(option *flags.Option) (carapace.Action, bool) {
+	if len(option.Choices) > 0 {
+		return carapace.ActionValues(option.Choices...), true
+	}
+
+	typ := elemType(option.Field().Type)
+
+	if typ == filenameType {
+		return carapace.ActionFiles(), true
+	}
+
+	// Completers are called on a zero value of their type,
+	// since the value of the option field is not exported.
+	completer, ok := reflect.New(typ).Interface().(flags.Completer)
+	if !ok {
+		return carapace.Action{}, false
+	}
+
+	return carapace.ActionCallback(func(c carapace.Context) carapace.Action {
+		var values []string
+
+		for _, item := range completer.Complete(c.Value) {
+			values = append(values, item.Item, item.Description)
+		}
+
+		return carapace.ActionValuesDescribed(values...)
+	}), true
+}
+
+// elemType returns the type of the values of an option field.
+func elemType(typ reflect.Type) reflect.Type {
+	if kind := typ.Kind(); kind == reflect.Slice || kind == reflect.Map {
+		typ = typ.Elem()
+	}
+
+	if typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	return typ
+}
+
+// usageDefault returns the default value of an option shown in its usage, if any.
+func usageDefault(option *flags.Option) string {
+	switch option.DefaultMask {
+	case "-":
+		return ""
+	case "":
+		return strings.Join(option.Default, ", ")
+	default:
+		return option.DefaultMask
+	}
+}
@@ -0,0 +1,184 @@
+package goflags
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/jessevdk/go-flags"
+	"github.com/spf13/cobra"
+
+	"github.com/reeflective/console"
+)
+
+type regions string
+
+func (regions) Complete(match string) []flags.Completion {
+	return []flags.Completion{{Item: "eu-west", Description: "Europe"}, {Item: "us-east", Description: "America"}}
+}
+
+type deployCommand struct {
+	Env    string   `short:"e" long:"env" choice:"dev" choice:"prod" default:"dev" description:"Environment"`
+	Tags   []string `short:"t" long:"tag" description:"Tags"`
+	Region regions  `long:"region" description:"Region"`
+	Args   struct {
+		Target string `positional-arg-name:"target" required:"yes"`
+	} `positional-args:"yes"`
+
+	executed func(cmd *deployCommand, args []string)
+}
+
+func (d *deployCommand) Execute(args []string) error {
+	d.executed(d, args)
+	return nil
+}
+
+type appOptions struct {
+	Verbose []bool `short:"v" long:"verbose" description:"Verbose output"`
+}
+
+// newParser returns a parser function, binding each parser to new options,
+// and the deploy command to a function called with its options when executed.
+func newParser(opts **appOptions, executed func(cmd *deployCommand, args []string)) func() *flags.Parser {
+	return func() *flags.Parser {
+		*opts = &appOptions{}
+
+		parser := flags.NewParser(*opts, flags.Default)
+		parser.Name = "app"
+
+		cmd, err := parser.AddCommand("deploy", "Deploy the application", "", &deployCommand{executed: executed})
+		if err != nil {
+			panic(err)
+		}
+
+		cmd.Aliases = []string{"dp"}
+
+		return parser
+	}
+}
+
+func TestCommandsTree(t *testing.T) {
+	var opts *appOptions
+
+	root := Commands(newParser(&opts, nil))()
+
+	deploy, _, err := root.Find([]string{"dp"})
+	if err != nil || deploy.Name() != "deploy" {
+		t.Fatalf("alias dp found %v (%v), want deploy", deploy, err)
+	}
+
+	if deploy.Use != "deploy <target>" {
+		t.Errorf("Use = %q, want the positional argument", deploy.Use)
+	}
+
+	env := deploy.PersistentFlags().Lookup("env")
+	if env == nil || env.Shorthand != "e" || env.Usage != "Environment (default: dev)" {
+		t.Fatalf("env flag = %+v", env)
+	}
+
+	if verbose := root.PersistentFlags().Lookup("verbose"); verbose == nil || verbose.NoOptDefVal != "true" {
+		t.Errorf("verbose flag = %+v, want a boolean flag", verbose)
+	}
+
+	if root.PersistentFlags().Lookup("help") != nil {
+		t.Error("the built-in help option is added")
+	}
+}
+
+func TestCommandsExecute(t *testing.T) {
+	var got *deployCommand
+	var gotArgs []string
+
+	var opts *appOptions
+
+	parser := newParser(&opts, func(cmd *deployCommand, args []string) {
+		got, gotArgs = cmd, args
+	})
+
+	app := console.New("app")
+	menu := app.ActiveMenu()
+	menu.SetCommands(Commands(parser))
+
+	line := "-vv dp -e prod --tag a -tb --region=eu-west web -- -x"
+	if err := menu.RunCommandLine(context.Background(), line); err != nil {
+		t.Fatal(err)
+	}
+
+	switch {
+	case got == nil:
+		t.Fatal("the command is not executed")
+	case got.Env != "prod" || !slices.Equal(got.Tags, []string{"a", "b"}) || got.Region != "eu-west":
+		t.Errorf("options = %q %q %q", got.Env, got.Tags, got.Region)
+	case got.Args.Target != "web" || !slices.Equal(gotArgs, []string{"-x"}):
+		t.Errorf("arguments = %q %q", got.Args.Target, gotArgs)
+	case len(opts.Verbose) != 2:
+		t.Errorf("verbose = %v, want given twice", opts.Verbose)
+	}
+
+	// Options not given again are reset to their defaults.
+	if err := menu.RunCommandLine(context.Background(), "deploy api"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Env != "dev" || len(got.Tags) != 0 || len(opts.Verbose) != 0 {
+		t.Errorf("options = %q %q, verbose %v, want their defaults", got.Env, got.Tags, opts.Verbose)
+	}
+
+	if err := menu.RunCommandLine(context.Background(), "deploy --env staging api"); err == nil {
+		t.Error("an invalid choice is not returned by the parser")
+	}
+}
+
+func TestCommandsCompletion(t *testing.T) {
+	var opts *appOptions
+
+	parser := newParser(&opts, nil)
+
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"deploy", "--env", ""}, []string{"dev", "prod"}},
+		{[]string{"deploy", "--region", ""}, []string{"eu-west", "us-east"}},
+	}
+
+	for _, test := range tests {
+		root := Commands(parser)()
+
+		if got := complete(t, root, test.args...); !slices.Equal(got, test.want) {
+			t.Errorf("%q completes %q, want %q", test.args, got, test.want)
+		}
+	}
+}
+
+// complete returns the values completed by carapace for the arguments.
+func complete(t *testing.T, root *cobra.Command, args ...string) (values []string) {
+	t.Helper()
+
+	var out bytes.Buffer
+
+	carapace.Gen(root)
+	root.SetOut(&out)
+	root.SetArgs(append([]string{"_carapace", "export", root.Name()}, args...))
+
+	if err := root.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	var export struct {
+		Values []struct{ Value string }
+	}
+
+	if err := json.Unmarshal(out.Bytes(), &export); err != nil {
+		t.Fatalf("%v: %s", err, out.String())
+	}
+
+	for _, value := range export.Values {
+		values = append(values, value.Value)
+	}
+
+	return values
+}
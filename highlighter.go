@@ -7,8 +7,11 @@ import (
 )
 
 var (
+	seqFgRed    = "\x1b[31m"
 	seqFgGreen  = "\x1b[32m"
 	seqFgYellow = "\x1b[33m"
+	seqFgBlue   = "\x1b[34m"
+	seqFgCyan   = "\x1b[36m"
 	seqFgReset  = "\x1b[39m"
 
 	seqBrightWigth = "\x1b[38;05;244m"
@@ -36,7 +39,9 @@ var (
 // This action has no effect if a custom syntax highlighter for the shell is set.
 // By default, the highlight code is green ("\x1b[32m").
 func (c *Console) SetDefaultCommandHighlight(seq string) {
+	c.mutex.Lock()
 	c.cmdHighlight = seq
+	c.mutex.Unlock()
 }
 
 // SetDefaultFlagHighlight allows the user to change the highlight color for a flag in the default syntax
@@ -44,20 +49,38 @@ func (c *Console) SetDefaultCommandHighlight(seq string) {
 // This action has no effect if a custom syntax highlighter for the shell is set.
 // By default, the highlight code is grey ("\x1b[38;05;244m").
 func (c *Console) SetDefaultFlagHighlight(seq string) {
+	c.mutex.Lock()
 	c.flagHighlight = seq
+	c.mutex.Unlock()
 }
 
 // highlightSyntax - Entrypoint to all input syntax highlighting in the Wiregost console.
 func (c *Console) highlightSyntax(input []rune) (line string) {
+	// Mask sensitive values once the line is accepted.
+	if c.masking {
+		input = c.activeMenu().maskLine(input)
+	} else if !c.accessible.Load() {
+		c.updateHints(input)
+		c.updatePreview(input)
+		c.updateStatus()
+	}
+
+	// Colors are noise for screen readers.
+	if c.accessible.Load() {
+		return string(input)
+	}
+
+	defer c.profilePhase("highlight")()
+
 	// Split the line as shellwords
 	args, unprocessed, err := split(string(input), true)
 	if err != nil {
 		args = append(args, unprocessed)
 	}
 
-	highlighted := make([]string, 0)   // List of processed words, append to
-	remain := args                     // List of words to process, draw from
-	trimmed := trimSpacesMatch(remain) // Match stuff against trimmed words
+	highlighted := make([]string, 0, len(args)) // List of processed words, append to
+	remain := args                              // List of words to process, draw from
+	trimmed := trimSpacesMatch(remain)          // Match stuff against trimmed words
 
 	// Highlight the root command when found.
 	cmd, _, _ := c.activeMenu().Find(trimmed)
@@ -74,54 +97,61 @@ func (c *Console) highlightSyntax(input []rune) (line string) {
 	// Join all words.
 	line = strings.Join(highlighted, "")
 
+	// Highlight the quotes/brackets pair under the cursor, and the active region.
+	if !c.masking {
+		line = c.highlightPair(input, line)
+		line = c.highlightRegion(input, line)
+	}
+
+	// Display right-to-left arguments in their visual order.
+	if c.BidiRendering && hasRTL(line) {
+		line = visualOrder(line)
+	}
+
 	return line
 }
 
-func (c *Console) highlightCommand(done, args []string, _ *cobra.Command) ([]string, []string) {
-	highlighted := make([]string, 0)
-	var rest []string
-
+func (c *Console) highlightCommand(done, args []string, cmd *cobra.Command) ([]string, []string) {
 	if len(args) == 0 {
 		return done, args
 	}
 
-	// Highlight the root command when found, or any of its aliases.
-	for _, cmd := range c.activeMenu().Commands() {
-		// Change 1: Highlight based on first arg in usage rather than the entire usage itself
-		cmdFound := strings.Split(cmd.Use, " ")[0] == strings.TrimSpace(args[0])
-
-		for _, alias := range cmd.Aliases {
-			if alias == strings.TrimSpace(args[0]) {
-				cmdFound = true
-				break
-			}
-		}
+	// The found command might be a subcommand of the root one.
+	for cmd.HasParent() && cmd.Parent().HasParent() {
+		cmd = cmd.Parent()
+	}
 
-		if cmdFound {
-			highlighted = append(highlighted, bold+c.cmdHighlight+args[0]+seqFgReset+boldReset)
-			rest = args[1:]
+	name := strings.TrimSpace(args[0])
 
-			return append(done, highlighted...), rest
-		}
+	// Highlight the root command when found, or any of its aliases.
+	if cmd.HasParent() && (cmd.Name() == name || cmd.HasAlias(name)) {
+		return append(done, bold+c.Theme().Command+args[0]+seqFgReset+boldReset), args[1:]
 	}
 
-	return append(done, highlighted...), args
+	return done, args
 }
 
 func (c *Console) highlightCommandFlags(done, args []string, _ *cobra.Command) ([]string, []string) {
-	highlighted := make([]string, 0)
+	highlighted := make([]string, 0, len(args))
 	var rest []string
 
 	if len(args) == 0 {
 		return done, args
 	}
 
+	terminated := false
+
 	for _, arg := range args {
-		if strings.HasPrefix(arg, "-") || strings.HasPrefix(arg, "--") {
-			highlighted = append(highlighted, bold+c.flagHighlight+arg+seqFgReset+boldReset)
+		word := strings.TrimSpace(arg)
+
+		// Words after a -- terminator, and negative numbers, are not flags.
+		if !terminated && (strings.HasPrefix(arg, "-") || strings.HasPrefix(arg, "--")) && !isNegativeNumber(word) {
+			highlighted = append(highlighted, bold+c.Theme().Flag+arg+seqFgReset+boldReset)
 		} else {
 			highlighted = append(highlighted, arg)
 		}
+
+		terminated = terminated || word == "--"
 	}
 
 	return append(done, highlighted...), rest
@@ -0,0 +1,205 @@
+package console
+
+import (
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// CommandHintKey is the cobra.Annotation key (and the pflag annotation key, for flags)
+// used to declare a static hint, shown below the input line when typing the command,
+// or the flag. Dynamic hints are registered with Menu.SetCommandHint/SetFlagHint.
+const CommandHintKey = "console-hint"
+
+// HintProvider returns a hint to display below the input line while the user is
+// typing a command (or one of its flags), given the command and the arguments
+// typed so far, excluding the command names. An empty hint displays nothing.
+type HintProvider func(cmd *cobra.Command, args []string) string
+
+// SetCommandHint registers a dynamic hint provider for a command of this menu,
+// given its path relative to the menu root command (eg. "deploy app"). The hint
+// is shown while the user is typing the command, and not a flag having a hint.
+// Dynamic hints have precedence over the static CommandHintKey annotations.
+func (m *Menu) SetCommandHint(path string, provider HintProvider) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.hints[strings.Join(strings.Fields(path), " ")] = provider
+	m.console.hintCache.clear()
+}
+
+// SetFlagHint registers a dynamic hint provider for a flag (given by its long name)
+// of a command in this menu, given its path (see SetCommandHint). The hint is shown
+// when the user is typing the flag or its argument.
+func (m *Menu) SetFlagHint(path, flag string, provider HintProvider) {
+	m.SetCommandHint(strings.Join(strings.Fields(path), " ")+" --"+flag, provider)
+}
+
+// SetDefaultHintHighlight allows the user to change the color of the command and flag
+// hints displayed below the input line, using an ansi code. By default, hints are dim.
+func (c *Console) SetDefaultHintHighlight(seq string) {
+	c.mutex.Lock()
+	c.hintHighlight = seq
+	c.mutex.Unlock()
+}
+
+// updateHints displays the hint of the command or flag currently being typed, if any.
+// Hints given by the completion engine (eg. usage messages) have precedence over them.
+func (c *Console) updateHints(input []rune) {
+	hint := c.shell.Hint
+
+	if hint.Len() > 0 && hint.Text() != c.lastHint {
+		return
+	}
+
+	text := c.cachedHint(input)
+	if text != "" {
+		text = c.Theme().Hint + text + reset
+	}
+
+	if text != "" || c.lastHint != "" {
+		hint.SetTemporary(text)
+	}
+
+	c.lastHint = text
+}
+
+// cachedHint returns the hint for the command or flag before the cursor,
+// computing it only if it is not cached for this input line.
+func (c *Console) cachedHint(input []rune) string {
+	key := cacheKey(c.activeMenu(), input, c.shell.Cursor().Pos())
+
+	text, found := c.hintCache.get(key)
+	if !found {
+		done := c.profilePhase("hint")
+		text = c.commandHint(input)
+		done()
+
+		c.hintCache.add(key, text, c.HintCacheSize)
+	}
+
+	return text
+}
+
+// commandHint returns the hint for the command or flag before the cursor.
+func (c *Console) commandHint(input []rune) string {
+	menu := c.activeMenu()
+	if menu.Command == nil {
+		return ""
+	}
+
+	pos := c.shell.Cursor().Pos()
+	if pos > len(input) {
+		pos = len(input)
+	}
+
+	args, remain, err := split(string(input[:pos]), false)
+	if err != nil {
+		args = append(args, remain)
+	}
+
+	target, targetArgs, err := menu.Command.Find(args)
+	if err != nil || target == nil || target == menu.Command {
+		return ""
+	}
+
+	path := strings.TrimSpace(strings.TrimPrefix(target.CommandPath(), menu.Command.Name()))
+
+	// The flag being typed, or whose argument is being typed.
+	if flag := currentFlag(target, args, pos > 0 && input[pos-1] == ' '); flag != nil {
+		if provider := menu.hint(path + " --" + flag.Name); provider != nil {
+			return provider(target, targetArgs)
+		}
+
+		if static := flag.Annotations[CommandHintKey]; len(static) > 0 {
+			return strings.Join(static, " ")
+		}
+	}
+
+	if provider := menu.hint(path); provider != nil {
+		return provider(target, targetArgs)
+	}
+
+	if target.Annotations != nil {
+		return target.Annotations[CommandHintKey]
+	}
+
+	return ""
+}
+
+// hint returns the dynamic hint provider registered for a command or flag path.
+func (m *Menu) hint(path string) HintProvider {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.hints[path]
+}
+
+// currentFlag returns the flag being typed, or the flag expecting the argument being typed.
+func currentFlag(cmd *cobra.Command, args []string, newWord bool) *pflag.Flag {
+	if len(args) == 0 {
+		return nil
+	}
+
+	last := args[len(args)-1]
+
+	// No flags after a -- terminator.
+	for _, arg := range args[:len(args)-1] {
+		if arg == "--" {
+			return nil
+		}
+	}
+
+	// When a new word is started, only a flag expecting an argument matters.
+	if newWord {
+		if flag, attached := wordFlag(cmd, last); flag != nil && flag.NoOptDefVal == "" && !attached {
+			return flag
+		}
+
+		return nil
+	}
+
+	if flag := lookupFlag(cmd, last); flag != nil {
+		return flag
+	}
+
+	if len(args) > 1 {
+		if flag, attached := wordFlag(cmd, args[len(args)-2]); flag != nil && flag.NoOptDefVal == "" && !attached {
+			return flag
+		}
+	}
+
+	return nil
+}
+
+// lookupFlag returns the flag of the command (including inherited ones) used in a word.
+// In a group of stacked short flags (eg. "-abc"), this is the last flag of the group.
+func lookupFlag(cmd *cobra.Command, word string) *pflag.Flag {
+	flag, _ := wordFlag(cmd, word)
+
+	return flag
+}
+
+// wordFlag returns the flag used in a word, and whether its value is attached to it
+// (eg. "--file=path", "-fpath" or "-f=path"), rather than given as the next word.
+func wordFlag(cmd *cobra.Command, word string) (flag *pflag.Flag, attached bool) {
+	switch {
+	case strings.HasPrefix(word, "--"):
+		name, _, attached := strings.Cut(word[2:], "=")
+		if flag = cmd.Flags().Lookup(name); flag == nil {
+			flag = cmd.InheritedFlags().Lookup(name)
+		}
+
+		return flag, attached
+	case strings.HasPrefix(word, "-") && len(word) > 1:
+		flags, attached := stackedFlags(cmd, word)
+		if len(flags) == 0 {
+			return nil, false
+		}
+
+		return flags[len(flags)-1], attached
+	}
+
+	return nil, false
+}
@@ -0,0 +1,50 @@
+package console
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestCommandHintRegistering(t *testing.T) {
+	c := New("test")
+	menu := c.ActiveMenu()
+
+	menu.SetCommands(func() *cobra.Command {
+		root := &cobra.Command{}
+		root.AddCommand(&cobra.Command{Use: "deploy", Run: func(*cobra.Command, []string) {}})
+
+		return root
+	})
+
+	menu.resetPreRun()
+
+	input := []rune("deploy ")
+	c.shell.Line().Set(input...)
+	c.shell.Cursor().Set(len(input))
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		for range 100 {
+			menu.SetCommandHint("deploy", func(*cobra.Command, []string) string { return "target" })
+		}
+	}()
+
+	for range 100 {
+		if hint := c.commandHint(input); hint != "" && hint != "target" {
+			t.Fatalf("hint = %q", hint)
+		}
+	}
+
+	wg.Wait()
+
+	if hint := c.commandHint(input); hint != "target" {
+		t.Fatalf("hint = %q, want %q", hint, "target")
+	}
+}
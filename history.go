@@ -0,0 +1,31 @@
+package console
+
+// HistoryLines returns the lines of the history source currently used
+// by the shell (in the active menu), from the oldest to the most recent.
+// Note that the values of sensitive flags and arguments are redacted in
+// history lines (see MarkFlagSensitive).
+func (c *Console) HistoryLines() []string {
+	source := c.shell.History.Current()
+	if source == nil {
+		return nil
+	}
+
+	lines := make([]string, 0, source.Len())
+
+	for i := 0; i < source.Len(); i++ {
+		if line, err := source.GetLine(i); err == nil {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines
+}
+
+// PrefillLine sets the input line of the next prompt, so that the user
+// can edit it before executing it. The line is not written to the history
+// sources unless it is accepted.
+func (c *Console) PrefillLine(line string) {
+	c.shell.Line().Set([]rune(line)...)
+	c.shell.Cursor().Set(len([]rune(line)))
+	c.shell.History.Accept(true, true, errRestoredLine)
+}
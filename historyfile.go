@@ -0,0 +1,277 @@
+package console
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/reeflective/readline"
+)
+
+var (
+	errHistoryIndex     = errors.New("history index out of range")
+	errHistoryCompacted = errors.New("the current history source cannot be compacted")
+)
+
+// historyEntry is a line of history files, in the readline file format.
+type historyEntry struct {
+	DateTime time.Time `json:"datetime"`
+	Block    string    `json:"block"`
+}
+
+// historyFile is a history source reading from and writing to a file, in the same
+// format as readline.NewHistoryFromFile. The file is only indexed (the position of
+// each line found) when the history is first used, and its lines are only decoded
+// when they are, so that large histories (100k+ lines) are loaded instantly.
+type historyFile struct {
+	path    string
+	loaded  bool
+	data    []byte   // The file contents when indexed.
+	offsets []int    // Start and end offsets of each line in data.
+	lines   []string // Decoded lines, and lines written since indexing.
+	decoded []bool   // Lines already decoded.
+	mutex   sync.Mutex
+}
+
+// newHistoryFile returns a history source for a file, not read yet.
+func newHistoryFile(path string) *historyFile {
+	return &historyFile{path: path}
+}
+
+// load indexes the history file, if not done yet.
+func (h *historyFile) load() {
+	if h.loaded {
+		return
+	}
+
+	h.loaded = true
+
+	data, err := os.ReadFile(h.path)
+	if err != nil {
+		return
+	}
+
+	h.data = data
+
+	for start := 0; start < len(data); {
+		end := bytes.IndexByte(data[start:], '\n')
+		if end < 0 {
+			end = len(data)
+		} else {
+			end += start
+		}
+
+		// Like readline, skip entries without command lines.
+		entry := bytes.TrimSpace(data[start:end])
+		if len(entry) > 0 && !bytes.Contains(entry, []byte(`"block":""`)) {
+			h.offsets = append(h.offsets, start, end)
+		}
+
+		start = end + 1
+	}
+
+	h.lines = make([]string, len(h.offsets)/2)
+	h.decoded = make([]bool, len(h.lines))
+}
+
+// Write appends a line to the history file, unless it is the same as the last one.
+func (h *historyFile) Write(line string) (int, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.load()
+
+	block := strings.TrimSpace(line)
+	if block == "" {
+		return len(h.lines), nil
+	}
+
+	if last := len(h.lines) - 1; last >= 0 && h.line(last) == block {
+		return len(h.lines), nil
+	}
+
+	data, err := json.Marshal(historyEntry{DateTime: time.Now(), Block: block})
+	if err != nil {
+		return len(h.lines), err
+	}
+
+	h.lines = append(h.lines, block)
+	h.decoded = append(h.decoded, true)
+
+	file, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return len(h.lines), fmt.Errorf("failed to open history file: %w", err)
+	}
+
+	_, err = file.Write(append(data, '\n'))
+	file.Close()
+
+	return len(h.lines), err
+}
+
+// GetLine returns a line of the history, decoding it if needed.
+func (h *historyFile) GetLine(pos int) (string, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.load()
+
+	if pos < 0 || pos >= len(h.lines) {
+		return "", errHistoryIndex
+	}
+
+	return h.line(pos), nil
+}
+
+// Len returns the number of lines in the history.
+func (h *historyFile) Len() int {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.load()
+
+	return len(h.lines)
+}
+
+// Dump returns all the lines of the history.
+func (h *historyFile) Dump() any {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.load()
+
+	lines := make([]string, len(h.lines))
+	for i := range h.lines {
+		lines[i] = h.line(i)
+	}
+
+	return lines
+}
+
+// line returns a line of the history, decoding it from the file data if needed.
+func (h *historyFile) line(pos int) string {
+	if h.decoded[pos] {
+		return h.lines[pos]
+	}
+
+	var entry historyEntry
+
+	if err := json.Unmarshal(h.data[h.offsets[pos*2]:h.offsets[pos*2+1]], &entry); err == nil {
+		h.lines[pos] = entry.Block
+	}
+
+	h.decoded[pos] = true
+
+	return h.lines[pos]
+}
+
+// compact rewrites the history file with only the most recent occurrence of each
+// line, and returns the number of lines removed.
+func (h *historyFile) compact() (removed int, err error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	removed, err = CompactHistoryFile(h.path)
+	if err != nil {
+		return removed, err
+	}
+
+	// Index the compacted file instead.
+	h.loaded, h.data, h.offsets, h.lines, h.decoded = false, nil, nil, nil, nil
+
+	return removed, nil
+}
+
+// CompactHistoryFile rewrites a history file (as written by the console history
+// sources, see Menu.AddHistorySourceFile) with only the most recent occurrence of
+// each command line, keeping their order, and returns the number of lines removed.
+// The file is replaced atomically, so that it is never left partially written.
+func CompactHistoryFile(path string) (removed int, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var entries [][]byte
+
+	blocks := make(map[string]int) // Index of the last entry, by command line.
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+
+	for scanner.Scan() {
+		var entry historyEntry
+
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil || entry.Block == "" {
+			removed++
+			continue
+		}
+
+		if _, found := blocks[entry.Block]; found {
+			removed++
+		}
+
+		blocks[entry.Block] = len(entries)
+		entries = append(entries, append([]byte(nil), scanner.Bytes()...))
+	}
+
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+
+	var compacted bytes.Buffer
+
+	for i, entry := range entries {
+		var decoded historyEntry
+		_ = json.Unmarshal(entry, &decoded)
+
+		if blocks[decoded.Block] == i {
+			compacted.Write(append(entry, '\n'))
+		}
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return 0, err
+	}
+
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.Write(compacted.Bytes()); err != nil {
+		temp.Close()
+		return 0, err
+	}
+
+	if err := temp.Close(); err != nil {
+		return 0, err
+	}
+
+	if info, err := os.Stat(path); err == nil {
+		_ = os.Chmod(temp.Name(), info.Mode())
+	}
+
+	return removed, os.Rename(temp.Name(), path)
+}
+
+// CompactHistory compacts the history file of the history source currently used
+// by the shell (in the active menu), so that it only contains the most recent
+// occurrence of each command line, and returns the number of lines removed.
+// Only sources added with Menu.AddHistorySourceFile can be compacted.
+func (c *Console) CompactHistory() (removed int, err error) {
+	source, ok := c.shell.History.Current().(*historyFile)
+	if !ok {
+		return 0, errHistoryCompacted
+	}
+
+	return source.compact()
+}
+
+// ensure the file history satisfies the readline history interface.
+var _ readline.History = (*historyFile)(nil)
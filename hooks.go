@@ -0,0 +1,88 @@
+package console
+
+import (
+	"errors"
+	"fmt"
+)
+
+// OnStart registers hooks run when the console starts, before the first prompt
+// (and before the logo is printed), for instance to connect to a server or load
+// some state. Hooks are run in the order they have been registered: if one of
+// them fails, the following ones are not run, the exit hooks are, and the error
+// is returned by Start (or StartStream), without reading any command line.
+func (c *Console) OnStart(hooks ...func() error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.startHooks = append(c.startHooks, hooks...)
+}
+
+// OnExit registers hooks run when the console stops: when exiting with Console.Exit
+// (eg. with the exit command), when the process is terminated (SIGTERM) while the user
+// is typing a command, or when Start (or StartStream) returns, eg. at the end of the
+// input in line mode. Hooks are run in the reverse order of their registration, like
+// deferred calls, so that resources are released in the reverse order of their setup.
+// All hooks are run even if some fail, and their errors are returned together.
+//
+// Exit hooks are run once per start, after the ShutdownHooks of the console.
+func (c *Console) OnExit(hooks ...func() error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.exitHooks = append(c.exitHooks, hooks...)
+}
+
+// runSession runs the start hooks, then the console until it returns,
+// then the exit hooks, and returns the errors of all of them, if any.
+func (c *Console) runSession(run func() error) error {
+	c.mutex.Lock()
+	c.exitHooksRun = false
+	hooks := append([]func() error{}, c.startHooks...)
+	c.mutex.Unlock()
+
+	for _, hook := range hooks {
+		if err := hook(); err != nil {
+			return errors.Join(fmt.Errorf("start error: %w", err), c.runExitHooks())
+		}
+	}
+
+	err := run()
+
+	return errors.Join(err, c.runExitHooks())
+}
+
+// runExitHooks runs the shutdown hooks in order, then the exit hooks
+// in reverse order, unless they have already been run since the start.
+func (c *Console) runExitHooks() error {
+	c.mutex.Lock()
+
+	if c.exitHooksRun {
+		c.mutex.Unlock()
+		return nil
+	}
+
+	c.exitHooksRun = true
+	hooks := append(append([]func() error{}, c.ShutdownHooks...), reversed(c.exitHooks)...)
+	c.mutex.Unlock()
+
+	var errs []error
+
+	for _, hook := range hooks {
+		if err := hook(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// reversed returns a copy of the hooks, in reverse order.
+func reversed(hooks []func() error) []func() error {
+	reversed := make([]func() error, 0, len(hooks))
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		reversed = append(reversed, hooks[i])
+	}
+
+	return reversed
+}
@@ -0,0 +1,93 @@
+package console
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// Terminal control sequences for hyperlinks (OSC 8).
+var (
+	seqHyperlinkFmt = "\x1b]8;;%s\x1b\\"
+	seqHyperlinkEnd = "\x1b]8;;\x1b\\"
+)
+
+// hyperlinks caches the detection of hyperlinks support by the terminal.
+var hyperlinks = sync.OnceValue(detectHyperlinks)
+
+func init() {
+	// Command help and usage templates can use {{hyperlink .URL "text"}}.
+	cobra.AddTemplateFunc("hyperlink", Hyperlink)
+}
+
+// Hyperlink returns a clickable hyperlink to url, displayed as text, if the terminal
+// supports hyperlinks (OSC 8). Otherwise, the text is returned followed by the url
+// in parenthesis (or only the url if text is empty or is the url itself). This can
+// be used in command output, hints, help strings and templates, where it is also
+// available as the "hyperlink" function (eg. {{hyperlink "https://example.com" "docs"}}).
+//
+// Note that text/tabwriter does not know that the link sequences have no width:
+// links in tables should be placed in their last column, so as to keep alignment.
+func Hyperlink(url, text string) string {
+	if !HyperlinksSupported() {
+		if text == "" || text == url {
+			return url
+		}
+
+		return text + " (" + url + ")"
+	}
+
+	if text == "" {
+		text = url
+	}
+
+	return fmt.Sprintf(seqHyperlinkFmt, url) + text + seqHyperlinkEnd
+}
+
+// HyperlinksSupported returns true if the standard output is a terminal supporting
+// hyperlinks (OSC 8). The support is guessed from the environment of well-known
+// terminal emulators, and can be forced with FORCE_HYPERLINK=1 (or disabled with 0).
+func HyperlinksSupported() bool {
+	return hyperlinks()
+}
+
+// detectHyperlinks guesses if the terminal supports hyperlinks from its environment.
+func detectHyperlinks() bool {
+	if force, found := os.LookupEnv("FORCE_HYPERLINK"); found {
+		enabled, err := strconv.ParseBool(force)
+		return err == nil && enabled
+	}
+
+	if !term.IsTerminal(int(os.Stdout.Fd())) || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+
+	switch os.Getenv("TERM_PROGRAM") {
+	case "iTerm.app", "WezTerm", "vscode", "Hyper", "ghostty", "tabby", "rio":
+		return true
+	}
+
+	for _, env := range []string{"KITTY_WINDOW_ID", "WT_SESSION", "KONSOLE_VERSION", "DOMTERM", "WEZTERM_EXECUTABLE"} {
+		if os.Getenv(env) != "" {
+			return true
+		}
+	}
+
+	// VTE-based terminals (GNOME Terminal, Tilix...) support them since 0.50.
+	if version, err := strconv.Atoi(os.Getenv("VTE_VERSION")); err == nil && version >= 5000 {
+		return true
+	}
+
+	for _, name := range []string{"kitty", "alacritty", "foot", "ghostty", "wezterm"} {
+		if strings.Contains(os.Getenv("TERM"), name) {
+			return true
+		}
+	}
+
+	return false
+}
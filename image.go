@@ -0,0 +1,249 @@
+package console
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"strings"
+
+	// Decoders for the most common formats, for sixel encoding.
+	_ "image/gif"
+	_ "image/jpeg"
+)
+
+// ImageProtocol is a protocol used by terminals to display inline images.
+type ImageProtocol int
+
+const (
+	ImageNone   ImageProtocol = iota // The terminal cannot display images.
+	ImageKitty                       // The kitty graphics protocol (kitty, ghostty, WezTerm...).
+	ImageITerm2                      // The iTerm2 inline images protocol (iTerm2, WezTerm, mintty...).
+	ImageSixel                       // Sixel graphics (foot, mlterm, xterm -ti vt340, Windows Terminal...).
+)
+
+// ErrImagesUnsupported is returned when printing an image in a terminal
+// that does not support any of the inline image protocols.
+var ErrImagesUnsupported = errors.New("images are not supported by the terminal")
+
+// maxSixelWidth is the maximum width (in pixels) of images encoded as sixels:
+// wider images are scaled down, since sixels are not scaled by terminals.
+const maxSixelWidth = 1024
+
+// kittyChunkSize is the maximum size of the base64 payload of kitty graphics commands.
+const kittyChunkSize = 4096
+
+// DetectImageProtocol returns the inline image protocol supported by the terminal,
+// as guessed from the environment of well-known terminal emulators, or ImageNone.
+func DetectImageProtocol() ImageProtocol {
+	termName := os.Getenv("TERM")
+	program := os.Getenv("TERM_PROGRAM")
+
+	switch {
+	case termName == "dumb":
+		return ImageNone
+	case termName == "xterm-kitty", os.Getenv("KITTY_WINDOW_ID") != "", program == "ghostty":
+		return ImageKitty
+	case program == "iTerm.app", program == "WezTerm", program == "mintty", os.Getenv("LC_TERMINAL") == "iTerm2":
+		return ImageITerm2
+	case os.Getenv("WT_SESSION") != "":
+		return ImageSixel
+	}
+
+	for _, name := range []string{"foot", "mlterm", "yaft", "sixel", "contour"} {
+		if strings.Contains(termName, name) {
+			return ImageSixel
+		}
+	}
+
+	return ImageNone
+}
+
+// PrintImage displays an image (PNG, JPEG or GIF) inline, in the console standard
+// output, with the protocol supported by the terminal (see DetectImageProtocol):
+// for instance to show QR codes, graphs or screenshots from within commands.
+// It returns ErrImagesUnsupported if the terminal cannot display images, so
+// that the caller can fall back to another representation if it wants.
+func (c *Console) PrintImage(r io.Reader) error {
+	protocol := DetectImageProtocol()
+	if protocol == ImageNone {
+		return ErrImagesUnsupported
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+
+	switch protocol {
+	case ImageKitty:
+		err = printKitty(out, data)
+	case ImageITerm2:
+		printITerm2(out, data)
+	case ImageSixel:
+		err = printSixel(out, data)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	out.WriteString("\n")
+
+	return out.Flush()
+}
+
+// printKitty transmits and displays a PNG image with the kitty graphics protocol.
+// Images in other formats are converted to PNG first.
+func printKitty(out io.Writer, data []byte) error {
+	if !bytes.HasPrefix(data, []byte("\x89PNG")) {
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			return err
+		}
+
+		data = buf.Bytes()
+	}
+
+	payload := base64.StdEncoding.EncodeToString(data)
+
+	for first := true; len(payload) > 0; first = false {
+		chunk := payload[:min(kittyChunkSize, len(payload))]
+		payload = payload[len(chunk):]
+
+		more := 0
+		if len(payload) > 0 {
+			more = 1
+		}
+
+		if first {
+			fmt.Fprintf(out, "\x1b_Gf=100,a=T,m=%d;%s\x1b\\", more, chunk)
+		} else {
+			fmt.Fprintf(out, "\x1b_Gm=%d;%s\x1b\\", more, chunk)
+		}
+	}
+
+	return nil
+}
+
+// printITerm2 displays an image with the iTerm2 inline images protocol,
+// which decodes all common formats itself.
+func printITerm2(out io.Writer, data []byte) {
+	fmt.Fprintf(out, "\x1b]1337;File=inline=1;size=%d;preserveAspectRatio=1:%s\a",
+		len(data), base64.StdEncoding.EncodeToString(data))
+}
+
+// printSixel decodes an image and displays it as sixels, with a 216 colors
+// palette (6 levels per channel). Transparent pixels are left untouched.
+func printSixel(out io.Writer, data []byte) error {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	// Scale the image down (nearest neighbour) if too wide.
+	scale := 1.0
+	if width > maxSixelWidth {
+		scale = float64(width) / maxSixelWidth
+		width, height = maxSixelWidth, int(float64(height)/scale)
+	}
+
+	// Map all pixels to the palette, -1 being transparent.
+	indexes := make([]int, width*height)
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			src := img.At(bounds.Min.X+int(float64(x)*scale), bounds.Min.Y+int(float64(y)*scale))
+			rgba := color.NRGBAModel.Convert(src).(color.NRGBA)
+
+			index := -1
+			if rgba.A >= 128 {
+				index = int(rgba.R)*6/256*36 + int(rgba.G)*6/256*6 + int(rgba.B)*6/256
+			}
+
+			indexes[y*width+x] = index
+		}
+	}
+
+	// Start the sixel sequence with a transparent background, and the palette.
+	fmt.Fprintf(out, "\x1bP0;1;0q\"1;1;%d;%d", width, height)
+
+	for i := 0; i < 216; i++ {
+		fmt.Fprintf(out, "#%d;2;%d;%d;%d", i, i/36*100/5, i/6%6*100/5, i%6*100/5)
+	}
+
+	for band := 0; band < height; band += 6 {
+		// Compute the sixels of each color used in this band of six rows.
+		rows := make(map[int][]byte)
+		var colors []int
+
+		for y := band; y < min(band+6, height); y++ {
+			for x := 0; x < width; x++ {
+				index := indexes[y*width+x]
+				if index < 0 {
+					continue
+				}
+
+				if rows[index] == nil {
+					rows[index] = make([]byte, width)
+					colors = append(colors, index)
+				}
+
+				rows[index][x] |= 1 << (y - band)
+			}
+		}
+
+		// Print a row of sixels for each color, going back to
+		// the start of the band each time, except for the last.
+		for i, index := range colors {
+			fmt.Fprintf(out, "#%d", index)
+			writeSixels(out, rows[index])
+
+			if i < len(colors)-1 {
+				io.WriteString(out, "$")
+			}
+		}
+
+		io.WriteString(out, "-")
+	}
+
+	io.WriteString(out, "\x1b\\")
+
+	return nil
+}
+
+// writeSixels writes a row of sixels, run-length encoded.
+func writeSixels(out io.Writer, bits []byte) {
+	for x := 0; x < len(bits); {
+		run := 1
+		for x+run < len(bits) && bits[x+run] == bits[x] {
+			run++
+		}
+
+		char := rune(bits[x] + '?')
+
+		if run > 3 {
+			fmt.Fprintf(out, "!%d%c", run, char)
+		} else {
+			io.WriteString(out, strings.Repeat(string(char), run))
+		}
+
+		x += run
+	}
+}
@@ -0,0 +1,118 @@
+package console
+
+import (
+	"bytes"
+	"os"
+	"regexp"
+	"sync"
+
+	"github.com/reeflective/readline"
+	"github.com/reeflective/readline/inputrc"
+)
+
+// Matches `$if app=name` conditionals in inputrc files.
+var appConditional = regexp.MustCompile(`(?m)^(\s*\$if\s+)app=`)
+
+// readInputrc reads an inputrc file and rewrites all `$if app=name` conditionals
+// into the `$if name` form understood by the readline inputrc parser. This allows
+// several applications embedding a console to keep distinct settings and binds
+// in the same, shared inputrc file, with conditionals such as:
+//
+//	$if app=mytool
+//	    set editing-mode vi
+//	$endif
+func readInputrc(name string) ([]byte, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return data, err
+	}
+
+	if !bytes.Contains(data, []byte("app=")) {
+		return data, nil
+	}
+
+	return appConditional.ReplaceAll(data, []byte("${1}")), nil
+}
+
+// loadInputrc (re)loads the user inputrc configuration, with support for
+// application-scoped conditionals. Any included file is read the same way.
+func (c *Console) loadInputrc() error {
+	c.shell.Config.ReadFileFunc = readInputrc
+	return c.shell.Keymap.ReloadConfig(c.shell.Opts...)
+}
+
+// consoles are the consoles created, by shell (see DefaultBinds).
+var consoles sync.Map
+
+// DefaultBinds returns the binds set by the console using the shell on top of the
+// inputrc files (eg. Alt-E inserting command examples), by keymap, so that they can
+// be told apart from the binds of the user (eg. `bind --diff` does not list them).
+// Binds later changed by the user are still returned with their default command.
+func DefaultBinds(shell *readline.Shell) map[string]map[string]inputrc.Bind {
+	value, found := consoles.Load(shell)
+	if !found {
+		return nil
+	}
+
+	c := value.(*Console)
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	binds := make(map[string]map[string]inputrc.Bind, len(c.binds))
+
+	for keymap, seqs := range c.binds {
+		binds[keymap] = make(map[string]inputrc.Bind, len(seqs))
+
+		for seq, bind := range seqs {
+			binds[keymap][seq] = bind
+		}
+	}
+
+	return binds
+}
+
+// bindDefault binds the key sequence to a command in a keymap, and
+// records it as a default bind of the console (see DefaultBinds).
+func (c *Console) bindDefault(keymap, seq, command string) {
+	bind := inputrc.Bind{Action: command}
+
+	if c.shell.Config.Binds[keymap] == nil {
+		c.shell.Config.Binds[keymap] = make(map[string]inputrc.Bind)
+	}
+
+	c.shell.Config.Binds[keymap][seq] = bind
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.binds[keymap] == nil {
+		c.binds[keymap] = make(map[string]inputrc.Bind)
+	}
+
+	c.binds[keymap][seq] = bind
+}
+
+// boundByUser returns true if the key sequence is bound in the keymap to
+// something else than the given console command (and than self-insert).
+func (c *Console) boundByUser(keymap, seq, command string) bool {
+	bind, found := c.shell.Config.Binds[keymap][seq]
+
+	return found && bind.Action != command && bind.Action != "self-insert"
+}
+
+// setupInitFile wraps the readline re-read-init-file command, so that the options
+// and binds of the console are applied again on top of the reloaded inputrc files.
+func (c *Console) setupInitFile() {
+	reread := c.shell.Keymap.Commands()["re-read-init-file"]
+	if reread == nil {
+		return
+	}
+
+	c.shell.Keymap.Register(map[string]func(){
+		"re-read-init-file": func() {
+			reread()
+			c.applyShellConfig()
+		},
+	})
+}
@@ -0,0 +1,31 @@
+package console
+
+import (
+	"testing"
+)
+
+func TestDefaultBinds(t *testing.T) {
+	c := New("test")
+
+	if bind := DefaultBinds(c.shell)["emacs"][seqMouseReport]; bind.Action != "mouse-event" {
+		t.Fatalf("default bind = %q, want mouse-event", bind.Action)
+	}
+
+	if binds := DefaultBinds(New("other").shell); binds["vi-insert"] == nil {
+		t.Fatal("no default binds for a second console")
+	}
+}
+
+func TestReReadInitFileKeepsDefaultBinds(t *testing.T) {
+	t.Setenv("INPUTRC", t.TempDir()+"/inputrc")
+
+	c := New("test")
+
+	delete(c.shell.Config.Binds["emacs"], seqMouseReport)
+
+	c.shell.Keymap.Commands()["re-read-init-file"]()
+
+	if bind := c.shell.Config.Binds["emacs"][seqMouseReport]; bind.Action != "mouse-event" {
+		t.Fatalf("bind after reload = %q, want mouse-event", bind.Action)
+	}
+}
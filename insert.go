@@ -0,0 +1,150 @@
+package console
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/kballard/go-shellquote"
+)
+
+// maxLastOutput is the number of bytes kept from the output of the last
+// command, when it is kept (see Console.KeepLastOutput).
+const maxLastOutput = 64 * 1024
+
+// insertState is the word last inserted by insert-last-arg or insert-last-output,
+// so as to replace it with the previous argument (or the next output line) when
+// the command is called again, before the line is edited.
+type insertState struct {
+	command string // Command which inserted the word.
+	index   int    // History entry or output line of the word.
+	start   int    // Start of the word in the input line.
+	line    string // Input line once the word was inserted.
+	cursor  int    // Cursor position once the word was inserted.
+}
+
+// headBuffer is a buffer keeping the first bytes written to it, up to its size.
+type headBuffer struct {
+	bytes.Buffer
+	size int
+}
+
+// Write keeps the bytes fitting in the buffer, and discards the others.
+func (b *headBuffer) Write(data []byte) (int, error) {
+	if room := b.size - b.Len(); room > 0 {
+		b.Buffer.Write(data[:min(room, len(data))])
+	}
+
+	return len(data), nil
+}
+
+// setupInsertCommands registers the insert-last-arg and insert-last-output commands.
+// The first one also replaces the readline insert-last-argument and yank-last-arg
+// commands (which do not walk back the history when repeated), and is thus bound
+// to Alt-. in the emacs keymaps, like them.
+func (c *Console) setupInsertCommands() {
+	c.shell.Keymap.Register(map[string]func(){
+		"insert-last-arg":      c.insertLastArg,
+		"insert-last-argument": c.insertLastArg,
+		"yank-last-arg":        c.insertLastArg,
+		"insert-last-output":   c.insertLastOutput,
+	})
+}
+
+// insertLastArg inserts the last argument of the previous history entry at the cursor.
+// When called again, the argument inserted is replaced with the one of the entry before.
+func (c *Console) insertLastArg() {
+	history := c.shell.History.Current()
+	if history == nil {
+		return
+	}
+
+	index := history.Len()
+	if c.repeatsInsert("insert-last-arg") {
+		index = c.lastInsert.index
+	}
+
+	for index--; index >= 0; index-- {
+		entry, err := history.GetLine(index)
+		if err != nil {
+			continue
+		}
+
+		words, remain, err := split(entry, false)
+		if err != nil && remain != "" {
+			words = append(words, remain)
+		}
+
+		if len(words) > 0 {
+			c.insertWord("insert-last-arg", index, words[len(words)-1])
+			return
+		}
+	}
+}
+
+// insertLastOutput inserts the first line of the output of the last command at
+// the cursor, if it is kept (see Console.KeepLastOutput). When called again, the
+// line inserted is replaced with the next one.
+func (c *Console) insertLastOutput() {
+	c.mutex.RLock()
+	output := c.lastOutput
+	c.mutex.RUnlock()
+
+	var lines []string
+
+	for _, line := range splitLines(strip(output)) {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	if len(lines) == 0 {
+		c.shell.Hint.SetTemporary(c.Theme().Hint + tr("No output to insert") + reset)
+		return
+	}
+
+	index := 0
+	if c.repeatsInsert("insert-last-output") {
+		index = (c.lastInsert.index + 1) % len(lines)
+	}
+
+	c.insertWord("insert-last-output", index, lines[index])
+}
+
+// repeatsInsert returns true if the word last inserted by the command is still the
+// one before the cursor, the line not having been edited since, so as to replace it.
+func (c *Console) repeatsInsert(command string) bool {
+	last := c.lastInsert
+
+	return last.command == command && last.line == string(*c.shell.Line()) && last.cursor == c.shell.Cursor().Pos()
+}
+
+// insertWord inserts a word at the cursor (or in place of the word inserted by the same
+// command before), quoted as a single shell word, and copies it to the kill buffer, so
+// that it can be pasted again with yank (or in the active vim register).
+func (c *Console) insertWord(command string, index int, word string) {
+	line := c.shell.Line()
+	cursor := c.shell.Cursor()
+
+	start, end := cursor.Pos(), cursor.Pos()
+	if c.repeatsInsert(command) {
+		start = c.lastInsert.start
+	}
+
+	quoted := []rune(shellquote.Join(word))
+
+	c.shell.History.Save()
+
+	runes := append(append(append([]rune{}, (*line)[:start]...), quoted...), (*line)[end:]...)
+	line.Set(runes...)
+	cursor.Set(start + len(quoted))
+
+	c.shell.Buffers.Write([]rune(word)...)
+
+	c.lastInsert = insertState{
+		command: command,
+		index:   index,
+		start:   start,
+		line:    string(runes),
+		cursor:  cursor.Pos(),
+	}
+}
@@ -0,0 +1,40 @@
+package console
+
+import (
+	"testing"
+)
+
+func TestInsertLastArg(t *testing.T) {
+	c := New("test")
+
+	history := c.shell.History.Current()
+	history.Write("echo one")
+	history.Write("echo two 'three four'")
+
+	insert := c.shell.Keymap.Commands()["insert-last-argument"]
+
+	insert()
+
+	if line := string(*c.shell.Line()); line != "'three four'" {
+		t.Fatalf("line = %q, want %q", line, "'three four'")
+	}
+
+	insert()
+
+	if line := string(*c.shell.Line()); line != "one" {
+		t.Fatalf("line after repeating = %q, want %q", line, "one")
+	}
+}
+
+func TestInsertLastArgBinds(t *testing.T) {
+	c := New("test")
+
+	// Alt-. is Esc followed by a dot, bound in the emacs Meta keymap.
+	if bind := c.shell.Config.Binds["emacs-meta"]["."]; bind.Action != "insert-last-argument" {
+		t.Errorf("emacs Alt-. = %q, want insert-last-argument", bind.Action)
+	}
+
+	if bind, found := c.shell.Config.Binds["vi-insert"]["\x1b."]; found {
+		t.Errorf("Esc-. is bound to %q in vi insert mode", bind.Action)
+	}
+}
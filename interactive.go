@@ -0,0 +1,80 @@
+package console
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+
+	"golang.org/x/term"
+)
+
+// Terminal control sequences used around full-screen programs.
+var (
+	seqAltScreenEnter = "\x1b[?1049h"
+	seqAltScreenLeave = "\x1b[?1049l"
+	seqMouseOff       = "\x1b[?1000l\x1b[?1002l\x1b[?1003l\x1b[?1006l"
+)
+
+// RunInteractive runs an external, interactive program (ssh, vim, a database
+// client, etc) and hands it the control of the terminal until it exits.
+// On Unix systems, the program is attached to a pseudo-terminal, to which the
+// console forwards the user input (including control characters like Ctrl-C,
+// which are thus handled by the program and not the console) and window size
+// changes. The terminal state is restored once the program has exited.
+//
+// This function should be called from within a command being executed (the
+// readline loop being suspended), and blocks until the program exits.
+// The returned error is the one returned by the program, if any.
+func (c *Console) RunInteractive(cmd *exec.Cmd) error {
+	c.mutex.Lock()
+	c.interactive = true
+	c.mutex.Unlock()
+
+	defer func() {
+		c.mutex.Lock()
+		c.interactive = false
+		c.mutex.Unlock()
+	}()
+
+	return runInteractive(cmd)
+}
+
+// EnterAltScreen switches the terminal to the alternate screen, and hands its full
+// control to a full-screen program (a bubbletea/tview dashboard, etc), run by fn with
+// the terminal device, to be used for both its input and output. Like RunInteractive,
+// this should be called from within a command being executed, and blocks until fn
+// returns (or panics, in which case the panic is returned as a PanicError).
+//
+// Once done, the main screen is restored as it was before, along with the terminal
+// state, the cursor and mouse modes, so that the console can read input again.
+func (c *Console) EnterAltScreen(fn func(tty *os.File) error) (err error) {
+	c.mutex.Lock()
+	c.interactive = true
+	c.mutex.Unlock()
+
+	tty, closeTTY := openTTY()
+	defer closeTTY()
+
+	stdin := int(os.Stdin.Fd())
+	state, _ := term.GetState(stdin)
+
+	fmt.Print(seqAltScreenEnter)
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = newPanicError(r)
+		}
+
+		if state != nil {
+			_ = term.Restore(stdin, state)
+		}
+
+		fmt.Print(seqMouseOff + seqShowCursor + seqAltScreenLeave)
+
+		c.mutex.Lock()
+		c.interactive = false
+		c.mutex.Unlock()
+	}()
+
+	return fn(tty)
+}
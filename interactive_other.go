@@ -0,0 +1,32 @@
+//go:build !unix
+
+package console
+
+import (
+	"os"
+	"os/exec"
+)
+
+// runInteractive runs the command directly attached to the console
+// standard streams, since pseudo-terminals are not supported here.
+func runInteractive(cmd *exec.Cmd) error {
+	if cmd.Stdin == nil {
+		cmd.Stdin = os.Stdin
+	}
+
+	if cmd.Stdout == nil {
+		cmd.Stdout = os.Stdout
+	}
+
+	if cmd.Stderr == nil {
+		cmd.Stderr = os.Stderr
+	}
+
+	return cmd.Run()
+}
+
+// openTTY returns the standard input, which full-screen programs
+// use as their terminal, since there is no controlling terminal here.
+func openTTY() (tty *os.File, closeTTY func()) {
+	return os.Stdin, func() {}
+}
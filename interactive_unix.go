@@ -0,0 +1,117 @@
+//go:build unix
+
+package console
+
+import (
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"github.com/creack/pty"
+	"golang.org/x/sys/unix"
+	"golang.org/x/term"
+)
+
+// inputPollTimeout is the time (in milliseconds) after which the
+// input forwarding loop checks whether the program has exited.
+const inputPollTimeout = 100
+
+// runInteractive starts the command in a pseudo-terminal and forwards
+// the standard input/output to it until the command exits.
+func runInteractive(cmd *exec.Cmd) error {
+	ptmx, err := pty.Start(cmd)
+	if err != nil {
+		return err
+	}
+	defer ptmx.Close()
+
+	// Forward terminal window size changes.
+	resize := make(chan os.Signal, 1)
+	signal.Notify(resize, syscall.SIGWINCH)
+
+	defer func() {
+		signal.Stop(resize)
+		close(resize)
+	}()
+
+	go func() {
+		for range resize {
+			pty.InheritSize(os.Stdin, ptmx)
+		}
+	}()
+	resize <- syscall.SIGWINCH
+
+	// All input keys must be passed as is to the program.
+	stdin := int(os.Stdin.Fd())
+
+	if term.IsTerminal(stdin) {
+		state, err := term.MakeRaw(stdin)
+		if err != nil {
+			return err
+		}
+
+		defer term.Restore(stdin, state)
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go forwardInput(stdin, ptmx, done)
+
+	// Reading the pty fails with EIO once the program has exited.
+	io.Copy(os.Stdout, ptmx)
+
+	return cmd.Wait()
+}
+
+// forwardInput copies the standard input to the pseudo-terminal until done is closed.
+// The input is polled so that no read is left pending (and no key of the user stolen
+// from the console shell) once the program has exited.
+func forwardInput(stdin int, ptmx *os.File, done <-chan struct{}) {
+	buf := make([]byte, 1024)
+	fds := []unix.PollFd{{Fd: int32(stdin), Events: unix.POLLIN}}
+
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+
+		ready, err := unix.Poll(fds, inputPollTimeout)
+		if errors.Is(err, unix.EINTR) {
+			continue
+		}
+
+		if err != nil {
+			return
+		}
+
+		if ready == 0 || fds[0].Revents&unix.POLLIN == 0 {
+			continue
+		}
+
+		read, err := unix.Read(stdin, buf)
+		if err != nil || read == 0 {
+			return
+		}
+
+		if _, err := ptmx.Write(buf[:read]); err != nil {
+			return
+		}
+	}
+}
+
+// openTTY opens the controlling terminal of the process, or
+// returns the standard input if there is no such terminal.
+func openTTY() (tty *os.File, closeTTY func()) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return os.Stdin, func() {}
+	}
+
+	return tty, func() { tty.Close() }
+}
@@ -9,15 +9,15 @@ package console
 // Many will want to use this to switch menus. Note that these interrupt errors only
 // work when the console is NOT currently executing a command, only when reading input.
 func (m *Menu) AddInterrupt(err error, handler func(c *Console)) {
-	m.mutex.RLock()
+	m.mutex.Lock()
 	m.interruptHandlers[err] = handler
-	m.mutex.RUnlock()
+	m.mutex.Unlock()
 }
 
 // DelInterrupt removes one or more interrupt handlers from the menu registered ones.
 // If no error is passed as argument, all handlers are removed.
 func (m *Menu) DelInterrupt(errs ...error) {
-	m.mutex.RLock()
+	m.mutex.Lock()
 	if len(errs) == 0 {
 		m.interruptHandlers = make(map[error]func(c *Console))
 	} else {
@@ -25,18 +25,18 @@ func (m *Menu) DelInterrupt(errs ...error) {
 			delete(m.interruptHandlers, err)
 		}
 	}
-	m.mutex.RUnlock()
+	m.mutex.Unlock()
 }
 
 func (m *Menu) handleInterrupt(err error) {
-	m.console.mutex.RLock()
+	m.console.mutex.Lock()
 	m.console.isExecuting = true
-	m.console.mutex.RUnlock()
+	m.console.mutex.Unlock()
 
 	defer func() {
-		m.console.mutex.RLock()
+		m.console.mutex.Lock()
 		m.console.isExecuting = false
-		m.console.mutex.RUnlock()
+		m.console.mutex.Unlock()
 	}()
 
 	// TODO: this is not a very, very safe way of comparing
@@ -0,0 +1,117 @@
+package console
+
+import (
+	"sync"
+
+	"github.com/spf13/cobra"
+)
+
+// lazyGroup is a command whose subcommands are built when it is first used.
+type lazyGroup struct {
+	cmd    *cobra.Command
+	build  func() []*cobra.Command
+	tree   *cobraTree // Resets the group command and its subcommands.
+	loaded bool       // The subcommands have been added to the group.
+
+	built sync.Once        // Builds the subcommands once, even when prefetched.
+	cmds  []*cobra.Command // The subcommands built.
+}
+
+// AddLazyGroup adds a command group to the menu, whose subcommands are only built (with
+// the build function) when the group is first completed, executed or asked help for.
+// This is meant for consoles with many commands, or with commands built from remote
+// schemas or plugins, so as to avoid building all of them when starting the console.
+//
+// The group command (its name, aliases, description, etc) is added to the menu root
+// command like those of AddCommands. Since the subcommands are built only once, they
+// are not regenerated after each command line, and their flags are reset instead,
+// like with FromCobra.
+func (m *Menu) AddLazyGroup(group *cobra.Command, build func() []*cobra.Command) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.lazyGroups = append(m.lazyGroups, &lazyGroup{
+		cmd:   group,
+		build: build,
+		tree:  m.console.newCobraTree(group),
+	})
+}
+
+// addLazyGroups resets the lazy group commands (and their subcommands
+// if they have been built), and adds them to the menu root command.
+func (m *Menu) addLazyGroups() {
+	for _, group := range m.lazyGroups {
+		group.tree.reset(group.cmd)
+
+		if group.cmd.Parent() == m.Command {
+			continue
+		}
+
+		if group.cmd.GroupID != "" && !m.Command.ContainsGroup(group.cmd.GroupID) {
+			m.Command.AddGroup(&cobra.Group{ID: group.cmd.GroupID, Title: group.cmd.GroupID})
+		}
+
+		m.Command.AddCommand(group.cmd)
+	}
+}
+
+// loadLazyGroups builds the commands of the lazy group named by the command
+// word of the line (after the root flags, and after `help`), if not built yet.
+// The other words are arguments, which might happen to be named like a group.
+func (m *Menu) loadLazyGroups(words []string) {
+	m.mutex.Lock()
+	groups := m.lazyGroups
+
+	var target *cobra.Command
+
+	if len(groups) > 0 && len(words) > 0 {
+		var args []string
+
+		// Finding commands merges their persistent flags.
+		target, args, _ = m.Command.Find(words)
+
+		if target != nil && target.Parent() == m.Command && target.Name() == "help" {
+			target, _, _ = m.Command.Find(args)
+		}
+	}
+
+	m.mutex.Unlock()
+
+	for _, group := range groups {
+		if group.loaded || group.cmd != target {
+			continue
+		}
+
+		cmds := group.commands()
+		group.cmd.AddCommand(cmds...)
+		group.loaded = true
+
+		// Save the default state of their flags.
+		for _, cmd := range cmds {
+			group.tree.reset(cmd)
+		}
+	}
+}
+
+// PrefetchLazyGroups builds the subcommands of the lazy groups of the menu in the
+// background (see AddLazyGroup), so that they are ready when first completed: this
+// keeps completions responsive when they are built from a remote schema over a slow
+// link. Groups being prefetched when used are waited for, instead of built again.
+func (m *Menu) PrefetchLazyGroups() {
+	m.mutex.RLock()
+	groups := m.lazyGroups
+	m.mutex.RUnlock()
+
+	for _, group := range groups {
+		go group.commands()
+	}
+}
+
+// commands returns the subcommands of the group, built the first time.
+func (g *lazyGroup) commands() []*cobra.Command {
+	g.built.Do(func() {
+		g.cmds = g.build()
+	})
+
+	return g.cmds
+}
@@ -0,0 +1,56 @@
+package console
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestLazyGroupsBuiltInCommandPosition(t *testing.T) {
+	c := New("test")
+	menu := c.ActiveMenu()
+
+	menu.SetCommands(func() *cobra.Command {
+		root := &cobra.Command{}
+		root.PersistentFlags().String("profile", "", "")
+		root.AddCommand(&cobra.Command{Use: "echo", Run: func(*cobra.Command, []string) {}})
+
+		return root
+	})
+
+	builds := 0
+	listed := false
+
+	menu.AddLazyGroup(&cobra.Command{Use: "remote", Aliases: []string{"rm"}}, func() []*cobra.Command {
+		builds++
+
+		return []*cobra.Command{{Use: "list", Run: func(*cobra.Command, []string) { listed = true }}}
+	})
+
+	for _, line := range []string{"echo remote", "echo --profile remote rm"} {
+		if err := menu.RunCommandLine(context.Background(), line); err != nil {
+			t.Fatal(err)
+		}
+
+		if builds != 0 {
+			t.Fatalf("%q builds the lazy group", line)
+		}
+	}
+
+	if err := menu.RunCommandLine(context.Background(), "--profile prod rm list"); err != nil {
+		t.Fatal(err)
+	}
+
+	if builds != 1 || !listed {
+		t.Fatalf("builds = %d, listed = %v, want the group built and its command run", builds, listed)
+	}
+
+	if err := menu.RunCommandLine(context.Background(), "remote list"); err != nil {
+		t.Fatal(err)
+	}
+
+	if builds != 1 {
+		t.Errorf("builds = %d, want the group built once", builds)
+	}
+}
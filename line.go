@@ -25,7 +25,8 @@ var (
 )
 
 // parse is in charge of removing all comments from the input line
-// before execution, and if successfully parsed, split into words.
+// before execution, and if successfully parsed, expanding the console
+// variables it references (see Console.SetVariable), and splitting it into words.
 func (c *Console) parse(line string) (args []string, err error) {
 	lineReader := strings.NewReader(line)
 	parser := syntax.NewParser(syntax.KeepComments(false))
@@ -43,8 +44,8 @@ func (c *Console) parse(line string) (args []string, err error) {
 		return nil, err
 	}
 
-	// Split the line into shell words.
-	return shellquote.Split(parsedLine.String())
+	// Expand references to console variables, and split the line into shell words.
+	return shellquote.Split(c.expandVariables(parsedLine.String()))
 }
 
 // acceptMultiline determines if the line just accepted is complete (in which case
@@ -53,6 +54,7 @@ func (c *Console) acceptMultiline(line []rune) (accept bool) {
 	// Errors are either: unterminated quotes, or unterminated escapes.
 	_, _, err := split(string(line), false)
 	if err == nil {
+		c.displayMasked(line)
 		return true
 	}
 
@@ -0,0 +1,137 @@
+package console
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// lineModeMaxLine is the maximum size of the lines read in line mode.
+const lineModeMaxLine = 1024 * 1024
+
+// streamEchoPrefix precedes the command lines echoed by StartStream.
+const streamEchoPrefix = "> "
+
+// LineMode returns true if the console reads its input in line mode: when its standard
+// input is not a terminal (eg. when commands are piped into the application), or when
+// the terminal is dumb, the console does not use the shell, and reads the command
+// lines on its standard input, one by one, until it is closed. No prompt is printed, no
+// escape sequences are used, and the histories are neither loaded nor written, so that
+// the output of the commands is clean. For the rest, lines are processed like usual
+// (comments, variables, command substitutions, line hooks), and lines ending with an
+// escape, or having unterminated quotes, are continued on the next one.
+func (c *Console) LineMode() bool {
+	return c.lineMode.Load()
+}
+
+// lineModeTerminal returns true if the input should be read in line mode. Only the
+// input matters: when the output is piped (eg. `app | tee log`), the user still types
+// the command lines in the terminal, with the shell.
+func lineModeTerminal() bool {
+	return !term.IsTerminal(int(os.Stdin.Fd())) || os.Getenv("TERM") == "dumb"
+}
+
+// StreamOptions configures how command lines are read and executed by StartStream.
+type StreamOptions struct {
+	// Echo prints each command line (after a "> " prefix) before its output.
+	Echo bool
+
+	// StopOnError stops reading command lines at the first one failing (to be
+	// parsed or executed), whose error is returned. Otherwise, errors are only
+	// handled by the menu error handler, and the following lines are executed.
+	StopOnError bool
+}
+
+// StartStream reads newline-delimited command lines from the input and executes them
+// sequentially in the active menu, in line mode (see LineMode), until the input is
+// closed or the context canceled: for instance, automation pipelines can stream their
+// commands to `app console --stdin` (see HookCobra). Each line is only read once the
+// previous one has been executed, so that producers are slowed down by the console.
+// StartStream returns the error of the failing command if opts.StopOnError is set.
+// Like Start, it runs the start and exit hooks (see OnStart and OnExit).
+func (c *Console) StartStream(ctx context.Context, in io.Reader, opts StreamOptions) error {
+	return c.runSession(func() error {
+		return c.stream(ctx, in, opts)
+	})
+}
+
+// stream reads and executes the command lines of the input, in line mode.
+func (c *Console) stream(ctx context.Context, in io.Reader, opts StreamOptions) error {
+	c.lineMode.Store(true)
+	defer c.lineMode.Store(false)
+
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 4096), lineModeMaxLine)
+
+	var pending []string
+
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		pending = append(pending, scanner.Text())
+		line := strings.Join(pending, "\n")
+
+		// Incomplete lines are continued on the next one.
+		if !lineComplete(line) {
+			continue
+		}
+
+		pending = nil
+
+		if err := c.streamLine(ctx, line, opts); err != nil && opts.StopOnError {
+			return err
+		}
+	}
+
+	// The last line might be incomplete: execute it anyway,
+	// so that it fails with a parsing error if it must.
+	if len(pending) > 0 {
+		if err := c.streamLine(ctx, strings.Join(pending, "\n"), opts); err != nil && opts.StopOnError {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+// streamLine executes a command line read by StartStream, and returns its error.
+func (c *Console) streamLine(ctx context.Context, line string, opts StreamOptions) error {
+	menu := c.activeMenu()
+	menu.resetPreRun()
+
+	if err := c.runAllE(c.PreReadlineHooks); err != nil {
+		err = PreReadError{newError(err, "Pre-read error")}
+		menu.ErrorHandler(err)
+
+		return err
+	}
+
+	if opts.Echo && !c.lineEmpty(line) {
+		fmt.Printf("%s%s\n", streamEchoPrefix, line)
+	}
+
+	_, err := c.executeLine(ctx, line)
+
+	return err
+}
+
+// lineComplete returns false if the line has unterminated quotes, or ends with an escape.
+func lineComplete(line string) bool {
+	_, _, err := split(line, false)
+
+	switch err {
+	case errUnterminatedDoubleQuote, errUnterminatedSingleQuote:
+		return false
+	case errUnterminatedEscape:
+		return !strings.HasSuffix(line, "\\")
+	}
+
+	return true
+}
@@ -0,0 +1,189 @@
+package console
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/spf13/cobra"
+)
+
+// localeTemplateKey is the command annotation storing the usage
+// template of a root command, before its headers are translated.
+const localeTemplateKey = "console-usage-template"
+
+// Translations of the built-in messages, by language, whose keys are the English messages.
+//
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+// locales are the translations used by all consoles of the process.
+var locales = struct {
+	mutex    sync.Mutex
+	catalogs map[string]map[string]string // Translations added with AddTranslations, by tag.
+	active   atomic.Pointer[map[string]string]
+}{
+	catalogs: make(map[string]map[string]string),
+}
+
+// usageHeaders are the headers of the cobra usage template, translated in help messages.
+var usageHeaders = []string{
+	"Usage:",
+	"Aliases:",
+	"Examples:",
+	"Available Commands:",
+	"Additional Commands:",
+	"Flags:",
+	"Global Flags:",
+	"Additional help topics:",
+	`Use "{{.CommandPath}} [command] --help" for more information about a command.`,
+}
+
+// SetLocale sets the language of the built-in messages of the console (errors, hints,
+// questions and help headers), given a language tag like "fr", "de-CH" or "es_ES.UTF-8"
+// (eg. from the LANG environment variable): if a regional variant is not available, its
+// base language is used. French ("fr"), German ("de") and Spanish ("es") translations are
+// embedded, and others can be added with AddTranslations. English ("en", or an empty tag)
+// is the default. Messages without a translation are printed in English.
+//
+// The locale is the one of the process, used by all its consoles, since some messages
+// are printed by package functions (eg. FormatError, or the error messages of flag
+// validators). Messages of commands, like their descriptions, are not translated: this
+// is up to the application.
+func SetLocale(tag string) error {
+	tag = strings.ToLower(strings.ReplaceAll(tag, "_", "-"))
+	tag, _, _ = strings.Cut(tag, ".")
+
+	if tag == "" || tag == "en" || strings.HasPrefix(tag, "en-") || tag == "c" || tag == "posix" {
+		locales.active.Store(nil)
+		clearConsoleCaches()
+
+		return nil
+	}
+
+	base, _, _ := strings.Cut(tag, "-")
+
+	for _, name := range []string{tag, base} {
+		if catalog := loadLocale(name); catalog != nil {
+			locales.active.Store(&catalog)
+			clearConsoleCaches()
+
+			return nil
+		}
+	}
+
+	return fmt.Errorf("no translations for locale: %s", tag)
+}
+
+// AddTranslations adds translations of the built-in messages for a language (eg. "it"),
+// or replaces some of the embedded ones: keys are the English messages, as found in the
+// locales directory of this package, and values their translations. Placeholders (eg.
+// %s) must be kept in the same order. The locale must be set again (see SetLocale) for
+// the translations to be used, if it is already the current one.
+func AddTranslations(tag string, messages map[string]string) {
+	tag = strings.ToLower(strings.ReplaceAll(tag, "_", "-"))
+
+	locales.mutex.Lock()
+	defer locales.mutex.Unlock()
+
+	catalog := locales.catalogs[tag]
+	if catalog == nil {
+		catalog = make(map[string]string, len(messages))
+		locales.catalogs[tag] = catalog
+	}
+
+	for message, translation := range messages {
+		catalog[message] = translation
+	}
+}
+
+// clearConsoleCaches clears the completion and hint caches of all consoles,
+// whose cached messages are in the previous locale.
+func clearConsoleCaches() {
+	consoles.Range(func(_, console any) bool {
+		console.(*Console).clearCaches()
+		return true
+	})
+}
+
+// loadLocale returns the embedded translations of a language, along
+// with those added by applications, or nil if there are none.
+func loadLocale(tag string) map[string]string {
+	catalog := make(map[string]string)
+
+	if data, err := localeFiles.ReadFile("locales/" + tag + ".json"); err == nil {
+		if err := json.Unmarshal(data, &catalog); err != nil {
+			return nil
+		}
+	}
+
+	locales.mutex.Lock()
+	for message, translation := range locales.catalogs[tag] {
+		catalog[message] = translation
+	}
+	locales.mutex.Unlock()
+
+	if len(catalog) == 0 {
+		return nil
+	}
+
+	return catalog
+}
+
+// tr returns the translation of a message in the current locale, if any.
+func tr(message string) string {
+	catalog := locales.active.Load()
+	if catalog == nil {
+		return message
+	}
+
+	if translation := (*catalog)[message]; translation != "" {
+		return translation
+	}
+
+	return message
+}
+
+// localizedError is an error whose message is translated, wrapping
+// the error with the English message, so that errors.Is still works.
+type localizedError struct {
+	message string
+	err     error
+}
+
+// newLocalizedError returns an error wrapping err, with a message
+// formatted from the translation of the format (in English).
+func newLocalizedError(err error, format string, args ...any) error {
+	return localizedError{message: fmt.Sprintf(tr(format), args...), err: err}
+}
+
+func (e localizedError) Error() string { return e.message }
+func (e localizedError) Unwrap() error { return e.err }
+
+// localizeUsage translates the headers of the usage template of a root command,
+// from its original template, so that the locale can be changed at any time.
+func localizeUsage(root *cobra.Command) {
+	if root.Annotations == nil {
+		root.Annotations = make(map[string]string)
+	}
+
+	template, saved := root.Annotations[localeTemplateKey]
+	if !saved {
+		template = root.UsageTemplate()
+		root.Annotations[localeTemplateKey] = template
+	}
+
+	if locales.active.Load() != nil {
+		replacements := make([]string, 0, 2*len(usageHeaders))
+		for _, header := range usageHeaders {
+			replacements = append(replacements, header, tr(header))
+		}
+
+		template = strings.NewReplacer(replacements...).Replace(template)
+	}
+
+	root.SetUsageTemplate(template)
+}
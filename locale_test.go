@@ -0,0 +1,101 @@
+package console
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// setLocale sets the locale of the process for a test.
+func setLocale(t *testing.T, tag string) {
+	t.Helper()
+
+	if err := SetLocale(tag); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { SetLocale("") })
+}
+
+func TestSetLocaleVariants(t *testing.T) {
+	setLocale(t, "de_CH.UTF-8")
+
+	if message := tr("unknown command"); message != "unbekannter Befehl" {
+		t.Errorf("unknown command is not translated in German: %q", message)
+	}
+
+	if err := SetLocale("xx"); err == nil {
+		t.Error("no error for a locale without translations")
+	}
+}
+
+func TestLocalizedMessages(t *testing.T) {
+	setLocale(t, "fr")
+
+	c := New("test")
+
+	if err := c.SetTheme("neon"); err == nil || err.Error() != "thème inconnu : neon" {
+		t.Errorf("theme error = %v", err)
+	}
+
+	parse := ParseError{newError(errUnterminatedSingleQuote, "Parsing error")}
+	if message := parse.Error(); message != "Erreur d'analyse: chaîne entre apostrophes non terminée" {
+		t.Errorf("parse error = %q", message)
+	}
+
+	if !errors.Is(parse, errUnterminatedSingleQuote) {
+		t.Error("the translated parse error does not wrap the original one")
+	}
+
+	var out bytes.Buffer
+
+	PrintFanOut(&out, []FanOutResult{{Target: "web-1"}, {Target: "web-2", Err: errors.New("down")}})
+
+	for _, message := range []string{"CIBLE", "échec", "2 cibles, 1 réussies, 1 en échec"} {
+		if !strings.Contains(out.String(), message) {
+			t.Errorf("fan-out summary without %q:\n%s", message, out.String())
+		}
+	}
+}
+
+func TestAddTranslations(t *testing.T) {
+	AddTranslations("it", map[string]string{"unknown command": "comando sconosciuto"})
+	setLocale(t, "it-IT")
+
+	if message := tr("unknown command"); message != "comando sconosciuto" {
+		t.Errorf("unknown command translated as %q", message)
+	}
+}
+
+func TestRunAnnotatedCommand(t *testing.T) {
+	c := New("test")
+	menu := c.ActiveMenu()
+
+	menu.SetCommands(func() *cobra.Command {
+		root := &cobra.Command{}
+
+		// The root command is annotated with its usage template.
+		annotated := &cobra.Command{Use: "annotated", Run: func(*cobra.Command, []string) {}}
+		annotated.Annotations = map[string]string{"key": "value"}
+		root.AddCommand(annotated)
+
+		return root
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- menu.RunCommandLine(context.Background(), "annotated") }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("running an annotated command blocks")
+	}
+}
@@ -0,0 +1,241 @@
+package console
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/term"
+)
+
+// Terminal control sequences used to divide the screen in two regions.
+var (
+	seqSaveCursor        = "\x1b7"
+	seqRestoreCursor     = "\x1b8"
+	seqClearScreen       = "\x1b[2J"
+	seqCursorPosFmt      = "\x1b[%d;%dH"
+	seqScrollRegionFmt   = "\x1b[%d;%dr"
+	seqResetScrollRegion = "\x1b[r"
+	seqClearLineAfter    = "\x1b[0K"
+)
+
+// logPanel is the top region of the screen when it is split in two regions:
+// asynchronous messages are printed in it, and the prompt in the bottom one.
+type logPanel struct {
+	rows    int          // Rows requested for the panel (half the screen if zero).
+	height  int          // Rows used by the panel, given the current terminal size.
+	partial bytes.Buffer // The last line written, if not terminated yet.
+	lines   []string     // Lines written, waiting to be printed by the input loop.
+	mutex   sync.Mutex
+}
+
+// EnableLogPanel divides the screen in two regions: the bottom one hosts the prompt,
+// the input line and the output of commands, and the top one, which is rows high
+// (or half of the screen if rows is zero or negative), is a scrolling log panel.
+// Once enabled, all asynchronous messages (printed with the console or menu Printf
+// and TransientPrintf functions, Notify or the LogPanel writer) are printed in the
+// panel, so that they never interleave with the line being typed, even visually.
+//
+// The screen is cleared when enabling the panel, and when the terminal is resized.
+func (c *Console) EnableLogPanel(rows int) {
+	c.mutex.Lock()
+	if c.logPanel == nil {
+		c.logPanel = &logPanel{}
+	}
+
+	panel := c.logPanel
+	c.logPanelRows = rows
+	c.mutex.Unlock()
+
+	panel.mutex.Lock()
+	panel.rows = rows
+	panel.mutex.Unlock()
+
+	c.runOnLoop(func() {
+		panel.mutex.Lock()
+		panel.layout()
+		panel.mutex.Unlock()
+
+		c.refreshLogPanel()
+	})
+}
+
+// DisableLogPanel restores the terminal as a single screen region, keeping the
+// prompt at the bottom of the screen. The panel is automatically disabled when
+// exiting the application with Console.Exit.
+func (c *Console) DisableLogPanel() {
+	c.mutex.Lock()
+	panel := c.logPanel
+	c.logPanel = nil
+	c.mutex.Unlock()
+
+	if panel == nil {
+		return
+	}
+
+	// The terminal is restored immediately, since it may be exiting.
+	panel.mutex.Lock()
+	defer panel.mutex.Unlock()
+
+	if panel.partial.Len() > 0 {
+		panel.lines = append(panel.lines, panel.partial.String())
+		panel.partial.Reset()
+	}
+
+	panel.printLines()
+
+	fmt.Print(seqSaveCursor + seqResetScrollRegion + seqRestoreCursor)
+}
+
+// LogPanel returns a writer printing to the log panel, to which loggers can write
+// their events. If the log panel is not enabled, the output is printed like Printf
+// (TransientPrintf) does, above the prompt. Lines are printed once terminated.
+func (c *Console) LogPanel() io.Writer {
+	return logPanelWriter{console: c}
+}
+
+// logPanelWriter writes to the log panel, or above the prompt if no panel is enabled.
+type logPanelWriter struct {
+	console *Console
+}
+
+// Write implements io.Writer.
+func (w logPanelWriter) Write(data []byte) (int, error) {
+	if !w.console.printLogPanel(string(data)) {
+		return w.console.TransientPrintf("%s", data)
+	}
+
+	w.console.publishLog(string(data))
+
+	return len(data), nil
+}
+
+// printLogPanel prints a message in the log panel, if it is enabled,
+// and returns true: otherwise it returns false and does nothing.
+func (c *Console) printLogPanel(msg string) bool {
+	c.mutex.RLock()
+	panel := c.logPanel
+	c.mutex.RUnlock()
+
+	if panel == nil {
+		return false
+	}
+
+	panel.mutex.Lock()
+
+	panel.partial.WriteString(msg)
+
+	text := panel.partial.String()
+	end := strings.LastIndex(text, "\n")
+
+	if end < 0 {
+		panel.mutex.Unlock()
+		return true
+	}
+
+	panel.partial.Reset()
+	panel.partial.WriteString(text[end+1:])
+
+	queued := len(panel.lines) > 0
+	panel.lines = append(panel.lines, strings.Split(text[:end], "\n")...)
+
+	panel.mutex.Unlock()
+
+	// The lines are printed by the input loop, since
+	// the cursor is moved to the panel and back meanwhile.
+	if !queued {
+		c.runOnLoop(func() {
+			panel.mutex.Lock()
+			defer panel.mutex.Unlock()
+
+			panel.printLines()
+		})
+	}
+
+	return true
+}
+
+// relayoutLogPanel clears the screen and divides it again, when the terminal
+// has been resized. It returns false if the log panel is not enabled.
+func (c *Console) relayoutLogPanel() bool {
+	c.mutex.RLock()
+	panel := c.logPanel
+	c.mutex.RUnlock()
+
+	if panel == nil {
+		return false
+	}
+
+	panel.mutex.Lock()
+	panel.layout()
+	panel.mutex.Unlock()
+
+	return true
+}
+
+// refreshLogPanel redraws the prompt and the input line at the bottom of the
+// screen, if the user is currently typing: otherwise the console will print the
+// prompt there anyway, once the current command has returned.
+func (c *Console) refreshLogPanel() {
+	c.mutex.RLock()
+	reading := c.reading && !c.isExecuting
+	c.mutex.RUnlock()
+
+	if !reading {
+		return
+	}
+
+	c.shell.Display.PrintPrimaryPrompt()
+	c.shell.Display.Refresh()
+}
+
+// layout clears the screen, draws the separator below the log panel,
+// and restricts scrolling to the bottom region, at the end of which the
+// cursor is moved.
+func (p *logPanel) layout() {
+	width, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || height < 4 {
+		width, height = 80, 24
+	}
+
+	p.height = p.rows
+	if p.height <= 0 {
+		p.height = height / 2
+	}
+
+	p.height = min(p.height, height-3)
+
+	fmt.Print(seqResetScrollRegion + seqClearScreen)
+	fmt.Printf(seqCursorPosFmt, p.height+1, 1)
+	fmt.Print(dim + strings.Repeat("─", width) + reset)
+	fmt.Printf(seqScrollRegionFmt, p.height+2, height)
+	fmt.Printf(seqCursorPosFmt, height, 1)
+}
+
+// printLines prints the lines written to the panel, with the panel locked.
+func (p *logPanel) printLines() {
+	for _, line := range p.lines {
+		p.printLine(line)
+	}
+
+	p.lines = nil
+}
+
+// printLine prints a line at the bottom of the log panel, after scrolling it up.
+// The scrolling region is then restored, and the cursor put back where it was.
+func (p *logPanel) printLine(line string) {
+	_, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || height < 4 {
+		height = 24
+	}
+
+	fmt.Print(seqSaveCursor)
+	fmt.Printf(seqScrollRegionFmt, 1, p.height)
+	fmt.Printf(seqCursorPosFmt, p.height, 1)
+	fmt.Print("\n\r" + strings.TrimSuffix(line, "\r") + reset + seqClearLineAfter)
+	fmt.Printf(seqScrollRegionFmt, p.height+2, height)
+	fmt.Print(seqRestoreCursor)
+}
@@ -0,0 +1,201 @@
+package console
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// Terminal device status report: the console asks the terminal for its status
+// to wake the input loop up, since the shell reads the reply like keys.
+var (
+	seqStatusQuery = "\x1b[5n"
+	seqStatusReply = "\x1b[0n"
+)
+
+// statusTimeout is the time the terminal has to reply to a status query: past it,
+// the terminal is assumed to ignore them, and the functions queued are run directly.
+var statusTimeout = 250 * time.Millisecond
+
+// loopKeymaps are the keymaps in which the terminal status reply is read: all of them,
+// since the reply ESC would otherwise cancel the local keymaps (eg. isearch, vi-opp).
+var loopKeymaps = []string{
+	"emacs", "emacs-standard", "vi-insert", "vi-command", "vi-move",
+	"vi-visual", "vi-opp", "isearch", "menu-select",
+}
+
+// loopKey is the context key marking the commands executed by the input loop,
+// which runs the functions queued while they execute.
+type loopKey struct{}
+
+// inputLoop holds the functions queued for the input loop (see Console.runOnLoop).
+type inputLoop struct {
+	running bool          // The input loop reads and executes command lines.
+	pending []func()      // Functions waiting to be run by the loop, in order.
+	queried bool          // The terminal status was asked, and its reply not read yet.
+	queries int           // Number of status queries, identifying the last one.
+	direct  bool          // The terminal is not asked for its status: functions are run directly.
+	wake    chan struct{} // Notified when a function is queued, for the loop waiting for a command.
+}
+
+// setupLoop registers the command running the functions queued for the input
+// loop, and binds it to the terminal status reply in all keymaps.
+func (c *Console) setupLoop() {
+	c.shell.Keymap.Register(map[string]func(){
+		"run-pending": c.statusReplied,
+	})
+
+	for _, keymap := range loopKeymaps {
+		c.bindDefault(keymap, seqStatusReply, "run-pending")
+	}
+}
+
+// runOnLoop runs a function on the goroutine of the input loop, which is the only one
+// allowed to use the shell display (eg. to print messages, redraw the prompt or reload
+// the configuration). While reading user input, the loop is woken up by asking for the
+// terminal status, whose reply is read by the shell and bound to run the function. While
+// executing a command, the function is run by the loop waiting for it, and otherwise
+// before reading the next line. Without an input loop (eg. before the console starts,
+// in line mode, or when driven with ExecuteLine), the function is run immediately, as
+// it is while reading if the standard output is not the terminal, or if the terminal
+// does not reply to status queries (until it replies to one).
+func (c *Console) runOnLoop(fn func()) {
+	c.mutex.Lock()
+
+	if !c.loop.running {
+		c.mutex.Unlock()
+		fn()
+
+		return
+	}
+
+	c.loop.pending = append(c.loop.pending, fn)
+	wake := c.wakeReading()
+
+	c.mutex.Unlock()
+
+	select {
+	case c.loop.wake <- struct{}{}:
+	default:
+	}
+
+	if wake != nil {
+		wake()
+	}
+}
+
+// wakeReading returns the function waking the reading shell up to run the functions queued,
+// to call with the console unlocked: it asks for the terminal status (unless already asked),
+// or runs them directly if the terminal is not asked. It returns nil if the shell is not
+// reading, or has nothing to run. It is called with the console locked.
+func (c *Console) wakeReading() func() {
+	switch {
+	case len(c.loop.pending) == 0 || !c.reading || c.isExecuting:
+		return nil
+	case c.loop.direct:
+		return c.runPending
+	case c.loop.queried:
+		return nil
+	}
+
+	c.loop.queried = true
+	c.loop.queries++
+	query := c.loop.queries
+
+	return func() {
+		fmt.Print(seqStatusQuery)
+		time.AfterFunc(statusTimeout, func() { c.statusUnanswered(query) })
+	}
+}
+
+// statusUnanswered runs the functions queued directly if the terminal has not replied to
+// a status query, and runs those queued next directly too, since it ignores the queries.
+func (c *Console) statusUnanswered(query int) {
+	c.mutex.Lock()
+
+	unanswered := c.loop.queried && c.loop.queries == query
+	if unanswered {
+		c.loop.queried, c.loop.direct = false, true
+	}
+
+	wake := c.wakeReading()
+
+	c.mutex.Unlock()
+
+	if unanswered && wake != nil {
+		wake()
+	}
+}
+
+// statusReplied runs the functions queued for the input loop, once the shell
+// has read the reply of the terminal to the status query, which is asked again.
+func (c *Console) statusReplied() {
+	c.mutex.Lock()
+	c.loop.direct = false
+	c.mutex.Unlock()
+
+	// The reply is no motion for a pending vi operator, which still waits for one.
+	if c.shell.Keymap.Local() == "vi-opp" {
+		c.shell.Keymap.Pending()
+		c.shell.Keymap.CancelPending()
+	}
+
+	c.runPending()
+}
+
+// runPending runs the functions queued for the input loop, in order.
+// Functions queued by them are run the next time the loop is woken up.
+func (c *Console) runPending() {
+	c.mutex.Lock()
+	pending := c.loop.pending
+	c.loop.pending = nil
+	c.loop.queried = false
+	c.mutex.Unlock()
+
+	for _, fn := range pending {
+		fn()
+	}
+}
+
+// startLoop marks the input loop as running, until the returned function is called.
+// The terminal is not asked for its status if it does not read the output.
+func (c *Console) startLoop(ctx context.Context) (context.Context, func()) {
+	c.mutex.Lock()
+	c.loop.running = true
+	c.loop.direct = !term.IsTerminal(int(os.Stdout.Fd()))
+	c.mutex.Unlock()
+
+	return context.WithValue(ctx, loopKey{}, true), func() {
+		c.mutex.Lock()
+		c.loop.running = false
+		c.mutex.Unlock()
+
+		c.runPending()
+	}
+}
+
+// loopWake returns the channel notified when functions are queued for the input
+// loop, if the command executed in this context is executed by the loop, or nil.
+func (c *Console) loopWake(ctx context.Context) <-chan struct{} {
+	if onLoop, _ := ctx.Value(loopKey{}).(bool); !onLoop {
+		return nil
+	}
+
+	return c.loop.wake
+}
+
+// refreshReading redraws the prompt and the input line if the shell is reading
+// user input, so that the changes made by a function run on the loop are shown.
+func (c *Console) refreshReading() {
+	c.mutex.RLock()
+	reading := c.reading && !c.isExecuting
+	c.mutex.RUnlock()
+
+	if reading {
+		c.redrawPrompt()
+		c.shell.Display.Refresh()
+	}
+}
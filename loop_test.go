@@ -0,0 +1,197 @@
+package console
+
+import (
+	"context"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func TestRunOnLoop(t *testing.T) {
+	c := New("test")
+
+	var ran []int
+
+	// Without an input loop, functions are run immediately.
+	c.runOnLoop(func() { ran = append(ran, 0) })
+
+	if !slices.Equal(ran, []int{0}) {
+		t.Fatalf("ran %v without a loop, want run immediately", ran)
+	}
+
+	_, stop := c.startLoop(context.Background())
+	defer stop()
+
+	// The standard output of the tests might not be a terminal.
+	c.mutex.Lock()
+	c.reading = true
+	c.loop.direct = false
+	c.mutex.Unlock()
+
+	output := captureStdout(t, func() {
+		c.runOnLoop(func() { ran = append(ran, 1) })
+		c.runOnLoop(func() { ran = append(ran, 2) })
+	})
+
+	if len(ran) != 1 {
+		t.Fatalf("ran %v while reading, want queued", ran)
+	}
+
+	if output != seqStatusQuery {
+		t.Errorf("output %q while reading, want a single status query", output)
+	}
+
+	for _, keymap := range loopKeymaps {
+		if bind := c.shell.Config.Binds[keymap][seqStatusReply]; bind.Action != "run-pending" {
+			t.Fatalf("status reply bound to %q in %s, want run-pending", bind.Action, keymap)
+		}
+	}
+
+	// The shell reads the reply of the terminal.
+	c.shell.Keymap.Commands()["run-pending"]()
+
+	if !slices.Equal(ran, []int{0, 1, 2}) || c.loop.queried {
+		t.Errorf("ran %v (queried %t), want the queued functions run in order", ran, c.loop.queried)
+	}
+}
+
+func TestRunOnLoopWithoutTerminal(t *testing.T) {
+	c := New("test")
+
+	var ran []int
+
+	output := captureStdout(t, func() {
+		_, stop := c.startLoop(context.Background())
+		defer stop()
+
+		c.mutex.Lock()
+		c.reading = true
+		c.mutex.Unlock()
+
+		c.runOnLoop(func() { ran = append(ran, 1) })
+	})
+
+	// The terminal cannot be asked for its status.
+	if !slices.Equal(ran, []int{1}) || output != "" {
+		t.Errorf("ran %v (printed %q) while reading, want run immediately", ran, output)
+	}
+}
+
+func TestRunOnLoopUnanswered(t *testing.T) {
+	c := New("test")
+	_, stop := c.startLoop(context.Background())
+
+	defer stop()
+
+	c.mutex.Lock()
+	c.reading = true
+	c.loop.direct = false
+	c.mutex.Unlock()
+
+	timeout := statusTimeout
+	statusTimeout = time.Millisecond
+
+	defer func() { statusTimeout = timeout }()
+
+	ran := make(chan int, 2)
+
+	captureStdout(t, func() {
+		c.runOnLoop(func() { ran <- 1 })
+	})
+
+	// The terminal does not reply to the status query.
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Fatal("function not run without a reply to the status query")
+	}
+
+	// Functions are then run directly, until the terminal replies.
+	if output := captureStdout(t, func() { c.runOnLoop(func() { ran <- 2 }) }); output != "" || len(ran) != 1 {
+		t.Errorf("function queued (printed %q) after an unanswered query, want run directly", output)
+	}
+
+	c.shell.Keymap.Commands()["run-pending"]()
+
+	if output := captureStdout(t, func() { c.runOnLoop(func() {}) }); output != seqStatusQuery {
+		t.Errorf("output %q after a reply to the status query, want the terminal asked again", output)
+	}
+}
+
+func TestPrintfReading(t *testing.T) {
+	c := New("test")
+
+	output := captureStdout(t, func() {
+		// The output is not a terminal, which is not asked for its status.
+		_, stop := c.startLoop(context.Background())
+		defer stop()
+
+		c.mutex.Lock()
+		c.reading = true
+		c.mutex.Unlock()
+
+		c.Printf("message\n")
+	})
+
+	if !strings.Contains(output, "message") || strings.Contains(output, seqStatusQuery) {
+		t.Errorf("printed %q while reading, want the message printed right away", output)
+	}
+}
+
+func TestRunOnLoopExecuting(t *testing.T) {
+	c := New("test")
+	ctx, stop := c.startLoop(context.Background())
+
+	defer stop()
+
+	ran := make(chan bool, 1)
+
+	menu := c.ActiveMenu()
+	menu.SetCommands(func() *cobra.Command {
+		root := &cobra.Command{}
+		root.AddCommand(&cobra.Command{
+			Use: "wait",
+			Run: func(*cobra.Command, []string) {
+				c.runOnLoop(func() { ran <- true })
+
+				// The loop waiting for the command runs the function meanwhile.
+				if !<-ran {
+					t.Error("function not run while executing")
+				}
+			},
+		})
+
+		return root
+	})
+
+	if err := menu.RunCommandArgs(ctx, []string{"wait"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// captureStdout returns what the function printed to the standard output.
+func captureStdout(t *testing.T, print func()) string {
+	t.Helper()
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdout := os.Stdout
+	os.Stdout = writer
+
+	print()
+
+	os.Stdout = stdout
+	writer.Close()
+
+	output, _ := io.ReadAll(reader)
+
+	return string(output)
+}
@@ -0,0 +1,357 @@
+// Package lua embeds a Lua runtime (gopher-lua) in console applications, so that
+// end users can extend them with scripts, without recompiling the application.
+//
+// Scripts use the global console table to register commands, hooks and prompt
+// segments:
+//
+//	console.command{
+//	    name = "greet",
+//	    short = "Greet someone",
+//	    flags = { { name = "greeting", short = "g", usage = "The greeting", default = "Hello" } },
+//	    run = function(args, flags) print(flags.greeting .. " " .. args[1]) end,
+//	    complete = function(args) return { "alice", "bob" } end,
+//	}
+//
+//	console.on("post_run", function() end)
+//	console.prompt_segment(function() return "[lua] " end)
+//	console.run("greet alice")
+//
+// Command functions can return an error message, or raise a Lua error, to report
+// a failure. Commands are added to the "scripts" group of the active menu when the
+// script is loaded, unless another menu is given with the menu field.
+package lua
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/spf13/cobra"
+	lua "github.com/yuin/gopher-lua"
+
+	"github.com/reeflective/console"
+)
+
+// GroupID is the command group in which script commands are added.
+const GroupID = "scripts"
+
+// Runtime is a Lua interpreter bound to a console application. Scripts share
+// the same interpreter state, and all calls into it are serialized.
+type Runtime struct {
+	app   *console.Console
+	state *lua.LState
+	mutex sync.Mutex
+}
+
+// New creates a new Lua runtime for the console application,
+// with the console API table available to scripts.
+func New(app *console.Console) *Runtime {
+	runtime := &Runtime{
+		app:   app,
+		state: lua.NewState(),
+	}
+
+	api := runtime.state.NewTable()
+
+	runtime.state.SetFuncs(api, map[string]lua.LGFunction{
+		"command":        runtime.registerCommand,
+		"on":             runtime.registerHook,
+		"prompt_segment": runtime.registerPromptSegment,
+		"run":            runtime.runLine,
+		"menu":           runtime.menuName,
+	})
+
+	runtime.state.SetGlobal("console", api)
+
+	return runtime
+}
+
+// LoadFile executes a Lua script.
+func (r *Runtime) LoadFile(path string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if err := r.state.DoFile(path); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	return nil
+}
+
+// LoadDir executes all the Lua scripts (*.lua files) of a directory, in lexical
+// order. A missing directory is not an error, and all scripts are loaded even if
+// some of them fail: their errors are returned together.
+func (r *Runtime) LoadDir(dir string) error {
+	if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	scripts, err := filepath.Glob(filepath.Join(dir, "*.lua"))
+	if err != nil {
+		return err
+	}
+
+	sort.Strings(scripts)
+
+	var errs []error
+
+	for _, script := range scripts {
+		if err := r.LoadFile(script); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Close closes the Lua interpreter.
+func (r *Runtime) Close() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.state.Close()
+}
+
+// call calls a Lua function with the given arguments, and returns its first
+// result, or an error if the function raised one or returned an error message.
+// The caller must hold the runtime mutex.
+func (r *Runtime) call(fn *lua.LFunction, args ...lua.LValue) (lua.LValue, error) {
+	if err := r.state.CallByParam(lua.P{Fn: fn, NRet: 1, Protect: true}, args...); err != nil {
+		return lua.LNil, err
+	}
+
+	result := r.state.Get(-1)
+	r.state.Pop(1)
+
+	return result, nil
+}
+
+// callError is like call, but only returns the error message returned by the function, if any.
+func (r *Runtime) callError(fn *lua.LFunction, args ...lua.LValue) error {
+	result, err := r.call(fn, args...)
+	if err != nil {
+		return err
+	}
+
+	if msg, ok := result.(lua.LString); ok && msg != "" {
+		return errors.New(string(msg))
+	}
+
+	return nil
+}
+
+//
+// Console API -----------------------------------------------------
+//
+
+// console.command{name=, short=, long=, aliases=, menu=, flags=, run=, complete=}
+func (r *Runtime) registerCommand(state *lua.LState) int {
+	spec := state.CheckTable(1)
+
+	name := lua.LVAsString(spec.RawGetString("name"))
+	run, _ := spec.RawGetString("run").(*lua.LFunction)
+
+	if name == "" || run == nil {
+		state.ArgError(1, "a command needs a name and a run function")
+		return 0
+	}
+
+	menu := r.app.ActiveMenu()
+	if menuName, ok := spec.RawGetString("menu").(lua.LString); ok {
+		if menu = r.app.Menu(string(menuName)); menu == nil {
+			state.ArgError(1, fmt.Sprintf("unknown menu %q", menuName))
+			return 0
+		}
+	}
+
+	short := lua.LVAsString(spec.RawGetString("short"))
+	long := lua.LVAsString(spec.RawGetString("long"))
+	aliases := stringList(spec.RawGetString("aliases"))
+	flags, _ := spec.RawGetString("flags").(*lua.LTable)
+	complete, _ := spec.RawGetString("complete").(*lua.LFunction)
+
+	menu.AddCommands(func() *cobra.Command {
+		cmd := &cobra.Command{
+			Use:     name,
+			Short:   short,
+			Long:    long,
+			Aliases: aliases,
+			GroupID: GroupID,
+
+			// Errors are reported by the console.
+			SilenceUsage:  true,
+			SilenceErrors: true,
+		}
+
+		var names []string
+
+		if flags != nil {
+			flags.ForEach(func(_, value lua.LValue) {
+				flag, ok := value.(*lua.LTable)
+				if !ok {
+					return
+				}
+
+				flagName := lua.LVAsString(flag.RawGetString("name"))
+				if flagName == "" {
+					return
+				}
+
+				names = append(names, flagName)
+
+				cmd.Flags().StringP(flagName,
+					lua.LVAsString(flag.RawGetString("short")),
+					lua.LVAsString(flag.RawGetString("default")),
+					lua.LVAsString(flag.RawGetString("usage")))
+			})
+		}
+
+		cmd.RunE = func(cmd *cobra.Command, args []string) error {
+			r.mutex.Lock()
+			defer r.mutex.Unlock()
+
+			values := r.state.NewTable()
+
+			for _, flagName := range names {
+				value, _ := cmd.Flags().GetString(flagName)
+				values.RawSetString(flagName, lua.LString(value))
+			}
+
+			return r.callError(run, r.stringTable(args), values)
+		}
+
+		if complete != nil {
+			carapace.Gen(cmd).PositionalAnyCompletion(carapace.ActionCallback(func(ctx carapace.Context) carapace.Action {
+				r.mutex.Lock()
+				defer r.mutex.Unlock()
+
+				result, err := r.call(complete, r.stringTable(ctx.Args))
+				if err != nil {
+					return carapace.ActionMessage(err.Error())
+				}
+
+				return carapace.ActionValues(stringList(result)...)
+			}))
+		}
+
+		return cmd
+	})
+
+	return 0
+}
+
+// console.on(event, fn), with event being one of pre_read, pre_run or post_run.
+func (r *Runtime) registerHook(state *lua.LState) int {
+	event := state.CheckString(1)
+	fn := state.CheckFunction(2)
+
+	hook := func() error {
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+
+		return r.callError(fn)
+	}
+
+	switch event {
+	case "pre_read":
+		r.app.PreReadlineHooks = append(r.app.PreReadlineHooks, hook)
+	case "pre_run":
+		r.app.PreCmdRunHooks = append(r.app.PreCmdRunHooks, hook)
+	case "post_run":
+		r.app.PostCmdRunHooks = append(r.app.PostCmdRunHooks, hook)
+	default:
+		state.ArgError(1, fmt.Sprintf("unknown event %q (pre_read, pre_run or post_run)", event))
+	}
+
+	return 0
+}
+
+// console.prompt_segment(fn), adding the string returned by fn before the primary prompt.
+func (r *Runtime) registerPromptSegment(state *lua.LState) int {
+	fn := state.CheckFunction(1)
+
+	prompt := r.app.ActiveMenu().Prompt()
+	primary := prompt.Primary
+
+	prompt.Primary = func() string {
+		var segment string
+
+		if r.mutex.TryLock() {
+			if result, err := r.call(fn); err == nil {
+				segment = lua.LVAsString(result)
+			}
+
+			r.mutex.Unlock()
+		}
+
+		if primary == nil {
+			return segment
+		}
+
+		return segment + primary()
+	}
+
+	return 0
+}
+
+// console.run(line), returning an error message if the command failed.
+func (r *Runtime) runLine(state *lua.LState) int {
+	line := state.CheckString(1)
+
+	ctx := state.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	// The command may call back into the runtime.
+	r.mutex.Unlock()
+	err := r.app.ActiveMenu().RunCommandLine(ctx, line)
+	r.mutex.Lock()
+
+	if err != nil {
+		state.Push(lua.LString(err.Error()))
+		return 1
+	}
+
+	return 0
+}
+
+// console.menu(), returning the name of the active menu.
+func (r *Runtime) menuName(state *lua.LState) int {
+	state.Push(lua.LString(r.app.ActiveMenu().Name()))
+	return 1
+}
+
+func (r *Runtime) stringTable(values []string) *lua.LTable {
+	table := r.state.NewTable()
+
+	for _, value := range values {
+		table.Append(lua.LString(value))
+	}
+
+	return table
+}
+
+// stringList returns the values of a Lua array, or a single-element list for strings.
+func stringList(value lua.LValue) []string {
+	var list []string
+
+	switch val := value.(type) {
+	case *lua.LTable:
+		val.ForEach(func(_, item lua.LValue) {
+			if str := lua.LVAsString(item); strings.TrimSpace(str) != "" {
+				list = append(list, str)
+			}
+		})
+	case lua.LString:
+		list = append(list, string(val))
+	}
+
+	return list
+}
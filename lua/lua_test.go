@@ -0,0 +1,61 @@
+package lua
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/reeflective/console"
+)
+
+const greetScript = `
+console.command{
+    name = "greet",
+    flags = { { name = "greeting", short = "g", default = "Hello" } },
+    run = function(args, flags) print(flags.greeting .. " " .. args[1]) end,
+}
+
+console.command{
+    name = "fail",
+    run = function() return "no luck" end,
+}
+`
+
+func TestLoadDir(t *testing.T) {
+	dir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(dir, "greet.lua"), []byte(greetScript), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "broken.lua"), []byte("console.command{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	app := console.New("test")
+
+	runtime := New(app)
+	defer runtime.Close()
+
+	// Invalid scripts do not prevent the others from being loaded.
+	if err := runtime.LoadDir(dir); err == nil || !strings.Contains(err.Error(), "broken.lua") {
+		t.Errorf("loading an invalid script returned %v", err)
+	}
+
+	output, err := app.CaptureOutput(func() error {
+		return app.ActiveMenu().RunCommandLine(context.Background(), "greet -g Hi alice")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if output != "Hi alice\n" {
+		t.Errorf("script command output = %q, want %q", output, "Hi alice\n")
+	}
+
+	if err := app.ActiveMenu().RunCommandLine(context.Background(), "fail"); err == nil || err.Error() != "no luck" {
+		t.Errorf("failing script command returned %v", err)
+	}
+}
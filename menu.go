@@ -12,6 +12,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/reeflective/readline"
+	"github.com/reeflective/readline/inputrc"
 )
 
 // Menu - A menu is a simple way to seggregate commands based on
@@ -31,6 +32,35 @@ type Menu struct {
 	// If not set, the error is printed to the console on os.Stderr.
 	ErrorHandler ErrorHandler
 
+	// DryRun enables the dry-run mode for this menu only (see Console.DryRun).
+	DryRun bool
+
+	// ExitBehavior defines whether exiting this menu (with the exit commands or
+	// Console.RequestExit) quits the application (the default), or only leaves
+	// the menu, switching back to the main one.
+	ExitBehavior ExitBehavior
+
+	// AutoPairs enables the automatic insertion of closing quotes and brackets
+	// when typing opening ones (except inside quoted strings), and highlights
+	// the pair under the cursor in the input line. This is false by default.
+	AutoPairs bool
+
+	// InputMode overrides the console editing mode while this menu is active:
+	// ModeEmacs, ModeViInsert or ModeViNormal, for instance for a "raw shell" menu
+	// using emacs bindings in an otherwise vim-mode console. The console mode is
+	// restored when leaving the menu. Empty (the default) keeps the console mode.
+	// Keybindings and options can also be overridden with SetBind and SetOption.
+	InputMode InputMode
+
+	// IncrementalCommands keeps the command tree of the menu between command lines,
+	// instead of regenerating it before each of them (and after each completion),
+	// which is costly for trees with hundreds of commands. The flags of the kept
+	// commands are reset instead, like with FromCobra. The commands functions
+	// (see SetCommands) are only called again after InvalidateCommands, while
+	// those of AddCommands are called once, when they are added. Only the commands
+	// (or groups) named to InvalidateCommands are then replaced in the kept tree.
+	IncrementalCommands bool
+
 	// Input/output channels
 	out *bytes.Buffer
 
@@ -42,14 +72,36 @@ type Menu struct {
 	*cobra.Command
 
 	// Command spawner
-	cmds Commands
+	cmds        Commands
+	partialCmds PartialCommands  // Builds some of the commands of cmds (see SetPartialCommands).
+	extraCmds   []Commands       // Commands added at runtime (scripts, plugins).
+	extraBuilt  []*cobra.Command // Commands built by extraCmds, kept with IncrementalCommands.
+	lazyGroups  []*lazyGroup     // Command groups whose commands are built on first use.
+	tree        *cobraTree       // Resets the command tree kept with IncrementalCommands.
+	dirty       bool             // The kept command tree must be regenerated.
+	invalid     map[string]bool  // Root commands and groups of the kept tree to regenerate.
 
 	// An error template to use to produce errors when a command is unavailable.
 	errFilteredTemplate string
 
+	// Dynamic hint providers, by command path (and flag).
+	hints map[string]HintProvider
+
+	// Completion preview providers, by command path (and flag).
+	previews map[string]PreviewProvider
+
+	// Values used for the command arguments and flags, ordering their candidates.
+	usage *usageStore
+
+	// Keybindings (by keymap and sequence) and readline options
+	// overriding the console ones while this menu is active.
+	binds   map[string]map[string]inputrc.Bind
+	options map[string]any
+
 	// History sources peculiar to this menu.
 	historyNames []string
 	histories    map[string]readline.History
+	unredacted   map[string]string // Lines accepted in this session, by redacted line.
 
 	// Concurrency management
 	mutex *sync.RWMutex
@@ -60,12 +112,18 @@ func newMenu(name string, console *Console) *Menu {
 		console:           console,
 		name:              name,
 		prompt:            newPrompt(console),
-		Command:           &cobra.Command{},
+		Command:           &cobra.Command{SilenceErrors: true},
 		out:               bytes.NewBuffer(nil),
 		interruptHandlers: make(map[error]func(c *Console)),
 		histories:         make(map[string]readline.History),
+		unredacted:        make(map[string]string),
+		hints:             make(map[string]HintProvider),
+		previews:          make(map[string]PreviewProvider),
+		usage:             &usageStore{},
+		binds:             make(map[string]map[string]inputrc.Bind),
+		options:           make(map[string]any),
 		mutex:             &sync.RWMutex{},
-		ErrorHandler:      defaultErrorHandler,
+		ErrorHandler:      console.defaultErrorHandler,
 	}
 
 	// Add a default in memory history to each menu
@@ -93,8 +151,8 @@ func (m *Menu) Prompt() *Prompt {
 // AddHistorySource adds a source of history commands that will
 // be accessible to the shell when the menu is active.
 func (m *Menu) AddHistorySource(name string, source readline.History) {
-	m.mutex.RLock()
-	defer m.mutex.RUnlock()
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 
 	if len(m.histories) == 1 && m.historyNames[0] == m.defaultHistoryName() {
 		delete(m.histories, m.defaultHistoryName())
@@ -107,10 +165,12 @@ func (m *Menu) AddHistorySource(name string, source readline.History) {
 
 // AddHistorySourceFile adds a new source of history populated from and writing
 // to the specified "filepath" parameter. On the first call to this function,
-// the default in-memory history source is removed.
+// the default in-memory history source is removed. The file is only read when
+// the history is first used, and its lines when they are (eg. when searched),
+// so that large histories load instantly. See Console.CompactHistory.
 func (m *Menu) AddHistorySourceFile(name string, filepath string) {
-	m.mutex.RLock()
-	defer m.mutex.RUnlock()
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 
 	if len(m.histories) == 1 && m.historyNames[0] == m.defaultHistoryName() {
 		delete(m.histories, m.defaultHistoryName())
@@ -118,7 +178,7 @@ func (m *Menu) AddHistorySourceFile(name string, filepath string) {
 	}
 
 	m.historyNames = append(m.historyNames, name)
-	m.histories[name], _ = readline.NewHistoryFromFile(filepath)
+	m.histories[name] = newHistoryFile(filepath)
 }
 
 // DeleteHistorySource removes a history source from the menu.
@@ -201,7 +261,8 @@ func (m *Menu) Printf(msg string, args ...any) (n int, err error) {
 
 // CheckIsAvailable checks if a target command is marked as filtered
 // by the console application registered/and or active filters (added
-// with console.Hide/ShowCommand()).
+// with console.Hide/ShowCommand()), or if it is not authorized by the
+// console authorizer, in which case the error wraps ErrUnauthorized.
 // If filtered, returns a template-formatted error message showing the
 // list of incompatible filters. If not filtered, no error is returned.
 func (m *Menu) CheckIsAvailable(cmd *cobra.Command) error {
@@ -209,6 +270,10 @@ func (m *Menu) CheckIsAvailable(cmd *cobra.Command) error {
 		return nil
 	}
 
+	if err := m.console.checkAuthorized(cmd); err != nil {
+		return err
+	}
+
 	filters := m.ActiveFiltersFor(cmd)
 	if len(filters) == 0 {
 		return nil
@@ -230,6 +295,8 @@ func (m *Menu) CheckIsAvailable(cmd *cobra.Command) error {
 
 // ActiveFiltersFor returns all the active menu filters that a given command
 // does not declare as compliant with (added with console.Hide/ShowCommand()).
+// If the command filter annotation is an expression (eg. "windows && admin"),
+// the active filters it uses are returned only if the expression is true.
 func (m *Menu) ActiveFiltersFor(cmd *cobra.Command) []string {
 	if cmd.Annotations == nil {
 		if cmd.HasParent() {
@@ -239,20 +306,10 @@ func (m *Menu) ActiveFiltersFor(cmd *cobra.Command) []string {
 		return nil
 	}
 
+	// Evaluate the filter expression of the command.
 	m.console.mutex.Lock()
-	defer m.console.mutex.Unlock()
-
-	// Get the filters on the command
-	filterStr := cmd.Annotations[CommandFilterKey]
-	var filters []string
-
-	for _, cmdFilter := range strings.Split(filterStr, ",") {
-		for _, filter := range m.console.filters {
-			if cmdFilter != "" && cmdFilter == filter {
-				filters = append(filters, cmdFilter)
-			}
-		}
-	}
+	filters := m.console.matchingFilters(cmd.Annotations[CommandFilterKey])
+	m.console.mutex.Unlock()
 
 	if len(filters) > 0 || !cmd.HasParent() {
 		return filters
@@ -273,25 +330,52 @@ func (m *Menu) SetErrFilteredCommandTemplate(s string) {
 // menu commands, and ensuring that the correct prompt is bound to the shell.
 func (m *Menu) resetPreRun() {
 	m.mutex.Lock()
-	defer m.mutex.Unlock()
 
-	// Commands
-	if m.cmds != nil {
-		m.Command = m.cmds()
+	// Commands, unless they are kept and still valid.
+	if m.dirty || m.tree == nil || !m.IncrementalCommands {
+		if m.cmds != nil {
+			m.Command = m.cmds()
+		}
+
+		m.releaseCommands()
+	} else if len(m.invalid) > 0 {
+		m.regenerateCommands()
 	}
 
 	if m.Command == nil {
 		m.Command = &cobra.Command{
-			Annotations: make(map[string]string),
+			Annotations:   make(map[string]string),
+			SilenceErrors: true,
 		}
 	}
 
+	m.addExtraCommands()
+	m.addLazyGroups()
+
+	if m.IncrementalCommands {
+		if m.tree == nil {
+			m.tree = m.console.newCobraTree(m.Command)
+		}
+
+		m.tree.reset(m.Command)
+	}
+
+	// Help headers in the language of the console.
+	localizeUsage(m.Command)
+	stackedUsage(m.Command)
+
 	// Hide commands that are not available
 	m.hideFilteredCommands(m.Command)
+	m.hideUnauthorizedCommands(m.Command)
 
 	// Menu setup
-	m.resetCmdOutput()             // Reset or adjust any buffered command output.
-	m.prompt.bind(m.console.shell) // Prompt binding
+	m.resetCmdOutput() // Reset or adjust any buffered command output.
+	m.mutex.Unlock()
+
+	// Prompt binding, not while printing asynchronous messages.
+	m.console.printMutex.Lock()
+	m.prompt.bind(m.console.shell)
+	m.console.printMutex.Unlock()
 }
 
 // hide commands that are filtered so that they are not
@@ -352,5 +436,6 @@ func tmpl(w io.Writer, text string, data interface{}) error {
 }
 
 var templateFuncs = template.FuncMap{
-	"trim": strings.TrimSpace,
+	"trim":      strings.TrimSpace,
+	"hyperlink": Hyperlink,
 }
@@ -0,0 +1,90 @@
+package console
+
+import (
+	"unicode"
+)
+
+// InputMode is the name of the editing mode currently used by the shell.
+// It is derived from the main and local keymaps of the readline instance.
+type InputMode string
+
+// These are the input modes reported by the console, which can be used by
+// prompt engines to render a mode indicator segment (INSERT/NORMAL, etc).
+const (
+	ModeEmacs    InputMode = "emacs"     // Any of the emacs keymaps.
+	ModeViInsert InputMode = "vi-insert" // Vim insert mode.
+	ModeViNormal InputMode = "vi-normal" // Vim command (movement) mode.
+	ModeViVisual InputMode = "vi-visual" // Vim visual selection mode.
+	ModeViOpp    InputMode = "vi-opp"    // Vim operator pending mode (after d/c/y).
+)
+
+// InputMode returns the current editing mode of the shell.
+// Local keymaps (visual, operator pending) have precedence over the main one.
+func (c *Console) InputMode() InputMode {
+	switch string(c.shell.Keymap.Local()) {
+	case "vi-visual":
+		return ModeViVisual
+	case "vi-opp":
+		return ModeViOpp
+	}
+
+	switch string(c.shell.Keymap.Main()) {
+	case "vi-insert":
+		return ModeViInsert
+	case "vi", "vi-command", "vi-move":
+		return ModeViNormal
+	default:
+		return ModeEmacs
+	}
+}
+
+// ModeIndicator returns a short, uppercase label for the current input mode,
+// (INSERT, NORMAL, VISUAL, PENDING), or an empty string when using emacs mode.
+// This is meant to be used in prompt functions, for instance the right prompt:
+//
+//	menu.Prompt().Right = func() string { return app.ModeIndicator() }
+func (c *Console) ModeIndicator() string {
+	switch c.InputMode() {
+	case ModeViInsert:
+		return "INSERT"
+	case ModeViNormal:
+		return "NORMAL"
+	case ModeViVisual:
+		return "VISUAL"
+	case ModeViOpp:
+		return "PENDING"
+	default:
+		return ""
+	}
+}
+
+// Register returns the contents of a named Vim register. Valid names are
+// letters (a-z, A-Z), digits (0-9, the ring of the last texts killed or
+// yanked, `"0` being the last one), and the `"` register (the kill buffer).
+// If the register is empty or invalid, an empty string is returned.
+func (c *Console) Register(name rune) string {
+	if name == '"' {
+		name = 0
+	}
+
+	return string(c.shell.Buffers.Get(name))
+}
+
+// SetRegister writes some contents into a named Vim register, making them
+// available to the `"<name>p` and similar commands in vim input mode.
+// Valid names are letters and the `"` register: as in Vim, the uppercase
+// name of a letter register appends to its lowercase counterpart. Digit
+// registers are only filled by the shell, when killing or yanking text:
+// writing the `"` register pushes the contents on top of them.
+func (c *Console) SetRegister(name rune, content string) {
+	switch {
+	case name == '"' || name == 0:
+		c.shell.Buffers.Write([]rune(content)...)
+	case unicode.IsUpper(name):
+		lower := unicode.ToLower(name)
+		appended := append(append([]rune{}, c.shell.Buffers.Get(lower)...), []rune(content)...)
+		c.shell.Buffers.WriteTo(lower, appended...)
+	case unicode.IsLetter(name):
+		c.shell.Buffers.WriteTo(name, []rune(content)...)
+	}
+}
@@ -0,0 +1,210 @@
+package console
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/rivo/uniseg"
+	"golang.org/x/term"
+)
+
+// Terminal control sequences for mouse reporting (clicks and wheel, in SGR format).
+var (
+	seqMouseOn     = "\x1b[?1000h\x1b[?1006h"
+	seqMouseReport = "\x1b[<"
+)
+
+// Mouse buttons, as reported by the terminal.
+const (
+	mouseLeft      = 0
+	mouseWheelUp   = 64
+	mouseWheelDown = 65
+	mouseMotion    = 32 // Added to the button when the mouse is being dragged.
+)
+
+// mouseKeymaps are the keymaps in which mouse reports are handled.
+var mouseKeymaps = []string{"emacs", "emacs-standard", "vi-insert", "vi-command", "vi-move", "menu-select"}
+
+// setupMouse registers the command handling mouse reports, and binds it
+// in all keymaps. Nothing is reported unless Console.Mouse is true.
+func (c *Console) setupMouse() {
+	c.shell.Keymap.Register(map[string]func(){
+		"mouse-event": c.mouseEvent,
+	})
+
+	for _, keymap := range mouseKeymaps {
+		c.bindDefault(keymap, seqMouseReport, "mouse-event")
+	}
+}
+
+// mouseEvent reads the end of a mouse report and handles the event: the wheel
+// walks the history (or the completion candidates when the completion menu is
+// active), and a left click moves the cursor where clicked in the input line.
+// In the completion menu, a click inserts the selected candidate and exits it.
+func (c *Console) mouseEvent() {
+	button, x, y, pressed := c.readMouseReport()
+	if !pressed || button&mouseMotion != 0 {
+		return
+	}
+
+	// The incremental search keymap shares the completion menu
+	// binds: the reports are only consumed, not to be inserted.
+	if c.shell.Keymap.Local() == "isearch" {
+		return
+	}
+
+	commands := c.shell.Keymap.Commands()
+	completing := c.shell.Keymap.Local() == "menu-select"
+
+	run := func(name string) {
+		if command := commands[name]; command != nil {
+			command()
+		}
+	}
+
+	switch {
+	case button == mouseWheelUp && completing:
+		run("menu-complete-backward")
+	case button == mouseWheelDown && completing:
+		run("menu-complete")
+	case button == mouseWheelUp:
+		run("previous-history")
+	case button == mouseWheelDown:
+		run("next-history")
+	case button == mouseLeft && completing:
+		c.acceptCandidate(run)
+	case button == mouseLeft:
+		c.clickLine(x, y)
+	}
+}
+
+// readMouseReport reads the button, coordinates (1-based) and press/release state
+// of a mouse report, of which the "\x1b[<" prefix has already been read.
+func (c *Console) readMouseReport() (button, x, y int, pressed bool) {
+	var report strings.Builder
+
+	for {
+		key, empty := c.shell.Keys.Pop()
+		if empty {
+			rkey, abort := c.shell.Keys.ReadKey()
+			if abort {
+				return -1, 0, 0, false
+			}
+
+			key = byte(rkey)
+		}
+
+		if key == 'M' || key == 'm' {
+			pressed = key == 'M'
+			break
+		}
+
+		report.WriteByte(key)
+	}
+
+	fields := strings.Split(report.String(), ";")
+	if len(fields) != 3 {
+		return -1, 0, 0, false
+	}
+
+	button, _ = strconv.Atoi(fields[0])
+	x, _ = strconv.Atoi(fields[1])
+	y, _ = strconv.Atoi(fields[2])
+
+	return button, x, y, pressed
+}
+
+// acceptCandidate inserts the selected completion candidate and exits the
+// completion menu, like typing any other key would, without changing the
+// editing mode the user is in.
+func (c *Console) acceptCandidate(run func(name string)) {
+	switch c.shell.Keymap.Main() {
+	case "vi-insert":
+		run("vi-movement-mode")
+		run("vi-append-mode")
+	case "vi-command", "vi-move", "vi":
+		run("vi-movement-mode")
+	default:
+		run("emacs-editing-mode")
+	}
+}
+
+// clickLine moves the cursor to the input line character displayed at the
+// given terminal coordinates, given the current coordinates of the cursor.
+// Clicks outside of the input line rows are ignored.
+func (c *Console) clickLine(x, y int) {
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 {
+		return
+	}
+
+	cursorX, cursorY := c.shell.Keys.GetCursorPos()
+	if cursorX < 0 {
+		return
+	}
+
+	line := []rune(*c.shell.Line())
+	pos := min(c.shell.Cursor().Pos(), len(line))
+
+	// The column at which the input line starts, after the prompt.
+	offset := lineCells(line[:pos], 0, width)[pos]
+	start := ((cursorX-1-offset[1])%width + width) % width
+
+	cells := lineCells(line, start, width)
+
+	// The row of the first line character, and the clicked row relative to it.
+	origin := cursorY - 1 - cells[pos][0]
+	row, col := y-1-origin, x-1
+
+	if row < 0 || row > cells[len(line)][0] {
+		return
+	}
+
+	target := 0
+
+	for i, cell := range cells {
+		if cell[0] < row || (cell[0] == row && cell[1] <= col) {
+			target = i
+		}
+	}
+
+	c.shell.Cursor().Set(target)
+}
+
+// lineCells returns the screen row and column (relative to the first row of the
+// input line) of each character in line, plus one for the end of line, given the
+// column at which the line starts (and subsequent lines are indented).
+func lineCells(line []rune, start, width int) [][2]int {
+	cells := make([][2]int, 0, len(line)+1)
+	row, col := 0, start%width
+
+	for _, char := range line {
+		cells = append(cells, [2]int{row, col})
+
+		if char == '\n' {
+			row, col = row+1, start%width
+			continue
+		}
+
+		col += max(uniseg.StringWidth(string(char)), 0)
+		if col >= width {
+			row, col = row+1, col-width
+		}
+	}
+
+	return append(cells, [2]int{row, col})
+}
+
+// enableMouse turns mouse reporting on, if the console uses the mouse,
+// while reading user input. The returned function turns it off again.
+func (c *Console) enableMouse() (disable func()) {
+	if !c.Mouse {
+		return func() {}
+	}
+
+	fmt.Print(seqMouseOn)
+
+	return func() { fmt.Print(seqMouseOff) }
+}
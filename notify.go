@@ -0,0 +1,112 @@
+package console
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// NotifyLevel is the severity of a notification, which determines its color.
+type NotifyLevel int
+
+const (
+	NotifyInfo    NotifyLevel = iota // Informational notification (blue).
+	NotifySuccess                    // Something has completed successfully (green).
+	NotifyWarning                    // Something might need attention (yellow).
+	NotifyError                      // Something has failed (red).
+)
+
+// String returns the name of the notification level.
+func (l NotifyLevel) String() string {
+	switch l {
+	case NotifySuccess:
+		return "success"
+	case NotifyWarning:
+		return "warning"
+	case NotifyError:
+		return "error"
+	default:
+		return "info"
+	}
+}
+
+// color returns the ansi code used to highlight notifications of this level.
+func (l NotifyLevel) color() string {
+	switch l {
+	case NotifySuccess:
+		return seqFgGreen
+	case NotifyWarning:
+		return seqFgYellow
+	case NotifyError:
+		return seqFgRed
+	default:
+		return seqFgBlue
+	}
+}
+
+// Notify notifies the user of an event, typically a long-running background job
+// finishing: it rings the terminal bell, and prints a colored banner above the
+// prompt (or below the output of the running command, see TransientPrintf).
+//
+// If Console.DesktopNotifications is true, the notification is also forwarded to
+// the desktop, with notify-send on Linux and BSDs, osascript on macOS, or with a
+// terminal notification sequence (OSC 9) if these are not available.
+func (c *Console) Notify(title, msg string, level NotifyLevel) {
+	fmt.Print("\a")
+
+	banner := bold + level.color() + "[" + strings.ToUpper(level.String()) + "]" + seqFgReset + " " + title + boldReset
+	if msg != "" {
+		banner += ": " + msg
+	}
+
+	c.TransientPrintf("%s\n", banner)
+
+	if c.DesktopNotifications {
+		desktopNotify(title, msg, level)
+	}
+}
+
+// desktopNotify forwards a notification to the desktop environment, first with
+// the platform notifier if it is available, or with a terminal sequence.
+func desktopNotify(title, msg string, level NotifyLevel) {
+	var cmd *exec.Cmd
+
+	switch runtime.GOOS {
+	case "darwin":
+		script := fmt.Sprintf("display notification %q with title %q", msg, title)
+		cmd = exec.Command("osascript", "-e", script)
+	case "windows":
+		// No notifier command, rely on the terminal sequence below.
+	default:
+		urgency := "normal"
+		if level == NotifyError {
+			urgency = "critical"
+		}
+
+		cmd = exec.Command("notify-send", "-u", urgency, title, msg)
+	}
+
+	if cmd != nil && cmd.Run() == nil {
+		return
+	}
+
+	// Many terminal emulators (iTerm2, kitty, WezTerm, Windows Terminal...)
+	// turn this sequence into a desktop notification when unfocused.
+	text := title
+	if msg != "" {
+		text += ": " + msg
+	}
+
+	fmt.Fprintf(os.Stdout, "\x1b]9;%s\x07", strings.Map(stripControl, text))
+}
+
+// stripControl removes control characters, which would end the notification sequence.
+func stripControl(r rune) rune {
+	if r < ' ' || r == 0x7f {
+		return -1
+	}
+
+	return r
+}
@@ -0,0 +1,281 @@
+package console
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/rivo/uniseg"
+	"golang.org/x/term"
+)
+
+// OverlayItem is an item selectable in an overlay list (see Console.Overlay).
+type OverlayItem struct {
+	Value       string // The item itself, matched against the user query.
+	Description string // Displayed after the value, and also matched.
+	Group       string // Displayed on the right of the item (eg. the command group).
+}
+
+// overlayMatch is an item matching the overlay query, with its score.
+type overlayMatch struct {
+	item  OverlayItem
+	score int
+}
+
+// Overlay displays a full-screen list of items over the console, filtered as the
+// user types with fuzzy matching (the characters typed must appear in order in the
+// item value or description), and returns the item selected with Enter. The list is
+// navigated with the arrows, Ctrl-P/Ctrl-N or Tab, and Esc/Ctrl-C/Ctrl-G cancels, in
+// which case false is returned. The screen is restored as it was once done.
+//
+// Overlay can be called from readline commands (see RegisterAction) or from
+// commands being executed, and blocks until an item is selected or canceled.
+func (c *Console) Overlay(title string, items []OverlayItem) (selected OverlayItem, ok bool) {
+	stdin := int(os.Stdin.Fd())
+
+	state, err := term.MakeRaw(stdin)
+	if err != nil {
+		return selected, false
+	}
+
+	c.mutex.Lock()
+	c.interactive = true
+	c.mutex.Unlock()
+
+	fmt.Print(seqAltScreenEnter)
+
+	defer func() {
+		_ = term.Restore(stdin, state)
+
+		fmt.Print(seqShowCursor + seqAltScreenLeave)
+
+		c.mutex.Lock()
+		c.interactive = false
+		c.mutex.Unlock()
+	}()
+
+	var query []rune
+
+	cursor, offset := 0, 0
+	matches := matchOverlay(items, "")
+
+	// Keys typed before the overlay was opened, and not read by the shell yet.
+	var pending []byte
+
+	for {
+		key, empty := c.shell.Keys.Pop()
+		if empty {
+			break
+		}
+
+		pending = append(pending, key)
+	}
+
+	buf := make([]byte, 64)
+
+	for {
+		offset = c.renderOverlay(title, string(query), matches, cursor, offset)
+
+		input := pending
+		pending = nil
+
+		if len(input) == 0 {
+			n, err := os.Stdin.Read(buf)
+			if err != nil || n == 0 {
+				return selected, false
+			}
+
+			input = buf[:n]
+		}
+
+		filter := false
+
+		for _, key := range splitKeys(input) {
+			switch key {
+			case "\x1b", "\x03", "\x07":
+				return selected, false
+			case "\r", "\n":
+				if len(matches) > 0 {
+					return matches[cursor].item, true
+				}
+			case "\x1b[A", "\x1bOA", "\x10", "\x1b[Z":
+				cursor = max(cursor-1, 0)
+			case "\x1b[B", "\x1bOB", "\x0e", "\t":
+				cursor = min(cursor+1, max(len(matches)-1, 0))
+			case "\x1b[5~":
+				cursor = max(cursor-10, 0)
+			case "\x1b[6~":
+				cursor = min(cursor+10, max(len(matches)-1, 0))
+			case "\x7f", "\x08":
+				if len(query) > 0 {
+					query, filter = query[:len(query)-1], true
+				}
+			case "\x15":
+				query, filter = nil, true
+			default:
+				if char := []rune(key)[0]; unicode.IsPrint(char) {
+					query, filter = append(query, char), true
+				}
+			}
+
+			if filter {
+				matches = matchOverlay(items, string(query))
+				cursor, offset, filter = 0, 0, false
+			}
+		}
+	}
+}
+
+// splitKeys splits terminal input in keys: escape sequences (a lone escape
+// being the Escape key itself), control characters and UTF-8 characters.
+func splitKeys(input []byte) (keys []string) {
+	for len(input) > 0 {
+		size := 1
+
+		switch {
+		case input[0] == '\x1b' && len(input) > 2 && input[1] == '[':
+			// CSI sequences end with a byte in the 0x40-0x7e range.
+			size = 2
+			for size < len(input) && (input[size] < 0x40 || input[size] > 0x7e) {
+				size++
+			}
+
+			size = min(size+1, len(input))
+		case input[0] == '\x1b' && len(input) > 2 && input[1] == 'O':
+			size = 3
+		case input[0] == '\x1b' && len(input) > 1:
+			size = 2 // Alt-key
+		case input[0] >= utf8.RuneSelf:
+			_, size = utf8.DecodeRune(input)
+		}
+
+		keys = append(keys, string(input[:size]))
+		input = input[size:]
+	}
+
+	return keys
+}
+
+// renderOverlay draws the overlay query and its matching items, the selected one
+// being highlighted, scrolling the list from the given offset as needed, and returns
+// the new offset.
+func (c *Console) renderOverlay(title, query string, matches []overlayMatch, cursor, offset int) int {
+	width, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 || height <= 0 {
+		width, height = 80, 24
+	}
+
+	rows := max(height-3, 1)
+
+	if cursor < offset {
+		offset = cursor
+	} else if cursor >= offset+rows {
+		offset = cursor - rows + 1
+	}
+
+	var out strings.Builder
+
+	out.WriteString(seqClearScreen + fmt.Sprintf(seqCursorPosFmt, 1, 1))
+	out.WriteString(bold + title + boldReset + dim + fmt.Sprintf("  (%d)", len(matches)) + dimReset + "\r\n")
+	out.WriteString(dim + strings.Repeat("─", width) + dimReset + "\r\n")
+
+	valueWidth := 0
+	for _, match := range matches {
+		valueWidth = max(valueWidth, uniseg.StringWidth(match.item.Value))
+	}
+
+	valueWidth = min(valueWidth, width/2)
+
+	for i := offset; i < min(offset+rows, len(matches)); i++ {
+		item := matches[i].item
+
+		group := ""
+		if item.Group != "" {
+			group = " " + item.Group
+		}
+
+		value := fitWidth(item.Value, valueWidth)
+		description := fitWidth("  "+item.Description, max(width-valueWidth-uniseg.StringWidth(group)-1, 0))
+		line := value + dim + description + group + dimReset
+
+		if i == cursor {
+			line = reverse + value + description + group + reset
+		}
+
+		out.WriteString(line + "\r\n")
+	}
+
+	// The query line, at the bottom, with the cursor.
+	out.WriteString(fmt.Sprintf(seqCursorPosFmt, height, 1) + bold + "> " + boldReset + query + seqShowCursor)
+
+	fmt.Print(out.String())
+
+	return offset
+}
+
+// matchOverlay returns the items fuzzy-matching the query, the best matches first.
+func matchOverlay(items []OverlayItem, query string) []overlayMatch {
+	matches := make([]overlayMatch, 0, len(items))
+
+	for _, item := range items {
+		score, found := fuzzyScore(query, item.Value)
+
+		if !found {
+			if score, found = fuzzyScore(query, item.Description); found {
+				score /= 2
+			}
+		}
+
+		if found {
+			matches = append(matches, overlayMatch{item: item, score: score})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+
+	return matches
+}
+
+// fuzzyScore returns true if all characters of the query appear, in order, in text
+// (case-insensitively), and a score favoring consecutive characters and word starts.
+func fuzzyScore(query, text string) (score int, found bool) {
+	if query == "" {
+		return 0, true
+	}
+
+	query, text = strings.ToLower(query), strings.ToLower(text)
+	textRunes := []rune(text)
+
+	pos, last := 0, -2
+
+	for _, char := range query {
+		for pos < len(textRunes) && textRunes[pos] != char {
+			pos++
+		}
+
+		if pos == len(textRunes) {
+			return 0, false
+		}
+
+		score++
+
+		if pos == last+1 {
+			score += 2
+		}
+
+		if pos == 0 || !unicode.IsLetter(textRunes[pos-1]) {
+			score += 3
+		}
+
+		last = pos
+		pos++
+	}
+
+	// Shorter texts are closer matches.
+	return score*100 - len(textRunes), true
+}
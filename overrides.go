@@ -0,0 +1,165 @@
+package console
+
+import (
+	"github.com/reeflective/readline/inputrc"
+)
+
+// menuOverrides saves the shell settings overridden by the active menu,
+// so as to restore them when switching to another menu.
+type menuOverrides struct {
+	menu    *Menu                              // The menu whose overrides are applied.
+	main    string                             // The main keymap before entering the menu.
+	mode    any                                // The editing-mode option before entering the menu.
+	options map[string]any                     // Overridden options, with their console value.
+	binds   map[string]map[string]inputrc.Bind // Overridden binds, with their console value.
+	unbound map[string]map[string]bool         // Overridden binds, which were not bound.
+}
+
+// SetBind binds a key sequence (in inputrc notation, eg. "\C-x\C-e") to a readline
+// command in a keymap (eg. "emacs", "vi-insert"), only while this menu is active.
+// The console bind for the sequence, if any, is restored when leaving the menu.
+func (m *Menu) SetBind(keymap, sequence, command string) {
+	m.mutex.Lock()
+	if m.binds[keymap] == nil {
+		m.binds[keymap] = make(map[string]inputrc.Bind)
+	}
+
+	m.binds[keymap][inputrc.Unescape(sequence)] = inputrc.Bind{Action: command}
+	m.mutex.Unlock()
+
+	m.console.reapplyMenuOverrides(m)
+}
+
+// SetOption sets a readline option (eg. "autocomplete", "completion-ignore-case",
+// "menu-complete-display-prefix") only while this menu is active, for instance to
+// change its completion behavior. The console value is restored when leaving it.
+func (m *Menu) SetOption(name string, value any) {
+	m.mutex.Lock()
+	m.options[name] = value
+	m.mutex.Unlock()
+
+	m.console.reapplyMenuOverrides(m)
+}
+
+// applyMenuOverrides applies the editing mode, binds and options of the menu to
+// the shell, after restoring the ones overridden by the previous menu, if any.
+// Nothing is done if the menu overrides are already applied.
+func (c *Console) applyMenuOverrides(menu *Menu) {
+	if overridden := c.menuOverrides(); overridden != nil && overridden.menu == menu {
+		return
+	}
+
+	c.restoreMenuOverrides()
+
+	menu.mutex.RLock()
+	defer menu.mutex.RUnlock()
+
+	if menu.InputMode == "" && len(menu.binds) == 0 && len(menu.options) == 0 {
+		return
+	}
+
+	cfg := c.shell.Config
+
+	saved := &menuOverrides{
+		menu:    menu,
+		main:    string(c.shell.Keymap.Main()),
+		mode:    cfg.Get("editing-mode"),
+		options: make(map[string]any),
+		binds:   make(map[string]map[string]inputrc.Bind),
+		unbound: make(map[string]map[string]bool),
+	}
+
+	switch menu.InputMode {
+	case ModeEmacs:
+		cfg.Set("editing-mode", "emacs")
+		c.shell.Keymap.SetMain("emacs")
+	case ModeViInsert:
+		cfg.Set("editing-mode", "vi")
+		c.shell.Keymap.SetMain("vi-insert")
+	case ModeViNormal:
+		cfg.Set("editing-mode", "vi")
+		c.shell.Keymap.SetMain("vi-command")
+	}
+
+	for name, value := range menu.options {
+		saved.options[name] = cfg.Get(name)
+		cfg.Set(name, value)
+	}
+
+	for keymap, binds := range menu.binds {
+		if cfg.Binds[keymap] == nil {
+			cfg.Binds[keymap] = make(map[string]inputrc.Bind)
+		}
+
+		saved.binds[keymap] = make(map[string]inputrc.Bind)
+		saved.unbound[keymap] = make(map[string]bool)
+
+		for seq, bind := range binds {
+			if current, found := cfg.Binds[keymap][seq]; found {
+				saved.binds[keymap][seq] = current
+			} else {
+				saved.unbound[keymap][seq] = true
+			}
+
+			cfg.Binds[keymap][seq] = bind
+		}
+	}
+
+	c.mutex.Lock()
+	c.overrides = saved
+	c.mutex.Unlock()
+}
+
+// restoreMenuOverrides restores the shell settings overridden by the active menu.
+func (c *Console) restoreMenuOverrides() {
+	saved := c.menuOverrides()
+	if saved == nil {
+		return
+	}
+
+	cfg := c.shell.Config
+
+	if saved.menu.InputMode != "" {
+		cfg.Set("editing-mode", saved.mode)
+		c.shell.Keymap.SetMain(saved.main)
+	}
+
+	for name, value := range saved.options {
+		cfg.Set(name, value)
+	}
+
+	for keymap, binds := range saved.binds {
+		for seq, bind := range binds {
+			cfg.Binds[keymap][seq] = bind
+		}
+	}
+
+	for keymap, seqs := range saved.unbound {
+		for seq := range seqs {
+			delete(cfg.Binds[keymap], seq)
+		}
+	}
+
+	c.mutex.Lock()
+	c.overrides = nil
+	c.mutex.Unlock()
+}
+
+// reapplyMenuOverrides applies again the overrides of the
+// menu, if they are currently applied, after they changed.
+func (c *Console) reapplyMenuOverrides(menu *Menu) {
+	if overridden := c.menuOverrides(); overridden == nil || overridden.menu != menu {
+		return
+	}
+
+	c.restoreMenuOverrides()
+	c.applyMenuOverrides(menu)
+}
+
+// menuOverrides returns the shell settings overridden by the active menu, if any.
+func (c *Console) menuOverrides() *menuOverrides {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.overrides
+}
@@ -0,0 +1,152 @@
+package console
+
+import (
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// paletteKeymaps are the keymaps in which Ctrl-P opens the command palette.
+var paletteKeymaps = []string{"emacs", "emacs-standard", "vi-insert"}
+
+// setupPalette registers the command-palette command, and binds it to Ctrl-P in
+// the emacs and vi insert keymaps, unless the key is bound to something else than
+// a history command (eg. in the user inputrc). The history command is ran instead
+// of the palette when it is disabled (see Console.CommandPalette).
+func (c *Console) setupPalette() {
+	if c.paletteFallback == nil {
+		c.paletteFallback = make(map[string]string)
+	}
+
+	c.shell.Keymap.Register(map[string]func(){
+		"command-palette": c.commandPalette,
+	})
+
+	for _, keymap := range paletteKeymaps {
+		bind, found := c.shell.Config.Binds[keymap]["\x10"]
+
+		// Still bound by a previous setup: the history command is known.
+		if bind.Action == "command-palette" {
+			continue
+		}
+
+		if found && !historyBind(bind.Action) && bind.Action != "self-insert" {
+			continue
+		}
+
+		c.paletteFallback[keymap] = bind.Action
+		c.bindDefault(keymap, "\x10", "command-palette")
+	}
+}
+
+// commandPalette opens the command palette overlay, listing all commands available
+// in the current menu: the selected one is inserted in the input line, or is run
+// directly if it accepts no arguments and has no required flags.
+func (c *Console) commandPalette() {
+	commands := c.shell.Keymap.Commands()
+
+	if !c.CommandPalette {
+		if command := commands[c.paletteFallback[string(c.shell.Keymap.Main())]]; command != nil {
+			command()
+		}
+
+		return
+	}
+
+	menu := c.activeMenu()
+	if menu.Command == nil {
+		return
+	}
+
+	selected, ok := c.Overlay("Commands", c.paletteItems(menu, menu.Command))
+	if !ok {
+		return
+	}
+
+	target, _, err := menu.Command.Find(strings.Fields(selected.Value))
+	if err != nil || target == nil {
+		return
+	}
+
+	line := selected.Value + " "
+
+	c.shell.History.Save()
+	c.shell.Line().Set([]rune(line)...)
+	c.shell.Cursor().Set(len([]rune(line)))
+	c.shell.Display.Refresh()
+
+	if takesNoArgs(target) {
+		if accept := commands["accept-line"]; accept != nil {
+			accept()
+		}
+	}
+}
+
+// paletteItems returns the available commands of the menu, under a parent and
+// recursively, with their path (relative to the menu root), usage and group.
+func (c *Console) paletteItems(menu *Menu, parent *cobra.Command) []OverlayItem {
+	var items []OverlayItem
+
+	for _, cmd := range parent.Commands() {
+		if !cmd.IsAvailableCommand() || menu.CheckIsAvailable(cmd) != nil {
+			continue
+		}
+
+		group := ""
+		if cmd.GroupID != "" {
+			if g := findGroup(parent, cmd.GroupID); g != nil {
+				group = g.Title
+			}
+		}
+
+		items = append(items, OverlayItem{
+			Value:       strings.TrimSpace(strings.TrimPrefix(cmd.CommandPath(), menu.Command.CommandPath())),
+			Description: cmd.Short,
+			Group:       group,
+		})
+
+		items = append(items, c.paletteItems(menu, cmd)...)
+	}
+
+	return items
+}
+
+// historyBind returns true if the command walks the history backward.
+func historyBind(action string) bool {
+	switch action {
+	case "previous-history", "up-line-or-history", "up-history", "history-search-backward":
+		return true
+	default:
+		return false
+	}
+}
+
+// findGroup returns the group of a command from its ID, if the command has it.
+func findGroup(cmd *cobra.Command, id string) *cobra.Group {
+	for _, group := range cmd.Groups() {
+		if group.ID == id {
+			return group
+		}
+	}
+
+	return nil
+}
+
+// takesNoArgs returns true if a command is runnable without any argument or flag,
+// and does not accept positional arguments, so that it can be run directly.
+func takesNoArgs(cmd *cobra.Command) bool {
+	if !cmd.Runnable() || cmd.ValidateArgs(nil) != nil || cmd.ValidateArgs([]string{""}) == nil {
+		return false
+	}
+
+	required := false
+
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if len(flag.Annotations[cobra.BashCompOneRequiredFlag]) > 0 {
+			required = true
+		}
+	})
+
+	return !required
+}
@@ -0,0 +1,27 @@
+package console
+
+import (
+	"testing"
+)
+
+func TestPaletteBindKeepsFallback(t *testing.T) {
+	t.Setenv("INPUTRC", t.TempDir()+"/inputrc")
+
+	c := New("test")
+
+	for range 2 {
+		if bind := c.shell.Config.Binds["emacs"]["\x10"]; bind.Action != "command-palette" {
+			t.Fatalf("Ctrl-P = %q, want command-palette", bind.Action)
+		}
+
+		if fallback := c.paletteFallback["emacs"]; fallback != "up-line-or-history" {
+			t.Fatalf("fallback = %q, want up-line-or-history", fallback)
+		}
+
+		c.shell.Keymap.Commands()["re-read-init-file"]()
+	}
+
+	if bind := DefaultBinds(c.shell)["vi-insert"]["\x10"]; bind.Action != "command-palette" {
+		t.Errorf("default vi-insert Ctrl-P = %q, want command-palette", bind.Action)
+	}
+}
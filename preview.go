@@ -0,0 +1,197 @@
+package console
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// previewReadSize is the number of bytes of a file read by FilePreview.
+const previewReadSize = 4096
+
+// PreviewProvider returns extended information about a completion candidate for the
+// arguments of a command (eg. the first lines of a file, or the details of a host),
+// given the command and the candidate value. An empty preview displays nothing.
+type PreviewProvider func(cmd *cobra.Command, candidate string) string
+
+// SetArgPreview registers a preview provider for the positional arguments of a command
+// of this menu, given its path relative to the menu root command (eg. "hosts connect").
+// The preview is shown while a candidate is selected in the completion menu, if the
+// Console.CompletionPreview pane is enabled. Subcommands are previewed by default with
+// their long description.
+func (m *Menu) SetArgPreview(path string, provider PreviewProvider) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.previews[strings.Join(strings.Fields(path), " ")] = provider
+}
+
+// SetFlagPreview registers a preview provider for the arguments of a flag (given by its
+// long name) of a command in this menu, given its path (see SetArgPreview).
+func (m *Menu) SetFlagPreview(path, flag string, provider PreviewProvider) {
+	m.SetArgPreview(strings.Join(strings.Fields(path), " ")+" --"+flag, provider)
+}
+
+// FilePreview is a preview provider showing the first lines of the file candidate, or
+// the entries of the directory candidate, for arguments completed with file paths:
+//
+//	menu.SetArgPreview("cat", console.FilePreview)
+func FilePreview(_ *cobra.Command, path string) string {
+	if rest, found := strings.CutPrefix(path, "~/"); found {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, rest)
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return ""
+		}
+
+		names := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			if entry.IsDir() {
+				names = append(names, entry.Name()+"/")
+			} else {
+				names = append(names, entry.Name())
+			}
+		}
+
+		return strings.Join(names, "\n")
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	head, err := io.ReadAll(io.LimitReader(file, previewReadSize))
+	if err != nil {
+		return ""
+	}
+
+	if bytes.IndexByte(head, 0) >= 0 {
+		return fmt.Sprintf("binary file, %d bytes", info.Size())
+	}
+
+	return strings.ToValidUTF8(string(head), "")
+}
+
+// updatePreview displays the preview of the completion candidate selected in the menu,
+// in place of the hints. The candidate is only inserted in the line being displayed,
+// which thus differs from the shell line while it is selected.
+func (c *Console) updatePreview(input []rune) {
+	if c.CompletionPreview <= 0 {
+		return
+	}
+
+	// Previews are only kept while cycling through candidates.
+	line := *c.shell.Line()
+	if string(input) == string(line) {
+		c.previewKey = ""
+		return
+	}
+
+	end := c.shell.Cursor().Pos() + len(input) - len(line)
+	end = min(max(end, 0), len(input))
+
+	key := cacheKey(c.activeMenu(), input, end)
+	if key != c.previewKey {
+		c.previewKey = key
+		c.previewText = c.formatPreview(c.candidatePreview(input[:end]))
+	}
+
+	if c.previewText != "" {
+		c.shell.Hint.SetTemporary(c.previewText)
+	}
+}
+
+// candidatePreview returns the preview of the last word of the input, being the
+// candidate selected: subcommands are described, other arguments use the provider
+// registered for their command (or flag), if any.
+func (c *Console) candidatePreview(input []rune) string {
+	menu := c.activeMenu()
+	if menu.Command == nil {
+		return ""
+	}
+
+	args, remain, err := split(string(input), false)
+	if err != nil {
+		args = append(args, remain)
+	}
+
+	if len(args) == 0 {
+		return ""
+	}
+
+	candidate := args[len(args)-1]
+
+	target, _, err := menu.Command.Find(args[:len(args)-1])
+	if err != nil || target == nil {
+		return ""
+	}
+
+	for _, sub := range target.Commands() {
+		if sub.Name() == candidate || sub.HasAlias(candidate) {
+			if sub.Long != "" {
+				return sub.Long
+			}
+
+			return sub.Short
+		}
+	}
+
+	// Flags are already described in the menu.
+	if strings.HasPrefix(candidate, "-") {
+		return ""
+	}
+
+	path := strings.TrimSpace(strings.TrimPrefix(target.CommandPath(), menu.Command.Name()))
+
+	if flag := currentFlag(target, args, false); flag != nil {
+		path += " --" + flag.Name
+	}
+
+	menu.mutex.RLock()
+	provider := menu.previews[path]
+	menu.mutex.RUnlock()
+
+	if provider == nil {
+		return ""
+	}
+
+	return provider(target, candidate)
+}
+
+// formatPreview keeps the first lines of a preview, fitted to the terminal
+// width and without colors, in a dim pane separated from the menu by a gutter.
+func (c *Console) formatPreview(text string) string {
+	lines := splitLines(strings.TrimSpace(strip(text)))
+	if len(lines) == 0 {
+		return ""
+	}
+
+	if len(lines) > c.CompletionPreview {
+		lines = append(lines[:c.CompletionPreview-1], "…")
+	}
+
+	width := terminalWidth() - 2
+
+	for i, line := range lines {
+		lines[i] = c.Theme().Hint + "│ " + strings.TrimRight(fitWidth(line, width), " ") + reset
+	}
+
+	return strings.Join(lines, "\r\n")
+}
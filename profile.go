@@ -0,0 +1,166 @@
+package console
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"runtime"
+	rpprof "runtime/pprof"
+)
+
+// ProfileLabel is the pprof label set on the profile samples of the console phases
+// ("prompt", "completion", "highlight", "hint" and "command"), while profiling: for
+// instance, `go tool pprof -tagfocus=console=completion` only shows the samples of
+// completion callbacks. Commands are also labeled with their path, under "command".
+const ProfileLabel = "console"
+
+var errProfiling = errors.New("already profiling")
+
+// profiler is the state of the console profiling, when enabled.
+type profiler struct {
+	server  *http.Server // The pprof HTTP server, if served.
+	cpuFile *os.File     // The CPU profile being written, if any.
+	heap    string       // The heap profile written on exit, if any.
+}
+
+// ServeProfiling serves the net/http/pprof endpoints (under /debug/pprof/) on the
+// given address, eg. "localhost:6060" (":0" picks a free port on localhost), and
+// returns the address being listened on. Profiles can then be collected while the
+// console runs, with `go tool pprof http://localhost:6060/debug/pprof/profile`.
+// The console phases are labeled in the profiles (see ProfileLabel), and the server
+// is stopped with StopProfiling, or when exiting with Console.Exit.
+//
+// Since profiles expose the internals of the application, the address should only
+// be reachable locally: addresses without a host are bound to localhost.
+func (c *Console) ServeProfiling(addr string) (net.Addr, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if host == "" {
+		addr = net.JoinHostPort("localhost", port)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.profiler.server != nil {
+		return nil, errProfiling
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	c.profiler.server = &http.Server{Handler: mux}
+	c.profiling.Store(true)
+
+	go c.profiler.server.Serve(listener)
+
+	return listener.Addr(), nil
+}
+
+// ProfileToFiles starts writing a CPU profile to cpuFile, like the `-cpuprofile`
+// flag of go test, until StopProfiling is called or the console exits, at which
+// point a heap profile is also written to heapFile. Either file can be empty, for
+// instance to only write a heap profile on exit. The console phases are labeled in
+// the CPU profile (see ProfileLabel). This is typically wired to the flags of the
+// application, so that users can send profiles when diagnosing performance issues.
+func (c *Console) ProfileToFiles(cpuFile, heapFile string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.profiler.cpuFile != nil || c.profiler.heap != "" {
+		return errProfiling
+	}
+
+	if cpuFile != "" {
+		file, err := os.Create(cpuFile)
+		if err != nil {
+			return err
+		}
+
+		if err := rpprof.StartCPUProfile(file); err != nil {
+			file.Close()
+			return err
+		}
+
+		c.profiler.cpuFile = file
+		c.profiling.Store(true)
+	}
+
+	c.profiler.heap = heapFile
+
+	return nil
+}
+
+// StopProfiling stops the profiling server, and the CPU profile started with
+// ProfileToFiles, writing the heap profile if any. It is called by Console.Exit.
+func (c *Console) StopProfiling() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var errs []error
+
+	if c.profiler.server != nil {
+		errs = append(errs, c.profiler.server.Close())
+	}
+
+	if c.profiler.cpuFile != nil {
+		rpprof.StopCPUProfile()
+		errs = append(errs, c.profiler.cpuFile.Close())
+	}
+
+	if c.profiler.heap != "" {
+		errs = append(errs, writeHeapProfile(c.profiler.heap))
+	}
+
+	c.profiler = profiler{}
+	c.profiling.Store(false)
+
+	return errors.Join(errs...)
+}
+
+// writeHeapProfile writes a heap profile, with up-to-date statistics.
+func writeHeapProfile(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	runtime.GC()
+
+	if err := rpprof.WriteHeapProfile(file); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write heap profile: %w", err)
+	}
+
+	return file.Close()
+}
+
+// profilePhase labels the profile samples of the calling goroutine with a console
+// phase (and optional label key/value pairs) while profiling, and returns the
+// function removing the labels once the phase is done. Nothing is done otherwise.
+func (c *Console) profilePhase(phase string, labels ...string) func() {
+	if !c.profiling.Load() {
+		return func() {}
+	}
+
+	ctx := rpprof.WithLabels(context.Background(), rpprof.Labels(append([]string{ProfileLabel, phase}, labels...)...))
+	rpprof.SetGoroutineLabels(ctx)
+
+	return func() { rpprof.SetGoroutineLabels(context.Background()) }
+}
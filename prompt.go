@@ -15,7 +15,7 @@ type Prompt struct {
 	Secondary func() string            // Secondary is the prompt used when the user is typing a multi-line command.
 	Transient func() string            // Transient is used if the console shell is configured to be transient.
 	Right     func() string            // Right is the prompt printed on the right side of the screen.
-	Tooltip   func(word string) string // Tooltip is used to hint on the root command, replacing right prompts if not empty.
+	Tooltip   func(word string) string // Tooltip is used to hint on the root command, replacing right prompts if not empty (see Console.CommandTooltip).
 
 	console *Console
 }
@@ -36,9 +36,11 @@ func newPrompt(app *Console) *Prompt {
 
 		// If the buffered command output is not empty,
 		// add a special status indicator to the prompt.
+		menu.mutex.RLock()
 		if strings.TrimSpace(menu.out.String()) != "" {
 			promptStr += " $(...)"
 		}
+		menu.mutex.RUnlock()
 
 		return promptStr + " > "
 	}
@@ -54,18 +56,61 @@ func (p *Prompt) bind(shell *readline.Shell) {
 	// must leave a newline after command/log output, wrap its function
 	// to add a newline before the prompt.
 	primary := func() string {
+		// The prompt configured by the user has precedence.
+		if prompt, found := p.console.configPrompt(); found {
+			return prompt
+		}
+
 		if p.Primary == nil {
 			return ""
 		}
 
+		defer p.console.profilePhase("prompt")()
+
 		prompt := p.Primary()
 
 		return prompt
 	}
 
-	prompt.Primary(primary)
-	prompt.Right(p.Right)
-	prompt.Secondary(p.Secondary)
-	prompt.Transient(p.Transient)
-	prompt.Tooltip(p.Tooltip)
+	prompt.Primary(p.plain(primary))
+	prompt.Right(p.decoration(p.Right))
+	prompt.Secondary(p.plain(p.Secondary))
+	prompt.Transient(p.plain(p.Transient))
+	prompt.Tooltip(func(word string) string {
+		if p.Tooltip == nil || p.console.accessible.Load() {
+			return ""
+		}
+
+		return p.Tooltip(word)
+	})
+}
+
+// plain wraps a prompt function to remove its colors in accessible mode.
+func (p *Prompt) plain(prompt func() string) func() string {
+	if prompt == nil {
+		return nil
+	}
+
+	return func() string {
+		if p.console.accessible.Load() {
+			return strip(prompt())
+		}
+
+		return prompt()
+	}
+}
+
+// decoration wraps a prompt function not to print it in accessible mode.
+func (p *Prompt) decoration(prompt func() string) func() string {
+	if prompt == nil {
+		return nil
+	}
+
+	return func() string {
+		if p.console.accessible.Load() {
+			return ""
+		}
+
+		return prompt()
+	}
 }
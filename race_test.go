@@ -0,0 +1,83 @@
+package console
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+// TestConcurrentAPI uses the console API from background goroutines (logging,
+// switching menus, registering commands, setting context values, themes and
+// status segments) while the main goroutine completes, highlights and executes
+// command lines, and runs the functions queued for it, like the input loop does.
+// It is meant for `go test -race`.
+func TestConcurrentAPI(t *testing.T) {
+	c := New("test")
+	c.NewMenu("client")
+	c.RegisterTheme("dark", Theme{Command: seqFgGreen})
+
+	echo := func() *cobra.Command {
+		root := &cobra.Command{}
+		root.AddCommand(&cobra.Command{Use: "echo", Run: func(*cobra.Command, []string) {}})
+
+		return root
+	}
+
+	for _, name := range []string{"", "client"} {
+		c.Menu(name).SetCommands(echo)
+	}
+
+	const iterations = 50
+
+	var workers sync.WaitGroup
+
+	background := []func(i int){
+		func(i int) { c.Printf("message %d\n", i) },
+		func(i int) { c.TransientPrintf("transient %d\n", i) },
+		func(i int) { fmt.Fprintf(c.LogPanel(), "log %d\n", i) },
+		func(i int) { c.SwitchMenu([]string{"", "client"}[i%2]) },
+		func(i int) {
+			c.ActiveMenu().AddCommands(func() *cobra.Command {
+				return &cobra.Command{Use: fmt.Sprintf("plugin%d", i), Run: func(*cobra.Command, []string) {}}
+			})
+		},
+		func(i int) { c.ActiveMenu().InvalidateCommands(fmt.Sprintf("plugin%d", i)) },
+		func(i int) { c.SetContextValue("target", fmt.Sprint(i)) },
+		func(i int) { c.SetStatusSegment("count", func() string { return fmt.Sprint(i) }) },
+		func(int) { c.HideCommands("windows") },
+		func(int) { c.ShowCommands("windows") },
+		func(int) { _ = c.SetTheme("dark") },
+	}
+
+	for _, work := range background {
+		workers.Add(1)
+
+		go func() {
+			defer workers.Done()
+
+			for i := range iterations {
+				work(i)
+			}
+		}()
+	}
+
+	ctx, stop := c.startLoop(context.Background())
+	line := []rune("echo ")
+
+	for range iterations {
+		c.runPending()
+		c.complete(line, len(line))
+		c.highlightSyntax(line)
+		c.Theme()
+
+		if err := c.ActiveMenu().RunCommandLine(ctx, "echo hello"); err != nil {
+			t.Error(err)
+		}
+	}
+
+	workers.Wait()
+	stop()
+}
@@ -0,0 +1,325 @@
+// Package rc evaluates a Starlark startup file (eg. ~/.apprc.star) to customize
+// a console application: this is a deterministic and sandboxed alternative to
+// plain command scripts, since Starlark programs cannot access the filesystem,
+// the network or the environment, nor load other files.
+//
+// The file can use the following builtins:
+//
+//	set("editing-mode", "vi")              # Set a readline option.
+//	alias("ll", "ls -l")                   # Define a command alias (arguments are appended).
+//	bind(r"\C-x\C-l", "clear-screen")      # Bind a key sequence to a readline command.
+//	bind(r"\C-x\C-l", "clear-screen", keymap="vi-insert")
+//
+//	def greet(args):
+//	    return "Hello " + " ".join(args)
+//
+//	command("greet", greet, short="Greet someone")  # Register a command.
+//
+// Command functions receive the command arguments as a list of strings, and the
+// string they return (if any) is printed. Aliases and commands are added to the
+// "rc" command group of the active menu. The file is evaluated again when the
+// console configuration is reloaded (eg. with the reload command).
+package rc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/kballard/go-shellquote"
+	"github.com/reeflective/readline/inputrc"
+	"github.com/spf13/cobra"
+	"go.starlark.net/starlark"
+
+	"github.com/reeflective/console"
+)
+
+// GroupID is the command group in which rc aliases and commands are added.
+const GroupID = "rc"
+
+// DefaultPath returns the default path of the startup file for an application,
+// that is, ~/.<app>rc.star.
+func DefaultPath(app string) string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(home, "."+strings.ToLower(app)+"rc.star")
+}
+
+// Load evaluates the Starlark startup file, if it exists. The file is evaluated again
+// each time the console configuration is reloaded (see console.Console.Reload), even if
+// it failed to load at first: aliases and commands removed from the file are removed from
+// the menu, and the aliases and commands added, removed or changed are reported.
+func Load(app *console.Console, path string) error {
+	rc := &startup{
+		app:      app,
+		menu:     app.ActiveMenu(),
+		path:     path,
+		commands: make(map[string]definition),
+		added:    make(map[string]bool),
+	}
+
+	app.OnReload(rc.load)
+
+	_, err := rc.load()
+
+	return err
+}
+
+type startup struct {
+	app  *console.Console
+	menu *console.Menu // Menu in which aliases and commands are added.
+	path string
+
+	mutex    sync.RWMutex
+	commands map[string]definition // Aliases and commands defined by the file, by name.
+	pending  map[string]definition // Aliases and commands defined while evaluating the file.
+	added    map[string]bool       // Names for which commands are generated in the menu.
+}
+
+// definition is an alias or a command defined by the startup file.
+type definition struct {
+	kind   string // "alias" or "command"
+	source string // The aliased line, or the command description.
+	build  func() *cobra.Command
+}
+
+// load evaluates the startup file, and replaces the aliases and commands
+// previously defined with the ones it defines, returning the changes.
+func (rc *startup) load() (changes []string, err error) {
+	src, err := os.ReadFile(rc.path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	rc.pending = make(map[string]definition)
+
+	// Without a file, all aliases and commands are removed.
+	if err == nil {
+		if err = rc.exec(src); err != nil {
+			return nil, err
+		}
+	}
+
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	var invalid []string
+
+	for name, def := range rc.commands {
+		if _, found := rc.pending[name]; !found {
+			changes = append(changes, fmt.Sprintf("%s %s: removed", def.kind, name))
+			invalid = append(invalid, name)
+		}
+	}
+
+	for name, def := range rc.pending {
+		previous, found := rc.commands[name]
+
+		switch {
+		case !found:
+			changes = append(changes, fmt.Sprintf("%s %s: added", def.kind, name))
+
+			// Added again after being removed.
+			if rc.added[name] {
+				invalid = append(invalid, name)
+			}
+		case previous.kind != def.kind || previous.source != def.source:
+			changes = append(changes, fmt.Sprintf("%s %s: changed", def.kind, name))
+			invalid = append(invalid, name)
+		}
+
+		if !rc.added[name] {
+			rc.added[name] = true
+			rc.menu.AddCommands(rc.generate(name))
+		}
+	}
+
+	rc.commands, rc.pending = rc.pending, nil
+
+	// Only the commands changed are built again.
+	if len(invalid) > 0 {
+		rc.menu.InvalidateCommands(invalid...)
+	}
+
+	sort.Strings(changes)
+
+	return changes, nil
+}
+
+// exec evaluates the source of the startup file.
+func (rc *startup) exec(src []byte) error {
+	builtins := starlark.StringDict{
+		"set":     starlark.NewBuiltin("set", rc.set),
+		"alias":   starlark.NewBuiltin("alias", rc.alias),
+		"bind":    starlark.NewBuiltin("bind", rc.bind),
+		"command": starlark.NewBuiltin("command", rc.command),
+	}
+
+	globals, err := starlark.ExecFile(newThread(rc.path), rc.path, src, builtins)
+	if err != nil {
+		var evalErr *starlark.EvalError
+		if errors.As(err, &evalErr) {
+			return fmt.Errorf("%s", evalErr.Backtrace())
+		}
+
+		return err
+	}
+
+	// Command functions may be called concurrently.
+	globals.Freeze()
+
+	return nil
+}
+
+// generate returns the generator of the command currently defined with
+// the name, which generates nothing once it is removed from the file.
+func (rc *startup) generate(name string) console.Commands {
+	return func() *cobra.Command {
+		rc.mutex.RLock()
+		def, found := rc.commands[name]
+		rc.mutex.RUnlock()
+
+		if !found {
+			return nil
+		}
+
+		return def.build()
+	}
+}
+
+// set(option, value)
+func (rc *startup) set(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var option string
+	var value starlark.Value
+
+	if err := starlark.UnpackPositionalArgs(fn.Name(), args, kwargs, 2, &option, &value); err != nil {
+		return nil, err
+	}
+
+	switch val := value.(type) {
+	case starlark.Bool:
+		rc.app.Shell().Config.Set(option, bool(val))
+	case starlark.Int:
+		number, _ := val.Int64()
+		rc.app.Shell().Config.Set(option, int(number))
+	case starlark.String:
+		rc.app.Shell().Config.Set(option, string(val))
+	default:
+		return nil, fmt.Errorf("%s: invalid value type %s for %s", fn.Name(), value.Type(), option)
+	}
+
+	return starlark.None, nil
+}
+
+// alias(name, line)
+func (rc *startup) alias(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var name, line string
+
+	if err := starlark.UnpackPositionalArgs(fn.Name(), args, kwargs, 2, &name, &line); err != nil {
+		return nil, err
+	}
+
+	rc.pending[name] = definition{kind: "alias", source: line, build: func() *cobra.Command {
+		return &cobra.Command{
+			Use:                name,
+			Short:              "Alias for " + line,
+			GroupID:            GroupID,
+			DisableFlagParsing: true,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				expanded := line
+
+				for _, arg := range args {
+					expanded += " " + shellquote.Join(arg)
+				}
+
+				return rc.app.ActiveMenu().RunCommandLine(cmd.Context(), expanded)
+			},
+		}
+	}}
+
+	return starlark.None, nil
+}
+
+// bind(sequence, command, keymap=<main keymap>)
+func (rc *startup) bind(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var seq, command, keymap string
+
+	if err := starlark.UnpackArgs(fn.Name(), args, kwargs, "sequence", &seq, "command", &command, "keymap?", &keymap); err != nil {
+		return nil, err
+	}
+
+	shell := rc.app.Shell()
+
+	if _, found := shell.Keymap.Commands()[command]; !found {
+		return nil, fmt.Errorf("%s: unknown command %q", fn.Name(), command)
+	}
+
+	if keymap == "" {
+		keymap = string(shell.Keymap.Main())
+	}
+
+	if shell.Config.Binds[keymap] == nil {
+		shell.Config.Binds[keymap] = make(map[string]inputrc.Bind)
+	}
+
+	shell.Config.Binds[keymap][inputrc.Unescape(seq)] = inputrc.Bind{Action: command}
+
+	return starlark.None, nil
+}
+
+// command(name, function, short="")
+func (rc *startup) command(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var name, short string
+	var run starlark.Callable
+
+	if err := starlark.UnpackArgs(fn.Name(), args, kwargs, "name", &name, "function", &run, "short?", &short); err != nil {
+		return nil, err
+	}
+
+	rc.pending[name] = definition{kind: "command", source: short, build: func() *cobra.Command {
+		return &cobra.Command{
+			Use:                name,
+			Short:              short,
+			GroupID:            GroupID,
+			DisableFlagParsing: true,
+			SilenceUsage:       true,
+			SilenceErrors:      true,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				list := make([]starlark.Value, 0, len(args))
+				for _, arg := range args {
+					list = append(list, starlark.String(arg))
+				}
+
+				result, err := starlark.Call(newThread(name), run, starlark.Tuple{starlark.NewList(list)}, nil)
+				if err != nil {
+					return err
+				}
+
+				if str, ok := result.(starlark.String); ok && str != "" {
+					fmt.Fprintln(cmd.OutOrStdout(), string(str))
+				}
+
+				return nil
+			},
+		}
+	}}
+
+	return starlark.None, nil
+}
+
+// newThread returns a sandboxed Starlark thread: load() statements are not allowed.
+func newThread(name string) *starlark.Thread {
+	return &starlark.Thread{
+		Name: name,
+		Print: func(_ *starlark.Thread, msg string) {
+			fmt.Println(msg)
+		},
+	}
+}
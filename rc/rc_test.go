@@ -0,0 +1,56 @@
+package rc
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/reeflective/console"
+)
+
+const greetRC = `
+set("completion-ignore-case", True)
+bind(r"\C-x\C-g", "clear-screen", keymap="emacs")
+
+def greet(args):
+    return "Hello " + " ".join(args)
+
+command("greet", greet, short="Greet someone")
+alias("hi", "greet there")
+`
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".testrc.star")
+
+	if err := os.WriteFile(path, []byte(greetRC), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	app := console.New("test")
+
+	if err := Load(app, path); err != nil {
+		t.Fatal(err)
+	}
+
+	if !app.Shell().Config.GetBool("completion-ignore-case") {
+		t.Error("option not set by the startup file")
+	}
+
+	if bind := app.Shell().Config.Binds["emacs"]["\x18\x07"]; bind.Action != "clear-screen" {
+		t.Errorf("sequence bound to %q, want clear-screen", bind.Action)
+	}
+
+	for line, want := range map[string]string{"greet you": "Hello you\n", "hi you": "Hello there you\n"} {
+		output, err := app.CaptureOutput(func() error {
+			return app.ActiveMenu().RunCommandLine(context.Background(), line)
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", line, err)
+		}
+
+		if output != want {
+			t.Errorf("%s: output = %q, want %q", line, output, want)
+		}
+	}
+}
@@ -0,0 +1,396 @@
+package console
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/kballard/go-shellquote"
+	"github.com/reeflective/readline"
+	"github.com/rivo/uniseg"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	// FlagSensitiveKey is the pflag annotation key used to mark a flag as sensitive
+	// (see MarkFlagSensitive): its values are redacted by the console.
+	FlagSensitiveKey = "console-sensitive"
+
+	// CommandSensitiveArgsKey should be used as a key to in a cobra.Annotation map.
+	// The value is a comma-separated list of the positional arguments indexes (from 0)
+	// whose values are sensitive and redacted by the console, or "*" for all of them.
+	CommandSensitiveArgsKey = "console-sensitive-args"
+)
+
+// defaultRedacted replaces sensitive values when no redactor is set.
+const defaultRedacted = "********"
+
+// MarkFlagSensitive marks one or more flags of a command as sensitive: their values
+// are redacted in the history, in the audit events, in the dry-run messages, and
+// in the input line displayed once it is accepted. Flags not found are ignored.
+func MarkFlagSensitive(cmd *cobra.Command, names ...string) {
+	for _, name := range names {
+		flags := cmd.Flags()
+
+		if flags.Lookup(name) == nil {
+			flags = cmd.PersistentFlags()
+		}
+
+		flags.SetAnnotation(name, FlagSensitiveKey, []string{"true"})
+	}
+}
+
+// MarkArgsSensitive marks some positional arguments of a command as sensitive, given
+// their indexes (starting from 0), or all of them if no index is given: their values
+// are redacted like those of sensitive flags (see MarkFlagSensitive).
+func MarkArgsSensitive(cmd *cobra.Command, positions ...int) {
+	if cmd.Annotations == nil {
+		cmd.Annotations = make(map[string]string)
+	}
+
+	if len(positions) == 0 {
+		cmd.Annotations[CommandSensitiveArgsKey] = "*"
+		return
+	}
+
+	indexes := make([]string, 0, len(positions))
+	for _, pos := range positions {
+		indexes = append(indexes, strconv.Itoa(pos))
+	}
+
+	cmd.Annotations[CommandSensitiveArgsKey] = strings.Join(indexes, ",")
+}
+
+// SetRedactor sets the function used to redact the values of sensitive flags
+// and arguments in the history, audit events and dry-run messages. By default,
+// values are replaced with "********". Note that the input line displayed once
+// accepted is always masked with as many '*' as there are (displayed) characters.
+func (c *Console) SetRedactor(redact func(value string) string) {
+	c.mutex.Lock()
+	c.redactor = redact
+	c.mutex.Unlock()
+}
+
+// RedactArgs returns a copy of the command line arguments (including the command
+// names) in which the values of sensitive flags and arguments have been redacted.
+func (m *Menu) RedactArgs(args []string) []string {
+	redacted := append([]string{}, args...)
+
+	for i, offset := range m.sensitiveArgs(args) {
+		if offset >= 0 {
+			runes := []rune(redacted[i])
+			redacted[i] = string(runes[:offset]) + m.console.redact(string(runes[offset:]))
+		}
+	}
+
+	return redacted
+}
+
+// RedactLine is like RedactArgs, but takes an unsplit command line. If the line has
+// no sensitive values (or cannot be split into words), it is returned unchanged.
+func (m *Menu) RedactLine(line string) string {
+	args, err := shellquote.Split(line)
+	if err != nil {
+		return line
+	}
+
+	redacted := m.RedactArgs(args)
+
+	for i := range args {
+		if args[i] != redacted[i] {
+			return joinWords(redacted)
+		}
+	}
+
+	return line
+}
+
+// UnredactLine returns a history line with its sensitive values, as it was typed, so
+// that it can be run again. Only the lines accepted since the console started are kept
+// in memory with these values: false is returned if the sensitive values of the line
+// are not known (they were redacted in a previous session).
+func (m *Menu) UnredactLine(line string) (string, bool) {
+	m.mutex.RLock()
+	unredacted, found := m.unredacted[line]
+	m.mutex.RUnlock()
+
+	if found {
+		return unredacted, true
+	}
+
+	args, err := shellquote.Split(line)
+	if err != nil {
+		return line, true
+	}
+
+	for _, offset := range m.sensitiveArgs(args) {
+		if offset >= 0 {
+			return "", false
+		}
+	}
+
+	return line, true
+}
+
+// redact redacts a sensitive value.
+func (c *Console) redact(value string) string {
+	c.mutex.RLock()
+	redactor := c.redactor
+	c.mutex.RUnlock()
+
+	if redactor == nil {
+		return defaultRedacted
+	}
+
+	return redactor(value)
+}
+
+// sensitiveArgs returns, for each argument, the offset (in runes) at which its
+// sensitive value starts (eg. after "--password="), or -1 if it is not sensitive.
+func (m *Menu) sensitiveArgs(args []string) []int {
+	offsets := make([]int, len(args))
+	for i := range offsets {
+		offsets[i] = -1
+	}
+
+	if m.Command == nil || len(args) == 0 {
+		return offsets
+	}
+
+	target, _, err := m.Command.Find(args)
+	if err != nil || target == nil {
+		return offsets
+	}
+
+	// The command names to skip, in order.
+	var path []*cobra.Command
+	for cmd := target; cmd != nil && cmd != m.Command; cmd = cmd.Parent() {
+		path = append([]*cobra.Command{cmd}, path...)
+	}
+
+	flags := pflag.NewFlagSet(target.Name(), pflag.ContinueOnError)
+	flags.AddFlagSet(target.Flags())
+	flags.AddFlagSet(target.InheritedFlags())
+
+	positional := 0
+	expectValue := false
+	sensitiveValue := false
+	terminated := false
+
+	for i, arg := range args {
+		switch {
+		case expectValue:
+			expectValue = false
+
+			if sensitiveValue {
+				offsets[i] = 0
+			}
+
+		case !terminated && arg == "--":
+			terminated = true
+
+		case !terminated && strings.HasPrefix(arg, "--"):
+			name, _, hasValue := strings.Cut(arg[2:], "=")
+			flag := flags.Lookup(name)
+
+			if flag == nil {
+				continue
+			}
+
+			if hasValue && isSensitiveFlag(flag) {
+				offsets[i] = len([]rune(name)) + 3
+			} else if !hasValue && flag.NoOptDefVal == "" {
+				expectValue, sensitiveValue = true, isSensitiveFlag(flag)
+			}
+
+		case !terminated && strings.HasPrefix(arg, "-") && len(arg) > 1:
+			// Short flags can be stacked, the last one possibly taking a value.
+			for pos, short := range []rune(arg[1:]) {
+				flag := flags.ShorthandLookup(string(short))
+				if flag == nil || flag.NoOptDefVal != "" {
+					continue
+				}
+
+				if pos+2 < len([]rune(arg)) {
+					if isSensitiveFlag(flag) {
+						offsets[i] = pos + 2
+					}
+				} else {
+					expectValue, sensitiveValue = true, isSensitiveFlag(flag)
+				}
+
+				break
+			}
+
+		case len(path) > 0 && !terminated && (arg == path[0].Name() || path[0].HasAlias(arg)):
+			path = path[1:]
+
+		default:
+			if isSensitiveArg(target, positional) {
+				offsets[i] = 0
+			}
+
+			positional++
+		}
+	}
+
+	return offsets
+}
+
+// maskLine masks the sensitive values of the input line with stars,
+// keeping the display width of the line to not confuse the shell.
+func (m *Menu) maskLine(line []rune) []rune {
+	words, ranges := wordRanges(line)
+
+	offsets := m.sensitiveArgs(words)
+
+	masked := make([]rune, 0, len(line))
+	last := 0
+
+	for i, offset := range offsets {
+		if offset < 0 || i >= len(ranges) {
+			continue
+		}
+
+		start, end := ranges[i][0]+offset, ranges[i][1]
+		if start > end {
+			continue
+		}
+
+		masked = append(masked, line[last:start]...)
+
+		for _, char := range line[start:end] {
+			masked = append(masked, []rune(strings.Repeat("*", uniseg.StringWidth(string(char))))...)
+		}
+
+		last = end
+	}
+
+	return append(masked, line[last:]...)
+}
+
+// redactedHistory is a history source in which accepted lines are written
+// with the values of sensitive flags and arguments redacted.
+type redactedHistory struct {
+	readline.History
+	menu *Menu
+}
+
+// Write writes the redacted line to the history source. The line
+// as typed is kept in memory, so that it can be run again.
+func (h *redactedHistory) Write(line string) (int, error) {
+	redacted := h.menu.RedactLine(line)
+
+	if redacted != line {
+		h.menu.mutex.Lock()
+		h.menu.unredacted[redacted] = line
+		h.menu.mutex.Unlock()
+	}
+
+	return h.History.Write(redacted)
+}
+
+// historySource returns the named history source of the menu, redacting sensitive values.
+func (m *Menu) historySource(name string) readline.History {
+	source := m.histories[name]
+	if source == nil {
+		return nil
+	}
+
+	return &redactedHistory{History: source, menu: m}
+}
+
+func isSensitiveFlag(flag *pflag.Flag) bool {
+	return len(flag.Annotations[FlagSensitiveKey]) > 0
+}
+
+func isSensitiveArg(cmd *cobra.Command, position int) bool {
+	if cmd.Annotations == nil {
+		return false
+	}
+
+	for _, index := range strings.Split(cmd.Annotations[CommandSensitiveArgsKey], ",") {
+		if index = strings.TrimSpace(index); index == "*" || index == strconv.Itoa(position) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// wordRanges splits a line into unquoted shell words, along with
+// the range of runes of each word in the (quoted) line.
+func wordRanges(line []rune) (words []string, ranges [][2]int) {
+	var word []rune
+
+	start := -1
+	var quote rune
+
+	for i := 0; i < len(line); i++ {
+		char := line[i]
+
+		switch {
+		case quote == 0 && unicode.IsSpace(char):
+			if start >= 0 {
+				words = append(words, string(word))
+				ranges = append(ranges, [2]int{start, i})
+				word, start = nil, -1
+			}
+
+			continue
+		case start < 0:
+			start = i
+		}
+
+		switch {
+		case char == '\\' && quote != '\'' && i+1 < len(line):
+			i++
+			word = append(word, line[i])
+		case quote == 0 && (char == '\'' || char == '"'):
+			quote = char
+		case quote != 0 && char == quote:
+			quote = 0
+		default:
+			word = append(word, char)
+		}
+	}
+
+	if start >= 0 {
+		words = append(words, string(word))
+		ranges = append(ranges, [2]int{start, len(line)})
+	}
+
+	return words, ranges
+}
+
+// displayMasked redisplays the accepted input line with its sensitive
+// values masked, if any (and without any highlighted quotes/brackets pair).
+// This is called right before the shell prints the line for the last time
+// and returns it.
+func (c *Console) displayMasked(line []rune) {
+	masked := c.activeMenu().maskLine(line)
+	if string(masked) == string(line) && !c.pairShown {
+		return
+	}
+
+	c.masking = true
+	c.shell.Display.Refresh()
+	c.masking = false
+	c.pairShown = false
+}
+
+// joinWords joins shell words into a line, only quoting those that need to be.
+func joinWords(words []string) string {
+	quoted := make([]string, 0, len(words))
+
+	for _, word := range words {
+		if word == "" || strings.ContainsAny(word, " \t\n'\"\\$`;&|<>()#") {
+			word = shellquote.Join(word)
+		}
+
+		quoted = append(quoted, word)
+	}
+
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,122 @@
+package console
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+// redactMenu returns the menu of a console with commands taking sensitive flags and arguments.
+func redactMenu(t *testing.T) *Menu {
+	t.Helper()
+
+	c := New("test")
+	menu := c.ActiveMenu()
+
+	menu.SetCommands(func() *cobra.Command {
+		root := &cobra.Command{}
+
+		login := &cobra.Command{Use: "login", Aliases: []string{"in"}, Run: func(*cobra.Command, []string) {}}
+		login.Flags().StringP("password", "p", "", "password")
+		login.Flags().StringP("user", "u", "", "user name")
+		login.Flags().BoolP("verbose", "v", false, "verbose output")
+		MarkFlagSensitive(login, "password")
+		MarkArgsSensitive(login, 1)
+
+		vault := &cobra.Command{Use: "vault"}
+		vault.PersistentFlags().String("token", "", "vault token")
+		MarkFlagSensitive(vault, "token")
+
+		unseal := &cobra.Command{Use: "unseal", Run: func(*cobra.Command, []string) {}}
+		MarkArgsSensitive(unseal)
+		vault.AddCommand(unseal)
+
+		root.AddCommand(login, vault)
+
+		return root
+	})
+
+	menu.resetPreRun()
+
+	return menu
+}
+
+func TestSensitiveArgs(t *testing.T) {
+	menu := redactMenu(t)
+
+	tests := []struct {
+		line string
+		want []int
+	}{
+		{"login", []int{-1}},
+		{"login --password=secret", []int{-1, 11}},
+		{"login --password secret", []int{-1, -1, 0}},
+		{"login --user=bob --password= bob", []int{-1, -1, 11, -1}},
+		{"login -psecret", []int{-1, 2}},
+		{"login -vpsecret", []int{-1, 3}},
+		{"login -vp secret", []int{-1, -1, 0}},
+		{"login -usecret -v", []int{-1, -1, -1}},
+		{"login bob secret other", []int{-1, -1, 0, -1}},
+		{"in -u bob bob secret", []int{-1, -1, -1, -1, 0}},
+		{"login -- --password secret", []int{-1, -1, -1, 0}},
+		{"login bob -- -psecret", []int{-1, -1, -1, 0}},
+		{"login --unknown secret", []int{-1, -1, -1}},
+		{"vault unseal --token t0k3n key1 key2", []int{-1, -1, -1, 0, 0, 0}},
+		{"vault --token=t0k3n unseal", []int{-1, 8, -1}},
+		{"unknown --password secret", []int{-1, -1, -1}},
+	}
+
+	for _, test := range tests {
+		if offsets := menu.sensitiveArgs(strings.Fields(test.line)); !slices.Equal(offsets, test.want) {
+			t.Errorf("%q: offsets are %v, want %v", test.line, offsets, test.want)
+		}
+	}
+}
+
+func TestRedactLine(t *testing.T) {
+	menu := redactMenu(t)
+	menu.console.SetRedactor(func(value string) string { return "[" + strings.Repeat("x", len(value)) + "]" })
+
+	tests := []struct {
+		line, want string
+	}{
+		{"login bob", "login bob"},
+		{"login --password=secret bob", "login --password=[xxxxxx] bob"},
+		{"login -p 'two words' bob", "login -p [xxxxxxxxx] bob"},
+		{"login -vpsecret", "login -vp[xxxxxx]"},
+		{"login bob secret", "login bob [xxxxxx]"},
+		{"login -- -x secret", "login -- -x [xxxxxx]"},
+		{"login 'unterminated", "login 'unterminated"},
+	}
+
+	for _, test := range tests {
+		if redacted := menu.RedactLine(test.line); redacted != test.want {
+			t.Errorf("%q redacted as %q, want %q", test.line, redacted, test.want)
+		}
+	}
+}
+
+func TestMaskLine(t *testing.T) {
+	menu := redactMenu(t)
+
+	tests := []struct {
+		line, want string
+	}{
+		{"login bob", "login bob"},
+		{"login --password=secret", "login --password=******"},
+		{"login --password 'se cret' -v", "login --password ********* -v"},
+		{"login -psecret", "login -p******"},
+		{"login -vp secret", "login -vp ******"},
+		{"login bob s\\ ecret", "login bob ********"},
+		{"login -- bob 日本", "login -- bob ****"},
+		{"vault unseal key1  key2", "vault unseal ****  ****"},
+	}
+
+	for _, test := range tests {
+		if masked := string(menu.maskLine([]rune(test.line))); masked != test.want {
+			t.Errorf("%q masked as %q, want %q", test.line, masked, test.want)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package console
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// pendingMessage is an asynchronous message waiting for the next redraw.
+type pendingMessage struct {
+	text      string
+	transient bool // Printed above the prompt (TransientPrintf), or below it (Printf).
+}
+
+// coalesceMessage queues an asynchronous message if the prompt has been redrawn too
+// recently (see Console.MaxRedrawRate), or if other messages are already waiting, and
+// returns true. The print lock must be held.
+func (c *Console) coalesceMessage(text string, transient bool) bool {
+	if c.MaxRedrawRate <= 0 {
+		return false
+	}
+
+	interval := time.Second / time.Duration(c.MaxRedrawRate)
+	elapsed := time.Since(c.lastRedraw)
+
+	if len(c.pendingMessages) == 0 && elapsed >= interval {
+		c.lastRedraw = time.Now()
+		return false
+	}
+
+	c.pendingMessages = append(c.pendingMessages, pendingMessage{text: text, transient: transient})
+
+	if !c.redrawScheduled {
+		c.redrawScheduled = true
+
+		time.AfterFunc(max(interval-elapsed, 0), func() {
+			c.runOnLoop(func() {
+				c.printMutex.Lock()
+				defer c.printMutex.Unlock()
+
+				c.redrawScheduled = false
+				c.lastRedraw = time.Now()
+				c.printPendingMessages()
+			})
+		})
+	}
+
+	return true
+}
+
+// printPendingMessages prints the messages waiting for a redraw, in order: consecutive
+// messages of the same kind are printed at once. If a command is being executed, they
+// are simply printed. The print lock must be held.
+func (c *Console) printPendingMessages() {
+	pending := c.pendingMessages
+	c.pendingMessages = nil
+
+	for len(pending) > 0 {
+		var text strings.Builder
+
+		transient := pending[0].transient
+
+		for len(pending) > 0 && pending[0].transient == transient {
+			text.WriteString(pending[0].text)
+			pending = pending[1:]
+		}
+
+		switch {
+		case c.executing():
+			fmt.Print(text.String())
+		case transient:
+			c.printTransient(text.String())
+		default:
+			c.shell.Printf("%s", text.String())
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package console
+
+// regionState is the region of the input line between the mark and the cursor,
+// activated by set-mark or exchange-point-and-mark: it is highlighted, and can be
+// killed or copied with kill-region and copy-region-as-kill, until the line changes.
+type regionState struct {
+	active bool
+	line   string // Input line when the region was activated.
+}
+
+// setupRegion replaces the readline mark commands with ones highlighting the region
+// between the mark and the cursor in emacs mode, while the line is not edited. The
+// region commands act on the vi visual selection instead, when there is one.
+func (c *Console) setupRegion() {
+	commands := c.shell.Keymap.Commands()
+	killRegion, copyRegion := commands["kill-region"], commands["copy-region-as-kill"]
+
+	if killRegion == nil || copyRegion == nil {
+		return
+	}
+
+	c.shell.Keymap.Register(map[string]func(){
+		"set-mark":                c.setMark,
+		"exchange-point-and-mark": c.exchangePointAndMark,
+		"kill-region": func() {
+			if !c.cutRegion(true) {
+				killRegion()
+			}
+		},
+		"copy-region-as-kill": func() {
+			if !c.cutRegion(false) {
+				copyRegion()
+			}
+		},
+	})
+}
+
+// setMark sets the mark at the cursor (or at the position given as numeric
+// argument), and activates the region between the mark and the cursor.
+func (c *Console) setMark() {
+	line := c.shell.Line()
+	cursor := c.shell.Cursor()
+
+	if c.shell.Iterations.IsSet() {
+		pos := cursor.Pos()
+
+		cursor.Set(min(max(c.shell.Iterations.Get(), 0), line.Len()))
+		cursor.SetMark()
+		cursor.Set(pos)
+	} else {
+		cursor.SetMark()
+	}
+
+	c.region = regionState{active: true, line: string(*line)}
+}
+
+// exchangePointAndMark swaps the cursor and the mark (set at the start of the line
+// if there is none), and activates the region between them.
+func (c *Console) exchangePointAndMark() {
+	line := c.shell.Line()
+	cursor := c.shell.Cursor()
+	pos, mark := cursor.Pos(), cursor.Mark()
+
+	if mark < 0 || mark > line.Len() {
+		cursor.Set(0)
+		cursor.SetMark()
+		cursor.Set(pos)
+	} else {
+		cursor.SetMark()
+		cursor.Set(mark)
+	}
+
+	c.region = regionState{active: true, line: string(*line)}
+}
+
+// activeRegion returns the bounds of the region, if it is active: the
+// region is deactivated once the line has been edited since activated.
+func (c *Console) activeRegion() (start, end int, active bool) {
+	line := c.shell.Line()
+	mark := c.shell.Cursor().Mark()
+
+	if !c.region.active || c.region.line != string(*line) || mark < 0 || mark > line.Len() {
+		c.region.active = false
+		return 0, 0, false
+	}
+
+	start, end = mark, c.shell.Cursor().Pos()
+	if start > end {
+		start, end = end, start
+	}
+
+	return start, end, start != end
+}
+
+// cutRegion copies the active region to the kill buffer, and removes it from the
+// line if cut is true. It returns false if the vi visual selection is active, or
+// if there is no active region, for the readline command to be used instead.
+func (c *Console) cutRegion(cut bool) bool {
+	if c.shell.Selection().Active() {
+		return false
+	}
+
+	start, end, active := c.activeRegion()
+	if !active {
+		return false
+	}
+
+	line := c.shell.Line()
+
+	c.shell.Buffers.Write([]rune(string((*line)[start:end]))...)
+
+	if cut {
+		c.shell.History.Save()
+		line.Cut(start, end)
+		c.shell.Cursor().Set(start)
+	}
+
+	c.region.active = false
+
+	return true
+}
+
+// highlightRegion highlights the active region of an already highlighted line,
+// unless the line displayed is not the input one (eg. with a completion inserted).
+func (c *Console) highlightRegion(input []rune, line string) string {
+	if string(input) != string(*c.shell.Line()) {
+		return line
+	}
+
+	start, end, active := c.activeRegion()
+	if !active {
+		return line
+	}
+
+	positions := make([]int, 0, end-start)
+	for pos := start; pos < end; pos++ {
+		positions = append(positions, pos)
+	}
+
+	return highlightRunes(line, positions...)
+}
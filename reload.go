@@ -0,0 +1,202 @@
+package console
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/reeflective/readline/inputrc"
+)
+
+// OnReload registers hooks run when the console configuration is reloaded (see Reload),
+// for the application to reload its own configuration files: prompts, aliases, options
+// set or keys bound with the shell API (which are otherwise reset by the inputrc files),
+// etc. Hooks return a description of what they changed (eg. "alias ll: added"), which
+// is reported with the other changes. Hooks are run in the order they are registered.
+func (c *Console) OnReload(hooks ...func() (changes []string, err error)) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.reloadHooks = append(c.reloadHooks, hooks...)
+}
+
+// Reload reloads the configuration of the console while it runs: the inputrc files (with
+// the options and binds of the console applied on top of them), then the configuration of
+// the application, with the hooks registered with OnReload (eg. startup files, see the rc
+// package). Changes are applied immediately, and are reported above the prompt: options
+// whose value changed, keys bound, rebound or unbound, and the changes of the hooks. The
+// prompt is redrawn with the new configuration once the current command (if any) is done.
+//
+// All hooks are run even if the inputrc files or some hooks fail: their errors are
+// returned together, and the valid parts of the configuration are still applied.
+func (c *Console) Reload() error {
+	before := snapshotConfig(c.shell.Config)
+
+	// The overrides of the active menu are applied again on the new configuration.
+	overridden := c.menuOverrides()
+	c.restoreMenuOverrides()
+
+	var errs []error
+
+	if err := c.loadConfig(); err != nil {
+		errs = append(errs, fmt.Errorf("inputrc: %w", err))
+	}
+
+	c.mutex.RLock()
+	hooks := append([]func() ([]string, error){}, c.reloadHooks...)
+	c.mutex.RUnlock()
+
+	var changes []string
+
+	for _, hook := range hooks {
+		changed, err := hook()
+		changes = append(changes, changed...)
+
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if overridden != nil {
+		c.applyMenuOverrides(overridden.menu)
+	}
+
+	// The line being typed uses the commands of the new configuration.
+	c.mutex.RLock()
+	reading := c.reading && !c.isExecuting
+	c.mutex.RUnlock()
+
+	if reading {
+		c.activeMenu().resetPreRun()
+	}
+
+	changes = append(configChanges(before, c.shell.Config), changes...)
+
+	// Completions and hints might depend on the configuration.
+	c.clearCaches()
+
+	if len(changes) == 0 {
+		c.TransientPrintf("%s\n", tr("Configuration reloaded (no changes)"))
+	} else {
+		c.TransientPrintf("%s\n  %s\n", tr("Configuration reloaded:"), strings.Join(changes, "\n  "))
+	}
+
+	return errors.Join(errs...)
+}
+
+// ReloadOnHangup reloads the configuration (see Reload) each time the process
+// receives SIGHUP, like daemons do, rather than exiting: users can thus apply the
+// changes made to their configuration files with `kill -HUP <pid>`, from another
+// terminal or from their editor. Errors are printed above the prompt, like
+// the changes. This is a shortcut for HandleSignal(syscall.SIGHUP, ...): the
+// reload is thus run by the input loop, in between the keys typed by the user
+// or while waiting for the current command, never concurrently with the shell.
+func (c *Console) ReloadOnHangup() {
+	c.HandleSignal(syscall.SIGHUP, func(c *Console) {
+		if err := c.Reload(); err != nil {
+			c.TransientPrintf("%s", FormatError(newError(err, "Reload error")))
+		}
+	})
+}
+
+// configSnapshot is a copy of the shell options and binds.
+type configSnapshot struct {
+	vars  map[string]string
+	binds map[string]map[string]inputrc.Bind
+}
+
+// snapshotConfig copies the options and binds of the configuration.
+func snapshotConfig(cfg *inputrc.Config) configSnapshot {
+	snapshot := configSnapshot{
+		vars:  make(map[string]string, len(cfg.Vars)),
+		binds: make(map[string]map[string]inputrc.Bind, len(cfg.Binds)),
+	}
+
+	for name, value := range cfg.Vars {
+		if name != "" && value != nil {
+			snapshot.vars[name] = fmt.Sprint(value)
+		}
+	}
+
+	for keymap, binds := range cfg.Binds {
+		snapshot.binds[keymap] = make(map[string]inputrc.Bind, len(binds))
+
+		for seq, bind := range binds {
+			snapshot.binds[keymap][seq] = bind
+		}
+	}
+
+	return snapshot
+}
+
+// configChanges describes the options and binds which differ between the snapshot
+// and the configuration, in .inputrc-like format (eg. `set editing-mode vi (was emacs)`).
+func configChanges(before configSnapshot, cfg *inputrc.Config) []string {
+	after := snapshotConfig(cfg)
+
+	var changes []string
+
+	for _, name := range sortedKeys(before.vars, after.vars) {
+		previous, had := before.vars[name]
+		current, has := after.vars[name]
+
+		switch {
+		case !has:
+			changes = append(changes, fmt.Sprintf("unset %s (was %s)", name, previous))
+		case !had:
+			changes = append(changes, fmt.Sprintf("set %s %s", name, current))
+		case previous != current:
+			changes = append(changes, fmt.Sprintf("set %s %s (was %s)", name, current, previous))
+		}
+	}
+
+	for _, keymap := range sortedKeys(before.binds, after.binds) {
+		previous, current := before.binds[keymap], after.binds[keymap]
+
+		for _, seq := range sortedKeys(previous, current) {
+			old, had := previous[seq]
+			bind, has := current[seq]
+
+			switch {
+			case !has:
+				changes = append(changes, fmt.Sprintf(`unbind "%s" in %s (was %s)`, inputrc.Escape(seq), keymap, describeBind(old)))
+			case !had:
+				changes = append(changes, fmt.Sprintf(`bind "%s" in %s: %s`, inputrc.Escape(seq), keymap, describeBind(bind)))
+			case old != bind:
+				changes = append(changes, fmt.Sprintf(`bind "%s" in %s: %s (was %s)`, inputrc.Escape(seq), keymap, describeBind(bind), describeBind(old)))
+			}
+		}
+	}
+
+	return changes
+}
+
+// describeBind returns the command of a bind, or its quoted macro.
+func describeBind(bind inputrc.Bind) string {
+	if bind.Macro {
+		return `"` + inputrc.EscapeMacro(bind.Action) + `"`
+	}
+
+	return bind.Action
+}
+
+// sortedKeys returns the keys of both maps, sorted and without duplicates.
+func sortedKeys[V any](first, second map[string]V) []string {
+	keys := make([]string, 0, len(first)+len(second))
+
+	for key := range first {
+		keys = append(keys, key)
+	}
+
+	for key := range second {
+		if _, found := first[key]; !found {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
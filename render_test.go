@@ -0,0 +1,98 @@
+package console
+
+import (
+	"testing"
+)
+
+// newRenderConsole returns a console with 600 root commands, for the benchmarks of the
+// render path, which runs on each keystroke: the line is highlighted and the prompt
+// printed again when redrawing, and completions are generated again (not from the
+// cache) when the line changed.
+//
+//	go test -run NONE -bench 'Highlight|Prompt|Complete' -benchmem
+func newRenderConsole(tb testing.TB) *Console {
+	tb.Helper()
+
+	c := New("bench")
+	menu := c.ActiveMenu()
+	menu.SetCommands(hugeCommands(6, 100, make(map[string]int)))
+	menu.resetPreRun()
+
+	return c
+}
+
+const renderLine = "group5-cmd99 list --name value --tags a,b arg"
+
+func BenchmarkHighlightSyntax(b *testing.B) {
+	c := newRenderConsole(b)
+	line := []rune(renderLine)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for range b.N {
+		c.highlightSyntax(line)
+	}
+}
+
+func BenchmarkPrompt(b *testing.B) {
+	c := newRenderConsole(b)
+	prompt := c.activeMenu().prompt
+	primary := prompt.plain(prompt.Primary)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for range b.N {
+		primary()
+	}
+}
+
+func BenchmarkComplete(b *testing.B) {
+	benchmarks := []struct {
+		name string
+		line string
+	}{
+		{"commands", "group"},
+		{"flags", "group5-cmd99 --"},
+	}
+
+	for _, bench := range benchmarks {
+		b.Run(bench.name, func(b *testing.B) {
+			c := newRenderConsole(b)
+			line := []rune(bench.line)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for range b.N {
+				c.completionCache.clear()
+				c.complete(line, len(line))
+			}
+		})
+	}
+}
+
+// TestRenderAllocations keeps the allocations of the render path within a budget,
+// well above the measured ones (28 for highlighting, 1 for the prompt), so that
+// regressions like matching every root command on each keystroke (585) fail.
+func TestRenderAllocations(t *testing.T) {
+	c := newRenderConsole(t)
+	line := []rune(renderLine)
+	prompt := c.activeMenu().prompt
+
+	budgets := []struct {
+		name   string
+		budget float64
+		render func()
+	}{
+		{"highlight", 60, func() { c.highlightSyntax(line) }},
+		{"prompt", 4, func() { prompt.plain(prompt.Primary)() }},
+	}
+
+	for _, budget := range budgets {
+		if allocs := testing.AllocsPerRun(100, budget.render); allocs > budget.budget {
+			t.Errorf("%s: %.0f allocations per run, budget %.0f", budget.name, allocs, budget.budget)
+		}
+	}
+}
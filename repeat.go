@@ -0,0 +1,66 @@
+package console
+
+import "strconv"
+
+// setupRepeat registers the universal-argument command, and replaces the readline
+// digit-argument and call-last-kbd-macro ones, so that numeric arguments behave like
+// in bash: C-u multiplies the argument by four (four without one), the digits typed
+// with Alt after it replace it, and the last keyboard macro is ran as many times.
+//
+// universal-argument is not bound by default, C-u being bound to unix-line-discard:
+//
+//	"\C-u": universal-argument
+func (c *Console) setupRepeat() {
+	commands := c.shell.Keymap.Commands()
+	digitArgument, callMacro := commands["digit-argument"], commands["call-last-kbd-macro"]
+
+	if digitArgument == nil || callMacro == nil {
+		return
+	}
+
+	c.shell.Keymap.Register(map[string]func(){
+		"universal-argument": c.universalArgument,
+		"digit-argument": func() {
+			// The argument of universal-argument is a default one, replaced by the digits.
+			if c.universalArg && c.shell.Iterations.IsSet() {
+				c.shell.Iterations.Get()
+			}
+
+			c.universalArg = false
+
+			digitArgument()
+		},
+		"call-last-kbd-macro": func() {
+			for range c.repeatCount() {
+				callMacro()
+			}
+		},
+	})
+}
+
+// universalArgument multiplies the numeric argument by four,
+// or sets it to four if there is none.
+func (c *Console) universalArgument() {
+	c.shell.History.SkipSave()
+
+	times := 4
+	if c.shell.Iterations.IsSet() {
+		times *= c.shell.Iterations.Get()
+	}
+
+	c.shell.Iterations.Add(strconv.Itoa(times))
+	c.universalArg = true
+}
+
+// repeatCount returns the number of times a command should be ran, given by the
+// numeric argument if there is one (negative arguments repeating the command as
+// many times), and drops the argument.
+func (c *Console) repeatCount() int {
+	if !c.shell.Iterations.IsSet() {
+		return 1
+	}
+
+	times := c.shell.Iterations.Get()
+
+	return max(times, -times)
+}
@@ -0,0 +1,64 @@
+package console
+
+import (
+	"time"
+)
+
+// resizeDelay is the time waited after a terminal resize event before dividing
+// the screen again, so that the burst of events generated while the user is
+// resizing the window is coalesced, and that the shell has finished its refresh.
+const resizeDelay = 50 * time.Millisecond
+
+// watchResize divides the screen again when the terminal is resized while reading
+// user input, if the log panel is enabled. The prompt, input line and completions
+// are reflowed by the shell itself, which watches resize events while reading: the
+// log panel relayout and the prompt redrawn below it are run on the input loop, so
+// as not to race the shell. The returned function stops watching resize events.
+func (c *Console) watchResize() (stop func()) {
+	resized, stopNotify := notifyResize()
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-resized:
+				time.Sleep(resizeDelay)
+				drainResize(resized)
+
+				c.runOnLoop(c.relayoutReading)
+
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		stopNotify()
+		close(done)
+	}
+}
+
+// relayoutReading divides the screen again and redraws the prompt below the
+// log panel, if it is enabled and if the shell is still reading user input.
+func (c *Console) relayoutReading() {
+	c.mutex.RLock()
+	reading := c.reading && !c.isExecuting
+	c.mutex.RUnlock()
+
+	if reading && c.relayoutLogPanel() {
+		c.shell.Display.PrintPrimaryPrompt()
+		c.shell.Display.Refresh()
+	}
+}
+
+// drainResize discards all pending resize events.
+func drainResize(resized <-chan struct{}) {
+	for {
+		select {
+		case <-resized:
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !unix && !windows
+
+package console
+
+// notifyResize is not supported on this platform, and never notifies.
+func notifyResize() (resized <-chan struct{}, stop func()) {
+	return make(chan struct{}), func() {}
+}
@@ -0,0 +1,37 @@
+//go:build unix
+
+package console
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// notifyResize returns a channel notified on each SIGWINCH signal.
+func notifyResize() (resized <-chan struct{}, stop func()) {
+	sigchan := make(chan os.Signal, 1)
+	events := make(chan struct{}, 1)
+	done := make(chan struct{})
+
+	signal.Notify(sigchan, syscall.SIGWINCH)
+
+	go func() {
+		for {
+			select {
+			case <-sigchan:
+				select {
+				case events <- struct{}{}:
+				default:
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return events, func() {
+		signal.Stop(sigchan)
+		close(done)
+	}
+}
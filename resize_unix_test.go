@@ -0,0 +1,30 @@
+//go:build unix
+
+package console
+
+import (
+	"context"
+	"syscall"
+	"testing"
+)
+
+func TestWatchResizeOnLoop(t *testing.T) {
+	c := New("test")
+	_, stop := c.startLoop(context.Background())
+
+	defer stop()
+
+	stopResize := c.watchResize()
+	defer stopResize()
+
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGWINCH); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without a log panel, the shell reflows the prompt alone.
+	waitQueued(t, c)
+
+	if output := captureStdout(t, c.runPending); output != "" {
+		t.Errorf("resize printed %q without a log panel", output)
+	}
+}
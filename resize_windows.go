@@ -0,0 +1,61 @@
+//go:build windows
+
+package console
+
+import (
+	"os"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+// resizePollInterval is how often the console buffer size is checked.
+const resizePollInterval = 200 * time.Millisecond
+
+// notifyResize returns a channel notified each time the size of the
+// console window changes. Since there is no signal for this on Windows,
+// the console screen buffer information is polled at regular intervals.
+func notifyResize() (resized <-chan struct{}, stop func()) {
+	events := make(chan struct{}, 1)
+	done := make(chan struct{})
+	handle := windows.Handle(os.Stdout.Fd())
+
+	go func() {
+		ticker := time.NewTicker(resizePollInterval)
+		defer ticker.Stop()
+
+		last := windowSize(handle)
+
+		for {
+			select {
+			case <-ticker.C:
+				size := windowSize(handle)
+				if size == last {
+					continue
+				}
+
+				last = size
+
+				select {
+				case events <- struct{}{}:
+				default:
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return events, func() { close(done) }
+}
+
+// windowSize returns the current size of the console window.
+func windowSize(handle windows.Handle) windows.SmallRect {
+	var info windows.ConsoleScreenBufferInfo
+
+	if err := windows.GetConsoleScreenBufferInfo(handle, &info); err != nil {
+		return windows.SmallRect{}
+	}
+
+	return info.Window
+}
@@ -0,0 +1,128 @@
+package console
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ResourceCompleter lists the resources of an API (eg. Kubernetes, cloud providers, or an
+// inventory) for completions: tools implement it once, and complete the resources of all
+// their commands consistently with CompleteResources.
+type ResourceCompleter interface {
+	// List returns the resources of a kind (eg. "pod" or "instance"), matching the
+	// filters given, by name (eg. "namespace" or "region"). Filters not set in the
+	// command line are not given.
+	List(ctx context.Context, kind string, filters map[string]string) ([]Candidate, error)
+}
+
+// ResourceFunc is a function implementing a ResourceCompleter.
+type ResourceFunc func(ctx context.Context, kind string, filters map[string]string) ([]Candidate, error)
+
+// List calls the function.
+func (f ResourceFunc) List(ctx context.Context, kind string, filters map[string]string) ([]Candidate, error) {
+	return f(ctx, kind, filters)
+}
+
+// CompleteResources returns a dynamic completer for resources of a kind, filtered with the
+// values of the given flags in the command line, the filters being named after the flags:
+//
+//	console.CompleteFlag(cmd, "instance", console.CompleteResources(cloud, "instance", "region"))
+//
+// Resources are not proposed if they cannot be listed in time, or if listing them fails.
+func CompleteResources(resources ResourceCompleter, kind string, filterFlags ...string) CompleterFunc {
+	return func(comp CompletionContext) []Candidate {
+		filters := make(map[string]string)
+
+		// Default values are not filters set by the user.
+		for _, flag := range filterFlags {
+			if comp.Changed(flag) {
+				filters[flag] = comp.Flag(flag)
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+		defer cancel()
+
+		candidates, err := resources.List(ctx, kind, filters)
+		if err != nil {
+			return nil
+		}
+
+		return candidates
+	}
+}
+
+// CacheResources returns a resource completer caching the resources listed by another
+// one for a while, by kind and filters, so that APIs are not queried on each completion.
+// Failures are not cached.
+func CacheResources(resources ResourceCompleter, ttl time.Duration) ResourceCompleter {
+	return &resourceCache{
+		resources: resources,
+		ttl:       ttl,
+		entries:   make(map[string]commandCompletion),
+	}
+}
+
+// resourceCache is a resource completer caching the resources of another.
+type resourceCache struct {
+	resources ResourceCompleter
+	ttl       time.Duration
+	entries   map[string]commandCompletion
+	mutex     sync.Mutex
+}
+
+// List returns the resources cached for the kind and filters, if they have not expired,
+// or lists them again.
+func (r *resourceCache) List(ctx context.Context, kind string, filters map[string]string) ([]Candidate, error) {
+	key := resourceKey(kind, filters)
+
+	r.mutex.Lock()
+	cached, found := r.entries[key]
+	r.mutex.Unlock()
+
+	if found && time.Now().Before(cached.expires) {
+		return cached.candidates, nil
+	}
+
+	candidates, err := r.resources.List(ctx, kind, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// Forget the expired resources, which would be listed again anyway.
+	for key, entry := range r.entries {
+		if time.Now().After(entry.expires) {
+			delete(r.entries, key)
+		}
+	}
+
+	r.entries[key] = commandCompletion{
+		candidates: candidates,
+		expires:    time.Now().Add(r.ttl),
+	}
+
+	return candidates, nil
+}
+
+// resourceKey returns the cache key of resources of a kind, listed with filters.
+func resourceKey(kind string, filters map[string]string) string {
+	names := make([]string, 0, len(filters))
+	for name := range filters {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	key := []string{kind}
+	for _, name := range names {
+		key = append(key, name+"="+filters[name])
+	}
+
+	return strings.Join(key, "\x00")
+}
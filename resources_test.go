@@ -0,0 +1,52 @@
+package console
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestCompleteResourcesFilters(t *testing.T) {
+	cmd := &cobra.Command{Use: "ssh"}
+	cmd.Flags().String("region", "us-east-1", "")
+	cmd.Flags().String("zone", "", "")
+
+	var given map[string]string
+
+	resources := ResourceFunc(func(_ context.Context, kind string, filters map[string]string) ([]Candidate, error) {
+		given = filters
+		return []Candidate{{Value: kind + "-1"}}, nil
+	})
+
+	complete := CompleteResources(resources, "instance", "region", "zone")
+
+	tests := []struct {
+		name  string
+		flags map[string][]string
+		want  map[string]string
+	}{
+		{"unset flags", nil, map[string]string{}},
+		{"set flag", map[string][]string{"region": {"eu-west-1"}}, map[string]string{"region": "eu-west-1"}},
+		{"empty value", map[string][]string{"zone": {""}}, map[string]string{"zone": ""}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			candidates := complete(CompletionContext{Command: cmd, Flags: test.flags})
+			if len(candidates) != 1 || candidates[0].Value != "instance-1" {
+				t.Fatalf("candidates = %v", candidates)
+			}
+
+			if len(given) != len(test.want) {
+				t.Fatalf("filters = %v, want %v", given, test.want)
+			}
+
+			for name, value := range test.want {
+				if given[name] != value {
+					t.Errorf("filter %s = %q, want %q", name, given[name], value)
+				}
+			}
+		})
+	}
+}
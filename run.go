@@ -4,8 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
-	"os/signal"
+	"sync"
 	"syscall"
 
 	"github.com/kballard/go-shellquote"
@@ -15,12 +16,33 @@ import (
 // Start - Start the console application (readline loop). Blocking.
 // The error returned will always be an error that the console
 // application does not understand or cannot handle.
+// When the standard input is not a terminal, the console
+// reads and executes its input line by line instead (see LineMode).
+// Hooks can be run when the console starts and stops (see OnStart and OnExit).
 func (c *Console) Start() error {
 	return c.StartContext(context.Background())
 }
 
 // StartContext is like console.Start(). with a user-provided context.
+// The console returns the context error once it is done, before reading
+// the next input line.
 func (c *Console) StartContext(ctx context.Context) error {
+	// Pipes and dumb terminals are read line by line, without the shell.
+	if lineModeTerminal() {
+		return c.StartStream(ctx, os.Stdin, StreamOptions{})
+	}
+
+	return c.runSession(func() error {
+		return c.run(ctx)
+	})
+}
+
+// run reads and executes command lines with the shell, forever.
+func (c *Console) run(ctx context.Context) error {
+	// Signal handlers, asynchronous messages, etc, are run by this loop.
+	ctx, stopLoop := c.startLoop(ctx)
+	defer stopLoop()
+
 	c.loadActiveHistories()
 
 	// Print the console logo
@@ -28,9 +50,16 @@ func (c *Console) StartContext(ctx context.Context) error {
 		c.printLogo(c)
 	}
 
+	// Offer to restore the session after an abnormal exit.
+	c.recoverState()
+
 	lastLine := "" // used to check if last read line is empty.
 
 	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
 		c.displayPostRun(lastLine)
 
 		// Always ensure we work with the active menu, with freshly
@@ -38,61 +67,116 @@ func (c *Console) StartContext(ctx context.Context) error {
 		menu := c.activeMenu()
 		menu.resetPreRun()
 
+		// Apply the editing mode and binds of the menu, if it overrides them.
+		c.applyMenuOverrides(menu)
+
 		if err := c.runAllE(c.PreReadlineHooks); err != nil {
 			menu.ErrorHandler(PreReadError{newError(err, "Pre-read error")})
 
 			continue
 		}
 
-		// Block and read user input.
-		line, err := c.shell.Readline()
+		// Block and read user input, dividing the log panel again on
+		// terminal resize events, and handling job-control signals.
+		stopResize := c.watchResize()
+		stopSuspend := c.watchSuspend()
+		stopTerm := c.watchTermination()
+		line, err := c.readline()
+		stopTerm()
+		stopSuspend()
+		stopResize()
 
 		c.displayPostRun(line)
 
-		if err != nil {
-			menu.handleInterrupt(err)
+		// The shell or one of its callbacks has panicked.
+		if errors.As(err, &PanicError{}) {
+			menu.ErrorHandler(err)
 
 			lastLine = line
 
 			continue
 		}
 
-		// Any call to the SwitchMenu() while we were reading user
-		// input (through an interrupt handler) might have changed it,
-		// so we must be sure we use the good one.
-		menu = c.activeMenu()
-
-		// Parse the line with bash-syntax, removing comments.
-		args, err := c.parse(line)
 		if err != nil {
-			menu.ErrorHandler(ParseError{newError(err, "Parsing error")})
-			continue
-		}
+			menu.handleInterrupt(err)
 
-		if len(args) == 0 {
 			lastLine = line
-			continue
-		}
 
-		// Run user-provided pre-run line hooks,
-		// which may modify the input line args.
-		args, err = c.runLineHooks(args)
-		if err != nil {
-			menu.ErrorHandler(LineHookError{newError(err, "Line error")})
 			continue
 		}
 
-		// Run all pre-run hooks and the command itself
-		// Don't check the error: if its a cobra error,
-		// the library user is responsible for setting
-		// the cobra behavior.
-		// If it's an interrupt, we take care of it.
-		if err := c.execute(ctx, menu, args, false); err != nil {
-			menu.ErrorHandler(ExecutionError{newError(err, "")})
+		if processed, _ := c.executeLine(ctx, line); processed {
+			lastLine = line
 		}
+	}
+}
 
-		lastLine = line
+// ExecuteLine processes and executes an input line in the active menu, as if it
+// had been typed by the user: command substitutions, comments, variables and line
+// hooks are all processed like for lines read by the console, and errors are passed
+// to the menu error handler. This is notably used to drive the console in tests.
+// An empty line only prepares the active menu (its commands, prompt, etc) to read
+// a new line, like the console does before each of them.
+func (c *Console) ExecuteLine(ctx context.Context, line string) {
+	c.activeMenu().resetPreRun()
+	c.executeLine(ctx, line)
+}
+
+// executeLine processes and executes an input line, and returns false if
+// the line could not be processed (in which case nothing is executed), along
+// with the error passed to the menu error handler, if any.
+func (c *Console) executeLine(ctx context.Context, line string) (processed bool, err error) {
+	// Any call to the SwitchMenu() while we were reading user
+	// input (through an interrupt handler) might have changed it,
+	// so we must be sure we use the good one.
+	menu := c.activeMenu()
+
+	// Replace command substitutions with their output.
+	line, err = c.substituteCommands(ctx, line, 0)
+	if err != nil {
+		err = ParseError{newError(err, "Parsing error")}
+		menu.ErrorHandler(err)
+
+		return false, err
 	}
+
+	// Parse the line with bash-syntax, removing comments.
+	args, err := c.parse(line)
+	if err != nil {
+		err = ParseError{newError(err, "Parsing error")}
+		menu.ErrorHandler(err)
+
+		return false, err
+	}
+
+	if len(args) == 0 {
+		return true, nil
+	}
+
+	// Run user-provided pre-run line hooks,
+	// which may modify the input line args.
+	args, err = c.runLineHooks(args)
+	if err != nil {
+		err = LineHookError{newError(err, "Line error")}
+		menu.ErrorHandler(err)
+
+		return false, err
+	}
+
+	// Run all pre-run hooks and the command itself.
+	// Errors are passed to the error handler, like all
+	// others: if it's a cobra error, the library user is
+	// responsible for setting the cobra behavior.
+	// If it's an interrupt, we take care of it.
+	if err = c.execute(ctx, menu, args, false); err != nil {
+		err = ExecutionError{newError(err, "")}
+		menu.ErrorHandler(err)
+	}
+
+	// Checkpoint the session state (menu, histories).
+	c.saveState("")
+
+	return true, err
 }
 
 // RunCommandArgs is a convenience function to run a command line in a given menu.
@@ -107,7 +191,7 @@ func (m *Menu) RunCommandArgs(ctx context.Context, args []string) (err error) {
 	m.resetPreRun()
 
 	// Run the command and associated helpers.
-	return m.console.execute(ctx, m, args, !m.console.isExecuting)
+	return m.console.execute(ctx, m, args, !m.console.executing())
 }
 
 // RunCommandLine is the equivalent of menu.RunCommandArgs(), but accepts
@@ -134,72 +218,247 @@ func (m *Menu) RunCommandLine(ctx context.Context, line string) (err error) {
 // instead of the menu itself, because if RunCommand() is asynchronously triggered while another
 // command is running, the menu's root command will be overwritten.
 func (c *Console) execute(ctx context.Context, menu *Menu, args []string, async bool) error {
+	c.mutex.Lock()
+	executing := c.isExecuting
+
 	if !async {
-		c.mutex.RLock()
 		c.isExecuting = true
-		c.mutex.RUnlock()
 	}
+	c.mutex.Unlock()
 
+	// Commands executed by others (eg. by Watch) leave them executing.
 	defer func() {
-		c.mutex.RLock()
-		c.isExecuting = false
-		c.mutex.RUnlock()
+		c.mutex.Lock()
+		c.isExecuting = executing
+		c.mutex.Unlock()
 	}()
 
 	// Our root command of interest, used throughout this function.
+	menu.mutex.RLock()
 	cmd := menu.Command
+	menu.mutex.RUnlock()
+
+	// Build the lazy command groups used in the line, including
+	// those whose name is corrected when suggesting close names.
+	menu.loadLazyGroups(args)
+
+	// Suggest (or correct to) close command names if not found.
+	args, err := c.correctCommand(menu, args)
+	if err != nil {
+		return err
+	}
+
+	menu.loadLazyGroups(args)
+
+	// Copy the command output to a file, if asked to.
+	args, teeFile := menu.teeArgs(args)
+
+	// Keep negative numbers as positional arguments.
+	args = menu.normalizeArgs(args)
 
 	// Find the target command: if this command is filtered, don't run it.
-	target, _, _ := cmd.Find(args)
+	target, flagArgs, _ := cmd.Find(args)
 
 	if err := menu.CheckIsAvailable(target); err != nil {
+		if errors.Is(err, ErrUnauthorized) {
+			c.audit(menu, target.CommandPath(), args, true, err)
+		}
+
 		return err
 	}
 
 	// Reset all flags to their default values.
-	resetFlagsDefaults(target)
+	c.resetFlags(target)
+
+	// Prompt for the flags of wizard-enabled commands.
+	args, flagArgs, err = c.runWizard(target, args, flagArgs)
+	if errors.Is(err, ErrAskAborted) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	// Check required flags and arguments before running anything.
+	if err := c.validateUsage(target, flagArgs); err != nil {
+		return err
+	}
+
+	// In dry-run mode, only commands knowing how to dry-run are executed.
+	dryRun := c.isDryRun(menu)
+
+	if dryRun && target.Runnable() && target.Annotations[CommandDryRunKey] == "" {
+		printDryRun(target, menu.RedactArgs(args))
+
+		return nil
+	}
 
 	// Console-wide pre-run hooks, cannot.
 	if err := c.runAllE(c.PreCmdRunHooks); err != nil {
-		return fmt.Errorf("pre-run error: %s", err.Error())
+		return fmt.Errorf("pre-run error: %w", err)
+	}
+
+	var tee *os.File
+
+	if teeFile != "" {
+		if tee, err = os.Create(teeFile); err != nil {
+			return err
+		}
 	}
 
-	// Assign those arguments to our parser.
+	// Warn about deprecated commands, once per session.
+	restoreDeprecated := c.warnDeprecated(target)
+	defer restoreDeprecated()
+
+	// Assign those arguments to our parser. Whether cobra prints
+	// the errors (in addition to the error handler) is the choice
+	// of the application, except for the trees wrapped by console.
 	cmd.SetArgs(args)
 
 	// The command execution should happen in a separate goroutine,
 	// and should notify the main goroutine when it is done.
-	ctx, cancel := context.WithCancelCause(ctx)
+	if dryRun {
+		ctx = context.WithValue(ctx, dryRunKey{}, true)
+	}
+
+	// Functions queued for the input loop are run while waiting for the command,
+	// if executed by the loop, but not by the commands it runs itself.
+	wake := c.loopWake(ctx)
+	ctx = context.WithValue(ctx, loopKey{}, false)
+
+	ctx, cancel := context.WithCancelCause(c.withDisplayContext(ctx))
 
 	cmd.SetContext(ctx)
 
-	// Start monitoring keyboard and OS signals.
+	// Start monitoring keyboard and OS signals, and the command timeout.
 	sigchan := c.monitorSignals()
 
-	// And start the command execution.
-	go c.executeCommand(cmd, cancel)
+	stopTimeout := c.watchTimeout(target, cancel)
+	defer stopTimeout()
+
+	// Copy the command output, restored before giving the prompt back.
+	restoreOutput, err := c.redirectCommandOutput(tee)
+	if err != nil {
+		cancel(nil)
+		return err
+	}
+
+	// And start the command execution (labeled in profiles, since
+	// goroutines inherit the labels of the one creating them).
+	profiled := c.profilePhase("command", "command", target.CommandPath())
+	go c.executeCommand(cmd, cancel, restoreOutput)
+	profiled()
 
 	// Wait for the command to finish, or for an OS signal to be caught.
-	select {
-	case <-ctx.Done():
-		cause := context.Cause(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			restoreOutput()
+
+			cause := context.Cause(ctx)
+
+			if errors.Is(cause, context.Canceled) {
+				cause = nil
+			}
+
+			c.audit(menu, target.CommandPath(), args, false, cause)
+
+			if cause == nil {
+				c.recordUsage(menu, target)
+			}
+
+			// The command is left running in the background,
+			// but we give the prompt back to the user.
+			if errors.Is(cause, ErrCommandTimeout) {
+				printTimeout(target, cause)
+
+				return nil
+			}
 
-		if !errors.Is(cause, context.Canceled) {
 			return cause
+
+		case signal := <-sigchan:
+			// Interactive programs handle their own signals.
+			c.mutex.RLock()
+			interactive := c.interactive
+			c.mutex.RUnlock()
+
+			if interactive {
+				continue
+			}
+
+			cancel(errors.New(signal.String()))
+			restoreOutput()
+
+			c.audit(menu, target.CommandPath(), args, false, context.Cause(ctx))
+
+			menu.handleInterrupt(errors.New(signal.String()))
+
+			return nil
+
+		case <-wake:
+			c.runPending()
 		}
+	}
+}
+
+// readline reads an input line with the shell, recovering from any panic
+// occurring either in the shell itself or in the callbacks it runs.
+func (c *Console) readline() (line string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			restoreTerminal()
+			fmt.Println()
 
-	case signal := <-sigchan:
-		cancel(errors.New(signal.String()))
+			err = newPanicError(r)
+		}
+	}()
 
-		menu.handleInterrupt(errors.New(signal.String()))
+	c.mutex.Lock()
+	c.reading = true
+	wake := c.wakeReading()
+	c.mutex.Unlock()
+
+	// Functions queued since the last line are run once reading.
+	if wake != nil {
+		wake()
 	}
 
-	return nil
+	// Completions and hints may change with the commands executed.
+	c.clearCaches()
+
+	defer func() {
+		c.mutex.Lock()
+		c.reading = false
+		c.mutex.Unlock()
+	}()
+
+	disableMouse := c.enableMouse()
+	defer disableMouse()
+
+	c.printCursor()
+
+	return c.shell.Readline()
 }
 
 // Run the command in a separate goroutine, and cancel the context when done.
-func (c *Console) executeCommand(cmd *cobra.Command, cancel context.CancelCauseFunc) {
-	if err := cmd.Execute(); err != nil {
+// If the command (or any post-run hook) panics, the terminal is restored and
+// the context is canceled with the panic error, so that we return to the prompt.
+// The standard streams redirected for the command are restored once it returns.
+func (c *Console) executeCommand(cmd *cobra.Command, cancel context.CancelCauseFunc, restoreOutput func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			restoreOutput()
+			restoreTerminal()
+
+			cancel(newPanicError(r))
+		}
+	}()
+
+	err := cmd.Execute()
+
+	restoreOutput()
+
+	if err != nil {
 		cancel(err)
 
 		return
@@ -217,11 +476,59 @@ func (c *Console) executeCommand(cmd *cobra.Command, cancel context.CancelCauseF
 	cancel(nil)
 }
 
+// redirectCommandOutput copies the output of a command to the tee file, if not nil,
+// and keeps it for insert-last-output, if asked to. The returned function restores
+// the standard streams, and closes the tee file: it is called both when the command
+// returns, and when the prompt is given back to the user (eg. if it is interrupted).
+func (c *Console) redirectCommandOutput(tee *os.File) (restore func(), err error) {
+	var writers []io.Writer
+
+	if tee != nil {
+		writers = append(writers, tee)
+	}
+
+	// Keep the output for insert-last-output, if asked to.
+	output := &headBuffer{size: maxLastOutput}
+
+	if c.KeepLastOutput {
+		writers = append(writers, output)
+	}
+
+	if len(writers) == 0 {
+		return func() {}, nil
+	}
+
+	restoreStreams, err := redirectStreams(true, writers...)
+	if err != nil {
+		if tee != nil {
+			tee.Close()
+		}
+
+		return nil, err
+	}
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			restoreStreams()
+
+			if tee != nil {
+				tee.Close()
+			}
+
+			c.mutex.Lock()
+			c.lastOutput = output.String()
+			c.mutex.Unlock()
+		})
+	}, nil
+}
+
 func (c *Console) loadActiveHistories() {
 	c.shell.History.Delete()
 
 	for _, name := range c.activeMenu().historyNames {
-		c.shell.History.Add(name, c.activeMenu().histories[name])
+		c.shell.History.Add(name, c.activeMenu().historySource(name))
 	}
 }
 
@@ -273,7 +580,9 @@ func (c *Console) displayPostRun(lastLine string) {
 		}
 	}
 
+	c.printMutex.Lock()
 	c.printed = false
+	c.printMutex.Unlock()
 }
 
 // monitorSignals - Monitor the signals that can be sent to the process
@@ -281,7 +590,8 @@ func (c *Console) displayPostRun(lastLine string) {
 func (c *Console) monitorSignals() <-chan os.Signal {
 	sigchan := make(chan os.Signal, 1)
 
-	signal.Notify(
+	// Signals handled by the application are left to it.
+	c.notifyUnhandled(
 		sigchan,
 		syscall.SIGINT,
 		syscall.SIGTERM,
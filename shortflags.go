@@ -0,0 +1,123 @@
+package console
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Stacked short flags are completed by carapace, which completes the next shorthand
+// flags of a group (eg. "-a<Tab>" completes "-al"), until one expecting an argument.
+func init() {
+	// The usage line of commands shows their stackable short flags.
+	cobra.AddTemplateFunc("stackedUseLine", stackedUseLine)
+}
+
+// stackedUsage makes the usage template of a root command print
+// its usage lines with stackable short flags (see stackedUseLine).
+func stackedUsage(root *cobra.Command) {
+	template := root.UsageTemplate()
+
+	if strings.Contains(template, "{{.UseLine}}") {
+		root.SetUsageTemplate(strings.ReplaceAll(template, "{{.UseLine}}", "{{stackedUseLine .}}"))
+	}
+}
+
+// stackedFlags returns the flags of a group of stacked short flags (eg. "-abc"), as
+// parsed by pflag: the group ends with the first flag expecting an argument, whose
+// value may be attached to it (eg. "-afjson"). No flags are returned if the group
+// contains a shorthand unknown to the command.
+func stackedFlags(cmd *cobra.Command, word string) (flags []*pflag.Flag, attached bool) {
+	group := []rune(strings.TrimPrefix(word, "-"))
+
+	for i, short := range group {
+		if short == '=' && i > 0 {
+			return flags, true
+		}
+
+		flag := shorthandFlag(cmd, short)
+		if flag == nil {
+			return nil, false
+		}
+
+		flags = append(flags, flag)
+
+		if flag.NoOptDefVal == "" {
+			return flags, i+1 < len(group)
+		}
+	}
+
+	return flags, false
+}
+
+// takesNextValue returns true if a command line word is a flag (or a group of stacked
+// short flags) whose value is the next word, because it is not attached to it.
+func takesNextValue(cmd *cobra.Command, word string) bool {
+	switch {
+	case !strings.HasPrefix(word, "-") || word == "-" || word == "--" || strings.Contains(word, "="):
+		return false
+
+	case strings.HasPrefix(word, "--"):
+		flag := cmd.Flags().Lookup(word[2:])
+		if flag == nil {
+			flag = cmd.InheritedFlags().Lookup(word[2:])
+		}
+
+		return flag != nil && flag.NoOptDefVal == ""
+	}
+
+	flags, attached := stackedFlags(cmd, word)
+
+	return len(flags) > 0 && !attached && flags[len(flags)-1].NoOptDefVal == ""
+}
+
+// shorthandFlag returns the flag of the command (including inherited ones) with a shorthand.
+func shorthandFlag(cmd *cobra.Command, short rune) *pflag.Flag {
+	if utf8.RuneLen(short) != 1 {
+		return nil
+	}
+
+	flag := cmd.Flags().ShorthandLookup(string(short))
+	if flag == nil {
+		flag = cmd.InheritedFlags().ShorthandLookup(string(short))
+	}
+
+	return flag
+}
+
+// stackedUseLine returns the usage line of a command, with its boolean short flags
+// shown as a group of stacked flags, as they can be given (eg. "ls [-ahl] [flags]").
+func stackedUseLine(cmd *cobra.Command) string {
+	line := cmd.UseLine()
+
+	var group []string
+
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if flag.Shorthand != "" && flag.NoOptDefVal != "" && !flag.Hidden && flag.Name != "help" {
+			group = append(group, flag.Shorthand)
+		}
+	})
+
+	if len(group) < 2 {
+		return line
+	}
+
+	sort.Slice(group, func(i, j int) bool {
+		if first, second := strings.ToLower(group[i]), strings.ToLower(group[j]); first != second {
+			return first < second
+		}
+
+		return group[i] < group[j]
+	})
+
+	stack := "[-" + strings.Join(group, "") + "]"
+
+	if before, after, found := strings.Cut(line, " [flags]"); found {
+		return before + " " + stack + " [flags]" + after
+	}
+
+	return line + " " + stack
+}
@@ -0,0 +1,58 @@
+package console
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+// stackedMenu returns the menu of a console with a command taking stackable short flags.
+func stackedMenu(t *testing.T) *Menu {
+	t.Helper()
+
+	c := New("test")
+	menu := c.ActiveMenu()
+
+	menu.SetCommands(func() *cobra.Command {
+		root := &cobra.Command{}
+
+		ls := &cobra.Command{Use: "ls", Run: func(*cobra.Command, []string) {}}
+		ls.Flags().BoolP("all", "a", false, "show hidden files")
+		ls.Flags().BoolP("long", "l", false, "long listing")
+		ls.Flags().StringP("format", "f", "", "output format")
+		root.AddCommand(ls)
+
+		return root
+	})
+
+	menu.resetPreRun()
+
+	return menu
+}
+
+func TestStackedUseLine(t *testing.T) {
+	menu := stackedMenu(t)
+
+	ls, _, _ := menu.Command.Find([]string{"ls"})
+
+	if usage := ls.UsageString(); !strings.Contains(usage, "ls [-al] [flags]") {
+		t.Errorf("usage does not show the stacked flags:\n%s", usage)
+	}
+}
+
+func TestCompleteStackedFlags(t *testing.T) {
+	menu := stackedMenu(t)
+
+	result := menu.console.completeArgs(menu, []string{"ls", "-a"})
+
+	var values []string
+	for _, value := range result.values {
+		values = append(values, value.Value)
+	}
+
+	if !slices.Contains(values, "-al") {
+		t.Errorf("completions of -a = %q, want -al", values)
+	}
+}
@@ -0,0 +1,91 @@
+package console
+
+import (
+	"os"
+	"os/signal"
+)
+
+// signalHandler is a handler registered with HandleSignal.
+type signalHandler struct {
+	signals chan os.Signal
+	done    chan struct{}
+}
+
+// HandleSignal registers a handler called each time the process receives the signal
+// (eg. SIGHUP to reload the configuration, SIGUSR1 to refresh the prompt), or removes
+// it if the handler is nil. A signal has at most one handler: registering another one
+// replaces it. Handlers are called by the input loop of the console, in between the keys
+// read or while waiting for the command being executed. When a handled signal is received
+// while the user is typing a command, the prompt and the input line are redrawn once the
+// handler returns, so that its changes (eg. to the prompt or status bar) are displayed.
+//
+// Handled signals are not handled by the console anymore: SIGINT and SIGQUIT do not
+// cancel the command being executed, and SIGTERM and SIGHUP do not exit the application
+// (running the exit hooks and saving the session state, see OnExit and SetStateFile).
+// The handler can use the console API for this, eg. with Console.Exit.
+func (c *Console) HandleSignal(sig os.Signal, handler func(c *Console)) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if current := c.signalHandlers[sig]; current != nil {
+		signal.Stop(current.signals)
+		close(current.done)
+		delete(c.signalHandlers, sig)
+	}
+
+	if handler == nil {
+		return
+	}
+
+	if c.signalHandlers == nil {
+		c.signalHandlers = make(map[os.Signal]*signalHandler)
+	}
+
+	registered := &signalHandler{
+		signals: make(chan os.Signal, 1),
+		done:    make(chan struct{}),
+	}
+
+	signal.Notify(registered.signals, sig)
+	c.signalHandlers[sig] = registered
+
+	go c.dispatchSignal(registered, handler)
+}
+
+// dispatchSignal calls the handler of a signal each time it is received,
+// on the input loop, so that the handler can use the shell like commands.
+func (c *Console) dispatchSignal(registered *signalHandler, handler func(c *Console)) {
+	for {
+		select {
+		case <-registered.signals:
+			c.runOnLoop(func() {
+				handler(c)
+				c.refreshReading()
+			})
+
+		case <-registered.done:
+			return
+		}
+	}
+}
+
+// notifyUnhandled relays the signals which have no handler
+// registered with HandleSignal to the channel, if any.
+func (c *Console) notifyUnhandled(sigchan chan<- os.Signal, sigs ...os.Signal) {
+	c.mutex.RLock()
+
+	unhandled := make([]os.Signal, 0, len(sigs))
+
+	for _, sig := range sigs {
+		if c.signalHandlers[sig] == nil {
+			unhandled = append(unhandled, sig)
+		}
+	}
+
+	c.mutex.RUnlock()
+
+	// Without signals, all of them would be relayed.
+	if len(unhandled) > 0 {
+		signal.Notify(sigchan, unhandled...)
+	}
+}
@@ -0,0 +1,103 @@
+//go:build unix
+
+package console
+
+import (
+	"context"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestHandleSignalOnLoop(t *testing.T) {
+	c := New("test")
+	_, stop := c.startLoop(context.Background())
+
+	defer stop()
+
+	handled := make(chan bool, 1)
+
+	c.HandleSignal(syscall.SIGUSR1, func(*Console) { handled <- true })
+	defer c.HandleSignal(syscall.SIGUSR1, nil)
+
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatal(err)
+	}
+
+	// The handler is queued for the loop, not called by the dispatcher.
+	waitQueued(t, c)
+
+	select {
+	case <-handled:
+		t.Fatal("handler called off the input loop")
+	default:
+	}
+
+	c.runPending()
+
+	select {
+	case <-handled:
+	default:
+		t.Error("handler not called by the input loop")
+	}
+}
+
+func TestReloadOnHangup(t *testing.T) {
+	t.Setenv("INPUTRC", t.TempDir()+"/inputrc")
+
+	c := New("test")
+	_, stop := c.startLoop(context.Background())
+
+	reloaded := make(chan bool, 1)
+
+	c.OnReload(func() ([]string, error) {
+		reloaded <- true
+		return nil, nil
+	})
+
+	c.ReloadOnHangup()
+	defer c.HandleSignal(syscall.SIGHUP, nil)
+
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+
+	waitQueued(t, c)
+
+	select {
+	case <-reloaded:
+		t.Fatal("configuration reloaded off the input loop")
+	default:
+	}
+
+	// The changes are printed once reloaded, by the loop too.
+	captureStdout(t, func() {
+		c.runPending()
+		stop()
+	})
+
+	select {
+	case <-reloaded:
+	default:
+		t.Error("configuration not reloaded by the input loop")
+	}
+}
+
+// waitQueued waits for a function to be queued for the input loop.
+func waitQueued(t *testing.T, c *Console) {
+	t.Helper()
+
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+		c.mutex.RLock()
+		queued := len(c.loop.pending)
+		c.mutex.RUnlock()
+
+		if queued > 0 {
+			return
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("nothing queued for the input loop")
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package console
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/reeflective/readline"
+)
+
+// errRestoredLine is used to hold the restored input line in the shell,
+// without writing it to the history sources.
+var errRestoredLine = errors.New("restored line")
+
+// sessionState is the console state persisted in the state file,
+// which can be restored when the console is started again.
+type sessionState struct {
+	Menu    string                         `json:"menu"`
+	Line    string                         `json:"line,omitempty"`
+	History map[string]map[string][]string `json:"history,omitempty"` // By menu and source names.
+}
+
+// SetStateFile enables the crash-safe persistence of the console session:
+// the current menu and the history lines of all menus written in this session
+// (except to history files, which persist them already) are saved to the given
+// file after each command, and the pending input line is saved as well if the
+// console is terminated (SIGTERM/SIGHUP) while reading it.
+//
+// If the file exists when the console starts, the user is asked whether to
+// recover the previous session. The file is removed when exiting cleanly,
+// that is, through Console.Exit(). An empty path disables the persistence.
+func (c *Console) SetStateFile(path string) {
+	c.mutex.Lock()
+	c.stateFile = path
+	c.mutex.Unlock()
+}
+
+// saveState writes the current session state to the state file, if any.
+func (c *Console) saveState(line string) error {
+	if c.stateFile == "" {
+		return nil
+	}
+
+	state := sessionState{
+		Menu:    c.activeMenu().name,
+		Line:    line,
+		History: make(map[string]map[string][]string),
+	}
+
+	c.mutex.Lock()
+
+	if c.stateLines == nil {
+		c.stateLines = make(map[readline.History]int)
+	}
+
+	for name, menu := range c.menus {
+		sources := make(map[string][]string)
+
+		for source, hist := range menu.histories {
+			if lines := c.sessionLines(hist); len(lines) > 0 {
+				sources[source] = lines
+			}
+		}
+
+		if len(sources) > 0 {
+			state.History[name] = sources
+		}
+	}
+
+	c.mutex.Unlock()
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first, so that the state
+	// file is never left half-written if we are killed.
+	if err := os.MkdirAll(filepath.Dir(c.stateFile), 0o700); err != nil {
+		return err
+	}
+
+	tmp := c.stateFile + ".tmp"
+
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, c.stateFile)
+}
+
+// sessionLines returns the lines written to a history source in this session (see
+// markSessionLines), if it is not a history file (see Menu.AddHistorySourceFile): those
+// are written as soon as they are accepted, so they do not have to be loaded and saved
+// after each command. The console must be locked.
+func (c *Console) sessionLines(hist readline.History) []string {
+	if _, persisted := hist.(*historyFile); persisted || hist == nil {
+		return nil
+	}
+
+	first, seen := c.stateLines[hist]
+	if !seen {
+		first = hist.Len()
+		c.stateLines[hist] = first
+	}
+
+	var lines []string
+
+	for i := first; i < hist.Len(); i++ {
+		if line, err := hist.GetLine(i); err == nil {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines
+}
+
+// markSessionLines records the number of lines of the history sources when the
+// session starts, which are not saved in the state file.
+func (c *Console) markSessionLines() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.stateLines = make(map[readline.History]int)
+
+	for _, menu := range c.menus {
+		for _, hist := range menu.histories {
+			if _, persisted := hist.(*historyFile); !persisted && hist != nil {
+				c.stateLines[hist] = hist.Len()
+			}
+		}
+	}
+}
+
+// recoverState offers to restore the session saved in the state file, if any:
+// the menu is switched back, history lines are added to the in-memory sources
+// (sources already populated, like history files, are left untouched), and the
+// pending input line is loaded in the shell for the first prompt.
+func (c *Console) recoverState() {
+	if c.stateFile == "" {
+		return
+	}
+
+	// Recovered lines are saved again, with those of the new session.
+	c.markSessionLines()
+
+	data, err := os.ReadFile(c.stateFile)
+	if err != nil {
+		return
+	}
+
+	var state sessionState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return
+	}
+
+	if !confirm(tr("Recover previous session?")) {
+		os.Remove(c.stateFile)
+		return
+	}
+
+	for name, sources := range state.History {
+		menu := c.menus[name]
+		if menu == nil {
+			continue
+		}
+
+		for source, lines := range sources {
+			hist := menu.histories[source]
+			if hist == nil || hist.Len() > 0 {
+				continue
+			}
+
+			for _, line := range lines {
+				hist.Write(line)
+			}
+		}
+	}
+
+	c.SwitchMenu(state.Menu)
+	c.loadActiveHistories()
+
+	if state.Line != "" {
+		c.PrefillLine(state.Line)
+	}
+}
+
+// watchTermination runs the exit hooks when the console is terminated while
+// reading user input, and if the session state is persisted, saves it along
+// with the pending input line (with sensitive values redacted). The returned
+// function stops watching signals.
+func (c *Console) watchTermination() (stop func()) {
+	sigchan := make(chan os.Signal, 1)
+	done := make(chan struct{})
+
+	// Signals handled by the application are left to it.
+	c.notifyUnhandled(sigchan, syscall.SIGTERM)
+
+	if c.stateFile != "" {
+		c.notifyUnhandled(sigchan, syscall.SIGHUP)
+	}
+
+	go func() {
+		select {
+		case sig := <-sigchan:
+			c.saveState(c.activeMenu().RedactLine(string(*c.shell.Line())))
+			restoreTerminal()
+
+			fmt.Println()
+
+			if err := c.runExitHooks(); err != nil {
+				fmt.Fprintf(os.Stderr, "Shutdown error: %s\n", err)
+			}
+
+			os.Exit(128 + int(sig.(syscall.Signal)))
+
+		case <-done:
+		}
+	}()
+
+	return func() {
+		signal.Stop(sigchan)
+		close(done)
+	}
+}
@@ -0,0 +1,63 @@
+package console
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/reeflective/readline"
+)
+
+func TestSaveSessionLines(t *testing.T) {
+	dir := t.TempDir()
+
+	c := New("test")
+	c.SetStateFile(filepath.Join(dir, "state.json"))
+
+	memory := readline.NewInMemoryHistory()
+	memory.Write("previous session")
+
+	menu := c.ActiveMenu()
+	menu.AddHistorySource("memory", memory)
+
+	path := filepath.Join(dir, "history")
+	if err := os.WriteFile(path, []byte(`{"datetime":"2026-01-01T00:00:00Z","block":"from file"}`+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	c.markSessionLines()
+	memory.Write("first")
+	memory.Write("second")
+
+	menu.AddHistorySourceFile("file", path)
+
+	if err := c.saveState(""); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var state sessionState
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatal(err)
+	}
+
+	sources := state.History[menu.name]
+
+	if lines := sources["memory"]; !slices.Equal(lines, []string{"first", "second"}) {
+		t.Errorf("saved lines = %q, want those of the session", lines)
+	}
+
+	if _, saved := sources["file"]; saved {
+		t.Error("the lines of the history file are saved in the state file")
+	}
+
+	if menu.histories["file"].(*historyFile).loaded {
+		t.Error("the history file is loaded when saving the state")
+	}
+}
@@ -0,0 +1,159 @@
+package console
+
+import (
+	"os"
+	"strings"
+
+	"github.com/rivo/uniseg"
+	"golang.org/x/term"
+)
+
+// statusSeparator separates the segments of the status bar.
+const statusSeparator = " │ "
+
+// SetStatusSegment adds a segment to the status bar, a line pinned below the
+// input line (like the tmux status line), or replaces the segment having the
+// same name. The segment function is called each time the input line is redrawn,
+// which should be quick (eg. returning the connection state, the number of jobs
+// running or the current menu name), and can return an empty string to be hidden.
+// Segments are displayed in the order they have been added. A nil function removes
+// the segment, and the status bar is hidden once it has no segments.
+//
+// Note that the status bar replaces the shell's own status hints, such as the
+// numeric argument being typed, or the macro being recorded.
+func (c *Console) SetStatusSegment(name string, segment func() string) {
+	c.mutex.Lock()
+
+	_, found := c.statusSegments[name]
+
+	switch {
+	case segment == nil && found:
+		delete(c.statusSegments, name)
+
+		for i, existing := range c.statusNames {
+			if existing == name {
+				c.statusNames = append(c.statusNames[:i], c.statusNames[i+1:]...)
+				break
+			}
+		}
+	case segment != nil && !found:
+		c.statusNames = append(c.statusNames, name)
+	}
+
+	if segment != nil {
+		c.statusSegments[name] = segment
+	}
+
+	c.mutex.Unlock()
+
+	c.RefreshStatus()
+}
+
+// SetStatus sets a status bar segment to a static text (see SetStatusSegment).
+// An empty text removes the segment.
+func (c *Console) SetStatus(name, text string) {
+	if text == "" {
+		c.SetStatusSegment(name, nil)
+		return
+	}
+
+	c.SetStatusSegment(name, func() string { return text })
+}
+
+// RefreshStatus redraws the status bar (along with the prompt and the input line)
+// if the user is currently typing a command: it can be called after the state shown
+// by a segment has changed, for instance when a background job has finished. The bar
+// is redrawn by the input loop, so this can be called from any goroutine.
+func (c *Console) RefreshStatus() {
+	c.runOnLoop(func() {
+		c.mutex.RLock()
+		reading := c.reading && !c.isExecuting
+		c.mutex.RUnlock()
+
+		if reading {
+			c.shell.Display.Refresh()
+		}
+	})
+}
+
+// updateStatus binds the current status bar to the shell, below the input line.
+func (c *Console) updateStatus() {
+	c.mutex.RLock()
+	bound := len(c.statusNames) > 0 || c.statusShown
+	c.mutex.RUnlock()
+
+	if !bound {
+		return
+	}
+
+	status := c.statusLine()
+	if status == "" {
+		c.shell.Hint.ResetPersist()
+	} else {
+		c.shell.Hint.Persist(status)
+	}
+
+	c.mutex.Lock()
+	c.statusShown = status != ""
+	c.mutex.Unlock()
+}
+
+// statusLine renders all non-empty status segments in a bar spanning the terminal width.
+func (c *Console) statusLine() string {
+	c.mutex.RLock()
+	names := append([]string{}, c.statusNames...)
+	segments := make([]func() string, 0, len(names))
+
+	for _, name := range names {
+		segments = append(segments, c.statusSegments[name])
+	}
+	c.mutex.RUnlock()
+
+	texts := make([]string, 0, len(segments))
+
+	for _, segment := range segments {
+		// Keep the bar in reverse video after colored segments.
+		if text := segment(); text != "" {
+			texts = append(texts, strings.ReplaceAll(text, reset, reset+reverse))
+		}
+	}
+
+	if len(texts) == 0 {
+		return ""
+	}
+
+	line := " " + strings.Join(texts, statusSeparator) + " "
+
+	// Fit the bar to the terminal width, minus one column so that
+	// the terminal does not wrap it (nor the cursor) below.
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err == nil && width > 1 {
+		line = fitColoredWidth(line, width-1)
+	}
+
+	return reverse + line + reverseReset + reset
+}
+
+// fitColoredWidth is like fitWidth, for a text with color sequences:
+// they are all kept, and do not count in the width of the text.
+func fitColoredWidth(text string, width int) string {
+	var fitted strings.Builder
+
+	used, last := 0, 0
+	sequences := append(re.FindAllStringIndex(text, -1), []int{len(text), len(text)})
+
+	for _, sequence := range sequences {
+		chunk := text[last:sequence[0]]
+		chunkWidth := min(width-used, uniseg.StringWidth(strings.ReplaceAll(chunk, "\t", "    ")))
+
+		if chunkWidth > 0 {
+			fitted.WriteString(fitWidth(chunk, chunkWidth))
+			used += chunkWidth
+		}
+
+		fitted.WriteString(text[sequence[0]:sequence[1]])
+		last = sequence[1]
+	}
+
+	return fitted.String() + strings.Repeat(" ", width-used)
+}
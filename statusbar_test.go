@@ -0,0 +1,26 @@
+package console
+
+import (
+	"testing"
+)
+
+func TestFitColoredWidth(t *testing.T) {
+	tests := []struct {
+		text  string
+		width int
+		want  string
+	}{
+		{"ab", 4, "ab  "},
+		{"abcdef", 3, "abc"},
+		{"\x1b[31mhello\x1b[0m world", 8, "\x1b[31mhello\x1b[0m wo"},
+		{"\x1b[31mhello\x1b[0m", 2, "\x1b[31mhe\x1b[0m"},
+		{"ab界c", 3, "ab "},
+		{"a\x1b[1m界\x1b[0m", 5, "a\x1b[1m界\x1b[0m  "},
+	}
+
+	for _, test := range tests {
+		if fitted := fitColoredWidth(test.text, test.width); fitted != test.want {
+			t.Errorf("fitColoredWidth(%q, %d) = %q, want %q", test.text, test.width, fitted, test.want)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package console
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/reeflective/readline"
+	"github.com/reeflective/readline/inputrc"
+	"golang.org/x/term"
+)
+
+// ErrExitSubRepl can be returned by the Execute function of a sub-REPL to
+// return to the parent console loop (see Console.SubRepl).
+var ErrExitSubRepl = errors.New("exit sub-REPL")
+
+// errSubReplTerminal is returned when a sub-REPL is started without a terminal.
+var errSubReplTerminal = errors.New("the sub-REPL must be run in a terminal")
+
+// SubReplOptions configures the line editor of a sub-REPL (see Console.SubRepl).
+type SubReplOptions struct {
+	// Name is the name of the sub-REPL (eg. "sql"), naming its history source.
+	Name string
+
+	// Prompt returns the primary prompt of the sub-REPL (eg. "sql> "),
+	// called before reading each line.
+	Prompt func() string
+
+	// Execute is called with each non-empty line read, until it returns
+	// ErrExitSubRepl. Other errors are passed to the active menu error handler,
+	// and the next line is read.
+	Execute func(ctx context.Context, line string) error
+
+	// Completer completes the sub-REPL lines, if not nil.
+	Completer func(line []rune, cursor int) readline.Completions
+
+	// Highlighter highlights the sub-REPL lines, if not nil.
+	Highlighter func(line []rune) string
+
+	// AcceptMultiline returns false if a line is not complete yet, in which case
+	// a newline is inserted instead of executing it (eg. SQL statements until the
+	// semicolon), if not nil.
+	AcceptMultiline func(line []rune) bool
+
+	// History is the history source of the sub-REPL lines. If nil, the lines are
+	// kept in HistoryFile, or in memory if it is empty. The history of the sub-REPL
+	// is never mixed with the one of the console menu.
+	History     readline.History
+	HistoryFile string
+
+	// ExitCommands are the lines returning to the parent console loop,
+	// "exit" and "quit" if empty. Ctrl-D on an empty line also exits.
+	ExitCommands []string
+}
+
+// SubRepl takes over the input loop from a command, reading and executing lines with
+// its own prompt, history and completer (eg. an interactive SQL or scripting shell),
+// then returns to the parent console loop once exited, or if the context is done once
+// the line being read is accepted (the context is not checked while reading a line):
+//
+//	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+//		return c.SubRepl(cmd.Context(), console.SubReplOptions{
+//			Name:    "sql",
+//			Prompt:  func() string { return "sql> " },
+//			Execute: db.Query,
+//		})
+//	}
+//
+// Ctrl-C clears the line being edited without exiting, and reading errors (eg. when the
+// terminal is closed) are returned. The sub-REPL uses the inputrc configuration of the
+// application, and cannot be run without a terminal.
+func (c *Console) SubRepl(ctx context.Context, opts SubReplOptions) error {
+	if c.lineMode.Load() || !term.IsTerminal(int(os.Stdin.Fd())) {
+		return errSubReplTerminal
+	}
+
+	shell := c.newSubReplShell(opts)
+
+	exits := opts.ExitCommands
+	if len(exits) == 0 {
+		exits = []string{"exit", "quit"}
+	}
+
+	for ctx.Err() == nil {
+		line, err := shell.Readline()
+
+		switch {
+		case errors.Is(err, io.EOF):
+			return nil
+		case errors.Is(err, readline.ErrInterrupt):
+			continue
+		case err != nil:
+			return err
+		}
+
+		line = strings.TrimSpace(line)
+
+		switch {
+		case line == "":
+			continue
+		case slices.Contains(exits, line):
+			return nil
+		case opts.Execute == nil:
+			continue
+		}
+
+		if err = opts.Execute(ctx, line); errors.Is(err, ErrExitSubRepl) {
+			return nil
+		} else if err != nil {
+			c.activeMenu().ErrorHandler(ExecutionError{newError(err, "")})
+		}
+	}
+
+	return nil
+}
+
+// newSubReplShell returns the shell reading the lines of a sub-REPL.
+func (c *Console) newSubReplShell(opts SubReplOptions) *readline.Shell {
+	shell := readline.NewShell(inputrc.WithApp(strings.ToLower(c.name)))
+
+	name := strings.TrimSpace(opts.Name + " history")
+
+	switch {
+	case opts.History != nil:
+		shell.History.Add(name, opts.History)
+	case opts.HistoryFile != "":
+		shell.History.Add(name, newHistoryFile(opts.HistoryFile))
+	}
+
+	if opts.Prompt != nil {
+		shell.Prompt.Primary(opts.Prompt)
+	}
+
+	if opts.Completer != nil {
+		shell.Completer = opts.Completer
+	}
+
+	if opts.Highlighter != nil {
+		shell.SyntaxHighlighter = opts.Highlighter
+	}
+
+	if opts.AcceptMultiline != nil {
+		shell.AcceptMultiline = opts.AcceptMultiline
+	}
+
+	return shell
+}
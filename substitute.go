@@ -0,0 +1,156 @@
+package console
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/kballard/go-shellquote"
+)
+
+// maxSubstitutionDepth is the maximum nesting level of command substitutions.
+const maxSubstitutionDepth = 4
+
+// ErrSubstitution is returned (wrapped) when a command substitution fails.
+var ErrSubstitution = errors.New("command substitution")
+
+// substituteCommands replaces all $(command args) substitutions of the line with
+// the output of the console commands they contain (see Console.CommandSubstitution).
+// Unquoted substitutions are split into words, like in shells, while those inside
+// double quotes are inserted as a single, escaped string.
+func (c *Console) substituteCommands(ctx context.Context, line string, depth int) (string, error) {
+	if !c.CommandSubstitution || !strings.Contains(line, "$(") {
+		return line, nil
+	}
+
+	if depth >= maxSubstitutionDepth {
+		return "", fmt.Errorf("%w: too many nested substitutions (max %d)", ErrSubstitution, maxSubstitutionDepth)
+	}
+
+	runes := []rune(line)
+
+	var substituted strings.Builder
+
+	var quote rune
+
+	for i := 0; i < len(runes); i++ {
+		char := runes[i]
+
+		switch {
+		case char == '\\' && quote != '\'' && i+1 < len(runes):
+			substituted.WriteRune(char)
+			i++
+			char = runes[i]
+
+		case quote == 0 && char == '#' && (i == 0 || strings.ContainsRune(splitChars, runes[i-1])):
+			// Comments are never substituted.
+			substituted.WriteString(string(runes[i:]))
+			return substituted.String(), nil
+
+		case quote != '\'' && char == '$' && i+1 < len(runes) && runes[i+1] == '(' &&
+			(i+2 >= len(runes) || runes[i+2] != '('):
+			end := substitutionEnd(runes, i+2)
+			if end < 0 {
+				return "", fmt.Errorf("%w: missing closing parenthesis", ErrSubstitution)
+			}
+
+			output, err := c.runSubstitution(ctx, string(runes[i+2:end]), depth)
+			if err != nil {
+				return "", err
+			}
+
+			substituted.WriteString(spliceOutput(output, quote == '"'))
+
+			i = end
+
+			continue
+
+		case quote == 0 && (char == '\'' || char == '"'):
+			quote = char
+
+		case quote != 0 && char == quote:
+			quote = 0
+		}
+
+		substituted.WriteRune(char)
+	}
+
+	return substituted.String(), nil
+}
+
+// runSubstitution executes a substituted command line and returns its output.
+func (c *Console) runSubstitution(ctx context.Context, line string, depth int) (string, error) {
+	line, err := c.substituteCommands(ctx, line, depth+1)
+	if err != nil {
+		return "", err
+	}
+
+	args, err := c.parse(line)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrSubstitution, err)
+	}
+
+	if len(args) == 0 {
+		return "", nil
+	}
+
+	output, err := captureOutput(func() error {
+		return c.activeMenu().RunCommandArgs(ctx, args)
+	})
+	if err != nil {
+		return "", fmt.Errorf("%w: %s: %w", ErrSubstitution, strings.Join(args, " "), err)
+	}
+
+	return strings.TrimRight(output, "\n"), nil
+}
+
+// substitutionEnd returns the position of the parenthesis closing a
+// substitution starting at the given position, or -1 if there is none.
+func substitutionEnd(runes []rune, start int) int {
+	depth := 0
+
+	var quote rune
+
+	for i := start; i < len(runes); i++ {
+		switch char := runes[i]; {
+		case char == '\\' && quote != '\'':
+			i++
+		case quote != 0:
+			if char == quote {
+				quote = 0
+			}
+		case char == '\'' || char == '"':
+			quote = char
+		case char == '(':
+			depth++
+		case char == ')':
+			if depth == 0 {
+				return i
+			}
+
+			depth--
+		}
+	}
+
+	return -1
+}
+
+// spliceOutput quotes the output of a substituted command for the outer line.
+func spliceOutput(output string, doubleQuoted bool) string {
+	if doubleQuoted {
+		var escaped strings.Builder
+
+		for _, char := range output {
+			if strings.ContainsRune(doubleEscapeChars, char) && char != '\n' {
+				escaped.WriteRune('\\')
+			}
+
+			escaped.WriteRune(char)
+		}
+
+		return escaped.String()
+	}
+
+	return shellquote.Join(strings.Fields(output)...)
+}
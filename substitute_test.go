@@ -0,0 +1,113 @@
+package console
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+// substituteConsole returns a console with command substitution enabled, with an
+// echo command, a command printing several lines, and a command always failing.
+func substituteConsole(t *testing.T) *Console {
+	t.Helper()
+
+	c := New("test")
+	c.CommandSubstitution = true
+
+	c.ActiveMenu().SetCommands(func() *cobra.Command {
+		root := &cobra.Command{SilenceErrors: true, SilenceUsage: true}
+
+		root.AddCommand(
+			&cobra.Command{Use: "echo", Run: func(cmd *cobra.Command, args []string) {
+				fmt.Fprintln(cmd.OutOrStdout(), strings.Join(args, " "))
+			}},
+			&cobra.Command{Use: "lines", Run: func(cmd *cobra.Command, _ []string) {
+				fmt.Fprint(cmd.OutOrStdout(), "one two\nthree\n\n")
+			}},
+			&cobra.Command{Use: "fail", RunE: func(*cobra.Command, []string) error {
+				return errors.New("failed")
+			}},
+		)
+
+		return root
+	})
+
+	return c
+}
+
+func TestSubstituteCommands(t *testing.T) {
+	c := substituteConsole(t)
+
+	tests := []struct {
+		line, want string
+	}{
+		{"echo hi", "echo hi"},
+		{"echo $(echo hi)", "echo hi"},
+		{"echo $(echo hi)$(echo there)", "echo hithere"},
+		{"echo $(lines) end", "echo one two three end"},
+		{`echo "$(lines)" end`, "echo \"one two\nthree\" end"},
+		{`echo "x $(echo '$HOME "quoted"')"`, `echo "x \$HOME \"quoted\""`},
+		{`echo $(echo "it's")`, `echo it\'s`},
+		{`echo $(echo "a)b" '(')`, `echo a\)b \(`},
+		{"echo $(echo $(echo inner))", "echo inner"},
+		{"echo $()", "echo "},
+		// Not substituted: single quotes, escapes, arithmetic and comments.
+		{"echo '$(echo hi)'", "echo '$(echo hi)'"},
+		{`echo \$(echo hi)`, `echo \$(echo hi)`},
+		{"echo $((1 + 2))", "echo $((1 + 2))"},
+		{"echo hi # $(echo hi)", "echo hi # $(echo hi)"},
+	}
+
+	for _, test := range tests {
+		line, err := c.substituteCommands(context.Background(), test.line, 0)
+		if err != nil {
+			t.Errorf("%q: %v", test.line, err)
+			continue
+		}
+
+		if line != test.want {
+			t.Errorf("%q substituted as %q, want %q", test.line, line, test.want)
+		}
+	}
+}
+
+func TestSubstituteCommandsErrors(t *testing.T) {
+	c := substituteConsole(t)
+
+	tests := []struct {
+		line, want string
+	}{
+		{"echo $(echo hi", "missing closing parenthesis"},
+		{"echo $(fail)", "failed"},
+		{"echo $(echo 'unterminated)", "missing closing parenthesis"},
+		{"echo $(echo $(echo $(echo $(echo $(echo deep)))))", "too many nested substitutions"},
+	}
+
+	for _, test := range tests {
+		line, err := c.substituteCommands(context.Background(), test.line, 0)
+		if !errors.Is(err, ErrSubstitution) || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%q: substituted as %q, error %v, want an ErrSubstitution error with %q", test.line, line, err, test.want)
+		}
+	}
+
+	// The maximum depth is allowed.
+	line, err := c.substituteCommands(context.Background(), "echo $(echo $(echo $(echo $(echo deep))))", 0)
+	if err != nil || line != "echo deep" {
+		t.Errorf("nested substitutions returned %q, %v", line, err)
+	}
+}
+
+func TestSubstituteCommandsDisabled(t *testing.T) {
+	c := substituteConsole(t)
+	c.CommandSubstitution = false
+
+	for _, line := range []string{"echo $(echo hi)", "echo $(fail)", "echo $(unterminated"} {
+		if substituted, err := c.substituteCommands(context.Background(), line, 0); err != nil || substituted != line {
+			t.Errorf("%q substituted as %q (%v) with substitution disabled", line, substituted, err)
+		}
+	}
+}
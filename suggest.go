@@ -0,0 +1,152 @@
+package console
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// ErrUnknownCommand is returned (wrapped, along with suggestions) when a command
+// line uses an unknown command name, for which close command names can be found.
+var ErrUnknownCommand = errors.New("unknown command")
+
+// defaultSuggestionDistance is the maximum Levenshtein distance between an unknown
+// command name and a suggested one, if the command does not specify one.
+const defaultSuggestionDistance = 2
+
+// correctCommand checks the command name typed by the user at the first level at
+// which it cannot be found, if any. When there are known command names close to it,
+// either the arguments are corrected with the closest one (if Console.AutoCorrect
+// is true and the match is unambiguous), or an error suggesting them is returned.
+func (c *Console) correctCommand(menu *Menu, args []string) ([]string, error) {
+	target, rest, err := menu.Command.Find(args)
+	if target == nil {
+		return args, nil
+	}
+
+	// Runnable commands take the word as an argument.
+	if err == nil && (target.Runnable() || !target.HasAvailableSubCommands()) {
+		return args, nil
+	}
+
+	index := typedCommand(target, rest)
+	if index < 0 {
+		return args, nil
+	}
+
+	typed := rest[index]
+
+	// Command names are removed from the arguments before the typed word.
+	index += len(args) - len(rest)
+
+	suggestions := menu.suggestCommands(target, typed)
+
+	switch {
+	case len(suggestions) == 0:
+		return args, nil
+
+	case len(suggestions) == 1 && c.AutoCorrect:
+		corrected := append([]string{}, args...)
+		corrected[index] = suggestions[0]
+
+		fmt.Fprintf(target.OutOrStdout(), tr("auto-corrected %q to %q")+"\n", typed, suggestions[0])
+
+		return corrected, nil
+
+	default:
+		return args, newLocalizedError(ErrUnknownCommand, "unknown command %q, did you mean: %s?", typed, strings.Join(suggestions, ", "))
+	}
+}
+
+// typedCommand returns the index of the first word of the arguments which is neither
+// a flag nor the value of one, or -1 if there is none.
+func typedCommand(cmd *cobra.Command, args []string) int {
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "--":
+			return -1
+		case !strings.HasPrefix(arg, "-") || arg == "-":
+			return i
+		case takesNextValue(cmd, arg):
+			i++
+		}
+	}
+
+	return -1
+}
+
+// suggestCommands returns the names of the available subcommands of a command
+// (or of their aliases) closest to the given word, from the closest one.
+func (m *Menu) suggestCommands(parent *cobra.Command, typed string) []string {
+	maxDistance := parent.SuggestionsMinimumDistance
+	if maxDistance <= 0 {
+		maxDistance = defaultSuggestionDistance
+	}
+
+	distances := make(map[string]int)
+
+	for _, cmd := range parent.Commands() {
+		if !cmd.IsAvailableCommand() || m.CheckIsAvailable(cmd) != nil {
+			continue
+		}
+
+		for _, name := range append([]string{cmd.Name()}, cmd.Aliases...) {
+			distance := levenshtein(strings.ToLower(typed), strings.ToLower(name))
+
+			// Typed prefixes are close enough, but not as close as typos.
+			if distance > maxDistance && strings.HasPrefix(strings.ToLower(cmd.Name()), strings.ToLower(typed)) {
+				distance = maxDistance
+			}
+
+			if current, found := distances[cmd.Name()]; distance <= maxDistance && (!found || distance < current) {
+				distances[cmd.Name()] = distance
+			}
+		}
+	}
+
+	suggestions := make([]string, 0, len(distances))
+	for name := range distances {
+		suggestions = append(suggestions, name)
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		if distances[suggestions[i]] != distances[suggestions[j]] {
+			return distances[suggestions[i]] < distances[suggestions[j]]
+		}
+
+		return suggestions[i] < suggestions[j]
+	})
+
+	return suggestions
+}
+
+// levenshtein returns the edit distance between two strings.
+func levenshtein(source, target string) int {
+	src, dst := []rune(source), []rune(target)
+
+	row := make([]int, len(dst)+1)
+	for j := range row {
+		row[j] = j
+	}
+
+	for i := 1; i <= len(src); i++ {
+		prev := row[0]
+		row[0] = i
+
+		for j := 1; j <= len(dst); j++ {
+			cost := 1
+			if src[i-1] == dst[j-1] {
+				cost = 0
+			}
+
+			current := row[j]
+			row[j] = min(row[j]+1, row[j-1]+1, prev+cost)
+			prev = current
+		}
+	}
+
+	return row[len(dst)]
+}
@@ -0,0 +1,52 @@
+package console
+
+import (
+	"io"
+	"slices"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestAutoCorrectTypedWord(t *testing.T) {
+	c := New("test")
+	c.AutoCorrect = true
+
+	menu := c.ActiveMenu()
+
+	menu.SetCommands(func() *cobra.Command {
+		root := &cobra.Command{}
+		root.SetOut(io.Discard)
+
+		remote := &cobra.Command{Use: "remote"}
+		remote.PersistentFlags().String("name", "", "remote name")
+		remote.PersistentFlags().Bool("verbose", false, "verbose output")
+		remote.AddCommand(&cobra.Command{Use: "status", Run: func(*cobra.Command, []string) {}})
+		root.AddCommand(remote)
+
+		return root
+	})
+
+	menu.resetPreRun()
+
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"remot", "status"}, []string{"remote", "status"}},
+		{[]string{"remote", "stat", "stat"}, []string{"remote", "status", "stat"}},
+		{[]string{"remote", "--name", "stat", "stat"}, []string{"remote", "--name", "stat", "status"}},
+		{[]string{"remote", "--name=stat", "--verbose", "stat"}, []string{"remote", "--name=stat", "--verbose", "status"}},
+	}
+
+	for _, test := range tests {
+		corrected, err := c.correctCommand(menu, test.args)
+		if err != nil {
+			t.Fatalf("%q: %v", test.args, err)
+		}
+
+		if !slices.Equal(corrected, test.want) {
+			t.Errorf("%q corrected to %q, want %q", test.args, corrected, test.want)
+		}
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package console
+
+// setupSuspend does nothing on platforms without job control.
+func (c *Console) setupSuspend() {}
+
+// watchSuspend does nothing on platforms without job control.
+func (c *Console) watchSuspend() (stop func()) {
+	return func() {}
+}
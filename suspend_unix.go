@@ -0,0 +1,156 @@
+//go:build unix
+
+package console
+
+import (
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+
+	"golang.org/x/term"
+)
+
+// selfSuspended is true when the process has been stopped by the suspend command,
+// which has already restored the terminal by the time SIGCONT is processed.
+var selfSuspended atomic.Bool
+
+// setupSuspend registers the suspend command, and binds it to Ctrl-Z in
+// the emacs and vi keymaps, unless the key is already bound to something
+// else than self-insert (eg. in the user inputrc).
+func (c *Console) setupSuspend() {
+	if cookedState == nil {
+		return
+	}
+
+	c.shell.Keymap.Register(map[string]func(){
+		"suspend": c.suspend,
+	})
+
+	for _, keymap := range []string{"emacs", "emacs-standard", "vi-insert", "vi-command", "vi-move"} {
+		if !c.boundByUser(keymap, "\x1a", "suspend") {
+			c.bindDefault(keymap, "\x1a", "suspend")
+		}
+	}
+}
+
+// watchSuspend handles the job-control signals received while reading user input:
+// the terminal is restored to cooked mode before the process is stopped, and is
+// put back in raw mode, with the prompt redrawn, when the process is continued.
+// The shell is only used by the input loop, on which the signals are handled.
+// The returned function stops watching these signals.
+func (c *Console) watchSuspend() (stop func()) {
+	if cookedState == nil {
+		return func() {}
+	}
+
+	sigchan := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	notify := func() { signal.Notify(sigchan, syscall.SIGTSTP, syscall.SIGCONT) }
+
+	selfSuspended.Store(false)
+	notify()
+
+	// Suspending resets the handling of SIGTSTP, watched again once continued.
+	c.mutex.Lock()
+	c.notifySuspend = notify
+	c.mutex.Unlock()
+
+	go func() {
+		for {
+			select {
+			case sig := <-sigchan:
+				switch sig {
+				case syscall.SIGTSTP:
+					c.runOnLoop(c.suspendReading)
+				case syscall.SIGCONT:
+					if selfSuspended.Swap(false) {
+						continue
+					}
+
+					// We have been stopped by something else than SIGTSTP
+					// (eg. SIGSTOP), and our parent shell has most probably
+					// restored the terminal to cooked mode in between: raw
+					// mode is needed first for the loop to read its wake-up.
+					_, _ = term.MakeRaw(int(os.Stdin.Fd()))
+
+					c.runOnLoop(c.continueReading)
+				}
+
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		c.mutex.Lock()
+		c.notifySuspend = nil
+		c.mutex.Unlock()
+
+		signal.Stop(sigchan)
+		close(done)
+	}
+}
+
+// suspendReading suspends the process, if the shell is still reading user input.
+func (c *Console) suspendReading() {
+	c.mutex.RLock()
+	reading := c.reading && !c.isExecuting
+	c.mutex.RUnlock()
+
+	if reading {
+		c.suspend()
+		c.shell.Display.Refresh()
+	}
+}
+
+// continueReading redraws the prompt and the input line once the process is continued
+// after having been stopped by another signal, if the shell is still reading user input.
+func (c *Console) continueReading() {
+	c.mutex.RLock()
+	reading := c.reading && !c.isExecuting
+	c.mutex.RUnlock()
+
+	if reading {
+		c.shell.Display.PrintPrimaryPrompt()
+		c.shell.Display.Refresh()
+	}
+}
+
+// suspend restores the terminal to cooked mode and stops the process, like
+// any job-controlled program would do when the user hits Ctrl-Z. When the
+// process is continued, the terminal is put back in raw mode, and the prompt
+// is printed again to display the current input line below it.
+func (c *Console) suspend() {
+	stdin := int(os.Stdin.Fd())
+
+	raw, err := term.GetState(stdin)
+	if err != nil || cookedState == nil {
+		return
+	}
+
+	// Leave the input line as is on screen, and go below it.
+	c.shell.Display.ClearHelpers()
+	c.shell.Display.CursorBelowLine()
+
+	restoreTerminal()
+
+	// Stop all processes in our group with the default behavior.
+	selfSuspended.Store(true)
+	signal.Reset(syscall.SIGTSTP)
+	_ = syscall.Kill(0, syscall.SIGTSTP)
+
+	// We have been continued.
+	_ = term.Restore(stdin, raw)
+
+	c.mutex.RLock()
+	notify := c.notifySuspend
+	c.mutex.RUnlock()
+
+	if notify != nil {
+		notify()
+	}
+
+	c.shell.Display.PrintPrimaryPrompt()
+}
@@ -0,0 +1,71 @@
+//go:build unix
+
+package console
+
+import (
+	"context"
+	"syscall"
+	"testing"
+
+	"github.com/reeflective/readline/inputrc"
+	"golang.org/x/term"
+)
+
+func TestSuspendBinds(t *testing.T) {
+	t.Setenv("INPUTRC", t.TempDir()+"/inputrc")
+
+	saved := cookedState
+	cookedState = &term.State{}
+
+	t.Cleanup(func() { cookedState = saved })
+
+	c := New("test")
+	c.shell.Config.Binds["vi-command"]["\x1a"] = inputrc.Bind{Action: "undo"}
+
+	c.shell.Keymap.Commands()["re-read-init-file"]()
+
+	if bind := c.shell.Config.Binds["emacs"]["\x1a"]; bind.Action != "suspend" {
+		t.Errorf("emacs Ctrl-Z = %q, want suspend", bind.Action)
+	}
+
+	if bind := c.shell.Config.Binds["vi-command"]["\x1a"]; bind.Action != "undo" {
+		t.Errorf("user bind of Ctrl-Z replaced with %q", bind.Action)
+	}
+}
+
+func TestWatchSuspendOnLoop(t *testing.T) {
+	saved := cookedState
+	cookedState = &term.State{}
+
+	t.Cleanup(func() { cookedState = saved })
+
+	c := New("test")
+	_, stop := c.startLoop(context.Background())
+
+	defer stop()
+
+	stopSuspend := c.watchSuspend()
+
+	// The signal is handled by the input loop, which is not reading.
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGTSTP); err != nil {
+		t.Fatal(err)
+	}
+
+	waitQueued(t, c)
+	c.runPending()
+
+	// Suspending with Ctrl-Z watches the signals again once continued.
+	c.mutex.RLock()
+	notify := c.notifySuspend
+	c.mutex.RUnlock()
+
+	if notify == nil {
+		t.Error("no function to watch the job-control signals again")
+	}
+
+	stopSuspend()
+
+	if c.notifySuspend != nil {
+		t.Error("job-control signals watched again after stopping")
+	}
+}
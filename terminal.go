@@ -0,0 +1,40 @@
+package console
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/term"
+)
+
+// seqShowCursor shows the cursor, which the shell hides while refreshing the line.
+var seqShowCursor = "\x1b[?25h"
+
+// cookedState is the terminal state before the shell puts it in raw mode.
+// It is saved when the console is created, and restored when suspending
+// the process, or when recovering from a panic.
+var cookedState *term.State
+
+// saveTerminal saves the current (cooked) state of the terminal, if any.
+func saveTerminal() {
+	stdin := int(os.Stdin.Fd())
+
+	if !term.IsTerminal(stdin) {
+		return
+	}
+
+	cookedState, _ = term.GetState(stdin)
+}
+
+// restoreTerminal restores the terminal to its state when the console was
+// created, and shows the cursor in case it was hidden by the shell, with
+// the cursor style of the user, in case it was changed by the shell.
+func restoreTerminal() {
+	if cookedState == nil {
+		return
+	}
+
+	_ = term.Restore(int(os.Stdin.Fd()), cookedState)
+
+	fmt.Print(seqShowCursor + seqCursorDefault)
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package console
+
+// setupTerminal performs platform-specific terminal setup.
+// Nothing is needed on non-Windows platforms.
+func (c *Console) setupTerminal() {}
+
+// editBuffer edits the buffer with the system editor, through the shell.
+func (c *Console) editBuffer(buffer []rune, filetype string) ([]rune, error) {
+	emacs := c.shell.Config.GetString("editing-mode") == "emacs"
+
+	return c.shell.Buffers.EditBuffer(buffer, "", filetype, emacs)
+}
@@ -0,0 +1,116 @@
+//go:build windows
+
+package console
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/windows"
+)
+
+// errEditorStart indicates that the command to start the editor failed.
+var errEditorStart = errors.New("failed to start editor")
+
+// setupTerminal enables virtual terminal processing for the console output,
+// (so that ANSI sequences used for colors, cursor movements and prompts are
+// interpreted instead of being printed), and replaces the shell commands
+// editing the line with the system editor, which are not supported by the
+// shell on Windows.
+func (c *Console) setupTerminal() {
+	for _, file := range []*os.File{os.Stdout, os.Stderr} {
+		var mode uint32
+
+		handle := windows.Handle(file.Fd())
+		if err := windows.GetConsoleMode(handle, &mode); err != nil {
+			continue
+		}
+
+		mode |= windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING | windows.ENABLE_PROCESSED_OUTPUT
+		windows.SetConsoleMode(handle, mode)
+	}
+
+	c.shell.Keymap.Register(map[string]func(){
+		"edit-command-line":           func() { c.editCommandLine(false) },
+		"vi-edit-command-line":        func() { c.editCommandLine(false) },
+		"edit-and-execute-command":    func() { c.editCommandLine(true) },
+		"vi-edit-and-execute-command": func() { c.editCommandLine(true) },
+	})
+}
+
+// editCommandLine edits the current input line in the system editor,
+// and optionally executes the resulting line once the editor is exited.
+func (c *Console) editCommandLine(execute bool) {
+	line := c.shell.Line()
+
+	edited, err := c.editBuffer(*line, "")
+	if err != nil {
+		c.shell.Hint.SetTemporary(err.Error())
+		return
+	}
+
+	c.shell.History.Save()
+
+	edited = []rune(strings.TrimSuffix(strings.TrimSuffix(string(edited), "\n"), "\r"))
+	line.Set(edited...)
+	c.shell.Cursor().Set(len(edited))
+
+	c.redrawPrompt()
+
+	if accept, found := c.shell.Keymap.Commands()["accept-line"]; found && execute {
+		accept()
+	}
+}
+
+// editBuffer writes the buffer to a temporary file, opens it in the system
+// editor ($VISUAL, $EDITOR, or notepad), and returns the edited contents.
+func (c *Console) editBuffer(buffer []rune, filetype string) ([]rune, error) {
+	pattern := "console-*"
+	if filetype != "" {
+		pattern += "." + strings.TrimPrefix(filetype, ".")
+	}
+
+	file, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return buffer, err
+	}
+
+	name := file.Name()
+	defer os.Remove(name)
+
+	_, err = file.WriteString(string(buffer))
+	file.Close()
+
+	if err != nil {
+		return buffer, err
+	}
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+
+	if editor == "" {
+		editor = "notepad.exe"
+	}
+
+	cmd := exec.Command(editor, filepath.Clean(name))
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err = cmd.Run(); err != nil {
+		return buffer, fmt.Errorf("%w: %s", errEditorStart, err.Error())
+	}
+
+	edited, err := os.ReadFile(name)
+	if err != nil {
+		return buffer, err
+	}
+
+	return []rune(string(edited)), nil
+}
@@ -0,0 +1,201 @@
+package console
+
+import (
+	"unicode"
+)
+
+// bracketObjects are the pairs of brackets of the vi block text objects (i(, a{, etc),
+// by opening and closing bracket, and by their Vim aliases (b for parens, B for braces).
+var bracketObjects = map[rune][2]rune{
+	'(': {'(', ')'}, ')': {'(', ')'}, 'b': {'(', ')'},
+	'[': {'[', ']'}, ']': {'[', ']'},
+	'{': {'{', '}'}, '}': {'{', '}'}, 'B': {'{', '}'},
+	'<': {'<', '>'}, '>': {'<', '>'},
+}
+
+// setupTextObjects replaces the readline word and inside/around selectors used
+// in vi operator pending and visual modes (diw, caw, yi", da(, etc) with ones
+// following the Vim semantics: a word is a run of word characters, of other
+// non-blank characters or of blanks, a quoted string is found on the line even
+// before the cursor enters it, and brackets are matched with their nesting.
+func (c *Console) setupTextObjects() {
+	c.shell.Keymap.Register(map[string]func(){
+		"select-in-word": func() { c.selectWord(false) },
+		"select-a-word":  func() { c.selectWord(true) },
+		"vi-select-inside": func() {
+			keys := c.shell.Keys.Caller()
+
+			key, empty := c.shell.Keys.Pop()
+			if empty || len(keys) == 0 {
+				return
+			}
+
+			c.selectInside(rune(key), keys[0] == 'a')
+		},
+	})
+}
+
+// selectWord selects the word under the cursor, with its trailing blanks
+// (or its leading ones if there are none) if around is true. On blanks,
+// the blanks are selected, with the following word if around is true.
+func (c *Console) selectWord(around bool) {
+	c.shell.History.SkipSave()
+
+	line := []rune(string(*c.shell.Line()))
+	if len(line) == 0 {
+		return
+	}
+
+	pos := min(c.shell.Cursor().Pos(), len(line)-1)
+	bpos, epos := wordBounds(line, pos)
+
+	if around {
+		switch {
+		case wordClass(line[pos]) == 0 && epos < len(line)-1:
+			_, epos = wordBounds(line, epos+1)
+		case epos < len(line)-1 && wordClass(line[epos+1]) == 0:
+			_, epos = wordBounds(line, epos+1)
+		case bpos > 0 && wordClass(line[bpos-1]) == 0:
+			bpos, _ = wordBounds(line, bpos-1)
+		}
+	}
+
+	c.shell.Selection().Mark(bpos)
+	c.shell.Cursor().Set(epos)
+}
+
+// selectInside selects the block in brackets in which the cursor is, or the string
+// quoted with any other character in which the cursor is (or the next one on the
+// line), excluding the brackets or quotes unless around is true, in which case the
+// trailing blanks (or the leading ones) of quoted strings are selected too. Nothing
+// is selected, and the operator does nothing, if there is no such block or string,
+// or if it is empty (the cursor is then moved inside it).
+func (c *Console) selectInside(char rune, around bool) {
+	c.shell.History.SkipSave()
+
+	line := []rune(string(*c.shell.Line()))
+	pos := c.shell.Cursor().Pos()
+
+	var bpos, epos int
+
+	pair, bracket := bracketObjects[char]
+	if bracket {
+		bpos, epos = bracketBounds(line, pair[0], pair[1], pos)
+	} else {
+		bpos, epos = quoteBounds(line, char, pos)
+	}
+
+	// No text object: the pending operator is cancelled.
+	if bpos < 0 {
+		c.shell.Selection().Reset()
+		return
+	}
+
+	switch {
+	case around && bracket:
+	case around:
+		if epos < len(line)-1 && wordClass(line[epos+1]) == 0 {
+			_, epos = wordBounds(line, epos+1)
+		} else if bpos > 0 && wordClass(line[bpos-1]) == 0 {
+			bpos, _ = wordBounds(line, bpos-1)
+		}
+	case epos == bpos+1:
+		c.shell.Selection().Reset()
+		c.shell.Cursor().Set(epos)
+
+		return
+	default:
+		bpos, epos = bpos+1, epos-1
+	}
+
+	c.shell.Selection().Mark(bpos)
+	c.shell.Cursor().Set(epos)
+}
+
+// wordClass returns the class of a character in a vi word: 0 for blanks,
+// 1 for word characters (letters, digits, underscores) and 2 for others.
+func wordClass(char rune) int {
+	switch {
+	case unicode.IsSpace(char):
+		return 0
+	case char == '_' || unicode.IsLetter(char) || unicode.IsDigit(char):
+		return 1
+	default:
+		return 2
+	}
+}
+
+// wordBounds returns the positions of the first and last characters
+// of the run of characters of the same class as the one at pos.
+func wordBounds(line []rune, pos int) (bpos, epos int) {
+	class := wordClass(line[pos])
+
+	bpos, epos = pos, pos
+
+	for bpos > 0 && wordClass(line[bpos-1]) == class {
+		bpos--
+	}
+
+	for epos < len(line)-1 && wordClass(line[epos+1]) == class {
+		epos++
+	}
+
+	return bpos, epos
+}
+
+// quoteBounds returns the positions of the opening and closing quotes of the
+// quoted string containing pos (quotes included), or of the first one after it.
+// Quotes escaped with a backslash are skipped. It returns -1, -1 if none is found.
+func quoteBounds(line []rune, quote rune, pos int) (bpos, epos int) {
+	bpos = -1
+
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && quote != '\'':
+			i++
+		case line[i] != quote:
+		case bpos < 0:
+			bpos = i
+		case pos <= i:
+			return bpos, i
+		default:
+			bpos = -1
+		}
+	}
+
+	return -1, -1
+}
+
+// bracketBounds returns the positions of the opening and closing brackets of the
+// innermost block containing pos (brackets included), or -1, -1 if there is none.
+func bracketBounds(line []rune, open, closing rune, pos int) (bpos, epos int) {
+	bpos = -1
+
+	for i, depth := min(pos, len(line)-1), 0; i >= 0 && bpos < 0; i-- {
+		switch {
+		case line[i] == closing && i != pos:
+			depth++
+		case line[i] == open && depth == 0:
+			bpos = i
+		case line[i] == open:
+			depth--
+		}
+	}
+
+	if bpos < 0 {
+		return -1, -1
+	}
+
+	for i, depth := bpos+1, 0; i < len(line); i++ {
+		switch {
+		case line[i] == open:
+			depth++
+		case line[i] == closing && depth == 0:
+			return bpos, i
+		case line[i] == closing:
+			depth--
+		}
+	}
+
+	return -1, -1
+}
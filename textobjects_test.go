@@ -0,0 +1,41 @@
+package console
+
+import "testing"
+
+func TestTextObjectBounds(t *testing.T) {
+	quoted := func(quote rune) func([]rune, int) (int, int) {
+		return func(line []rune, pos int) (int, int) { return quoteBounds(line, quote, pos) }
+	}
+
+	brackets := func(line []rune, pos int) (int, int) { return bracketBounds(line, '(', ')', pos) }
+
+	tests := []struct {
+		name       string
+		bounds     func(line []rune, pos int) (int, int)
+		line       string
+		pos        int
+		bpos, epos int
+	}{
+		{"word", wordBounds, "deploy now_2 x", 8, 7, 11},
+		{"punctuation", wordBounds, `say "hi"`, 4, 4, 4},
+		{"blanks", wordBounds, "a   b", 2, 1, 3},
+		{"inside quotes", quoted('"'), `a "b c" d`, 4, 2, 6},
+		{"on closing quote", quoted('"'), `a "b c" d`, 6, 2, 6},
+		{"quotes after", quoted('"'), `a "b" "c"`, 0, 2, 4},
+		{"between quotes", quoted('"'), `a "b" "c"`, 5, 6, 8},
+		{"escaped quote", quoted('"'), `"a \" b" c`, 1, 0, 7},
+		{"no quotes after", quoted('"'), `"a" b`, 4, -1, -1},
+		{"unterminated", quoted('\''), `a 'b`, 0, -1, -1},
+		{"brackets", brackets, "f(a, g(b)) c", 3, 1, 9},
+		{"nested", brackets, "f(a, g(b)) c", 7, 6, 8},
+		{"on bracket", brackets, "f(a, g(b)) c", 9, 1, 9},
+		{"outside", brackets, "f(a) c", 5, -1, -1},
+	}
+
+	for _, test := range tests {
+		bpos, epos := test.bounds([]rune(test.line), test.pos)
+		if bpos != test.bpos || epos != test.epos {
+			t.Errorf("%s: bounds = %d, %d, want %d, %d", test.name, bpos, epos, test.bpos, test.epos)
+		}
+	}
+}
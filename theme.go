@@ -0,0 +1,84 @@
+package console
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Theme is a set of colors used by the console, given as ANSI sequences.
+// Empty fields use no colors (the text is still printed in bold, if it is).
+type Theme struct {
+	Command string // Commands in the input line (see SetDefaultCommandHighlight).
+	Flag    string // Flags in the input line (see SetDefaultFlagHighlight).
+	Hint    string // Command and flag hints (see SetDefaultHintHighlight).
+}
+
+// builtinThemes are the themes available in all consoles.
+var builtinThemes = map[string]Theme{
+	"default":    {Command: seqFgGreen, Flag: seqBrightWigth, Hint: dim},
+	"monochrome": {Command: "", Flag: "", Hint: dim},
+
+	// Blue and orange/yellow, distinct with red-green color blindness (see CheckTheme).
+	"deuteranopia": {Command: "\x1b[38;5;32m", Flag: "\x1b[38;5;214m", Hint: dim},
+	"protanopia":   {Command: "\x1b[38;5;39m", Flag: "\x1b[38;5;220m", Hint: dim},
+}
+
+// RegisterTheme registers a theme with a name, for users to select it in their
+// configuration (see Config), replacing any theme with the same name. Themes
+// "default" (green commands, grey flags and dim hints) and "monochrome" exist,
+// as well as "deuteranopia" and "protanopia" for color blind users.
+func (c *Console) RegisterTheme(name string, theme Theme) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.themes[name] = theme
+}
+
+// SetTheme sets the colors of the console to those of a registered theme.
+func (c *Console) SetTheme(name string) error {
+	c.mutex.RLock()
+	theme, found := c.themes[name]
+	c.mutex.RUnlock()
+
+	if !found {
+		return fmt.Errorf(tr("unknown theme: %s"), name)
+	}
+
+	c.mutex.Lock()
+	c.cmdHighlight = theme.Command
+	c.flagHighlight = theme.Flag
+	c.hintHighlight = theme.Hint
+	c.mutex.Unlock()
+
+	c.clearCaches()
+
+	return nil
+}
+
+// Theme returns the colors currently used by the console, either set with
+// SetTheme or with the SetDefault*Highlight functions.
+func (c *Console) Theme() Theme {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return Theme{
+		Command: c.cmdHighlight,
+		Flag:    c.flagHighlight,
+		Hint:    c.hintHighlight,
+	}
+}
+
+// Themes returns the names of the registered themes, sorted.
+func (c *Console) Themes() []string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	names := make([]string, 0, len(c.themes))
+	for name := range c.themes {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,65 @@
+package console
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// CommandTimeoutKey should be used as a key to in a cobra.Annotation map.
+// The value is the maximum execution duration of the command (and of its
+// subcommands), in time.ParseDuration format, overriding Console.Timeout.
+// A zero duration disables the timeout for the command.
+const CommandTimeoutKey = "console-timeout"
+
+// ErrCommandTimeout is the cause of the command context cancellation (wrapped,
+// along with the timeout) when a command runs for longer than its timeout.
+var ErrCommandTimeout = errors.New("command timed out")
+
+// SetTimeout sets the timeout of a command (see CommandTimeoutKey).
+func SetTimeout(cmd *cobra.Command, timeout time.Duration) {
+	if cmd.Annotations == nil {
+		cmd.Annotations = make(map[string]string)
+	}
+
+	cmd.Annotations[CommandTimeoutKey] = timeout.String()
+}
+
+// commandTimeout returns the timeout for the command, which is the one of the
+// closest command (or parent) having a valid timeout annotation, or the console
+// default one.
+func (c *Console) commandTimeout(cmd *cobra.Command) time.Duration {
+	for ; cmd != nil; cmd = cmd.Parent() {
+		if value, found := cmd.Annotations[CommandTimeoutKey]; found {
+			if timeout, err := time.ParseDuration(value); err == nil {
+				return timeout
+			}
+		}
+	}
+
+	return c.Timeout
+}
+
+// watchTimeout cancels the command context with ErrCommandTimeout once the command
+// timeout is exceeded, if any. The returned function stops the timer.
+func (c *Console) watchTimeout(cmd *cobra.Command, cancel func(cause error)) (stop func()) {
+	timeout := c.commandTimeout(cmd)
+	if timeout <= 0 {
+		return func() {}
+	}
+
+	timer := time.AfterFunc(timeout, func() {
+		cancel(fmt.Errorf("%w after %s", ErrCommandTimeout, timeout))
+	})
+
+	return func() { timer.Stop() }
+}
+
+// printTimeout warns the user that a command has been canceled after its timeout.
+func printTimeout(cmd *cobra.Command, err error) {
+	fmt.Fprintf(os.Stderr, "Warning: %s: %s\n", strings.TrimSpace(cmd.CommandPath()), err)
+}
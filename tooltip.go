@@ -0,0 +1,62 @@
+package console
+
+import (
+	"strings"
+)
+
+// CommandTooltip returns the short description of the command (or the usage of
+// the flag) under the cursor, or an empty string if the cursor is on any other
+// word. It is meant to be used as a menu tooltip prompt, which is then updated
+// as the user moves through the input line, and replaces the right prompt when
+// not empty:
+//
+//	menu.Prompt().Tooltip = app.CommandTooltip
+//
+// The word parameter (the first word of the line) is ignored.
+func (c *Console) CommandTooltip(_ string) string {
+	menu := c.activeMenu()
+	if menu.Command == nil {
+		return ""
+	}
+
+	line := *c.shell.Line()
+	pos := c.shell.Cursor().Pos()
+
+	words, ranges := wordRanges(line)
+
+	current := -1
+
+	for i, bounds := range ranges {
+		if pos >= bounds[0] && pos <= bounds[1] {
+			current = i
+			break
+		}
+	}
+
+	if current < 0 {
+		return ""
+	}
+
+	target, _, err := menu.Command.Find(words[:current+1])
+	if err != nil || target == nil || target == menu.Command {
+		return ""
+	}
+
+	var tooltip string
+
+	word := words[current]
+
+	if strings.HasPrefix(word, "-") {
+		if flag := lookupFlag(target, word); flag != nil {
+			tooltip = flag.Usage
+		}
+	} else if word == target.Name() || target.HasAlias(word) {
+		tooltip = target.Short
+	}
+
+	if tooltip == "" {
+		return ""
+	}
+
+	return c.Theme().Hint + tooltip + reset
+}
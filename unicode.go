@@ -0,0 +1,146 @@
+package console
+
+import (
+	"strings"
+
+	"github.com/rivo/uniseg"
+)
+
+// graphemeBounds returns the rune offsets at which each grapheme cluster of
+// the line starts, followed by the length of the line. Characters such as
+// emoji with modifiers, flags or combining sequences span several runes but
+// are displayed (and should be edited) as a single character.
+func graphemeBounds(line []rune) []int {
+	bounds := make([]int, 0, len(line)+1)
+	graphemes := uniseg.NewGraphemes(string(line))
+	offset := 0
+
+	for graphemes.Next() {
+		bounds = append(bounds, offset)
+		offset += len(graphemes.Runes())
+	}
+
+	return append(bounds, offset)
+}
+
+// prevGrapheme returns the start offset of the grapheme cluster preceding pos.
+func prevGrapheme(line []rune, pos int) int {
+	bounds := graphemeBounds(line)
+
+	for i := len(bounds) - 1; i >= 0; i-- {
+		if bounds[i] < pos {
+			return bounds[i]
+		}
+	}
+
+	return 0
+}
+
+// nextGrapheme returns the start offset of the grapheme cluster following pos.
+func nextGrapheme(line []rune, pos int) int {
+	for _, bound := range graphemeBounds(line) {
+		if bound > pos {
+			return bound
+		}
+	}
+
+	return len(line)
+}
+
+// graphemeCommands are the readline commands moving the cursor over, or deleting,
+// the character before (backward) or under (forward) the cursor.
+var graphemeCommands = map[string]bool{
+	"forward-char":            true,
+	"backward-char":           false,
+	"vi-forward-char":         true,
+	"vi-backward-char":        false,
+	"delete-char":             true,
+	"vi-delete":               true,
+	"backward-delete-char":    false,
+	"vi-backward-delete-char": false,
+	"vi-rubout":               false,
+}
+
+// setupGraphemes wraps the readline character movement and deletion commands so that
+// they operate on whole grapheme clusters instead of single runes: wide or composed
+// characters are thus never split while editing the line, whatever the keys bound to
+// these commands. The readline commands still move or delete the first rune of each
+// character (honoring the vi end of line, registers, autosuggestions, etc), and the
+// rest of the character is then skipped or deleted along with it.
+func (c *Console) setupGraphemes() {
+	commands := c.shell.Keymap.Commands()
+	wrapped := make(map[string]func(), len(graphemeCommands))
+
+	for name, forward := range graphemeCommands {
+		command := commands[name]
+		if command == nil {
+			continue
+		}
+
+		wrapped[name] = func() {
+			for range c.repeatCount() {
+				c.graphemeCommand(name, command, forward)
+			}
+		}
+	}
+
+	c.shell.Keymap.Register(wrapped)
+}
+
+// graphemeCommand runs a character movement or deletion command once, and extends
+// its effect to the whole grapheme cluster after (forward) or before the cursor.
+func (c *Console) graphemeCommand(name string, command func(), forward bool) {
+	line := c.shell.Line()
+	cursor := c.shell.Cursor()
+
+	deletes := strings.Contains(name, "delete") || name == "vi-rubout"
+
+	// Delete both characters of an empty pair of quotes/brackets.
+	if deletes && !forward && c.deletesPair(*line, cursor.Pos()) {
+		line.CutRune(cursor.Pos())
+	}
+
+	before := append([]rune{}, *line...)
+	pos := cursor.Pos()
+
+	start, end := prevGrapheme(before, pos), nextGrapheme(before, pos)
+
+	command()
+
+	switch {
+	case !deletes && string(*line) != string(before):
+		// The line has changed (eg. autosuggestion accepted).
+		return
+
+	case !deletes && forward && cursor.Pos() > pos && cursor.Pos() < end:
+		// vi command mode never moves past the last character.
+		if name == "vi-forward-char" && end == len(before) {
+			cursor.Set(pos)
+		} else {
+			cursor.Set(end)
+		}
+
+	case !deletes && !forward && cursor.Pos() < pos && cursor.Pos() > start:
+		cursor.Set(start)
+
+	case deletes && forward && line.Len() == len(before)-1 && cursor.Pos() == pos && end-pos > 1:
+		runes := append(append([]rune{}, (*line)[:pos]...), (*line)[end-1:]...)
+		line.Set(runes...)
+		cursor.Set(pos)
+
+	case deletes && !forward && line.Len() < len(before) && cursor.Pos() == pos-1 && pos-start > 1:
+		runes := append(append([]rune{}, (*line)[:start]...), (*line)[pos-1:]...)
+		line.Set(runes...)
+		cursor.Set(start)
+	}
+}
+
+// deletesPair returns true if the character deleted before the cursor is
+// the opener of an automatically inserted pair, in menus enabling them.
+func (c *Console) deletesPair(line []rune, cursor int) bool {
+	if c.shell.Config.GetBool("autopairs") || !c.activeMenu().AutoPairs {
+		return false
+	}
+
+	return isEmptyPair(line, cursor)
+}
@@ -0,0 +1,113 @@
+package console
+
+import (
+	"testing"
+)
+
+func TestGraphemeBounds(t *testing.T) {
+	tests := []struct {
+		line   string
+		bounds []int
+	}{
+		{"", []int{0}},
+		{"abc", []int{0, 1, 2, 3}},
+		{"a👍🏽b", []int{0, 1, 3, 4}},
+		{"🇫🇷", []int{0, 2}},
+		{"e\u0301t", []int{0, 2, 3}},
+	}
+
+	for _, test := range tests {
+		bounds := graphemeBounds([]rune(test.line))
+		if !equalInts(bounds, test.bounds) {
+			t.Errorf("graphemeBounds(%q) = %v, want %v", test.line, bounds, test.bounds)
+		}
+	}
+}
+
+func TestGraphemeCommands(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		line    string
+		cursor  int
+		want    string
+		wantPos int
+	}{
+		{"forward over emoji", "forward-char", "a👍🏽b", 1, "a👍🏽b", 3},
+		{"forward over rune", "forward-char", "abc", 1, "abc", 2},
+		{"backward over emoji", "backward-char", "a👍🏽b", 3, "a👍🏽b", 1},
+		{"backward over flag", "backward-char", "🇫🇷", 2, "🇫🇷", 0},
+		{"vi forward over accent", "vi-forward-char", "e\u0301tt", 0, "e\u0301tt", 2},
+		{"vi forward on last character", "vi-forward-char", "ae\u0301", 1, "ae\u0301", 1},
+		{"vi backward over accent", "vi-backward-char", "e\u0301t", 2, "e\u0301t", 0},
+		{"delete emoji", "delete-char", "a👍🏽b", 1, "ab", 1},
+		{"delete rune", "delete-char", "abc", 0, "bc", 0},
+		{"vi delete flag", "vi-delete", "a🇫🇷", 1, "a", 1},
+		{"backward delete emoji", "backward-delete-char", "a👍🏽b", 3, "ab", 1},
+		{"backward delete accent", "backward-delete-char", "e\u0301", 2, "", 0},
+		{"backward delete rune", "backward-delete-char", "abc", 2, "ac", 1},
+		{"vi rubout flag", "vi-rubout", "🇫🇷a", 2, "a", 0},
+		{"vi backward delete emoji", "vi-backward-delete-char", "👍🏽", 2, "", 0},
+	}
+
+	c := New("test")
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c.shell.Line().Set([]rune(test.line)...)
+			c.shell.Cursor().Set(test.cursor)
+
+			c.shell.Keymap.Commands()[test.command]()
+
+			if line := string(*c.shell.Line()); line != test.want {
+				t.Errorf("line = %q, want %q", line, test.want)
+			}
+
+			if pos := c.shell.Cursor().Pos(); pos != test.wantPos {
+				t.Errorf("cursor = %d, want %d", pos, test.wantPos)
+			}
+		})
+	}
+}
+
+func TestGraphemeCommandsRepeat(t *testing.T) {
+	c := New("test")
+
+	c.shell.Line().Set([]rune("👍🏽🇫🇷ab")...)
+	c.shell.Cursor().Set(0)
+	c.shell.Iterations.Add("3")
+
+	c.shell.Keymap.Commands()["delete-char"]()
+
+	if line := string(*c.shell.Line()); line != "b" {
+		t.Errorf("line = %q, want %q", line, "b")
+	}
+}
+
+func TestGraphemeBackwardDeletePair(t *testing.T) {
+	c := New("test")
+	c.activeMenu().AutoPairs = true
+
+	c.shell.Line().Set([]rune(`echo ""`)...)
+	c.shell.Cursor().Set(6)
+
+	c.shell.Keymap.Commands()["backward-delete-char"]()
+
+	if line := string(*c.shell.Line()); line != "echo " {
+		t.Errorf("line = %q, want %q", line, "echo ")
+	}
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
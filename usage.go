@@ -0,0 +1,371 @@
+package console
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/reeflective/readline"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// maxUsageValues is the number of values remembered for each command argument
+// or flag, the least recently used values being forgotten first.
+const maxUsageValues = 100
+
+// CandidateOrder is the order in which completion candidates are proposed
+// for the arguments of commands and flags (see Console.CandidateOrder).
+type CandidateOrder int
+
+const (
+	// OrderDefault keeps the order of the completers (the shell sorts them).
+	OrderDefault CandidateOrder = iota
+
+	// OrderRecent lists the values most recently used first.
+	OrderRecent
+
+	// OrderFrequent lists the values most often used first,
+	// and the most recently used first among those used as often.
+	OrderFrequent
+)
+
+// usageEntry records the uses of a value for a command argument or flag.
+type usageEntry struct {
+	Count int       `json:"count"`
+	Last  time.Time `json:"last"`
+}
+
+// usageStore records the values used for the arguments and flags of the commands
+// of a menu, by command path (and flag, eg. "deploy --env"). They are saved to a
+// file next to the first history file of the menu, if any.
+type usageStore struct {
+	path   string
+	loaded bool
+	values map[string]map[string]*usageEntry
+	mutex  sync.Mutex
+}
+
+// usageFile returns the file in which the values used in the menu commands are saved,
+// next to the first history file of the menu, or an empty path if it has none.
+func (m *Menu) usageFile() string {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	for _, name := range m.historyNames {
+		if file, ok := m.histories[name].(*historyFile); ok {
+			return file.path + ".usage"
+		}
+	}
+
+	return ""
+}
+
+// load reads the values used from the usage file, if not done yet.
+func (u *usageStore) load(path string) {
+	if u.loaded && u.path == path {
+		return
+	}
+
+	u.loaded = true
+	u.path = path
+	u.values = make(map[string]map[string]*usageEntry)
+
+	if path == "" {
+		return
+	}
+
+	if data, err := os.ReadFile(path); err == nil {
+		_ = json.Unmarshal(data, &u.values)
+	}
+}
+
+// save writes the values used to the usage file, through a temporary file.
+func (u *usageStore) save() error {
+	if u.path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(u.values)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(u.path), 0o700); err != nil {
+		return err
+	}
+
+	tmp := u.path + ".tmp"
+
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, u.path)
+}
+
+// recordUsage records the values of the arguments and flags of a command which
+// has been executed successfully, except the sensitive ones and boolean flags.
+func (c *Console) recordUsage(menu *Menu, target *cobra.Command) {
+	limit, excluded := c.recentSettings()
+
+	if (c.CandidateOrder == OrderDefault && limit == 0) || menu.Command == nil {
+		return
+	}
+
+	path := strings.TrimSpace(strings.TrimPrefix(target.CommandPath(), menu.Command.Name()))
+	used := make(map[string][]string)
+
+	for i, arg := range target.Flags().Args() {
+		if !isSensitiveArg(target, i) && !excluded(path) {
+			used[path] = append(used[path], arg)
+		}
+	}
+
+	target.Flags().Visit(func(flag *pflag.Flag) {
+		key := path + " --" + flag.Name
+
+		if flag.NoOptDefVal != "" || isSensitiveFlag(flag) || excluded(key) {
+			return
+		}
+
+		if slice, ok := flag.Value.(pflag.SliceValue); ok {
+			used[key] = append(used[key], slice.GetSlice()...)
+		} else {
+			used[key] = append(used[key], flag.Value.String())
+		}
+	})
+
+	if len(used) == 0 {
+		return
+	}
+
+	file := menu.usageFile()
+	now := time.Now()
+
+	menu.usage.mutex.Lock()
+	defer menu.usage.mutex.Unlock()
+
+	menu.usage.load(file)
+
+	for key, values := range used {
+		entries := menu.usage.values[key]
+		if entries == nil {
+			entries = make(map[string]*usageEntry)
+			menu.usage.values[key] = entries
+		}
+
+		for _, value := range values {
+			if entries[value] == nil {
+				entries[value] = &usageEntry{}
+			}
+
+			entries[value].Count++
+			entries[value].Last = now
+		}
+
+		forgetOldest(entries)
+	}
+
+	menu.usage.save()
+}
+
+// forgetOldest removes the least recently used values over maxUsageValues.
+func forgetOldest(entries map[string]*usageEntry) {
+	if len(entries) <= maxUsageValues {
+		return
+	}
+
+	values := make([]string, 0, len(entries))
+	for value := range entries {
+		values = append(values, value)
+	}
+
+	sort.Slice(values, func(i, j int) bool {
+		return entries[values[i]].Last.After(entries[values[j]].Last)
+	})
+
+	for _, value := range values[maxUsageValues:] {
+		delete(entries, value)
+	}
+}
+
+// usageKey returns the key of the values used for the argument or flag being completed,
+// given the words of the line, or false when completing flags themselves.
+func usageKey(menu *Menu, args []string) (string, bool) {
+	if menu.Command == nil || len(args) == 0 {
+		return "", false
+	}
+
+	// Flags themselves have no recorded values.
+	if strings.HasPrefix(args[len(args)-1], "-") {
+		return "", false
+	}
+
+	target, _, err := menu.Command.Find(args[:len(args)-1])
+	if err != nil || target == nil {
+		return "", false
+	}
+
+	key := strings.TrimSpace(strings.TrimPrefix(target.CommandPath(), menu.Command.Name()))
+
+	if flag := currentFlag(target, args, false); flag != nil {
+		key += " --" + flag.Name
+	}
+
+	return key, true
+}
+
+// usedValues returns the values used for a command argument or flag.
+func (m *Menu) usedValues(key string) map[string]usageEntry {
+	file := m.usageFile()
+
+	m.usage.mutex.Lock()
+	defer m.usage.mutex.Unlock()
+
+	m.usage.load(file)
+	entries := make(map[string]usageEntry, len(m.usage.values[key]))
+
+	for value, entry := range m.usage.values[key] {
+		entries[value] = *entry
+	}
+
+	return entries
+}
+
+// orderByUsage sorts the candidates for an argument or flag value, with the values
+// used before first (see Console.CandidateOrder), and the others alphabetically.
+// It returns false if none of them were used, leaving their order to the shell.
+func (c *Console) orderByUsage(menu *Menu, args []string, candidates []readline.Completion) bool {
+	if c.CandidateOrder == OrderDefault {
+		return false
+	}
+
+	key, found := usageKey(menu, args)
+	if !found {
+		return false
+	}
+
+	entries := menu.usedValues(key)
+	found = false
+
+	for _, candidate := range candidates {
+		if _, used := entries[candidate.Value]; used {
+			found = true
+			break
+		}
+	}
+
+	if !found {
+		return false
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		first, firstUsed := entries[candidates[i].Value]
+		second, secondUsed := entries[candidates[j].Value]
+
+		switch {
+		case firstUsed != secondUsed:
+			return firstUsed
+		case !firstUsed:
+			return candidates[i].Value < candidates[j].Value
+		case c.CandidateOrder == OrderFrequent && first.Count != second.Count:
+			return first.Count > second.Count
+		default:
+			return first.Last.After(second.Last)
+		}
+	})
+
+	return true
+}
+
+// recentValues returns the candidates for an argument or flag value, with the values
+// most recently used first, up to Console.RecentValues, in a "recent values" group.
+// The candidates of the completer which are recent values are moved to this group.
+func (c *Console) recentValues(menu *Menu, args []string, candidates []readline.Completion) ([]readline.Completion, bool) {
+	limit, excluded := c.recentSettings()
+	if limit == 0 {
+		return candidates, false
+	}
+
+	key, found := usageKey(menu, args)
+	if !found || excluded(key) {
+		return candidates, false
+	}
+
+	entries := menu.usedValues(key)
+	if len(entries) == 0 {
+		return candidates, false
+	}
+
+	values := make([]string, 0, len(entries))
+	for value := range entries {
+		values = append(values, value)
+	}
+
+	sort.Slice(values, func(i, j int) bool {
+		return entries[values[i]].Last.After(entries[values[j]].Last)
+	})
+
+	values = values[:min(limit, len(values))]
+
+	recent := make([]readline.Completion, len(values), len(values)+len(candidates))
+	indexes := make(map[string]int, len(values))
+
+	for i, value := range values {
+		recent[i] = readline.Completion{Value: value}
+		indexes[value] = i
+	}
+
+	for _, candidate := range candidates {
+		if i, isRecent := indexes[candidate.Value]; isRecent {
+			recent[i] = candidate
+		} else {
+			recent = append(recent, candidate)
+		}
+	}
+
+	for i := range values {
+		recent[i].Tag = tr("recent values")
+	}
+
+	return recent, true
+}
+
+// recentSettings returns the number of recent values proposed, from the configuration
+// or from the console settings when not configured, and a function returning true for
+// the arguments or flags (by usage key) not remembered: those excluded by the user in
+// the configuration are added to the ones excluded by the application, never replacing
+// them, since these are most likely sensitive.
+func (c *Console) recentSettings() (limit int, excluded func(key string) bool) {
+	config := c.Config()
+
+	limit = c.RecentValues
+	if configured, err := strconv.Atoi(config.RecentValues); err == nil && configured >= 0 {
+		limit = configured
+	}
+
+	exclude := append([]string{}, c.RecentValuesExclude...)
+	if config.RecentExclude != "" {
+		exclude = append(exclude, strings.Split(config.RecentExclude, ",")...)
+	}
+
+	excluded = func(key string) bool {
+		for _, name := range exclude {
+			name = strings.TrimPrefix(strings.TrimSpace(name), "--")
+
+			if name != "" && (key == name || strings.HasSuffix(key, " --"+name)) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return limit, excluded
+}
@@ -0,0 +1,37 @@
+package console
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRecentSettingsMergesExclusions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "console.yaml")
+	if err := os.WriteFile(path, []byte("recent-values: 3\nrecent-values-exclude: color\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	c := New("test")
+	c.RecentValues = 10
+	c.RecentValuesExclude = []string{"token", "login --password"}
+
+	if err := c.LoadConfig(path); err != nil {
+		t.Fatal(err)
+	}
+
+	limit, excluded := c.recentSettings()
+	if limit != 3 {
+		t.Errorf("limit = %d, want 3", limit)
+	}
+
+	for _, key := range []string{"connect --token", "login --password", "paint --color"} {
+		if !excluded(key) {
+			t.Errorf("%q is not excluded", key)
+		}
+	}
+
+	if excluded("connect --host") {
+		t.Error(`"connect --host" is excluded`)
+	}
+}
@@ -0,0 +1,64 @@
+package console
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// validateUsage parses the flags of the target command and validates its required
+// flags and positional arguments, so that usage errors are reported concisely and
+// before any pre-run hook. Flags are reset afterwards, since the command parses
+// them again when executed.
+func (c *Console) validateUsage(target *cobra.Command, args []string) error {
+	if target.DisableFlagParsing || !target.Runnable() {
+		return nil
+	}
+
+	defer c.resetFlags(target)
+
+	// These are otherwise only added by cobra when executing.
+	target.InitDefaultHelpFlag()
+	target.InitDefaultVersionFlag()
+
+	if err := target.ParseFlags(args); err != nil {
+		return newUsageError(target, err)
+	}
+
+	// Let cobra print the help/version when requested.
+	for _, name := range []string{"help", "version"} {
+		if flag := target.Flags().Lookup(name); flag != nil && flag.Changed {
+			return nil
+		}
+	}
+
+	var missing []string
+
+	target.Flags().VisitAll(func(flag *pflag.Flag) {
+		if len(flag.Annotations[cobra.BashCompOneRequiredFlag]) > 0 && !flag.Changed {
+			missing = append(missing, bold+c.Theme().Flag+"--"+flag.Name+seqFgReset+boldReset)
+		}
+	})
+
+	if len(missing) == 1 {
+		return newUsageError(target, fmt.Errorf(tr("missing required flag %s"), missing[0]))
+	} else if len(missing) > 1 {
+		return newUsageError(target, fmt.Errorf(tr("missing required flags %s"), strings.Join(missing, ", ")))
+	}
+
+	if err := target.ValidateArgs(target.Flags().Args()); err != nil {
+		return newUsageError(target, err)
+	}
+
+	return nil
+}
+
+// newUsageError creates a new UsageError for the command.
+func newUsageError(cmd *cobra.Command, err error) UsageError {
+	return UsageError{
+		Err:     newError(err, bold+strings.TrimSpace(cmd.CommandPath())+boldReset),
+		Command: cmd,
+	}
+}
@@ -0,0 +1,161 @@
+package console
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// FlagValidator validates a value given to a flag on the command line,
+// before the flag is set. For slice flags, it is called with each of
+// the (unsplit) values given to the flag.
+type FlagValidator func(value string) error
+
+// ValidateFlag attaches validators to a flag of a command: they are ran when
+// the command line is parsed (before the command is executed), and an invalid
+// value produces a UsageError. The default value of the flag is always valid,
+// and flags not found are ignored.
+func ValidateFlag(cmd *cobra.Command, name string, validators ...FlagValidator) {
+	flag := cmd.Flags().Lookup(name)
+	if flag == nil {
+		flag = cmd.PersistentFlags().Lookup(name)
+	}
+
+	if flag == nil || len(validators) == 0 {
+		return
+	}
+
+	validated := &validatedValue{
+		Value:      flag.Value,
+		defValue:   flag.DefValue,
+		validators: validators,
+	}
+
+	if slice, ok := flag.Value.(pflag.SliceValue); ok {
+		flag.Value = &validatedSliceValue{validatedValue: validated, slice: slice}
+	} else {
+		flag.Value = validated
+	}
+}
+
+// FlagEnum restricts the values of a flag to the given ones (see ValidateFlag
+// and OneOf), which are also used as the completions for the flag values.
+func FlagEnum(cmd *cobra.Command, name string, values ...string) {
+	ValidateFlag(cmd, name, OneOf(values...))
+
+	// Wizards propose the values (see EnableWizard).
+	if cmd.Flags().Lookup(name) != nil {
+		cmd.Flags().SetAnnotation(name, flagEnumKey, values)
+	} else if cmd.PersistentFlags().Lookup(name) != nil {
+		cmd.PersistentFlags().SetAnnotation(name, flagEnumKey, values)
+	}
+
+	carapace.Gen(cmd).FlagCompletion(carapace.ActionMap{
+		name: carapace.ActionValues(values...),
+	})
+}
+
+// OneOf returns a validator accepting only the given values.
+func OneOf(values ...string) FlagValidator {
+	return func(value string) error {
+		for _, valid := range values {
+			if value == valid {
+				return nil
+			}
+		}
+
+		return fmt.Errorf(tr("must be one of: %s"), strings.Join(values, ", "))
+	}
+}
+
+// InRange returns a validator accepting only numbers between low and high (inclusive).
+func InRange(low, high float64) FlagValidator {
+	return func(value string) error {
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return errors.New(tr("must be a number"))
+		}
+
+		if number < low || number > high {
+			return fmt.Errorf(tr("must be between %v and %v"), low, high)
+		}
+
+		return nil
+	}
+}
+
+// MatchRegexp returns a validator accepting only values matching the
+// regular expression, which panics if the expression cannot be compiled.
+func MatchRegexp(pattern string) FlagValidator {
+	expr := regexp.MustCompile(pattern)
+
+	return func(value string) error {
+		if !expr.MatchString(value) {
+			return fmt.Errorf(tr("must match %q"), pattern)
+		}
+
+		return nil
+	}
+}
+
+// validatedValue is a flag value validating values before setting them.
+type validatedValue struct {
+	pflag.Value
+	defValue   string
+	validators []FlagValidator
+}
+
+// Set validates the value, and sets it if valid.
+func (v *validatedValue) Set(value string) error {
+	if err := v.validate(value); err != nil {
+		return err
+	}
+
+	return v.Value.Set(value)
+}
+
+func (v *validatedValue) validate(value string) error {
+	if value == v.defValue {
+		return nil
+	}
+
+	for _, validator := range v.validators {
+		if err := validator(value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validatedSliceValue is a validated slice flag value, still implementing
+// pflag.SliceValue so that its values can be reset between executions.
+type validatedSliceValue struct {
+	*validatedValue
+	slice pflag.SliceValue
+}
+
+// Append validates the value, and appends it if valid.
+func (v *validatedSliceValue) Append(value string) error {
+	if err := v.validate(value); err != nil {
+		return err
+	}
+
+	return v.slice.Append(value)
+}
+
+// Replace replaces all values of the slice, without validating them.
+func (v *validatedSliceValue) Replace(values []string) error {
+	return v.slice.Replace(values)
+}
+
+// GetSlice returns the values of the slice.
+func (v *validatedSliceValue) GetSlice() []string {
+	return v.slice.GetSlice()
+}
@@ -0,0 +1,258 @@
+package console
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// variablePattern matches a variable reference at the start of
+// a command line part, like $name or $name.field.0, with its fields.
+var variablePattern = regexp.MustCompile(`^\$([A-Za-z_][A-Za-z0-9_]*)((?:\.[A-Za-z0-9_-]+)*)`)
+
+// resultKey is the context key used to pass the result holder to captured commands.
+type resultKey struct{}
+
+// resultHolder holds the structured result of a captured command.
+type resultHolder struct {
+	value any
+	set   bool
+}
+
+// SetVariable sets a console variable, which can then be referenced in command
+// lines with $name, or $name.field for fields of structured values (structs, maps,
+// and slices indexes). A nil value deletes the variable.
+func (c *Console) SetVariable(name string, value any) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.variables == nil {
+		c.variables = make(map[string]any)
+	}
+
+	if value == nil {
+		delete(c.variables, name)
+		return
+	}
+
+	c.variables[name] = value
+}
+
+// Variable returns the value of a console variable, if it exists.
+func (c *Console) Variable(name string) (value any, found bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	value, found = c.variables[name]
+
+	return value, found
+}
+
+// Variables returns the names of all console variables, sorted.
+func (c *Console) Variables() []string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	names := make([]string, 0, len(c.variables))
+	for name := range c.variables {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// SetResult sets the structured result of a command, which is used as the value
+// captured by Console.Capture (eg. with `set x = command`) instead of its output.
+// This has no effect if the command output is not being captured.
+func SetResult(cmd *cobra.Command, value any) {
+	if holder, ok := cmd.Context().Value(resultKey{}).(*resultHolder); ok {
+		holder.value, holder.set = value, true
+	}
+}
+
+// Capture executes a command line (already split into words) in the active menu,
+// and returns its result: either the value set by the command with SetResult, or its
+// output, which is decoded if it is valid JSON, or trimmed from surrounding spaces.
+func (c *Console) Capture(ctx context.Context, args []string) (any, error) {
+	holder := &resultHolder{}
+	ctx = context.WithValue(ctx, resultKey{}, holder)
+
+	output, err := captureOutput(func() error {
+		return c.activeMenu().RunCommandArgs(ctx, args)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if holder.set {
+		return holder.value, nil
+	}
+
+	var decoded any
+	if json.Unmarshal([]byte(output), &decoded) == nil && decoded != nil {
+		return decoded, nil
+	}
+
+	return strings.TrimSpace(output), nil
+}
+
+// expandVariables replaces the references to console variables in a command line,
+// before it is split into words: like in shells, references are not expanded inside
+// single quotes or when their $ is escaped, and values are quoted like the words they
+// are in. References to unknown variables or fields are left untouched.
+func (c *Console) expandVariables(line string) string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	if len(c.variables) == 0 {
+		return line
+	}
+
+	var expanded strings.Builder
+
+	var quote rune
+
+	for i := 0; i < len(line); i++ {
+		char := rune(line[i])
+
+		switch {
+		case char == escapeChar && quote != singleChar && i+1 < len(line):
+			expanded.WriteString(line[i : i+2])
+			i++
+
+			continue
+		case quote != 0 && char == quote:
+			quote = 0
+		case quote == 0 && (char == singleChar || char == doubleChar):
+			quote = char
+		case char == '$' && quote != singleChar:
+			if match := variablePattern.FindStringSubmatch(line[i:]); match != nil {
+				if value, found := c.variableValue(match[1], match[2]); found {
+					expanded.WriteString(quoteWord(value, quote))
+					i += len(match[0]) - 1
+
+					continue
+				}
+			}
+		}
+
+		expanded.WriteByte(line[i])
+	}
+
+	return expanded.String()
+}
+
+// variableValue returns the formatted value of a variable, or of one of its
+// fields if fields is not empty (eg. ".name.0"), if it exists.
+func (c *Console) variableValue(name, fields string) (string, bool) {
+	value, found := c.variables[name]
+	if !found {
+		return "", false
+	}
+
+	if fields != "" {
+		if value, found = lookupField(value, strings.Split(fields[1:], ".")); !found {
+			return "", false
+		}
+	}
+
+	return formatValue(value), true
+}
+
+// lookupField returns the value at a path of fields in a structured value:
+// map keys, struct fields (by name, case-insensitive, or json tag) or indexes.
+func lookupField(value any, path []string) (any, bool) {
+	for _, field := range path {
+		val := reflect.ValueOf(value)
+		for val.Kind() == reflect.Pointer || val.Kind() == reflect.Interface {
+			if val.IsNil() {
+				return nil, false
+			}
+
+			val = val.Elem()
+		}
+
+		switch val.Kind() {
+		case reflect.Map:
+			if val.Type().Key().Kind() != reflect.String {
+				return nil, false
+			}
+
+			item := val.MapIndex(reflect.ValueOf(field).Convert(val.Type().Key()))
+			if !item.IsValid() {
+				return nil, false
+			}
+
+			value = item.Interface()
+
+		case reflect.Struct:
+			item, found := structField(val, field)
+			if !found {
+				return nil, false
+			}
+
+			value = item.Interface()
+
+		case reflect.Slice, reflect.Array:
+			index, err := strconv.Atoi(field)
+			if err != nil || index < 0 || index >= val.Len() {
+				return nil, false
+			}
+
+			value = val.Index(index).Interface()
+
+		default:
+			return nil, false
+		}
+	}
+
+	return value, true
+}
+
+// structField returns the exported field of a struct matching the name.
+func structField(val reflect.Value, name string) (reflect.Value, bool) {
+	for i := 0; i < val.NumField(); i++ {
+		field := val.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+
+		if strings.EqualFold(field.Name, name) || tag == name {
+			return val.Field(i), true
+		}
+	}
+
+	return reflect.Value{}, false
+}
+
+// formatValue formats a variable value for use in a command line word.
+func formatValue(value any) string {
+	switch val := value.(type) {
+	case string:
+		return val
+	case fmt.Stringer:
+		return val.String()
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	}
+
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Map, reflect.Struct, reflect.Slice, reflect.Array, reflect.Pointer:
+		if data, err := json.Marshal(value); err == nil {
+			return string(data)
+		}
+	}
+
+	return fmt.Sprint(value)
+}
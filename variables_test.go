@@ -0,0 +1,110 @@
+package console
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestExpandVariables(t *testing.T) {
+	c := New("test")
+	c.SetVariable("name", "web server")
+	c.SetVariable("host", map[string]any{"ports": []any{80, 443}})
+
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"echo $name", []string{"echo", "web server"}},
+		{"echo $name-1", []string{"echo", "web server-1"}},
+		{`echo "$name's"`, []string{"echo", "web server's"}},
+		{`echo '$name'`, []string{"echo", "$name"}},
+		{`echo \$name`, []string{"echo", "$name"}},
+		{`echo "\$name"`, []string{"echo", "$name"}},
+		{"echo $host.ports.1", []string{"echo", "443"}},
+		{"echo $host.missing $unknown", []string{"echo", "$host.missing", "$unknown"}},
+	}
+
+	for _, test := range tests {
+		args, err := c.parse(test.line)
+		if err != nil {
+			t.Fatalf("%s: %v", test.line, err)
+		}
+
+		if !slices.Equal(args, test.want) {
+			t.Errorf("%s parsed as %q, want %q", test.line, args, test.want)
+		}
+	}
+}
+
+func TestExpandQuotedValues(t *testing.T) {
+	c := New("test")
+	c.SetVariable("quote", `say "hi" \o/`)
+
+	for _, line := range []string{"echo $quote", `echo "$quote"`} {
+		args, err := c.parse(line)
+		if err != nil {
+			t.Fatalf("%s: %v", line, err)
+		}
+
+		if want := []string{"echo", `say "hi" \o/`}; !slices.Equal(args, want) {
+			t.Errorf("%s parsed as %q, want %q", line, args, want)
+		}
+	}
+}
+
+func TestCaptureExecuting(t *testing.T) {
+	c := New("test")
+
+	var executing []bool
+
+	c.ActiveMenu().SetCommands(func() *cobra.Command {
+		root := &cobra.Command{}
+
+		root.AddCommand(&cobra.Command{
+			Use: "hostname",
+			Run: func(*cobra.Command, []string) { fmt.Println("web") },
+		})
+
+		// Like `set host = hostname`, capturing twice.
+		root.AddCommand(&cobra.Command{
+			Use: "set",
+			RunE: func(cmd *cobra.Command, _ []string) error {
+				for range 2 {
+					value, err := c.Capture(cmd.Context(), []string{"hostname"})
+					if err != nil {
+						return err
+					}
+
+					c.SetVariable("host", value)
+
+					// The set command itself is still executing.
+					executing = append(executing, c.executing())
+				}
+
+				return nil
+			},
+		})
+
+		return root
+	})
+
+	captureStdout(t, func() {
+		c.ExecuteLine(context.Background(), "set")
+	})
+
+	if !slices.Equal(executing, []bool{true, true}) {
+		t.Errorf("executing states %v after captures, want the set command executing", executing)
+	}
+
+	if host, _ := c.Variable("host"); host != "web" {
+		t.Errorf("captured %v, want the command output", host)
+	}
+
+	if c.executing() {
+		t.Error("console still executing after the set command")
+	}
+}
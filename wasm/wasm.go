@@ -0,0 +1,267 @@
+// Package wasm loads console commands from WebAssembly modules, which are executed
+// in a sandbox (with wazero), and are portable across platforms and architectures.
+//
+// A plugin module must export its memory, along with the following functions:
+//
+//	alloc(size i32) i32        // Allocate size bytes in the module memory.
+//	describe() i64             // Return the command manifest (JSON), as (pointer << 32 | length).
+//	run(ptr i32, len i32) i32  // Run the command with its input (JSON), returning an exit code.
+//
+// The manifest describes the command and its flags:
+//
+//	{"name": "hello", "short": "Say hello", "flags": [
+//	    {"name": "loud", "shorthand": "l", "type": "bool", "usage": "Shout"},
+//	    {"name": "greeting", "type": "string", "default": "Hello"}
+//	]}
+//
+// The run input contains the positional arguments and the flag values (as strings):
+//
+//	{"args": ["world"], "flags": {"loud": "true", "greeting": "Hello"}}
+//
+// Modules can use WASI (preview 1) to write to the command output and error streams,
+// but have no access to the filesystem, network or environment. A new instance of
+// the module is used for each run, and the _initialize function is called if it is
+// exported (eg. by reactor modules). Plugin commands are added to the "plugins" group.
+package wasm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+
+	"github.com/reeflective/console"
+)
+
+// GroupID is the command group in which plugin commands are added.
+const GroupID = "plugins"
+
+// Manifest describes a plugin command, as returned by its describe function.
+type Manifest struct {
+	Name  string     `json:"name"`
+	Short string     `json:"short,omitempty"`
+	Long  string     `json:"long,omitempty"`
+	Flags []FlagSpec `json:"flags,omitempty"`
+}
+
+// FlagSpec describes a flag of a plugin command.
+type FlagSpec struct {
+	Name      string `json:"name"`
+	Shorthand string `json:"shorthand,omitempty"`
+	Type      string `json:"type,omitempty"` // "string" (default) or "bool".
+	Usage     string `json:"usage,omitempty"`
+	Default   string `json:"default,omitempty"`
+}
+
+// input is the JSON input passed to the run function of a plugin.
+type input struct {
+	Args  []string          `json:"args"`
+	Flags map[string]string `json:"flags"`
+}
+
+// Loader compiles and runs WebAssembly plugins for a console application.
+type Loader struct {
+	app     *console.Console
+	runtime wazero.Runtime
+}
+
+// New creates a new plugin loader for the console application.
+// The context is used for compiling modules, and its Close method
+// should be called when plugins are not used anymore. Plugins are
+// stopped when the context of their command is done (eg. with Ctrl-C),
+// even if they never return (eg. stuck in a loop).
+func New(ctx context.Context, app *console.Console) *Loader {
+	config := wazero.NewRuntimeConfig().WithCloseOnContextDone(true)
+	runtime := wazero.NewRuntimeWithConfig(ctx, config)
+	wasi_snapshot_preview1.MustInstantiate(ctx, runtime)
+
+	return &Loader{
+		app:     app,
+		runtime: runtime,
+	}
+}
+
+// Close releases all the resources used by the plugins.
+func (l *Loader) Close(ctx context.Context) error {
+	return l.runtime.Close(ctx)
+}
+
+// LoadDir loads all the plugins (*.wasm files) of a directory, in lexical order.
+// A missing directory is not an error, and all plugins are loaded even if some
+// of them fail: their errors are returned together.
+func (l *Loader) LoadDir(ctx context.Context, dir string) error {
+	if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	plugins, err := filepath.Glob(filepath.Join(dir, "*.wasm"))
+	if err != nil {
+		return err
+	}
+
+	sort.Strings(plugins)
+
+	var errs []error
+
+	for _, plugin := range plugins {
+		if err := l.LoadFile(ctx, plugin); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// LoadFile compiles a plugin module, and adds its command to the active menu.
+func (l *Loader) LoadFile(ctx context.Context, path string) error {
+	code, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	compiled, err := l.runtime.CompileModule(ctx, code)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	manifest, err := l.describe(ctx, compiled)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	l.app.ActiveMenu().AddCommands(func() *cobra.Command {
+		return l.command(compiled, manifest)
+	})
+
+	return nil
+}
+
+// describe instantiates the module to read its manifest.
+func (l *Loader) describe(ctx context.Context, compiled wazero.CompiledModule) (*Manifest, error) {
+	module, err := l.instantiate(ctx, compiled, wazero.NewModuleConfig())
+	if err != nil {
+		return nil, err
+	}
+	defer module.Close(ctx)
+
+	describe := module.ExportedFunction("describe")
+	if describe == nil {
+		return nil, errors.New("the module does not export a describe function")
+	}
+
+	results, err := describe.Call(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ptr, length := uint32(results[0]>>32), uint32(results[0])
+
+	data, ok := module.Memory().Read(ptr, length)
+	if !ok {
+		return nil, errors.New("invalid manifest memory range")
+	}
+
+	manifest := &Manifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+
+	if manifest.Name == "" {
+		return nil, errors.New("the manifest has no command name")
+	}
+
+	return manifest, nil
+}
+
+// command returns the cobra command running the plugin.
+func (l *Loader) command(compiled wazero.CompiledModule, manifest *Manifest) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:           manifest.Name,
+		Short:         manifest.Short,
+		Long:          manifest.Long,
+		GroupID:       GroupID,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+
+	for _, flag := range manifest.Flags {
+		if flag.Type == "bool" {
+			cmd.Flags().BoolP(flag.Name, flag.Shorthand, flag.Default == "true", flag.Usage)
+		} else {
+			cmd.Flags().StringP(flag.Name, flag.Shorthand, flag.Default, flag.Usage)
+		}
+	}
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		run := input{Args: args, Flags: make(map[string]string)}
+
+		for _, flag := range manifest.Flags {
+			run.Flags[flag.Name] = cmd.Flags().Lookup(flag.Name).Value.String()
+		}
+
+		return l.run(cmd, compiled, run)
+	}
+
+	return cmd
+}
+
+// run runs the plugin command in a new module instance.
+func (l *Loader) run(cmd *cobra.Command, compiled wazero.CompiledModule, run input) error {
+	ctx := cmd.Context()
+
+	config := wazero.NewModuleConfig().
+		WithStdout(cmd.OutOrStdout()).
+		WithStderr(cmd.ErrOrStderr()).
+		WithArgs(cmd.Name())
+
+	module, err := l.instantiate(ctx, compiled, config)
+	if err != nil {
+		return err
+	}
+	defer module.Close(ctx)
+
+	data, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+
+	alloc, runFunc := module.ExportedFunction("alloc"), module.ExportedFunction("run")
+	if alloc == nil || runFunc == nil {
+		return errors.New("the plugin does not export the alloc and run functions")
+	}
+
+	results, err := alloc.Call(ctx, uint64(len(data)))
+	if err != nil {
+		return err
+	}
+
+	ptr := uint32(results[0])
+
+	if !module.Memory().Write(ptr, data) {
+		return errors.New("invalid input memory range")
+	}
+
+	results, err = runFunc.Call(ctx, uint64(ptr), uint64(len(data)))
+	if err != nil {
+		return err
+	}
+
+	if code := int32(results[0]); code != 0 {
+		return fmt.Errorf("%s: exited with code %d", cmd.Name(), code)
+	}
+
+	return nil
+}
+
+// instantiate creates a new, anonymous instance of the module, and initializes it.
+func (l *Loader) instantiate(ctx context.Context, compiled wazero.CompiledModule, config wazero.ModuleConfig) (api.Module, error) {
+	return l.runtime.InstantiateModule(ctx, compiled, config.WithName("").WithStartFunctions("_initialize"))
+}
@@ -0,0 +1,129 @@
+package wasm
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/reeflective/console"
+)
+
+// echoManifest is the manifest of the echo plugin module.
+const echoManifest = `{"name": "echo", "short": "Print the run input", "flags": [
+	{"name": "loud", "shorthand": "l", "type": "bool"},
+	{"name": "greeting", "default": "Hello"}]}`
+
+// echoModule returns a plugin module (hand-assembled, to not require a compiler)
+// whose command writes its JSON run input to the standard output, with WASI.
+func echoModule() []byte {
+	uleb := func(value int) []byte {
+		var data []byte
+
+		for ; value >= 0x80; value >>= 7 {
+			data = append(data, byte(value&0x7f|0x80))
+		}
+
+		return append(data, byte(value))
+	}
+
+	// Vectors and sections are prefixed with their length.
+	vec := func(items ...[]byte) []byte {
+		data := uleb(len(items))
+		for _, item := range items {
+			data = append(data, item...)
+		}
+
+		return data
+	}
+
+	sized := func(data []byte) []byte { return append(uleb(len(data)), data...) }
+	name := func(name string) []byte { return sized([]byte(name)) }
+	section := func(id byte, data []byte) []byte { return append([]byte{id}, sized(data)...) }
+	concat := func(parts ...[]byte) []byte {
+		var data []byte
+		for _, part := range parts {
+			data = append(data, part...)
+		}
+
+		return data
+	}
+
+	const (
+		i32, i64    = 0x7f, 0x7e
+		iovec, high = 0x80, 0x10 // The bytes of 2048 in signed LEB128: the iovec is written there, the count at 2056.
+	)
+
+	// The input is allocated at 4096, and the manifest is stored at 0.
+	run := concat(
+		[]byte{0x41, iovec, high, 0x20, 0, 0x36, 2, 0},                     // i32.store(2048, ptr)
+		[]byte{0x41, iovec + 4, high, 0x20, 1, 0x36, 2, 0},                 // i32.store(2052, len)
+		[]byte{0x41, 1, 0x41, iovec, high, 0x41, 1, 0x41, iovec + 8, high}, // fd_write(1, 2048, 1, 2056)
+		[]byte{0x10, 0, 0x1a, 0x41, 0, 0x0b},                               // drop the errno, and return 0.
+	)
+
+	return concat(
+		[]byte{0, 'a', 's', 'm', 1, 0, 0, 0},
+		section(1, vec(
+			[]byte{0x60, 4, i32, i32, i32, i32, 1, i32}, // fd_write
+			[]byte{0x60, 1, i32, 1, i32},                // alloc
+			[]byte{0x60, 0, 1, i64},                     // describe
+			[]byte{0x60, 2, i32, i32, 1, i32},           // run
+		)),
+		section(2, vec(concat(name("wasi_snapshot_preview1"), name("fd_write"), []byte{0, 0}))),
+		section(3, vec([]byte{1}, []byte{2}, []byte{3})),
+		section(5, vec([]byte{0, 1})),
+		section(7, vec(
+			concat(name("memory"), []byte{2, 0}),
+			concat(name("alloc"), []byte{0, 1}),
+			concat(name("describe"), []byte{0, 2}),
+			concat(name("run"), []byte{0, 3}),
+		)),
+		section(10, vec(
+			sized([]byte{0, 0x41, 0x80, 0x20, 0x0b}), // return 4096
+			sized(concat([]byte{0, 0x42}, uleb(len(echoManifest)), []byte{0x0b})),
+			sized(concat([]byte{0}, run)),
+		)),
+		section(11, vec(concat([]byte{0, 0x41, 0, 0x0b}, sized([]byte(echoManifest))))),
+	)
+}
+
+func TestLoadDir(t *testing.T) {
+	ctx := context.Background()
+
+	dir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(dir, "echo.wasm"), echoModule(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "broken.wasm"), []byte("not wasm"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	app := console.New("test")
+
+	loader := New(ctx, app)
+	defer loader.Close(ctx)
+
+	// Invalid plugins do not prevent the others from being loaded.
+	if err := loader.LoadDir(ctx, dir); err == nil || !strings.Contains(err.Error(), "broken.wasm") {
+		t.Errorf("loading an invalid plugin returned %v", err)
+	}
+
+	output, err := app.CaptureOutput(func() error {
+		return app.ActiveMenu().RunCommandLine(ctx, "echo -l world 'and you'")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := `{"args":["world","and you"],"flags":{"greeting":"Hello","loud":"true"}}`; output != want {
+		t.Errorf("plugin output = %q, want %q", output, want)
+	}
+
+	if err := loader.LoadDir(ctx, filepath.Join(dir, "missing")); err != nil {
+		t.Errorf("loading a missing directory returned %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package console
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rivo/uniseg"
+	"golang.org/x/term"
+)
+
+// Watch executes a command line (already split into words) in the active menu
+// every interval, until the context is canceled (eg. when the user presses Ctrl-C
+// while the watch is running). The output of each execution (including errors)
+// replaces the output of the previous one in place, below a short header.
+func (c *Console) Watch(ctx context.Context, args []string, interval time.Duration) error {
+	if len(args) == 0 {
+		return nil
+	}
+
+	if interval <= 0 {
+		return fmt.Errorf("invalid watch interval: %s", interval)
+	}
+
+	rows := 0
+
+	for {
+		output, err := captureOutput(func() error {
+			return c.activeMenu().RunCommandArgs(ctx, args)
+		})
+
+		// The command may have been interrupted.
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if err != nil {
+			output += fmt.Sprintf("Error: %s\n", err)
+		}
+
+		header := fmt.Sprintf("Every %s: %s\t%s\n\n", interval, strings.Join(args, " "), time.Now().Format(time.TimeOnly))
+		block := header + output
+
+		if !strings.HasSuffix(block, "\n") {
+			block += "\n"
+		}
+
+		// Replace the previous output block.
+		if rows > 0 {
+			fmt.Printf(seqCursorUpFmt, rows)
+			fmt.Print(seqCarriageReturn + seqClearScreenBelow)
+		}
+
+		fmt.Print(block)
+
+		rows = blockRows(block)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// blockRows returns the number of terminal rows used to display a block of text.
+func blockRows(block string) int {
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 {
+		width = 80
+	}
+
+	rows := 0
+
+	for _, line := range strings.Split(strings.TrimSuffix(block, "\n"), "\n") {
+		lineWidth := uniseg.StringWidth(strings.ReplaceAll(line, "\t", "        "))
+		rows += max(1, (lineWidth+width-1)/width)
+	}
+
+	return rows
+}
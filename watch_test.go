@@ -0,0 +1,68 @@
+package console
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func TestWatchExecuting(t *testing.T) {
+	c := New("test")
+	menu := c.ActiveMenu()
+
+	var (
+		executing []bool
+		cancel    context.CancelFunc
+	)
+
+	menu.SetCommands(func() *cobra.Command {
+		root := &cobra.Command{}
+
+		root.AddCommand(&cobra.Command{
+			Use: "target",
+			Run: func(*cobra.Command, []string) {
+				executing = append(executing, c.executing())
+
+				// Stop watching after a few iterations.
+				if len(executing) == 3 {
+					cancel()
+				}
+			},
+		})
+
+		root.AddCommand(&cobra.Command{
+			Use: "watch",
+			Run: func(cmd *cobra.Command, _ []string) {
+				var ctx context.Context
+				ctx, cancel = context.WithCancel(cmd.Context())
+
+				_ = c.Watch(ctx, []string{"target"}, time.Millisecond)
+
+				// The watch command itself is still executing.
+				executing = append(executing, c.executing())
+			},
+		})
+
+		return root
+	})
+
+	captureStdout(t, func() {
+		c.ExecuteLine(context.Background(), "watch")
+	})
+
+	if len(executing) != 4 {
+		t.Fatalf("executing states %v, want those of 3 iterations and of the watch", executing)
+	}
+
+	for i, running := range executing {
+		if !running {
+			t.Errorf("console not executing at step %d of %v", i, executing)
+		}
+	}
+
+	if c.executing() {
+		t.Error("console still executing after the watch command")
+	}
+}
@@ -0,0 +1,48 @@
+package console
+
+// Widget is a user-defined line editing command. It receives a copy of
+// the current input line and the cursor position, and returns the new line
+// and cursor position to use. This is the equivalent of a ZLE user widget.
+type Widget func(line []rune, cursor int) ([]rune, int)
+
+// RegisterWidget registers a custom editing command under the given name.
+// Once registered, the widget can be bound to key sequences like any other
+// readline command, either with the `bind` command or in the inputrc file:
+//
+//	"\C-x\C-u": my-widget
+//
+// If a widget or builtin command with the same name already exists, it is
+// overwritten. The line state before the widget is ran is saved in the undo
+// history, so that its effect can be reverted with undo. Like readline commands,
+// widgets are repeated with numeric arguments (eg. Alt-3 or universal-argument).
+func (c *Console) RegisterWidget(name string, widget Widget) {
+	if name == "" || widget == nil {
+		return
+	}
+
+	c.shell.Keymap.Register(map[string]func(){
+		name: func() {
+			c.runWidget(widget)
+		},
+	})
+}
+
+// runWidget calls the widget on the current line and updates both the
+// line and the cursor according to the results of the call. The widget
+// is called as many times as the numeric argument, if one is given.
+func (c *Console) runWidget(widget Widget) {
+	line := c.shell.Line()
+	cursor := c.shell.Cursor()
+
+	c.shell.History.Save()
+
+	for range c.repeatCount() {
+		current := make([]rune, len(*line))
+		copy(current, *line)
+
+		newLine, newPos := widget(current, cursor.Pos())
+
+		line.Set(newLine...)
+		cursor.Set(newPos)
+	}
+}
@@ -0,0 +1,165 @@
+package console
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/reeflective/readline"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"golang.org/x/term"
+)
+
+// CommandWizardKey should be used as a key to in a cobra.Annotation map.
+// Commands having it are run in wizard mode (see EnableWizard) when they
+// are given no arguments, or when the --interactive flag is used.
+const CommandWizardKey = "console-wizard"
+
+// wizardFlag is the flag added to wizard-enabled commands.
+const wizardFlag = "interactive"
+
+// flagEnumKey is the flag annotation key storing the values accepted by a flag.
+const flagEnumKey = "console-enum"
+
+// EnableWizard enables the wizard (interactive form) mode for a command: when it
+// is executed without arguments, or with the --interactive flag (added by this
+// function), the user is prompted for the value of each of its visible flags not
+// given on the command line, before the command is executed with them.
+//
+// Flags whose values are restricted with FlagEnum complete them, boolean ones
+// ask for a yes/no answer, and values are checked with the flag validators
+// (see ValidateFlag) before going to the next flag. Required flags (see
+// cobra.MarkFlagRequired) must be given a value, and others keep their
+// default value if left empty. Ctrl-C or Ctrl-D aborts the command.
+func EnableWizard(cmd *cobra.Command) {
+	if cmd.Annotations == nil {
+		cmd.Annotations = make(map[string]string)
+	}
+
+	cmd.Annotations[CommandWizardKey] = "true"
+
+	if cmd.Flags().Lookup(wizardFlag) == nil {
+		cmd.Flags().Bool(wizardFlag, false, "Prompt for the command flags")
+	}
+}
+
+// runWizard prompts the user for the flags of the target command if it is run in
+// wizard mode, and returns the command line arguments (and those of the target
+// command) with the values given by the user, and without the --interactive flag.
+func (c *Console) runWizard(target *cobra.Command, args, flagArgs []string) ([]string, []string, error) {
+	if target.Annotations[CommandWizardKey] == "" || !term.IsTerminal(int(os.Stdin.Fd())) {
+		return args, flagArgs, nil
+	}
+
+	interactive := len(flagArgs) == 0
+
+	for _, arg := range flagArgs {
+		if arg == "--"+wizardFlag || arg == "--"+wizardFlag+"=true" {
+			interactive = true
+		}
+	}
+
+	if !interactive {
+		return args, flagArgs, nil
+	}
+
+	defer c.resetFlags(target)
+
+	fmt.Printf("%s%s%s: %s\n", bold, strings.TrimSpace(target.CommandPath()), boldReset, tr("interactive mode (Ctrl-C to abort)"))
+
+	shell := c.newAskShell()
+
+	var values []string
+
+	var err error
+
+	target.LocalFlags().VisitAll(func(flag *pflag.Flag) {
+		if err != nil || flag.Hidden || flag.Deprecated != "" || flag.Name == wizardFlag || flag.Name == "help" {
+			return
+		}
+
+		if wizardFlagGiven(target, flag, flagArgs) {
+			return
+		}
+
+		var value string
+		if value, err = c.promptFlag(shell, flag); err == nil && value != "" {
+			values = append(values, "--"+flag.Name+"="+value)
+		}
+	})
+
+	if err != nil {
+		return args, flagArgs, err
+	}
+
+	return append(removeWizardFlag(args), values...), append(removeWizardFlag(flagArgs), values...), nil
+}
+
+// promptFlag prompts the user for the value of a flag until it is valid,
+// and returns it, or an empty value to keep the default one.
+func (c *Console) promptFlag(shell *readline.Shell, flag *pflag.Flag) (string, error) {
+	_, required := flag.Annotations[cobra.BashCompOneRequiredFlag]
+
+	question := Question{
+		Label:    "--" + flag.Name,
+		Usage:    flag.Usage,
+		Choices:  flag.Annotations[flagEnumKey],
+		Required: required,
+		Confirm:  flag.Value.Type() == "bool",
+		Validate: flag.Value.Set,
+	}
+
+	if flag.DefValue != "[]" && !question.Confirm {
+		question.Default = flag.DefValue
+	}
+
+	value, err := c.ask(shell, question)
+
+	// The default value of the flag is kept by not giving it.
+	if value == flag.DefValue {
+		return "", err
+	}
+
+	return value, err
+}
+
+// wizardFlagGiven returns true if the flag is given in the command line arguments,
+// including in a group of stacked short flags.
+func wizardFlagGiven(cmd *cobra.Command, flag *pflag.Flag, args []string) bool {
+	for _, arg := range args {
+		if arg == "--" {
+			return false
+		}
+
+		name, _, _ := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+
+		switch {
+		case strings.HasPrefix(arg, "--"):
+			if name == flag.Name {
+				return true
+			}
+		case strings.HasPrefix(arg, "-") && flag.Shorthand != "":
+			flags, _ := stackedFlags(cmd, arg)
+			if slices.Contains(flags, flag) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// removeWizardFlag removes the --interactive flag from command line arguments.
+func removeWizardFlag(args []string) []string {
+	kept := make([]string, 0, len(args))
+
+	for _, arg := range args {
+		if arg != "--"+wizardFlag && arg != "--"+wizardFlag+"=true" {
+			kept = append(kept, arg)
+		}
+	}
+
+	return kept
+}